@@ -0,0 +1,53 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+// Aliases returns true if the given values are container values which are backed by the same root slab,
+// i.e. mutating one of the values also affects the other value.
+//
+// Optionals and ephemeral references are unwrapped.
+// Storage references are not dereferenced, as that requires an interpreter.
+// Empty containers which have not been allocated in storage yet never alias.
+//
+func Aliases(a, b Value) bool {
+	storageIDA, ok := containerRootStorageID(unwrapAliasedValue(a))
+	if !ok {
+		return false
+	}
+
+	storageIDB, ok := containerRootStorageID(unwrapAliasedValue(b))
+	if !ok {
+		return false
+	}
+
+	return storageIDA == storageIDB
+}
+
+func unwrapAliasedValue(value Value) Value {
+	for {
+		switch unwrapped := value.(type) {
+		case *SomeValue:
+			value = unwrapped.Value
+		case *EphemeralReferenceValue:
+			value = unwrapped.Value
+		default:
+			return value
+		}
+	}
+}
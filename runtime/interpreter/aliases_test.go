@@ -0,0 +1,100 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestAliases(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	newArray := func() *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(1),
+			NewIntValueFromInt64(2),
+		)
+	}
+
+	array := newArray().Transfer(
+		inter,
+		ReturnEmptyLocationRange,
+		atree.Address(address),
+		true,
+		nil,
+	)
+
+	storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(array))
+
+	stored := storage.ReadValue(inter, address, "test")
+
+	t.Run("borrowed", func(t *testing.T) {
+		borrowed, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		require.True(t, Aliases(stored, borrowed))
+		require.True(t, Aliases(borrowed, stored))
+	})
+
+	t.Run("reference", func(t *testing.T) {
+		reference := &EphemeralReferenceValue{
+			Value: stored,
+		}
+
+		require.True(t, Aliases(stored, reference))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		require.False(t, Aliases(stored, CopyValue(inter, stored)))
+	})
+
+	t.Run("equal", func(t *testing.T) {
+		require.False(t, Aliases(newArray(), newArray()))
+	})
+
+	t.Run("non-container", func(t *testing.T) {
+		value := NewIntValueFromInt64(1)
+
+		require.False(t, Aliases(value, value))
+	})
+}
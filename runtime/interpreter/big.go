@@ -73,3 +73,61 @@ func UnsignedBigIntToBigEndianBytes(bigInt *big.Int) []byte {
 		panic(errors.NewUnreachableError())
 	}
 }
+
+// MinimalByteWidth returns the minimal number of bytes needed
+// to store the current value of the given number in big-endian byte order.
+//
+// Unsigned values are encoded as their magnitude,
+// signed values are encoded in two's complement, so they include a sign bit.
+// Zero requires one byte.
+//
+// Fixed-point values are measured by their underlying integer representation.
+//
+func MinimalByteWidth(v NumberValue) int {
+	switch v := v.(type) {
+	case IntValue:
+		return len(SignedBigIntToBigEndianBytes(v.BigInt))
+	case Int8Value:
+		return len(SignedBigIntToBigEndianBytes(big.NewInt(int64(v))))
+	case Int16Value:
+		return len(SignedBigIntToBigEndianBytes(big.NewInt(int64(v))))
+	case Int32Value:
+		return len(SignedBigIntToBigEndianBytes(big.NewInt(int64(v))))
+	case Int64Value:
+		return len(SignedBigIntToBigEndianBytes(big.NewInt(int64(v))))
+	case Int128Value:
+		return len(SignedBigIntToBigEndianBytes(v.BigInt))
+	case Int256Value:
+		return len(SignedBigIntToBigEndianBytes(v.BigInt))
+	case Fix64Value:
+		return len(SignedBigIntToBigEndianBytes(big.NewInt(int64(v))))
+
+	case UIntValue:
+		return len(UnsignedBigIntToBigEndianBytes(v.BigInt))
+	case UInt8Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case UInt16Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case UInt32Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case UInt64Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case UInt128Value:
+		return len(UnsignedBigIntToBigEndianBytes(v.BigInt))
+	case UInt256Value:
+		return len(UnsignedBigIntToBigEndianBytes(v.BigInt))
+	case Word8Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case Word16Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case Word32Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case Word64Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+	case UFix64Value:
+		return len(UnsignedBigIntToBigEndianBytes(new(big.Int).SetUint64(uint64(v))))
+
+	default:
+		panic(errors.NewUnreachableError())
+	}
+}
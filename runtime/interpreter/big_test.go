@@ -0,0 +1,65 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2020 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+func TestMinimalByteWidth(t *testing.T) {
+
+	t.Parallel()
+
+	tests := map[string]struct {
+		value    NumberValue
+		expected int
+	}{
+		"UInt8 zero":       {UInt8Value(0), 1},
+		"UInt8 max":        {UInt8Value(math.MaxUint8), 1},
+		"UInt64 small":     {UInt64Value(200), 1},
+		"UInt64 two bytes": {UInt64Value(256), 2},
+		"UInt64 max":       {UInt64Value(math.MaxUint64), 8},
+		"Word32 three":     {Word32Value(0x10000), 3},
+		"Int8 positive":    {Int8Value(math.MaxInt8), 1},
+		"Int8 negative":    {Int8Value(math.MinInt8), 1},
+		"Int16 sign bit":   {Int16Value(200), 2},
+		"Int64 minus one":  {Int64Value(-1), 1},
+		"Int64 max":        {Int64Value(math.MaxInt64), 8},
+		"Int small":        {NewIntValueFromInt64(127), 1},
+		"Int sign bit":     {NewIntValueFromInt64(128), 2},
+		"Int negative":     {NewIntValueFromInt64(-129), 2},
+		"UInt large":       {NewUIntValueFromBigInt(new(big.Int).Lsh(big.NewInt(1), 100)), 13},
+		"Int256 min":       {NewInt256ValueFromBigInt(sema.Int256TypeMinIntBig), 32},
+		"UInt256 small":    {NewUInt256ValueFromUint64(200), 1},
+		"UInt256 max":      {NewUInt256ValueFromBigInt(sema.UInt256TypeMaxIntBig), 32},
+		"UFix64 one":       {UFix64Value(sema.Fix64Factor), 4},
+		"Fix64 negative":   {Fix64Value(-sema.Fix64Factor), 4},
+	}
+
+	for name, test := range tests {
+		assert.Equal(t, test.expected, MinimalByteWidth(test.value), name)
+	}
+}
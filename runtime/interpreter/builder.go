@@ -0,0 +1,168 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// ValueBuilder allows constructing nested values concisely.
+//
+// All values built by the builder are owned by the builder's owner,
+// and nested values are transferred into their containers.
+//
+type ValueBuilder struct {
+	interpreter *Interpreter
+	owner       common.Address
+}
+
+func NewBuilder(interpreter *Interpreter, owner common.Address) *ValueBuilder {
+	return &ValueBuilder{
+		interpreter: interpreter,
+		owner:       owner,
+	}
+}
+
+// Array returns a builder for a variable-sized array with the given element type.
+//
+func (b *ValueBuilder) Array(elementType StaticType) *ArrayBuilder {
+	return b.ArrayWithType(
+		VariableSizedStaticType{
+			Type: elementType,
+		},
+	)
+}
+
+// ArrayWithType returns a builder for an array with the given array type.
+//
+func (b *ValueBuilder) ArrayWithType(arrayType ArrayStaticType) *ArrayBuilder {
+	return &ArrayBuilder{
+		builder:   b,
+		arrayType: arrayType,
+	}
+}
+
+// Dictionary returns a builder for a dictionary with the given key and value types.
+//
+func (b *ValueBuilder) Dictionary(keyType, valueType StaticType) *DictionaryBuilder {
+	return &DictionaryBuilder{
+		builder: b,
+		dictionaryType: DictionaryStaticType{
+			KeyType:   keyType,
+			ValueType: valueType,
+		},
+	}
+}
+
+// Composite returns a builder for a composite with the given location, qualified identifier, and kind.
+//
+func (b *ValueBuilder) Composite(
+	location common.Location,
+	qualifiedIdentifier string,
+	kind common.CompositeKind,
+) *CompositeBuilder {
+	return &CompositeBuilder{
+		builder:             b,
+		location:            location,
+		qualifiedIdentifier: qualifiedIdentifier,
+		kind:                kind,
+	}
+}
+
+// ArrayBuilder
+
+type ArrayBuilder struct {
+	builder   *ValueBuilder
+	arrayType ArrayStaticType
+	elements  []Value
+}
+
+// Add appends the given elements to the array.
+//
+func (b *ArrayBuilder) Add(elements ...Value) *ArrayBuilder {
+	b.elements = append(b.elements, elements...)
+	return b
+}
+
+func (b *ArrayBuilder) Build() *ArrayValue {
+	return NewArrayValue(
+		b.builder.interpreter,
+		b.arrayType,
+		b.builder.owner,
+		b.elements...,
+	)
+}
+
+// DictionaryBuilder
+
+type DictionaryBuilder struct {
+	builder        *ValueBuilder
+	dictionaryType DictionaryStaticType
+	keysAndValues  []Value
+}
+
+// Add adds the given entry to the dictionary.
+//
+func (b *DictionaryBuilder) Add(key, value Value) *DictionaryBuilder {
+	b.keysAndValues = append(b.keysAndValues, key, value)
+	return b
+}
+
+func (b *DictionaryBuilder) Build() *DictionaryValue {
+	return NewDictionaryValueWithAddress(
+		b.builder.interpreter,
+		b.dictionaryType,
+		b.builder.owner,
+		b.keysAndValues...,
+	)
+}
+
+// CompositeBuilder
+
+type CompositeBuilder struct {
+	builder             *ValueBuilder
+	location            common.Location
+	qualifiedIdentifier string
+	kind                common.CompositeKind
+	fields              []CompositeField
+}
+
+// Field sets the field with the given name to the given value.
+//
+func (b *CompositeBuilder) Field(name string, value Value) *CompositeBuilder {
+	b.fields = append(
+		b.fields,
+		CompositeField{
+			Name:  name,
+			Value: value,
+		},
+	)
+	return b
+}
+
+func (b *CompositeBuilder) Build() *CompositeValue {
+	return NewCompositeValue(
+		b.builder.interpreter,
+		b.location,
+		b.qualifiedIdentifier,
+		b.kind,
+		b.fields,
+		b.builder.owner,
+	)
+}
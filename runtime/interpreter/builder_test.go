@@ -0,0 +1,158 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestValueBuilder(t *testing.T) {
+
+	t.Parallel()
+
+	owner := common.Address{0x1}
+
+	t.Run("array", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		expected := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			owner,
+			NewIntValueFromInt64(1),
+			NewIntValueFromInt64(2),
+		)
+
+		actual := NewBuilder(inter, owner).
+			Array(PrimitiveStaticTypeInt).
+			Add(NewIntValueFromInt64(1)).
+			Add(NewIntValueFromInt64(2)).
+			Build()
+
+		utils.RequireValuesEqual(t, inter, expected, actual)
+		require.Equal(t, owner, actual.GetOwner())
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		expected := NewDictionaryValueWithAddress(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeBool,
+			},
+			owner,
+			NewStringValue("a"), BoolValue(true),
+			NewStringValue("b"), BoolValue(false),
+		)
+
+		actual := NewBuilder(inter, owner).
+			Dictionary(PrimitiveStaticTypeString, PrimitiveStaticTypeBool).
+			Add(NewStringValue("a"), BoolValue(true)).
+			Add(NewStringValue("b"), BoolValue(false)).
+			Build()
+
+		utils.RequireValuesEqual(t, inter, expected, actual)
+		require.Equal(t, owner, actual.GetOwner())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		elementsType := VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		}
+
+		dictionaryType := DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: elementsType,
+		}
+
+		expected := NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Test",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name:  "name",
+					Value: NewStringValue("test"),
+				},
+				{
+					Name: "xs",
+					Value: NewDictionaryValueWithAddress(
+						inter,
+						dictionaryType,
+						owner,
+						NewStringValue("a"),
+						NewArrayValue(
+							inter,
+							elementsType,
+							owner,
+							NewIntValueFromInt64(1),
+							NewIntValueFromInt64(2),
+						),
+					),
+				},
+			},
+			owner,
+		)
+
+		b := NewBuilder(inter, owner)
+
+		actual := b.Composite(utils.TestLocation, "Test", common.CompositeKindStructure).
+			Field("name", NewStringValue("test")).
+			Field(
+				"xs",
+				b.Dictionary(PrimitiveStaticTypeString, elementsType).
+					Add(
+						NewStringValue("a"),
+						b.Array(PrimitiveStaticTypeInt).
+							Add(
+								NewIntValueFromInt64(1),
+								NewIntValueFromInt64(2),
+							).
+							Build(),
+					).
+					Build(),
+			).
+			Build()
+
+		utils.RequireValuesEqual(t, inter, expected, actual)
+		require.Equal(t, owner, actual.GetOwner())
+	})
+}
@@ -0,0 +1,148 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sort"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// Canonicalize returns the canonical form of the given value,
+// so that equal values have a single encoding, e.g. before hashing them or comparing their encodings.
+//
+// Strings are normalized to NFC, which is also used when comparing strings.
+// Number values are already canonical, as big integers are always normalized,
+// i.e. they never have redundant leading zero bytes.
+// Containers are canonicalized recursively, and the fields of composites are sorted by name.
+//
+// Canonicalized containers are new values which are not owned by any account,
+// the given value is unchanged.
+// If the keys of a dictionary are equal after canonicalization,
+// a DuplicateDictionaryKeyError is returned.
+//
+func Canonicalize(interpreter *Interpreter, value Value) (Value, error) {
+	switch value := value.(type) {
+	case *StringValue:
+		return NewStringValue(value.NormalForm()), nil
+
+	case *SomeValue:
+		innerValue, err := Canonicalize(interpreter, value.Value)
+		if err != nil {
+			return nil, err
+		}
+		return NewSomeValueNonCopying(innerValue), nil
+
+	case *ArrayValue:
+		elements := make([]Value, 0, value.Count())
+
+		var err error
+		value.Iterate(func(element Value) (resume bool) {
+			element, err = Canonicalize(interpreter, element)
+			if err != nil {
+				return false
+			}
+			elements = append(elements, element)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewArrayValue(
+			interpreter,
+			value.Type,
+			common.Address{},
+			elements...,
+		), nil
+
+	case *DictionaryValue:
+		keysAndValues := make([]Value, 0, value.Count()*2)
+
+		var err error
+		value.Iterate(func(key, value Value) (resume bool) {
+			key, err = Canonicalize(interpreter, key)
+			if err != nil {
+				return false
+			}
+
+			value, err = Canonicalize(interpreter, value)
+			if err != nil {
+				return false
+			}
+
+			keysAndValues = append(keysAndValues, key, value)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if key, ok := findDuplicateDictionaryKey(interpreter, keysAndValues); ok {
+			return nil, DuplicateDictionaryKeyError{
+				Key: key,
+			}
+		}
+
+		return NewDictionaryValue(
+			interpreter,
+			value.Type,
+			keysAndValues...,
+		), nil
+
+	case *CompositeValue:
+		var fields []CompositeField
+
+		var err error
+		value.ForEachField(func(name string, fieldValue Value) {
+			if err != nil {
+				return
+			}
+
+			fieldValue, err = Canonicalize(interpreter, fieldValue)
+			if err != nil {
+				return
+			}
+
+			fields = append(fields, CompositeField{
+				Name:  name,
+				Value: fieldValue,
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(fields, func(i, j int) bool {
+			return fields[i].Name < fields[j].Name
+		})
+
+		return NewCompositeValue(
+			interpreter,
+			value.Location,
+			value.QualifiedIdentifier,
+			value.Kind,
+			fields,
+			common.Address{},
+		), nil
+
+	default:
+		return value, nil
+	}
+}
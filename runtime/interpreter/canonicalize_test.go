@@ -0,0 +1,117 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+)
+
+func TestCanonicalize(t *testing.T) {
+
+	t.Parallel()
+
+	// "é" as a single code point, and as "e" followed by a combining acute accent
+
+	const composed = "\u00e9"
+	const decomposed = "e\u0301"
+
+	newValue := func(inter *Interpreter, str string) Value {
+		return NewSomeValueNonCopying(
+			NewDictionaryValue(
+				inter,
+				DictionaryStaticType{
+					KeyType: PrimitiveStaticTypeString,
+					ValueType: VariableSizedStaticType{
+						Type: PrimitiveStaticTypeAnyStruct,
+					},
+				},
+				NewStringValue(str),
+				NewArrayValue(
+					inter,
+					VariableSizedStaticType{
+						Type: PrimitiveStaticTypeAnyStruct,
+					},
+					common.Address{},
+					NewStringValue(str),
+					NewIntValueFromInt64(42),
+				),
+			),
+		)
+	}
+
+	t.Run("equal values", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		value1 := newValue(inter, composed)
+		value2 := newValue(inter, decomposed)
+
+		encoded1, err := MarshalValue(inter, value1)
+		require.NoError(t, err)
+
+		encoded2, err := MarshalValue(inter, value2)
+		require.NoError(t, err)
+
+		require.NotEqual(t, encoded1, encoded2)
+
+		canonical1, err := Canonicalize(inter, value1)
+		require.NoError(t, err)
+
+		canonical2, err := Canonicalize(inter, value2)
+		require.NoError(t, err)
+
+		encoded1, err = MarshalValue(inter, canonical1)
+		require.NoError(t, err)
+
+		encoded2, err = MarshalValue(inter, canonical2)
+		require.NoError(t, err)
+
+		require.Equal(t, encoded1, encoded2)
+	})
+
+	t.Run("duplicate keys", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		dictionary := NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeInt,
+			},
+			NewStringValue(composed), NewIntValueFromInt64(1),
+			NewStringValue(decomposed), NewIntValueFromInt64(2),
+		)
+
+		_, err := Canonicalize(inter, dictionary)
+
+		var duplicateErr DuplicateDictionaryKeyError
+		require.True(t, errors.As(err, &duplicateErr))
+	})
+}
@@ -0,0 +1,217 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// CanonicalJSON returns the canonical JSON encoding of the given value,
+// so that equal values have byte-identical encodings, e.g. for content hashing.
+//
+// The encoding contains no whitespace, and:
+//   - Numbers are encoded as JSON strings of their decimal representation,
+//     e.g. "42", "-1", or "1.50000000"
+//   - Booleans are encoded as JSON booleans, nil and void as null
+//   - Strings are encoded as JSON strings, addresses, paths, capabilities, links, and types
+//     as JSON strings of their representation
+//   - Optionals are encoded as their inner value
+//   - Arrays are encoded as JSON arrays
+//   - Dictionaries are encoded as JSON arrays of [key, value] pairs,
+//     sorted by the canonical encoding of the keys
+//   - Composites are encoded as JSON objects with the type ID and the fields,
+//     which are sorted by name
+//
+// Other values, e.g. functions or references, cannot be encoded.
+//
+func CanonicalJSON(interpreter *Interpreter, value Value) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := writeCanonicalJSON(interpreter, &buffer, value)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeCanonicalJSON(interpreter *Interpreter, buffer *bytes.Buffer, value Value) error {
+	switch value := value.(type) {
+	case NilValue, VoidValue:
+		buffer.WriteString("null")
+
+	case BoolValue:
+		if value {
+			buffer.WriteString("true")
+		} else {
+			buffer.WriteString("false")
+		}
+
+	case *SomeValue:
+		return writeCanonicalJSON(interpreter, buffer, value.Value)
+
+	case *StringValue:
+		return writeCanonicalJSONString(buffer, value.Str)
+
+	case NumberValue:
+		return writeCanonicalJSONString(buffer, value.String())
+
+	case AddressValue:
+		return writeCanonicalJSONString(buffer, value.String())
+
+	case PathValue:
+		return writeCanonicalJSONString(buffer, value.String())
+
+	case *CapabilityValue:
+		return writeCanonicalJSONString(buffer, value.String())
+
+	case LinkValue:
+		return writeCanonicalJSONString(buffer, value.String())
+
+	case TypeValue:
+		return writeCanonicalJSONString(buffer, value.String())
+
+	case *ArrayValue:
+		buffer.WriteByte('[')
+
+		var err error
+		index := 0
+		value.Iterate(func(element Value) (resume bool) {
+			if index > 0 {
+				buffer.WriteByte(',')
+			}
+			index++
+
+			err = writeCanonicalJSON(interpreter, buffer, element)
+			return err == nil
+		})
+		if err != nil {
+			return err
+		}
+
+		buffer.WriteByte(']')
+
+	case *DictionaryValue:
+		type entry struct {
+			key   []byte
+			value Value
+		}
+
+		entries := make([]entry, 0, value.Count())
+
+		var err error
+		value.Iterate(func(key, value Value) (resume bool) {
+			var encodedKey []byte
+			encodedKey, err = CanonicalJSON(interpreter, key)
+			if err != nil {
+				return false
+			}
+
+			entries = append(entries, entry{
+				key:   encodedKey,
+				value: value,
+			})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+
+		buffer.WriteByte('[')
+
+		for i, entry := range entries {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			buffer.WriteByte('[')
+			buffer.Write(entry.key)
+			buffer.WriteByte(',')
+
+			err := writeCanonicalJSON(interpreter, buffer, entry.value)
+			if err != nil {
+				return err
+			}
+
+			buffer.WriteByte(']')
+		}
+
+		buffer.WriteByte(']')
+
+	case *CompositeValue:
+		fields := map[string]Value{}
+		value.ForEachField(func(name string, value Value) {
+			fields[name] = value
+		})
+
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		buffer.WriteString(`{"fields":{`)
+
+		for i, name := range names {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+
+			err := writeCanonicalJSONString(buffer, name)
+			if err != nil {
+				return err
+			}
+
+			buffer.WriteByte(':')
+
+			err = writeCanonicalJSON(interpreter, buffer, fields[name])
+			if err != nil {
+				return err
+			}
+		}
+
+		buffer.WriteString(`},"type":`)
+
+		err := writeCanonicalJSONString(buffer, string(value.TypeID()))
+		if err != nil {
+			return err
+		}
+
+		buffer.WriteByte('}')
+
+	default:
+		return fmt.Errorf("cannot encode value of type %T as canonical JSON", value)
+	}
+
+	return nil
+}
+
+func writeCanonicalJSONString(buffer *bytes.Buffer, s string) error {
+	encoded, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	buffer.Write(encoded)
+	return nil
+}
@@ -0,0 +1,192 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	newDictionary := func(keysAndValues ...Value) *DictionaryValue {
+		return NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeAnyStruct,
+			},
+			keysAndValues...,
+		)
+	}
+
+	newComposite := func(fields ...CompositeField) *CompositeValue {
+		return NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Test",
+			common.CompositeKindStructure,
+			fields,
+			common.Address{},
+		)
+	}
+
+	t.Run("equal values", func(t *testing.T) {
+
+		t.Parallel()
+
+		first := newComposite(
+			CompositeField{
+				Name:  "name",
+				Value: NewStringValue("a \"b\""),
+			},
+			CompositeField{
+				Name: "balances",
+				Value: newDictionary(
+					NewStringValue("x"), NewUInt256ValueFromUint64(1),
+					NewStringValue("y"), NilValue{},
+					NewStringValue("z"), NewSomeValueNonCopying(UFix64Value(150000000)),
+				),
+			},
+			CompositeField{
+				Name: "values",
+				Value: NewArrayValue(
+					inter,
+					VariableSizedStaticType{
+						Type: PrimitiveStaticTypeAnyStruct,
+					},
+					common.Address{},
+					NewIntValueFromInt64(-1),
+					BoolValue(true),
+					AddressValue{0x1},
+					PathValue{
+						Domain:     common.PathDomainStorage,
+						Identifier: "foo",
+					},
+				),
+			},
+		)
+
+		second := newComposite(
+			CompositeField{
+				Name: "values",
+				Value: NewArrayValue(
+					inter,
+					VariableSizedStaticType{
+						Type: PrimitiveStaticTypeAnyStruct,
+					},
+					common.Address{},
+					NewIntValueFromInt64(-1),
+					BoolValue(true),
+					AddressValue{0x1},
+					PathValue{
+						Domain:     common.PathDomainStorage,
+						Identifier: "foo",
+					},
+				),
+			},
+			CompositeField{
+				Name: "balances",
+				Value: newDictionary(
+					NewStringValue("z"), NewSomeValueNonCopying(UFix64Value(150000000)),
+					NewStringValue("x"), NewUInt256ValueFromUint64(1),
+					NewStringValue("y"), NilValue{},
+				),
+			},
+			CompositeField{
+				Name:  "name",
+				Value: NewStringValue("a \"b\""),
+			},
+		)
+
+		firstJSON, err := CanonicalJSON(inter, first)
+		require.NoError(t, err)
+
+		secondJSON, err := CanonicalJSON(inter, second)
+		require.NoError(t, err)
+
+		require.Equal(t, firstJSON, secondJSON)
+
+		require.Equal(t,
+			`{"fields":{`+
+				`"balances":[["x","1"],["y",null],["z","1.50000000"]],`+
+				`"name":"a \"b\"",`+
+				`"values":["-1",true,"0x0100000000000000","/storage/foo"]`+
+				`},"type":"S.test.Test"}`,
+			string(firstJSON),
+		)
+	})
+
+	t.Run("capabilities, links, and types", func(t *testing.T) {
+
+		t.Parallel()
+
+		path := PathValue{
+			Domain:     common.PathDomainStorage,
+			Identifier: "foo",
+		}
+
+		for _, value := range []Value{
+			NewUnauthorizedCapability(AddressValue{0x1}, path, PrimitiveStaticTypeInt),
+			LinkValue{
+				TargetPath: path,
+				Type:       PrimitiveStaticTypeInt,
+			},
+			TypeValue{
+				Type: PrimitiveStaticTypeInt,
+			},
+		} {
+			expected, err := json.Marshal(value.String())
+			require.NoError(t, err)
+
+			actual, err := CanonicalJSON(inter, value)
+			require.NoError(t, err)
+
+			require.Equal(t, string(expected), string(actual))
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+
+		t.Parallel()
+
+		function := NewHostFunctionValue(
+			func(_ Invocation) Value {
+				return VoidValue{}
+			},
+			&sema.FunctionType{
+				ReturnTypeAnnotation: sema.NewTypeAnnotation(sema.VoidType),
+			},
+		)
+
+		_, err := CanonicalJSON(inter, function)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,84 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/onflow/atree"
+)
+
+// CodecProfiler is a function that is called after a storable was encoded or decoded,
+// with the operation (see CodecOperationEncode, CodecOperationDecode, and CodecOperationSize),
+// the number of encoded bytes, and the duration of the operation.
+//
+type CodecProfiler func(op string, bytes int, dur time.Duration)
+
+const (
+	// CodecOperationEncode is the operation of encoding a storable, see EncodeStorable
+	CodecOperationEncode = "encode"
+	// CodecOperationDecode is the operation of decoding a storable, see DecodeStorable
+	CodecOperationDecode = "decode"
+	// CodecOperationSize is the operation of determining the encoded size of a storable, see StorableSize
+	CodecOperationSize = "size"
+)
+
+// codecProfiler holds the current CodecProfiler, which is nil if profiling is disabled.
+//
+// The profiler is process-wide, as storables are encoded and decoded
+// independent of an interpreter. It is stored atomically,
+// as it may be set while other goroutines encode and decode storables.
+//
+var codecProfiler atomic.Value
+
+// loadCodecProfiler returns the current CodecProfiler, or nil if profiling is disabled.
+//
+func loadCodecProfiler() CodecProfiler {
+	profiler, _ := codecProfiler.Load().(CodecProfiler)
+	return profiler
+}
+
+// SetCodecProfiler sets the function that is called around the encoding and decoding of storables.
+// Passing nil disables profiling, which is the default.
+//
+// Slabs decoded by atree decode their elements using DecodeStorable, so their decoding is profiled.
+// Slabs encoded by atree, e.g. when a persistent storage is committed, are not profiled,
+// only encodings performed using EncodeStorable and StorableSize.
+//
+func SetCodecProfiler(profiler CodecProfiler) {
+	codecProfiler.Store(profiler)
+}
+
+// EncodeStorable returns the encoding of the given storable, or slab.
+//
+func EncodeStorable(storable atree.Storable) ([]byte, error) {
+	profiler := loadCodecProfiler()
+	if profiler == nil {
+		return atree.Encode(storable, CBOREncMode)
+	}
+
+	start := time.Now()
+
+	data, err := atree.Encode(storable, CBOREncMode)
+
+	profiler(CodecOperationEncode, len(data), time.Since(start))
+
+	return data, err
+}
@@ -0,0 +1,167 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"math"
+	"sort"
+
+	"github.com/onflow/atree"
+)
+
+// CompactPolicy determines which stored values are rewritten by InMemoryStorage.Compact.
+//
+type CompactPolicy struct {
+	// MinFillRatio is the minimum ratio of the number of slabs a stored value
+	// needs at least, to the number of slabs it actually occupies.
+	// Values with a lower fill ratio are rewritten.
+	MinFillRatio float64
+}
+
+// DefaultCompactPolicy only rewrites values which occupy
+// more than twice the number of slabs they need.
+//
+var DefaultCompactPolicy = CompactPolicy{
+	MinFillRatio: 0.5,
+}
+
+// Compact rewrites the stored values which are fragmented according to the given policy,
+// i.e. which are stored in more slabs than necessary, into densely packed slabs.
+// The contents of the values are unchanged.
+//
+// The interpreter must use this storage. Compact returns the number of rewritten values.
+//
+func (i InMemoryStorage) Compact(interpreter *Interpreter, policy CompactPolicy) (int, error) {
+
+	storageKeys := make([]StorageKey, 0, len(i.AccountStorage))
+	for storageKey := range i.AccountStorage {
+		storageKeys = append(storageKeys, storageKey)
+	}
+
+	// Rewrite in a deterministic order, so storage IDs are allocated deterministically
+
+	sort.Slice(storageKeys, func(i, j int) bool {
+		return storageKeys[i].IsLess(storageKeys[j])
+	})
+
+	compacted := 0
+
+	for _, storageKey := range storageKeys {
+		storable := i.AccountStorage[storageKey]
+
+		fillRatio, err := i.fillRatio(storable)
+		if err != nil {
+			return compacted, err
+		}
+
+		if fillRatio >= policy.MinFillRatio {
+			continue
+		}
+
+		value := StoredValue(storable, i)
+
+		compactedValue := value.Clone(interpreter)
+
+		value.DeepRemove(interpreter)
+		interpreter.RemoveReferencedSlab(storable)
+
+		compactedStorable, err := compactedValue.Storable(
+			i,
+			atree.Address(storageKey.Address),
+			math.MaxUint64,
+		)
+		if err != nil {
+			return compacted, err
+		}
+
+		i.AccountStorage[storageKey] = compactedStorable
+
+		compacted++
+	}
+
+	return compacted, nil
+}
+
+// fillRatio returns the ratio of the number of slabs needed at least
+// to store the slabs referenced by the given storable, to their actual number.
+//
+// The fill ratio is at most 1. Inlined storables and values stored in a single slab
+// are not fragmented and have a fill ratio of 1.
+//
+func (i InMemoryStorage) fillRatio(storable atree.Storable) (float64, error) {
+	var slabCount int
+	var totalSize uint64
+
+	var walk func(storable atree.Storable) error
+	walk = func(storable atree.Storable) error {
+		storageID, ok := referencedStorageID(storable)
+		if !ok {
+			for _, child := range storable.ChildStorables() {
+				err := walk(child)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		slab, ok, err := i.Retrieve(storageID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		slabCount++
+		totalSize += uint64(slab.ByteSize())
+
+		for _, child := range slab.ChildStorables() {
+			err := walk(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err := walk(storable)
+	if err != nil {
+		return 0, err
+	}
+
+	if slabCount == 0 {
+		return 1, nil
+	}
+
+	// The maximum inline element size is half of the target slab size,
+	// excluding the slab's encoding overhead
+
+	targetSlabSize := 2 * atree.MaxInlineArrayElementSize
+
+	minSlabCount := (totalSize + targetSlabSize - 1) / targetSlabSize
+	if minSlabCount == 0 {
+		minSlabCount = 1
+	}
+
+	// Densely packed slabs may exceed the target slab size
+
+	return math.Min(1, float64(minSlabCount)/float64(slabCount)), nil
+}
@@ -0,0 +1,145 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestStorageCompact(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	const entryCount = 1000
+
+	newDictionary := func() *DictionaryValue {
+		keysAndValues := make([]Value, 0, entryCount*2)
+		for i := 0; i < entryCount; i++ {
+			keysAndValues = append(
+				keysAndValues,
+				NewStringValue(fmt.Sprintf("key%d", i)),
+				NewStringValue(fmt.Sprintf("value%d", i)),
+			)
+		}
+
+		return NewDictionaryValueWithAddress(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeString,
+			},
+			address,
+			keysAndValues...,
+		)
+	}
+
+	// Fragment a dictionary by removing most of its entries
+
+	fragmented := newDictionary()
+	for i := 0; i < entryCount; i++ {
+		if i%5 == 0 {
+			continue
+		}
+		fragmented.Remove(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+		)
+	}
+
+	storage.WriteValue(inter, address, "fragmented", NewSomeValueNonCopying(fragmented))
+	storage.WriteValue(inter, address, "dense", NewSomeValueNonCopying(newDictionary()))
+
+	fragmentedKey := StorageKey{Address: address, Key: "fragmented"}
+	denseKey := StorageKey{Address: address, Key: "dense"}
+
+	fragmentedStorable := storage.AccountStorage[fragmentedKey]
+	denseStorable := storage.AccountStorage[denseKey]
+
+	slabCount := storage.Count()
+
+	// The default policy is conservative
+
+	compacted, err := storage.Compact(inter, DefaultCompactPolicy)
+	require.NoError(t, err)
+	require.Equal(t, 0, compacted)
+
+	compacted, err = storage.Compact(inter, CompactPolicy{MinFillRatio: 0.8})
+	require.NoError(t, err)
+	require.Equal(t, 1, compacted)
+
+	// Only the fragmented dictionary was rewritten, into fewer slabs
+
+	require.NotEqual(t, fragmentedStorable, storage.AccountStorage[fragmentedKey])
+	require.Equal(t, denseStorable, storage.AccountStorage[denseKey])
+	require.Less(t, storage.Count(), slabCount)
+
+	require.NoError(t, storage.CheckHealth())
+
+	// The contents are unchanged
+
+	compactedValue := storage.ReadValue(inter, address, "fragmented").(*SomeValue).Value
+	compactedDictionary := compactedValue.(*DictionaryValue)
+	require.Equal(t, entryCount/5, compactedDictionary.Count())
+
+	for i := 0; i < entryCount; i++ {
+		value, ok := compactedDictionary.Get(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+		)
+
+		if i%5 != 0 {
+			require.False(t, ok)
+			continue
+		}
+
+		require.True(t, ok)
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			NewStringValue(fmt.Sprintf("value%d", i)),
+			value,
+		)
+	}
+
+	// Compacted values are not rewritten again
+
+	compacted, err = storage.Compact(inter, CompactPolicy{MinFillRatio: 0.8})
+	require.NoError(t, err)
+	require.Equal(t, 0, compacted)
+}
@@ -0,0 +1,94 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sort"
+)
+
+// ApplyPatch sets the fields of the composite to the values of the given patch, by field name.
+// The values are transferred into the composite, and the replaced values are deep-removed.
+//
+// If the composite does not have a field of the patch, an UnknownCompositeFieldError is returned,
+// and no field is set. See ApplyPatchCreatingMissing to add such fields instead.
+//
+func (v *CompositeValue) ApplyPatch(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	patch map[string]Value,
+) error {
+	return v.applyPatch(interpreter, getLocationRange, patch, false)
+}
+
+// ApplyPatchCreatingMissing is like ApplyPatch,
+// but adds the fields of the patch which the composite does not have.
+//
+func (v *CompositeValue) ApplyPatchCreatingMissing(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	patch map[string]Value,
+) error {
+	return v.applyPatch(interpreter, getLocationRange, patch, true)
+}
+
+func (v *CompositeValue) applyPatch(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	patch map[string]Value,
+	createMissing bool,
+) error {
+
+	// Set the fields in a deterministic order,
+	// so storage IDs are allocated deterministically
+
+	names := make([]string, 0, len(patch))
+	for name := range patch {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Check all fields before setting any,
+	// so the composite is unchanged if the patch is invalid
+
+	if !createMissing {
+		for _, name := range names {
+			exists, err := v.dictionary.Has(
+				stringAtreeComparator,
+				stringAtreeHashInput,
+				stringAtreeValue(name),
+			)
+			if err != nil {
+				panic(ExternalError{err})
+			}
+
+			if !exists {
+				return UnknownCompositeFieldError{
+					TypeID:    v.TypeID(),
+					FieldName: name,
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		v.SetMember(interpreter, getLocationRange, name, patch[name])
+	}
+
+	return nil
+}
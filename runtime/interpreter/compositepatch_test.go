@@ -0,0 +1,194 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestCompositeValueApplyPatch(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	newStringArray := func(inter *Interpreter, address common.Address, elements ...Value) *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			address,
+			elements...,
+		)
+	}
+
+	newComposite := func(t *testing.T) (InMemoryStorage, *Interpreter, *CompositeValue) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		composite := NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Test",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name:  "a",
+					Value: newStringArray(inter, address, NewStringValue("a")),
+				},
+				{
+					Name:  "b",
+					Value: NewStringValue("b"),
+				},
+				{
+					Name:  "c",
+					Value: UInt8Value(1),
+				},
+			},
+			address,
+		)
+
+		return storage, inter, composite
+	}
+
+	t.Run("multiple fields", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter, composite := newComposite(t)
+
+		newArray := newStringArray(inter, common.Address{}, NewStringValue("x"), NewStringValue("y"))
+
+		slabCount := storage.Count()
+
+		err := composite.ApplyPatch(
+			inter,
+			ReturnEmptyLocationRange,
+			map[string]Value{
+				"a": newArray,
+				"b": NewStringValue("z"),
+			},
+		)
+		require.NoError(t, err)
+
+		// The replaced array was removed, and the new array was transferred into the composite
+
+		require.Equal(t, slabCount, storage.Count())
+
+		patchedArray := composite.GetField(inter, ReturnEmptyLocationRange, "a").(*ArrayValue)
+		require.Equal(t, atree.Address(address), patchedArray.StorageID().Address)
+
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			newStringArray(inter, common.Address{}, NewStringValue("x"), NewStringValue("y")),
+			patchedArray,
+		)
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			NewStringValue("z"),
+			composite.GetField(inter, ReturnEmptyLocationRange, "b"),
+		)
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			UInt8Value(1),
+			composite.GetField(inter, ReturnEmptyLocationRange, "c"),
+		)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter, composite := newComposite(t)
+
+		slabCount := storage.Count()
+
+		err := composite.ApplyPatch(
+			inter,
+			ReturnEmptyLocationRange,
+			map[string]Value{
+				"b": NewStringValue("z"),
+				"d": NewStringValue("d"),
+			},
+		)
+
+		var unknownFieldErr UnknownCompositeFieldError
+		require.True(t, errors.As(err, &unknownFieldErr))
+		require.Equal(t, "d", unknownFieldErr.FieldName)
+
+		// No field was set
+
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			NewStringValue("b"),
+			composite.GetField(inter, ReturnEmptyLocationRange, "b"),
+		)
+		require.Nil(t, composite.GetField(inter, ReturnEmptyLocationRange, "d"))
+		require.Equal(t, slabCount, storage.Count())
+	})
+
+	t.Run("create missing", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, inter, composite := newComposite(t)
+
+		err := composite.ApplyPatchCreatingMissing(
+			inter,
+			ReturnEmptyLocationRange,
+			map[string]Value{
+				"b": NewStringValue("z"),
+				"d": NewStringValue("d"),
+			},
+		)
+		require.NoError(t, err)
+
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			NewStringValue("z"),
+			composite.GetField(inter, ReturnEmptyLocationRange, "b"),
+		)
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			NewStringValue("d"),
+			composite.GetField(inter, ReturnEmptyLocationRange, "d"),
+		)
+	})
+}
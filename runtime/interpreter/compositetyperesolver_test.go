@@ -0,0 +1,169 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestCompositeTypeResolver(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	// Store a composite, using an interpreter which has the composite type declared
+
+	storage := NewInMemoryStorage()
+
+	elaboration := sema.NewElaboration()
+	elaboration.CompositeTypes[testCompositeValueType.ID()] = testCompositeValueType
+
+	inter, err := NewInterpreter(
+		&Program{
+			Elaboration: elaboration,
+		},
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	storage.WriteValue(
+		inter,
+		address,
+		"value",
+		NewSomeValueNonCopying(
+			NewCompositeValue(
+				inter,
+				utils.TestLocation,
+				"Test",
+				common.CompositeKindStructure,
+				[]CompositeField{
+					{
+						Name:  "value",
+						Value: NewStringValue("test"),
+					},
+				},
+				address,
+			),
+		),
+	)
+
+	// Load the composite using an interpreter
+	// at another location, which has no program for the location of the composite
+
+	readComposite := func(t *testing.T, options ...Option) (*Interpreter, *CompositeValue) {
+		inter, err := NewInterpreter(
+			nil,
+			common.StringLocation("other"),
+			append(
+				[]Option{WithStorage(storage)},
+				options...,
+			)...,
+		)
+		require.NoError(t, err)
+
+		value := storage.ReadValue(inter, address, "value")
+		require.IsType(t, &SomeValue{}, value)
+
+		composite := value.(*SomeValue).Value
+		require.IsType(t, &CompositeValue{}, composite)
+
+		return inter, composite.(*CompositeValue)
+	}
+
+	t.Run("resolved", func(t *testing.T) {
+
+		var resolved []string
+
+		inter, composite := readComposite(t,
+			WithCompositeTypeResolver(
+				func(location common.Location, qualifiedIdentifier string) (*sema.CompositeType, error) {
+					resolved = append(resolved, qualifiedIdentifier)
+					require.Equal(t, utils.TestLocation, location)
+					return testCompositeValueType, nil
+				},
+			),
+		)
+
+		require.Equal(t,
+			CompositeDynamicType{
+				StaticType: testCompositeValueType,
+			},
+			composite.DynamicType(inter, SeenReferences{}),
+		)
+		require.Equal(t, []string{"Test"}, resolved)
+
+		utils.RequireValuesEqual(t,
+			inter,
+			NewStringValue("test"),
+			composite.GetField(inter, ReturnEmptyLocationRange, "value"),
+		)
+	})
+
+	t.Run("resolution fails", func(t *testing.T) {
+
+		resolverErr := errors.New("unknown type")
+
+		inter, composite := readComposite(t,
+			WithCompositeTypeResolver(
+				func(_ common.Location, _ string) (*sema.CompositeType, error) {
+					return nil, resolverErr
+				},
+			),
+		)
+
+		_, err := inter.GetCompositeType(
+			composite.Location,
+			composite.QualifiedIdentifier,
+			composite.TypeID(),
+		)
+
+		var typeLoadingErr TypeLoadingError
+		require.True(t, errors.As(err, &typeLoadingErr))
+		require.Equal(t, composite.TypeID(), typeLoadingErr.TypeID)
+		require.ErrorIs(t, err, resolverErr)
+	})
+
+	t.Run("no resolver", func(t *testing.T) {
+
+		inter, composite := readComposite(t)
+
+		_, err := inter.GetCompositeType(
+			composite.Location,
+			composite.QualifiedIdentifier,
+			composite.TypeID(),
+		)
+
+		require.Equal(t,
+			TypeLoadingError{
+				TypeID: composite.TypeID(),
+			},
+			err,
+		)
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+// CompositeView is a read-only view of a composite value,
+// which only exposes the fields it allows, see CompositeValue.RestrictedView.
+//
+type CompositeView struct {
+	value   *CompositeValue
+	allowed map[string]struct{}
+}
+
+// RestrictedView returns a view of the composite value
+// in which only the fields with the given names are visible.
+//
+// The composite value itself is unchanged.
+// The visible field values are not restricted, i.e. nested composites expose all their fields.
+//
+func (v *CompositeValue) RestrictedView(allowed []string) CompositeView {
+	allowedFields := make(map[string]struct{}, len(allowed))
+	for _, name := range allowed {
+		allowedFields[name] = struct{}{}
+	}
+
+	return CompositeView{
+		value:   v,
+		allowed: allowedFields,
+	}
+}
+
+// GetField returns the value of the field with the given name,
+// or nil if the composite has no such field, or if the field is not visible.
+//
+func (v CompositeView) GetField(interpreter *Interpreter, getLocationRange func() LocationRange, name string) Value {
+	if _, ok := v.allowed[name]; !ok {
+		return nil
+	}
+
+	return v.value.GetField(interpreter, getLocationRange, name)
+}
+
+// ForEachField calls the given function for each visible field of the composite.
+//
+func (v CompositeView) ForEachField(f func(fieldName string, fieldValue Value)) {
+	v.value.ForEachField(func(fieldName string, fieldValue Value) {
+		if _, ok := v.allowed[fieldName]; !ok {
+			return
+		}
+
+		f(fieldName, fieldValue)
+	})
+}
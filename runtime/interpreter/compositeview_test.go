@@ -0,0 +1,88 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestCompositeRestrictedView(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	value := NewCompositeValue(
+		inter,
+		utils.TestLocation,
+		"Test",
+		common.CompositeKindStructure,
+		[]CompositeField{
+			{
+				Name:  "name",
+				Value: NewStringValue("test"),
+			},
+			{
+				Name:  "balance",
+				Value: UInt64Value(42),
+			},
+			{
+				Name:  "secret",
+				Value: NewStringValue("hidden"),
+			},
+		},
+		common.Address{},
+	)
+
+	view := value.RestrictedView([]string{"name", "balance", "missing"})
+
+	utils.RequireValuesEqual(t,
+		inter,
+		NewStringValue("test"),
+		view.GetField(inter, ReturnEmptyLocationRange, "name"),
+	)
+	utils.RequireValuesEqual(t,
+		inter,
+		UInt64Value(42),
+		view.GetField(inter, ReturnEmptyLocationRange, "balance"),
+	)
+
+	require.Nil(t, view.GetField(inter, ReturnEmptyLocationRange, "secret"))
+	require.Nil(t, view.GetField(inter, ReturnEmptyLocationRange, "missing"))
+
+	var fieldNames []string
+	view.ForEachField(func(fieldName string, _ Value) {
+		fieldNames = append(fieldNames, fieldName)
+	})
+	require.ElementsMatch(t, []string{"name", "balance"}, fieldNames)
+
+	// The underlying value is unchanged
+
+	utils.RequireValuesEqual(t,
+		inter,
+		NewStringValue("hidden"),
+		value.GetField(inter, ReturnEmptyLocationRange, "secret"),
+	)
+}
@@ -0,0 +1,100 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"time"
+
+	"github.com/onflow/atree"
+)
+
+// containerHooks are the optional checks and instrumentation of container operations:
+// the transfer slab budget, the transfer ownership check, and the operation tracer.
+//
+// The container hooks of an interpreter are nil if none of them is enabled,
+// so container operations only check a single pointer when they are disabled.
+//
+// Container hooks are not mutated after they are set (see updateContainerHooks),
+// so they are shared with sub-interpreters.
+//
+type containerHooks struct {
+	// transferSlabBudget is 0 if the number of slabs a transfer may allocate is not limited
+	transferSlabBudget     int
+	transferOwnershipCheck bool
+	// onOperationTrace is nil if value operations are not traced
+	onOperationTrace OnOperationTraceFunc
+}
+
+func (hooks containerHooks) enabled() bool {
+	return hooks.transferSlabBudget > 0 ||
+		hooks.transferOwnershipCheck ||
+		hooks.onOperationTrace != nil
+}
+
+// updateContainerHooks applies the given function to a copy of the container hooks of the interpreter,
+// and sets the container hooks to the copy, or to nil if none of them is enabled.
+//
+func (interpreter *Interpreter) updateContainerHooks(update func(hooks *containerHooks)) {
+	var hooks containerHooks
+	if interpreter.containerHooks != nil {
+		hooks = *interpreter.containerHooks
+	}
+
+	update(&hooks)
+
+	if hooks.enabled() {
+		interpreter.containerHooks = &hooks
+	} else {
+		interpreter.containerHooks = nil
+	}
+}
+
+// transfer transfers the given value with the enabled transfer checks and returns true,
+// or returns false if no transfer check is enabled, or the transfer is performed by a check.
+//
+func (hooks *containerHooks) transfer(
+	interpreter *Interpreter,
+	value Value,
+	getLocationRange func() LocationRange,
+	address atree.Address,
+	remove bool,
+	storable atree.Storable,
+) (Value, bool) {
+
+	if hooks.transferSlabBudget > 0 && !interpreter.checkingTransferSlabBudget {
+		return interpreter.budgetedTransfer(value, getLocationRange, address, remove, storable, hooks.transferSlabBudget), true
+	}
+
+	if hooks.transferOwnershipCheck && !interpreter.checkingTransferOwnership {
+		return interpreter.checkedTransfer(value, getLocationRange, address, remove, storable), true
+	}
+
+	return nil, false
+}
+
+// reportOperationTrace reports the given value operation, which started at the given time,
+// to the operation tracer, if any.
+//
+func (hooks *containerHooks) reportOperationTrace(operation string, kind string, startTime time.Time) {
+	if hooks.onOperationTrace == nil {
+		return
+	}
+
+	hooks.onOperationTrace(operation, kind, time.Since(startTime))
+}
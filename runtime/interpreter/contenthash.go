@@ -0,0 +1,181 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"github.com/onflow/atree"
+)
+
+// EnableContentHash enables the content hash of the dictionary, see ContentHash.
+// The hash is computed from the current entries of the dictionary.
+//
+// Only this dictionary value updates its content hash:
+// the hash is not stored, so it is not enabled for transferred copies of the dictionary,
+// or for dictionary values which are read from storage again.
+//
+// Calling EnableContentHash again recomputes the hash,
+// e.g. after values nested in the dictionary were mutated.
+//
+// An error is returned if an entry cannot be encoded as canonical JSON, see CanonicalJSON.
+//
+func (v *DictionaryValue) EnableContentHash(interpreter *Interpreter) error {
+	hash, err := v.RecomputeContentHash(interpreter)
+	if err != nil {
+		return err
+	}
+
+	v.contentHash = &hash
+
+	return nil
+}
+
+// updatesContentHash returns true if the content hash of the dictionary is enabled.
+//
+func (v *DictionaryValue) updatesContentHash() bool {
+	return v.contentHash != nil
+}
+
+// ContentHash returns the content hash of the dictionary.
+// The content hash must be enabled using EnableContentHash,
+// and is then updated incrementally when entries are inserted or removed.
+// A ContentHashNotEnabledError is returned if the content hash is not enabled.
+//
+// The content hash is the XOR of the SHA-256 hashes of the canonical JSON encodings
+// of all entries (see CanonicalJSON). It is independent of the order of the entries.
+//
+// NOTE: Mutations of values nested in the dictionary, e.g. appending to an array value,
+// are not tracked: only the insertion and removal of entries of the dictionary itself update the hash.
+// After a nested mutation, the content hash differs from the hash recomputed from the entries
+// (see RecomputeContentHash), until it is recomputed using EnableContentHash.
+//
+func (v *DictionaryValue) ContentHash() ([32]byte, error) {
+	if v.contentHash == nil {
+		return [32]byte{}, ContentHashNotEnabledError{}
+	}
+
+	return *v.contentHash, nil
+}
+
+// RecomputeContentHash computes the content hash of the dictionary from its entries, see ContentHash.
+//
+func (v *DictionaryValue) RecomputeContentHash(interpreter *Interpreter) ([32]byte, error) {
+	var hash [32]byte
+	var err error
+
+	v.Iterate(func(key, value Value) (resume bool) {
+		var entryHash [32]byte
+		entryHash, err = dictionaryEntryHash(interpreter, key, value)
+		if err != nil {
+			return false
+		}
+
+		xorContentHash(&hash, entryHash)
+
+		return true
+	})
+
+	return hash, err
+}
+
+// contentHashChange returns the change of the content hash caused by
+// inserting the given entry, if value is not nil, and removing the existing entry for the given key, if any.
+//
+// It must be called before the dictionary is mutated, so an entry which cannot be encoded
+// is rejected with a ContentHashEncodingError before the dictionary and its content hash get out of sync.
+// The returned change is applied after the mutation using xorContentHash.
+//
+func (v *DictionaryValue) contentHashChange(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	key, value Value,
+) (change [32]byte) {
+
+	entryHash := func(key, value Value) [32]byte {
+		hash, err := dictionaryEntryHash(interpreter, key, value)
+		if err != nil {
+			panic(ContentHashEncodingError{
+				Err:           err,
+				LocationRange: getLocationRange(),
+			})
+		}
+		return hash
+	}
+
+	if value != nil {
+		xorContentHash(&change, entryHash(key, value))
+	}
+
+	if !v.hasRootSlab() {
+		return
+	}
+
+	valueComparator := newValueComparator(interpreter, getLocationRange)
+	hashInputProvider := newHashInputProvider(interpreter, getLocationRange)
+
+	existingValueStorable, err := v.dictionary.Get(
+		valueComparator,
+		hashInputProvider,
+		key,
+	)
+	if err != nil {
+		if _, ok := err.(*atree.KeyNotFoundError); ok {
+			return
+		}
+		panic(ExternalError{err})
+	}
+
+	existingValue := StoredValue(existingValueStorable, v.dictionary.Storage)
+	xorContentHash(&change, entryHash(key, existingValue))
+
+	return
+}
+
+// dictionaryEntryHash returns the SHA-256 hash of the canonical JSON encoding
+// of the given entry, a JSON array of the key and value.
+//
+func dictionaryEntryHash(interpreter *Interpreter, key, value Value) ([32]byte, error) {
+	var buffer bytes.Buffer
+
+	buffer.WriteByte('[')
+
+	err := writeCanonicalJSON(interpreter, &buffer, key)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	buffer.WriteByte(',')
+
+	err = writeCanonicalJSON(interpreter, &buffer, value)
+	if err != nil {
+		return [32]byte{}, err
+	}
+
+	buffer.WriteByte(']')
+
+	return sha256.Sum256(buffer.Bytes()), nil
+}
+
+func xorContentHash(hash *[32]byte, entryHash [32]byte) {
+	for i := range hash {
+		hash[i] ^= entryHash[i]
+	}
+}
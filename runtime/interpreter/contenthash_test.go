@@ -0,0 +1,279 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+func requireContentHash(t *testing.T, dictionary *DictionaryValue) [32]byte {
+	hash, err := dictionary.ContentHash()
+	require.NoError(t, err)
+	return hash
+}
+
+func TestDictionaryValueContentHash(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	newDictionary := func(keysAndValues ...Value) *DictionaryValue {
+		return NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeAnyStruct,
+			},
+			keysAndValues...,
+		)
+	}
+
+	dictionary := newDictionary(
+		NewStringValue("a"), NewIntValueFromInt64(1),
+		NewStringValue("b"), NewIntValueFromInt64(2),
+	)
+
+	err := dictionary.EnableContentHash(inter)
+	require.NoError(t, err)
+
+	initialHash := requireContentHash(t, dictionary)
+
+	// Insert new entries, overwrite an existing entry, and remove entries
+
+	for i := 0; i < 100; i++ {
+		dictionary.Insert(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeInt,
+				},
+				common.Address{},
+				NewIntValueFromInt64(int64(i)),
+			),
+		)
+	}
+
+	require.NotEqual(t, initialHash, requireContentHash(t, dictionary))
+
+	dictionary.Insert(
+		inter,
+		ReturnEmptyLocationRange,
+		NewStringValue("a"),
+		NewStringValue("replaced"),
+	)
+
+	for i := 0; i < 100; i += 2 {
+		dictionary.Remove(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+		)
+	}
+
+	// Removing a missing key does not change the hash
+
+	dictionary.Remove(inter, ReturnEmptyLocationRange, NewStringValue("missing"))
+
+	recomputedHash, err := dictionary.RecomputeContentHash(inter)
+	require.NoError(t, err)
+	require.Equal(t, recomputedHash, requireContentHash(t, dictionary))
+
+	// A dictionary with the same entries, inserted in a different order, has the same hash
+
+	keysAndValues := []Value{
+		NewStringValue("b"), NewIntValueFromInt64(2),
+		NewStringValue("a"), NewStringValue("replaced"),
+	}
+	for i := 99; i >= 0; i -= 2 {
+		keysAndValues = append(
+			keysAndValues,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeInt,
+				},
+				common.Address{},
+				NewIntValueFromInt64(int64(i)),
+			),
+		)
+	}
+
+	otherHash, err := newDictionary(keysAndValues...).RecomputeContentHash(inter)
+	require.NoError(t, err)
+	require.Equal(t, otherHash, requireContentHash(t, dictionary))
+
+	// Removing all entries results in the hash of an empty dictionary
+
+	for _, key := range []string{"a", "b"} {
+		dictionary.Remove(inter, ReturnEmptyLocationRange, NewStringValue(key))
+	}
+	for i := 1; i < 100; i += 2 {
+		dictionary.Remove(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+		)
+	}
+
+	require.Equal(t, [32]byte{}, requireContentHash(t, dictionary))
+}
+
+func TestDictionaryValueContentHashNotEnabled(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	dictionary := NewDictionaryValue(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: PrimitiveStaticTypeInt,
+		},
+	)
+
+	_, err := dictionary.ContentHash()
+	require.ErrorAs(t, err, &ContentHashNotEnabledError{})
+
+	// Enabling the content hash of another dictionary does not enable it for this dictionary
+
+	other := NewDictionaryValue(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: PrimitiveStaticTypeInt,
+		},
+	)
+
+	err = other.EnableContentHash(inter)
+	require.NoError(t, err)
+
+	dictionary.Insert(inter, ReturnEmptyLocationRange, NewStringValue("a"), NewIntValueFromInt64(1))
+
+	_, err = dictionary.ContentHash()
+	require.ErrorAs(t, err, &ContentHashNotEnabledError{})
+
+	require.Equal(t, [32]byte{}, requireContentHash(t, other))
+}
+
+func TestDictionaryValueContentHashNestedMutation(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	arrayType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeInt,
+	}
+
+	dictionary := NewDictionaryValue(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: arrayType,
+		},
+		NewStringValue("a"),
+		NewArrayValue(
+			inter,
+			arrayType,
+			common.Address{},
+			NewIntValueFromInt64(1),
+		),
+	)
+
+	err := dictionary.EnableContentHash(inter)
+	require.NoError(t, err)
+
+	hash := requireContentHash(t, dictionary)
+
+	// Mutating the nested array does not update the content hash
+
+	value, ok := dictionary.Get(inter, ReturnEmptyLocationRange, NewStringValue("a"))
+	require.True(t, ok)
+
+	value.(*ArrayValue).Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+
+	require.Equal(t, hash, requireContentHash(t, dictionary))
+
+	recomputedHash, err := dictionary.RecomputeContentHash(inter)
+	require.NoError(t, err)
+	require.NotEqual(t, recomputedHash, hash)
+
+	// Enabling the content hash again recomputes it
+
+	err = dictionary.EnableContentHash(inter)
+	require.NoError(t, err)
+
+	require.Equal(t, recomputedHash, requireContentHash(t, dictionary))
+}
+
+func TestDictionaryValueContentHashEncodingError(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	dictionary := NewDictionaryValue(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: PrimitiveStaticTypeAnyStruct,
+		},
+		NewStringValue("a"), NewIntValueFromInt64(1),
+	)
+
+	err := dictionary.EnableContentHash(inter)
+	require.NoError(t, err)
+
+	hash := requireContentHash(t, dictionary)
+
+	// Functions cannot be encoded as canonical JSON,
+	// so inserting one fails before the dictionary is mutated
+
+	function := NewHostFunctionValue(
+		func(_ Invocation) Value {
+			return VoidValue{}
+		},
+		&sema.FunctionType{
+			ReturnTypeAnnotation: sema.NewTypeAnnotation(sema.VoidType),
+		},
+	)
+
+	require.PanicsWithError(t,
+		"cannot update content hash: cannot encode value of type *interpreter.HostFunctionValue as canonical JSON",
+		func() {
+			dictionary.Insert(inter, ReturnEmptyLocationRange, NewStringValue("b"), function)
+		},
+	)
+
+	require.Equal(t, 1, dictionary.Count())
+	require.Equal(t, hash, requireContentHash(t, dictionary))
+}
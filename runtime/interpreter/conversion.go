@@ -19,10 +19,15 @@
 package interpreter
 
 import (
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
+	"strings"
 
 	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
 )
 
 func ByteArrayValueToByteSlice(value Value) ([]byte, error) {
@@ -98,3 +103,233 @@ func ByteSliceToByteArrayValue(interpreter *Interpreter, buf []byte) *ArrayValue
 		values...,
 	)
 }
+
+// ByteArrayValueToHex hex-encodes the given array of UInt8 values.
+// The element type of the array must be UInt8.
+//
+func ByteArrayValueToHex(
+	_ *Interpreter,
+	_ func() LocationRange,
+	v *ArrayValue,
+) (string, error) {
+
+	if v.Type.ElementType() != PrimitiveStaticTypeUInt8 {
+		return "", errors.New("array element type is not UInt8")
+	}
+
+	result := make([]byte, 0, v.Count())
+
+	var err error
+	v.Iterate(func(element Value) (resume bool) {
+		b, ok := element.(UInt8Value)
+		if !ok {
+			err = errors.New("array element is not a UInt8 value")
+			return false
+		}
+
+		result = append(result, byte(b))
+
+		return true
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(result), nil
+}
+
+// HexToByteArrayValue hex-decodes the given string
+// and returns an array of UInt8 values owned by the given address.
+//
+func HexToByteArrayValue(interpreter *Interpreter, s string, address common.Address) (*ArrayValue, error) {
+	bs, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]Value, len(bs))
+	for i, b := range bs {
+		values[i] = UInt8Value(b)
+	}
+
+	return NewArrayValue(
+		interpreter,
+		ByteArrayStaticType,
+		address,
+		values...,
+	), nil
+}
+
+// ParseIntegerValue parses the given decimal string, or hexadecimal string if prefixed with `0x`,
+// into an integer value of the given target type.
+//
+// The string may have a leading sign. An error is returned if the string is empty or invalid,
+// if the target type is not an integer type, or if the parsed number is out of range for the target type.
+//
+func ParseIntegerValue(s string, target StaticType) (NumberValue, error) {
+
+	primitiveType, ok := target.(PrimitiveStaticType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported integer type: %s", target)
+	}
+
+	semaType := primitiveType.SemaType()
+	if !sema.IsSubType(semaType, sema.IntegerType) {
+		return nil, fmt.Errorf("unsupported integer type: %s", target)
+	}
+
+	rangedType, ok := semaType.(sema.IntegerRangedType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported integer type: %s", target)
+	}
+
+	literal := s
+
+	negative := false
+	if len(literal) > 0 {
+		switch literal[0] {
+		case '-':
+			negative = true
+			literal = literal[1:]
+		case '+':
+			literal = literal[1:]
+		}
+	}
+
+	base := 10
+	if strings.HasPrefix(literal, "0x") {
+		base = 16
+		literal = literal[2:]
+	}
+
+	if len(literal) == 0 {
+		return nil, fmt.Errorf("invalid integer literal: %q", s)
+	}
+
+	// NOTE: signs were already handled above,
+	// big.Int would otherwise also accept a second sign
+
+	if literal[0] == '-' || literal[0] == '+' {
+		return nil, fmt.Errorf("invalid integer literal: %q", s)
+	}
+
+	value, ok := new(big.Int).SetString(literal, base)
+	if !ok {
+		return nil, fmt.Errorf("invalid integer literal: %q", s)
+	}
+
+	if negative {
+		value.Neg(value)
+	}
+
+	minInt := rangedType.MinInt()
+	if minInt != nil && value.Cmp(minInt) < 0 {
+		return nil, fmt.Errorf("integer literal %q is out of range for type %s", s, target)
+	}
+
+	maxInt := rangedType.MaxInt()
+	if maxInt != nil && value.Cmp(maxInt) > 0 {
+		return nil, fmt.Errorf("integer literal %q is out of range for type %s", s, target)
+	}
+
+	construct, ok := integerConstructors[primitiveType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported integer type: %s", target)
+	}
+
+	return construct(value), nil
+}
+
+// integerConstructors are the constructors of the integer values of each integer type.
+// The constructors do not check the range of the given integer.
+//
+var integerConstructors = map[PrimitiveStaticType]func(*big.Int) NumberValue{
+	PrimitiveStaticTypeInt: func(value *big.Int) NumberValue {
+		return NewIntValueFromBigInt(value)
+	},
+	PrimitiveStaticTypeInt8: func(value *big.Int) NumberValue {
+		return Int8Value(value.Int64())
+	},
+	PrimitiveStaticTypeInt16: func(value *big.Int) NumberValue {
+		return Int16Value(value.Int64())
+	},
+	PrimitiveStaticTypeInt32: func(value *big.Int) NumberValue {
+		return Int32Value(value.Int64())
+	},
+	PrimitiveStaticTypeInt64: func(value *big.Int) NumberValue {
+		return Int64Value(value.Int64())
+	},
+	PrimitiveStaticTypeInt128: func(value *big.Int) NumberValue {
+		return NewInt128ValueFromBigInt(value)
+	},
+	PrimitiveStaticTypeInt256: func(value *big.Int) NumberValue {
+		return NewInt256ValueFromBigInt(value)
+	},
+	PrimitiveStaticTypeUInt: func(value *big.Int) NumberValue {
+		return NewUIntValueFromBigInt(value)
+	},
+	PrimitiveStaticTypeUInt8: func(value *big.Int) NumberValue {
+		return UInt8Value(value.Uint64())
+	},
+	PrimitiveStaticTypeUInt16: func(value *big.Int) NumberValue {
+		return UInt16Value(value.Uint64())
+	},
+	PrimitiveStaticTypeUInt32: func(value *big.Int) NumberValue {
+		return UInt32Value(value.Uint64())
+	},
+	PrimitiveStaticTypeUInt64: func(value *big.Int) NumberValue {
+		return UInt64Value(value.Uint64())
+	},
+	PrimitiveStaticTypeUInt128: func(value *big.Int) NumberValue {
+		return NewUInt128ValueFromBigInt(value)
+	},
+	PrimitiveStaticTypeUInt256: func(value *big.Int) NumberValue {
+		return NewUInt256ValueFromBigInt(value)
+	},
+	PrimitiveStaticTypeWord8: func(value *big.Int) NumberValue {
+		return Word8Value(value.Uint64())
+	},
+	PrimitiveStaticTypeWord16: func(value *big.Int) NumberValue {
+		return Word16Value(value.Uint64())
+	},
+	PrimitiveStaticTypeWord32: func(value *big.Int) NumberValue {
+		return Word32Value(value.Uint64())
+	},
+	PrimitiveStaticTypeWord64: func(value *big.Int) NumberValue {
+		return Word64Value(value.Uint64())
+	},
+}
+
+// NumericConstructor returns the constructor of the integer values of the given integer type,
+// or false if the type is not an integer type.
+//
+// The constructor checks that the given integer is in the range of the type,
+// and panics with an OverflowError or UnderflowError otherwise.
+//
+func NumericConstructor(t StaticType) (func(*big.Int) NumberValue, bool) {
+	primitiveType, ok := t.(PrimitiveStaticType)
+	if !ok {
+		return nil, false
+	}
+
+	construct, ok := integerConstructors[primitiveType]
+	if !ok {
+		return nil, false
+	}
+
+	rangedType := primitiveType.SemaType().(sema.IntegerRangedType)
+	minInt := rangedType.MinInt()
+	maxInt := rangedType.MaxInt()
+
+	return func(value *big.Int) NumberValue {
+		if minInt != nil && value.Cmp(minInt) < 0 {
+			panic(UnderflowError{})
+		}
+
+		if maxInt != nil && value.Cmp(maxInt) > 0 {
+			panic(OverflowError{})
+		}
+
+		return construct(value)
+	}, true
+}
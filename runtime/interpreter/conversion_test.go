@@ -27,6 +27,7 @@ import (
 
 	"github.com/onflow/cadence/runtime/common"
 	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
 )
 
 func TestByteArrayValueToByteSlice(t *testing.T) {
@@ -147,3 +148,239 @@ func TestByteValueToByte(t *testing.T) {
 		}
 	})
 }
+
+func TestByteArrayValueHex(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("round-trip", func(t *testing.T) {
+
+		inter := newTestInterpreter(t)
+
+		for _, s := range []string{"", "00", "0102ff", "deadbeef"} {
+			array, err := HexToByteArrayValue(inter, s, common.Address{})
+			require.NoError(t, err)
+			require.Equal(t, len(s)/2, array.Count())
+
+			result, err := ByteArrayValueToHex(inter, ReturnEmptyLocationRange, array)
+			require.NoError(t, err)
+			require.Equal(t, s, result)
+		}
+	})
+
+	t.Run("invalid hex", func(t *testing.T) {
+
+		inter := newTestInterpreter(t)
+
+		for _, s := range []string{"0", "abc", "zz"} {
+			_, err := HexToByteArrayValue(inter, s, common.Address{})
+			require.Error(t, err)
+		}
+	})
+
+	t.Run("invalid element type", func(t *testing.T) {
+
+		inter := newTestInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeUInt64,
+			},
+			common.Address{},
+			UInt64Value(1),
+		)
+
+		_, err := ByteArrayValueToHex(inter, ReturnEmptyLocationRange, array)
+		require.Error(t, err)
+	})
+}
+
+func TestParseIntegerValue(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("boundaries", func(t *testing.T) {
+
+		t.Parallel()
+
+		one := big.NewInt(1)
+
+		for _, integerType := range sema.AllIntegerTypes {
+
+			rangedType, ok := integerType.(sema.IntegerRangedType)
+			if !ok {
+				continue
+			}
+
+			staticType := ConvertSemaToPrimitiveStaticType(integerType)
+
+			t.Run(integerType.String(), func(t *testing.T) {
+
+				minInt := rangedType.MinInt()
+				if minInt != nil {
+					value, err := ParseIntegerValue(minInt.String(), staticType)
+					require.NoError(t, err)
+					require.Equal(t, minInt.String(), value.String())
+					require.Equal(t, staticType, value.StaticType())
+
+					belowMin := new(big.Int).Sub(minInt, one)
+					_, err = ParseIntegerValue(belowMin.String(), staticType)
+					require.Error(t, err)
+				}
+
+				maxInt := rangedType.MaxInt()
+				if maxInt != nil {
+					value, err := ParseIntegerValue(maxInt.String(), staticType)
+					require.NoError(t, err)
+					require.Equal(t, maxInt.String(), value.String())
+					require.Equal(t, staticType, value.StaticType())
+
+					aboveMax := new(big.Int).Add(maxInt, one)
+					_, err = ParseIntegerValue(aboveMax.String(), staticType)
+					require.Error(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+
+		t.Parallel()
+
+		largeBigInt, ok := new(big.Int).SetString("1000000000000000000000000000000000000000000000", 10)
+		require.True(t, ok)
+
+		for _, test := range []struct {
+			literal    string
+			staticType StaticType
+			expected   NumberValue
+		}{
+			{"42", PrimitiveStaticTypeInt, NewIntValueFromInt64(42)},
+			{"+42", PrimitiveStaticTypeInt8, Int8Value(42)},
+			{"-42", PrimitiveStaticTypeInt16, Int16Value(-42)},
+			{"0x1f", PrimitiveStaticTypeUInt8, UInt8Value(31)},
+			{"-0x10", PrimitiveStaticTypeInt32, Int32Value(-16)},
+			{"0xff", PrimitiveStaticTypeWord8, Word8Value(255)},
+			{"-1000000000000000000000000000000000000000000000", PrimitiveStaticTypeInt, NewIntValueFromBigInt(new(big.Int).Neg(largeBigInt))},
+			{"1000000000000000000000000000000000000000000000", PrimitiveStaticTypeUInt, NewUIntValueFromBigInt(largeBigInt)},
+		} {
+			value, err := ParseIntegerValue(test.literal, test.staticType)
+			require.NoError(t, err)
+			require.Equal(t, test.expected, value)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+
+		t.Parallel()
+
+		for _, test := range []struct {
+			literal    string
+			staticType StaticType
+		}{
+			{"", PrimitiveStaticTypeInt},
+			{"-", PrimitiveStaticTypeInt},
+			{"+", PrimitiveStaticTypeInt},
+			{"0x", PrimitiveStaticTypeInt},
+			{"--1", PrimitiveStaticTypeInt},
+			{"+-1", PrimitiveStaticTypeInt},
+			{"1_000", PrimitiveStaticTypeInt},
+			{"abc", PrimitiveStaticTypeInt},
+			{"0xfg", PrimitiveStaticTypeInt},
+			{"1.5", PrimitiveStaticTypeInt},
+			{"-1", PrimitiveStaticTypeUInt},
+			{"1", PrimitiveStaticTypeFix64},
+			{"1", PrimitiveStaticTypeString},
+			{"1", VariableSizedStaticType{Type: PrimitiveStaticTypeInt}},
+		} {
+			_, err := ParseIntegerValue(test.literal, test.staticType)
+			require.Error(t, err, "%q", test.literal)
+		}
+	})
+}
+
+func TestNumericConstructor(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("max values", func(t *testing.T) {
+
+		t.Parallel()
+
+		one := big.NewInt(1)
+
+		largeBigInt, ok := new(big.Int).SetString("1000000000000000000000000000000000000000000000", 10)
+		require.True(t, ok)
+
+		for _, integerType := range sema.AllIntegerTypes {
+
+			// Abstract integer types have no values
+
+			switch integerType {
+			case sema.IntegerType, sema.SignedIntegerType:
+				continue
+			}
+
+			rangedType, ok := integerType.(sema.IntegerRangedType)
+			if !ok {
+				continue
+			}
+
+			staticType := ConvertSemaToPrimitiveStaticType(integerType)
+
+			t.Run(integerType.String(), func(t *testing.T) {
+
+				construct, ok := NumericConstructor(staticType)
+				require.True(t, ok)
+
+				maxInt := rangedType.MaxInt()
+				if maxInt == nil {
+					// Unbounded types have no maximum, use a large value
+					maxInt = largeBigInt
+				}
+
+				value := construct(maxInt)
+				require.Equal(t, maxInt.String(), value.String())
+				require.Equal(t, staticType, value.StaticType())
+
+				if rangedType.MaxInt() != nil {
+					require.PanicsWithValue(t,
+						OverflowError{},
+						func() {
+							construct(new(big.Int).Add(maxInt, one))
+						},
+					)
+				}
+
+				minInt := rangedType.MinInt()
+				if minInt != nil {
+					require.PanicsWithValue(t,
+						UnderflowError{},
+						func() {
+							construct(new(big.Int).Sub(minInt, one))
+						},
+					)
+				}
+			})
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+
+		t.Parallel()
+
+		for _, staticType := range []StaticType{
+			PrimitiveStaticTypeInteger,
+			PrimitiveStaticTypeSignedInteger,
+			PrimitiveStaticTypeFix64,
+			PrimitiveStaticTypeString,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+		} {
+			_, ok := NumericConstructor(staticType)
+			require.False(t, ok)
+		}
+	})
+}
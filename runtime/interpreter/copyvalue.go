@@ -0,0 +1,106 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// CopyValue returns a deep copy of the given value, which is not owned by any account.
+//
+// The copy of a non-resource value is independent of the given value:
+// mutating the copy, or any value nested in it, does not affect the given value, and vice versa.
+// The slabs of the copy are allocated in the interpreter's storage,
+// and must be removed by the caller when the copy is no longer needed, e.g. using DeepRemoveAll.
+//
+// NOTE: Resources must not be copied, as the copy would duplicate the resource.
+// Resources which are not owned by an account are returned as-is,
+// as transferring them within the same account does not copy them.
+//
+func CopyValue(interpreter *Interpreter, value Value) Value {
+	return value.Transfer(
+		interpreter,
+		ReturnEmptyLocationRange,
+		atree.Address{},
+		false,
+		nil,
+	)
+}
+
+// CopyValueRemap returns a deep copy of the given value, which is stored in the source storage,
+// in the destination storage, owned by the given owner.
+//
+// Addresses of address values and capabilities in the copy are rewritten using the given address map.
+// Addresses which are not in the map are left unchanged.
+//
+// NOTE: Dictionary keys are not rewritten, as rewriting them could merge entries.
+//
+func CopyValueRemap(
+	interpreter *Interpreter,
+	src, dst Storage,
+	value Value,
+	addressMap map[common.Address]common.Address,
+	newOwner atree.Address,
+) (Value, error) {
+
+	if storageID, ok := containerRootStorageID(value); ok {
+		_, ok, err := src.Retrieve(storageID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("cannot copy value: not stored in source storage")
+		}
+	}
+
+	// A resource which is not moved to another account is not copied
+
+	if value.IsResourceKinded(interpreter) && !value.NeedsStoreTo(newOwner) {
+		return nil, fmt.Errorf("cannot copy resource: already owned by %s", common.Address(newOwner))
+	}
+
+	// The containers of the value are backed by the source storage,
+	// so only the transferred containers are stored in the destination storage.
+	//
+	// The value is transferred using a dedicated interpreter,
+	// so the destination storage and the address map only apply to this copy,
+	// and not to any other transfer performed by the given interpreter
+
+	copyInterpreter, err := interpreter.NewSubInterpreter(
+		interpreter.Program,
+		interpreter.Location,
+		WithStorage(dst),
+		withTransferAddressMap(addressMap),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return value.Transfer(
+		copyInterpreter,
+		ReturnEmptyLocationRange,
+		newOwner,
+		false,
+		nil,
+	), nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestCopyValueRemap(t *testing.T) {
+
+	t.Parallel()
+
+	src := NewInMemoryStorage()
+	dst := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(src),
+	)
+	require.NoError(t, err)
+
+	sourceA := common.Address{0x1}
+	sourceB := common.Address{0x2}
+	unmapped := common.Address{0x3}
+	destination := common.Address{0x4}
+
+	path := PathValue{
+		Domain:     common.PathDomainPublic,
+		Identifier: "test",
+	}
+
+	// [Capability(0x1, /public/test), 0x2, 0x3, Some(0x1)]
+
+	value := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeAnyStruct,
+		},
+		sourceA,
+		&CapabilityValue{
+			Address: AddressValue(sourceA),
+			Path:    path,
+		},
+		AddressValue(sourceB),
+		AddressValue(unmapped),
+		NewSomeValueNonCopying(AddressValue(sourceA)),
+	)
+
+	srcSlabCount := src.Count()
+
+	result, err := CopyValueRemap(
+		inter,
+		src,
+		dst,
+		value,
+		map[common.Address]common.Address{
+			sourceA: destination,
+			sourceB: destination,
+		},
+		atree.Address(destination),
+	)
+	require.NoError(t, err)
+
+	copied := result.(*ArrayValue)
+	require.Equal(t, destination, copied.GetOwner())
+
+	// The copy is stored in the destination storage
+
+	require.Equal(t, srcSlabCount, src.Count())
+	require.Equal(t, 1, dst.Count())
+	require.Same(t, src.BasicSlabStorage, inter.Storage.(InMemoryStorage).BasicSlabStorage)
+
+	utils.RequireValuesEqual(
+		t,
+		inter,
+		NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			common.Address{},
+			&CapabilityValue{
+				Address: AddressValue(destination),
+				Path:    path,
+			},
+			AddressValue(destination),
+			AddressValue(unmapped),
+			NewSomeValueNonCopying(AddressValue(destination)),
+		),
+		copied,
+	)
+
+	// The source value is unchanged
+
+	capability := value.Get(inter, ReturnEmptyLocationRange, 0).(*CapabilityValue)
+	require.Equal(t, AddressValue(sourceA), capability.Address)
+	require.Equal(t, AddressValue(sourceB), value.Get(inter, ReturnEmptyLocationRange, 1))
+
+	// Other transfers performed by the interpreter do not rewrite addresses
+
+	require.Equal(t,
+		AddressValue(sourceA),
+		AddressValue(sourceA).Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(destination),
+			false,
+			nil,
+		),
+	)
+}
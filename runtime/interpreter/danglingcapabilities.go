@@ -0,0 +1,115 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sort"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// CapabilityLocation is the location of a capability in storage:
+// the capability is stored in, or nested in, the value stored under the given key.
+//
+// If key hashing is enabled, the key is the hashed key.
+//
+type CapabilityLocation struct {
+	Address    common.Address
+	Key        string
+	Capability *CapabilityValue
+}
+
+// FindDanglingCapabilities returns the locations of all stored capabilities
+// which do not resolve to a stored value anymore, e.g. because their target was removed.
+//
+// A capability is resolved like when it is borrowed: links are followed
+// until a value which is not a link is found. Capabilities with cyclic links are dangling.
+// In contrast to borrowing, the borrow type of the capability is not checked.
+//
+// The locations are sorted by address and key.
+//
+func FindDanglingCapabilities(interpreter *Interpreter, storage InMemoryStorage) []CapabilityLocation {
+
+	storageKeys := make([]StorageKey, 0, len(storage.AccountStorage))
+	for storageKey := range storage.AccountStorage {
+		storageKeys = append(storageKeys, storageKey)
+	}
+
+	sort.Slice(storageKeys, func(i, j int) bool {
+		return storageKeys[i].IsLess(storageKeys[j])
+	})
+
+	var result []CapabilityLocation
+
+	for _, storageKey := range storageKeys {
+		storable := storage.AccountStorage[storageKey]
+		value := StoredValue(storable, storage)
+
+		var walk func(value Value)
+		walk = func(value Value) {
+			if capability, ok := value.(*CapabilityValue); ok {
+				if !capabilityTargetExists(interpreter, storage, capability) {
+					result = append(result, CapabilityLocation{
+						Address:    storageKey.Address,
+						Key:        storageKey.Key,
+						Capability: capability,
+					})
+				}
+				return
+			}
+
+			value.Walk(walk)
+		}
+
+		walk(value)
+	}
+
+	return result
+}
+
+// capabilityTargetExists returns true if the links of the given capability
+// resolve to a stored value.
+//
+func capabilityTargetExists(interpreter *Interpreter, storage InMemoryStorage, capability *CapabilityValue) bool {
+	address := capability.Address.ToAddress()
+	key := PathToStorageKey(capability.Path)
+
+	seenKeys := map[string]struct{}{}
+
+	for {
+		// Detect cyclic links
+
+		if _, ok := seenKeys[key]; ok {
+			return false
+		}
+		seenKeys[key] = struct{}{}
+
+		someValue, ok := storage.ReadValue(interpreter, address, key).(*SomeValue)
+		if !ok {
+			return false
+		}
+
+		link, ok := someValue.Value.(LinkValue)
+		if !ok {
+			return true
+		}
+
+		key = PathToStorageKey(link.TargetPath)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestFindDanglingCapabilities(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	targetPath := PathValue{
+		Domain:     common.PathDomainStorage,
+		Identifier: "target",
+	}
+
+	linkPath := PathValue{
+		Domain:     common.PathDomainPublic,
+		Identifier: "link",
+	}
+
+	write := func(path PathValue, value Value) {
+		storage.WriteValue(
+			inter,
+			address,
+			PathToStorageKey(path),
+			NewSomeValueNonCopying(value),
+		)
+	}
+
+	write(targetPath, NewStringValue("target"))
+
+	write(
+		linkPath,
+		LinkValue{
+			TargetPath: targetPath,
+			Type:       PrimitiveStaticTypeString,
+		},
+	)
+
+	linkedCapability := &CapabilityValue{
+		Address: AddressValue(address),
+		Path:    linkPath,
+	}
+
+	directCapability := &CapabilityValue{
+		Address: AddressValue(address),
+		Path:    targetPath,
+	}
+
+	capabilitiesPath := PathValue{
+		Domain:     common.PathDomainStorage,
+		Identifier: "capabilities",
+	}
+
+	write(
+		capabilitiesPath,
+		NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			address,
+			linkedCapability,
+			NewSomeValueNonCopying(directCapability),
+		),
+	)
+
+	require.Empty(t, FindDanglingCapabilities(inter, storage))
+
+	// Remove the target
+
+	storage.WriteValue(inter, address, PathToStorageKey(targetPath), Nil())
+
+	dangling := FindDanglingCapabilities(inter, storage)
+	require.Len(t, dangling, 2)
+
+	for _, location := range dangling {
+		require.Equal(t, address, location.Address)
+		require.Equal(t, PathToStorageKey(capabilitiesPath), location.Key)
+	}
+
+	require.Equal(t, linkPath, dangling[0].Capability.Path)
+	require.Equal(t, targetPath, dangling[1].Capability.Path)
+}
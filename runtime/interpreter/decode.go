@@ -21,6 +21,7 @@ package interpreter
 import (
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/onflow/atree"
@@ -53,19 +54,151 @@ func (e UnsupportedTagDecodingError) Error() string {
 	)
 }
 
+// DecoderOption is an option for a storable decoder, see NewStorableDecoder.
+//
+type DecoderOption func(*decoderConfig)
+
+type decoderConfig struct {
+	// maxStringLength is 0 if the length of decoded strings is not limited
+	maxStringLength int
+	// sizeLimits are the maximum encoded sizes of storables, see WithDecodeSizeLimits.
+	// The sizes are not limited if it is empty
+	sizeLimits map[StorableKind]uint64
+}
+
+func newDecoderConfig(options ...DecoderOption) *decoderConfig {
+	config := &decoderConfig{}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+// WithMaxDecodedStringLength returns a decoder option which sets
+// the maximum length of decoded string values, in bytes.
+// Zero means unlimited, which is the default.
+//
+// Decoding a longer string fails with a StringLengthLimitExceededError.
+//
+func WithMaxDecodedStringLength(length int) DecoderOption {
+	return func(config *decoderConfig) {
+		if length < 0 {
+			length = 0
+		}
+		config.maxStringLength = length
+	}
+}
+
+// defaultDecoderConfig is the configuration of DecodeStorable
+//
+var defaultDecoderConfig = newDecoderConfig()
+
+// NewStorableDecoder returns a function which decodes storables like DecodeStorable,
+// but which is configured with the given options.
+//
+func NewStorableDecoder(options ...DecoderOption) atree.StorableDecoder {
+	config := newDecoderConfig(options...)
+
+	return func(decoder *cbor.StreamDecoder, slabStorageID atree.StorageID) (atree.Storable, error) {
+		return decodeStorable(decoder, slabStorageID, config)
+	}
+}
+
 func DecodeStorable(
 	decoder *cbor.StreamDecoder,
 	slabStorageID atree.StorageID,
 ) (atree.Storable, error) {
-	return Decoder{
+	return decodeStorable(decoder, slabStorageID, defaultDecoderConfig)
+}
+
+func decodeStorable(
+	decoder *cbor.StreamDecoder,
+	slabStorageID atree.StorageID,
+	config *decoderConfig,
+) (atree.Storable, error) {
+	profiler := loadCodecProfiler()
+	if profiler == nil {
+		return decodeLimitedStorable(decoder, slabStorageID, config)
+	}
+
+	start := time.Now()
+	startBytes := decoder.NumBytesDecoded()
+
+	storable, err := decodeLimitedStorable(decoder, slabStorageID, config)
+
+	profiler(CodecOperationDecode, decoder.NumBytesDecoded()-startBytes, time.Since(start))
+
+	return storable, err
+}
+
+// decodeLimitedStorable decodes the next storable.
+//
+// If decode size limits are configured, the size of the encoding is checked first,
+// see decodeCheckedStorable. Otherwise, the storable is decoded directly.
+//
+func decodeLimitedStorable(
+	decoder *cbor.StreamDecoder,
+	slabStorageID atree.StorageID,
+	config *decoderConfig,
+) (atree.Storable, error) {
+	if len(config.sizeLimits) > 0 {
+		return decodeCheckedStorable(decoder, slabStorageID, config)
+	}
+
+	d := Decoder{
 		decoder:       decoder,
 		slabStorageID: slabStorageID,
-	}.decodeStorable()
+		config:        config,
+	}
+
+	return d.decodeStorable()
+}
+
+// decodeCheckedStorable decodes the next storable,
+// after checking the size of its encoding against the decode size limit for its kind.
+//
+// The encoding is checked before it is decoded, so crafted encodings are rejected
+// before they can cause any allocations. The limit of the storable also bounds
+// all element counts and byte lengths of the values nested in it.
+//
+func decodeCheckedStorable(
+	decoder *cbor.StreamDecoder,
+	slabStorageID atree.StorageID,
+	config *decoderConfig,
+) (atree.Storable, error) {
+
+	// Zero-copy is only supported by decoders which read from a byte slice,
+	// like the decoders atree uses for slabs. Other decoders copy the encoding.
+	//
+	// If the zero-copy read failed because the data is invalid,
+	// the decoder returns the same error again.
+
+	data, err := decoder.DecodeRawBytesZeroCopy()
+	if err != nil {
+		data, err = decoder.DecodeRawBytes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = config.checkDecodeSize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	d := Decoder{
+		decoder:       CBORDecMode.NewByteStreamDecoder(data),
+		slabStorageID: slabStorageID,
+		config:        config,
+	}
+
+	return d.decodeStorable()
 }
 
 type Decoder struct {
 	decoder       *cbor.StreamDecoder
 	slabStorageID atree.StorageID
+	config        *decoderConfig
 }
 
 func (d Decoder) decodeStorable() (atree.Storable, error) {
@@ -93,7 +226,7 @@ func (d Decoder) decodeStorable() (atree.Storable, error) {
 		if err != nil {
 			return nil, err
 		}
-		storable = NilValue{}
+		storable = Nil()
 
 	case cbor.TextStringType:
 		v, err := d.decoder.DecodeString()
@@ -119,14 +252,15 @@ func (d Decoder) decodeStorable() (atree.Storable, error) {
 			if err != nil {
 				return nil, err
 			}
-			storable = VoidValue{}
+			storable = Void()
 
 		case CBORTagStringValue:
-			v, err := d.decoder.DecodeString()
+			var v string
+			v, err = d.decoder.DecodeString()
 			if err != nil {
 				return nil, err
 			}
-			storable = d.decodeString(v)
+			storable, err = d.decodeString(v)
 
 		case CBORTagSomeValue:
 			storable, err = d.decodeSome()
@@ -218,6 +352,9 @@ func (d Decoder) decodeStorable() (atree.Storable, error) {
 		case CBORTagTypeValue:
 			storable, err = d.decodeType()
 
+		case CBORTagEmptyContainerValue:
+			storable, err = d.decodeEmptyContainer()
+
 		default:
 			return nil, UnsupportedTagDecodingError{
 				Tag: num,
@@ -238,8 +375,16 @@ func (d Decoder) decodeStorable() (atree.Storable, error) {
 	return storable, nil
 }
 
-func (d Decoder) decodeString(v string) *StringValue {
-	return NewStringValue(v)
+func (d Decoder) decodeString(v string) (*StringValue, error) {
+	limit := d.config.maxStringLength
+	if limit > 0 && len(v) > limit {
+		return nil, StringLengthLimitExceededError{
+			Length: len(v),
+			Limit:  limit,
+		}
+	}
+
+	return NewStringValue(v), nil
 }
 
 func decodeLocation(dec *cbor.StreamDecoder) (common.Location, error) {
@@ -1020,6 +1165,54 @@ func (d Decoder) decodeType() (TypeValue, error) {
 	}, nil
 }
 
+func (d Decoder) decodeEmptyContainer() (EmptyContainerStorable, error) {
+	const expectedLength = encodedEmptyContainerLength
+
+	size, err := d.decoder.DecodeArrayHead()
+	if err != nil {
+		if e, ok := err.(*cbor.WrongTypeError); ok {
+			return EmptyContainerStorable{}, fmt.Errorf(
+				"invalid empty container encoding: expected [%d]interface{}, got %s",
+				expectedLength,
+				e.ActualType.String(),
+			)
+		}
+		return EmptyContainerStorable{}, err
+	}
+
+	if size != expectedLength {
+		return EmptyContainerStorable{}, fmt.Errorf(
+			"invalid empty container encoding: expected [%d]interface{}, got [%d]interface{}",
+			expectedLength,
+			size,
+		)
+	}
+
+	// Decode storage ID at array index encodedEmptyContainerStorageIDFieldKey
+	num, err := d.decoder.DecodeTagNumber()
+	if err != nil {
+		return EmptyContainerStorable{}, fmt.Errorf("invalid empty container storage ID encoding: %w", err)
+	}
+	if num != atree.CBORTagStorageID {
+		return EmptyContainerStorable{}, fmt.Errorf("invalid empty container storage ID encoding: expected CBOR tag %d, got %d", atree.CBORTagStorageID, num)
+	}
+	storable, err := atree.DecodeStorageIDStorable(d.decoder)
+	if err != nil {
+		return EmptyContainerStorable{}, fmt.Errorf("invalid empty container storage ID encoding: %w", err)
+	}
+
+	// Decode type at array index encodedEmptyContainerTypeFieldKey
+	staticType, err := decodeStaticType(d.decoder)
+	if err != nil {
+		return EmptyContainerStorable{}, fmt.Errorf("invalid empty container type encoding: %w", err)
+	}
+
+	return EmptyContainerStorable{
+		StorageID: atree.StorageID(storable.(atree.StorageIDStorable)),
+		Type:      staticType,
+	}, nil
+}
+
 func decodeStaticType(dec *cbor.StreamDecoder) (StaticType, error) {
 	number, err := dec.DecodeTagNumber()
 	if err != nil {
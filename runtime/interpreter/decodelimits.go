@@ -0,0 +1,211 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+// StorableKind is the kind of a decoded storable,
+// which determines its decode size limit (see WithDecodeSizeLimits).
+//
+//go:generate go run golang.org/x/tools/cmd/stringer -type=StorableKind -trimprefix=StorableKind
+//
+type StorableKind uint
+
+const (
+	StorableKindUnknown StorableKind = iota
+	StorableKindBool
+	StorableKindNil
+	StorableKindVoid
+	StorableKindString
+	StorableKindSome
+	StorableKindAddress
+	StorableKindNumber
+	StorableKindPath
+	StorableKindCapability
+	StorableKindLink
+	StorableKindType
+)
+
+// recommendedDecodeSizeLimits are the recommended maximum encoded sizes of storables, in bytes.
+//
+// The limits are generous, but finite: they are far larger than any valid encoding of the kind,
+// but reject crafted slabs which would cause huge allocations.
+//
+// Numbers are not limited, as integers have arbitrary precision,
+// so no size of their encoding can be ruled out.
+//
+var recommendedDecodeSizeLimits = map[StorableKind]uint64{
+	StorableKindBool:       16,
+	StorableKindNil:        16,
+	StorableKindVoid:       16,
+	StorableKindString:     64 << 20,
+	StorableKindSome:       64 << 20,
+	StorableKindAddress:    64,
+	StorableKindPath:       1 << 20,
+	StorableKindCapability: 1 << 20,
+	StorableKindLink:       1 << 20,
+	StorableKindType:       1 << 20,
+}
+
+// RecommendedDecodeSizeLimits returns the recommended maximum encoded sizes of storables, in bytes,
+// which can be passed to WithDecodeSizeLimits.
+//
+// The returned map is a copy, so modifying it does not change the recommended limits.
+//
+func RecommendedDecodeSizeLimits() map[StorableKind]uint64 {
+	limits := make(map[StorableKind]uint64, len(recommendedDecodeSizeLimits))
+	for kind, limit := range recommendedDecodeSizeLimits {
+		limits[kind] = limit
+	}
+	return limits
+}
+
+// WithDecodeSizeLimits returns a decoder option which sets
+// the maximum encoded sizes of decoded storables, per kind, in bytes.
+//
+// The sizes of storables are not limited by default.
+// Kinds which are not in the given map are not limited,
+// e.g. RecommendedDecodeSizeLimits does not limit numbers.
+// The given map is copied, so modifying it later does not change the limits of the decoder.
+//
+// Decoding a storable which exceeds the limit for its kind fails with a SuspiciousStorableError.
+//
+func WithDecodeSizeLimits(limits map[StorableKind]uint64) DecoderOption {
+	return func(config *decoderConfig) {
+		newLimits := make(map[StorableKind]uint64, len(limits))
+		for kind, limit := range limits {
+			newLimits[kind] = limit
+		}
+		config.sizeLimits = newLimits
+	}
+}
+
+// encodedStorableKind returns the kind of the storable with the given encoding.
+//
+// Only the head of the encoding is inspected, so the kind can be determined
+// before the storable is decoded.
+//
+func encodedStorableKind(data []byte) StorableKind {
+	if len(data) == 0 {
+		return StorableKindUnknown
+	}
+
+	switch data[0] {
+	case 0xf4, 0xf5:
+		return StorableKindBool
+	case 0xf6:
+		return StorableKindNil
+	}
+
+	const (
+		majorTypeMask       = 0xe0
+		majorTypeTextString = 0x60
+		majorTypeTag        = 0xc0
+	)
+
+	switch data[0] & majorTypeMask {
+	case majorTypeTextString:
+		return StorableKindString
+
+	case majorTypeTag:
+		tag, ok := decodeHeadArgument(data)
+		if !ok {
+			return StorableKindUnknown
+		}
+		return taggedStorableKind(tag)
+
+	default:
+		return StorableKindUnknown
+	}
+}
+
+// decodeHeadArgument returns the argument of the CBOR head at the start of the given data,
+// e.g. the tag number of a tag.
+//
+func decodeHeadArgument(data []byte) (uint64, bool) {
+	additionalInformation := data[0] & 0x1f
+
+	var size int
+	switch {
+	case additionalInformation < 24:
+		return uint64(additionalInformation), true
+	case additionalInformation == 24:
+		size = 1
+	case additionalInformation == 25:
+		size = 2
+	case additionalInformation == 26:
+		size = 4
+	case additionalInformation == 27:
+		size = 8
+	default:
+		return 0, false
+	}
+
+	if len(data) < 1+size {
+		return 0, false
+	}
+
+	var argument uint64
+	for _, b := range data[1 : 1+size] {
+		argument = argument<<8 | uint64(b)
+	}
+	return argument, true
+}
+
+func taggedStorableKind(tag uint64) StorableKind {
+	switch tag {
+	case CBORTagVoidValue:
+		return StorableKindVoid
+	case CBORTagStringValue:
+		return StorableKindString
+	case CBORTagSomeValue:
+		return StorableKindSome
+	case CBORTagAddressValue:
+		return StorableKindAddress
+	case CBORTagPathValue:
+		return StorableKindPath
+	case CBORTagCapabilityValue:
+		return StorableKindCapability
+	case CBORTagLinkValue:
+		return StorableKindLink
+	case CBORTagTypeValue:
+		return StorableKindType
+	}
+
+	if tag >= CBORTagIntValue && tag <= CBORTagUFix64Value {
+		return StorableKindNumber
+	}
+
+	return StorableKindUnknown
+}
+
+// checkDecodeSize checks the given encoding of a storable against the size limit for its kind.
+//
+func (c *decoderConfig) checkDecodeSize(data []byte) error {
+	kind := encodedStorableKind(data)
+	size := uint64(len(data))
+
+	limit, ok := c.sizeLimits[kind]
+	if ok && size > limit {
+		return SuspiciousStorableError{
+			Kind:  kind,
+			Size:  size,
+			Limit: limit,
+		}
+	}
+	return nil
+}
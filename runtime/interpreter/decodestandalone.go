@@ -0,0 +1,73 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+)
+
+// DecodeStorableStandalone decodes the given encoded storable, e.g. a value stored in account storage,
+// without a storage or an interpreter.
+//
+// Only values which are fully contained in the encoded data can be decoded,
+// e.g. numbers, strings, paths, and optionals of such values.
+// Arrays, dictionaries, and composites are stored in separate slabs,
+// so a StandaloneDecodingError is returned for them.
+//
+func DecodeStorableStandalone(data []byte) (Value, error) {
+	decoder := CBORDecMode.NewByteStreamDecoder(data)
+
+	storable, err := DecodeStorable(decoder, atree.StorageIDUndefined)
+	if err != nil {
+		return nil, err
+	}
+
+	return standaloneStoredValue(storable)
+}
+
+func standaloneStoredValue(storable atree.Storable) (Value, error) {
+	switch storable := storable.(type) {
+	case atree.StorageIDStorable:
+		return nil, StandaloneDecodingError{
+			StorageID: atree.StorageID(storable),
+		}
+
+	case EmptyContainerStorable:
+		return nil, StandaloneDecodingError{
+			StorageID: storable.StorageID,
+		}
+
+	case SomeStorable:
+		value, err := standaloneStoredValue(storable.Storable)
+		if err != nil {
+			return nil, err
+		}
+		return NewSomeValueNonCopying(value), nil
+
+	case stringAtreeValue:
+		return NewStringValue(string(storable)), nil
+	}
+
+	storedValue, err := storable.StoredValue(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ConvertStoredValue(storedValue)
+}
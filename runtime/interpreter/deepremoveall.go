@@ -0,0 +1,156 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sort"
+
+	"github.com/onflow/atree"
+)
+
+// DeepRemoveAll removes the given values, including all their nested values, from the given storage.
+//
+// Unlike calling DeepRemove and RemoveReferencedSlab for each value,
+// the slabs of all values are first collected, and then removed in one pass at the end.
+// The final state of the storage is the same as with sequential removal.
+//
+// The values must not be used after they were removed.
+//
+func DeepRemoveAll(interpreter *Interpreter, storage InMemoryStorage, values []Value) error {
+
+	for _, value := range values {
+		if isReadOnlyValue(value) {
+			return ReadOnlyValueMutationError{}
+		}
+	}
+
+	// Shared copies do not own any slabs
+
+	ownedValues := make([]Value, 0, len(values))
+	for _, value := range values {
+		if sharedValue, ok := value.(sharedValue); ok && sharedValue.sharedCopy() != nil {
+			if sharedValue.sharedCopy().root == sharedValue {
+				sharedValue.sharedCopy().release(interpreter)
+				continue
+			}
+			sharedValue.unshare(interpreter)
+		}
+		ownedValues = append(ownedValues, value)
+	}
+
+	storageIDs := map[atree.StorageID]struct{}{}
+
+	var visit func(storable atree.Storable) error
+	visit = func(storable atree.Storable) error {
+		if storageID, ok := referencedStorageID(storable); ok {
+			if _, ok := storageIDs[storageID]; ok {
+				return nil
+			}
+
+			slab, ok, err := storage.Retrieve(storageID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+
+			storageIDs[storageID] = struct{}{}
+
+			if len(interpreter.sharedContainers) > 0 {
+				interpreter.unshareCopies(storageID)
+			}
+
+			storable = slab
+		}
+
+		for _, child := range storable.ChildStorables() {
+			err := visit(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, value := range ownedValues {
+		storageID, ok := containerRootStorageID(value)
+		if !ok {
+			continue
+		}
+
+		err := visit(atree.StorageIDStorable(storageID))
+		if err != nil {
+			return err
+		}
+	}
+
+	sortedStorageIDs := make([]atree.StorageID, 0, len(storageIDs))
+	for storageID := range storageIDs {
+		sortedStorageIDs = append(sortedStorageIDs, storageID)
+	}
+	sort.Slice(sortedStorageIDs, func(i, j int) bool {
+		return sortedStorageIDs[i].Compare(sortedStorageIDs[j]) < 0
+	})
+
+	for _, storageID := range sortedStorageIDs {
+		err := storage.Remove(storageID)
+		if err != nil {
+			return err
+		}
+
+		interpreter.invalidateEqualityCache(storageID)
+	}
+
+	return nil
+}
+
+// containerRootStorageID returns the storage ID of the root slab of the given value,
+// if the value is a container which has been allocated in storage.
+//
+func containerRootStorageID(value Value) (atree.StorageID, bool) {
+	for {
+		someValue, ok := value.(*SomeValue)
+		if !ok {
+			break
+		}
+		value = someValue.Value
+	}
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		if !value.hasRootSlab() {
+			return atree.StorageID{}, false
+		}
+		return value.StorageID(), true
+
+	case *DictionaryValue:
+		if !value.hasRootSlab() {
+			return atree.StorageID{}, false
+		}
+		return value.StorageID(), true
+
+	case *CompositeValue:
+		return value.StorageID(), true
+
+	default:
+		return atree.StorageID{}, false
+	}
+}
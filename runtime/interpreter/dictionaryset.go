@@ -0,0 +1,131 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+// Union returns a new dictionary which contains the entries of this dictionary,
+// and the entries of the other dictionary whose keys are not in this dictionary.
+//
+// The dictionaries must have the same key type, otherwise a DictionaryKeyTypeMismatchError is panicked.
+// The result has the type of this dictionary, is not owned by any account,
+// and contains copies of the keys and values. Both dictionaries are unchanged.
+//
+func (v *DictionaryValue) Union(
+	interpreter *Interpreter,
+	locationRange func() LocationRange,
+	other *DictionaryValue,
+) *DictionaryValue {
+
+	v.checkSetOperationKeyType(locationRange, other)
+
+	keysAndValues := make([]Value, 0, (v.Count()+other.Count())*2)
+
+	v.Iterate(func(key, value Value) (resume bool) {
+		keysAndValues = append(keysAndValues, key, value)
+		return true
+	})
+
+	other.Iterate(func(key, value Value) (resume bool) {
+		if !v.ContainsKey(interpreter, locationRange, key) {
+			keysAndValues = append(keysAndValues, key, value)
+		}
+		return true
+	})
+
+	return v.newSetOperationResult(interpreter, keysAndValues)
+}
+
+// Intersect returns a new dictionary which contains the entries of this dictionary
+// whose keys are also in the other dictionary.
+//
+// The dictionaries must have the same key type, otherwise a DictionaryKeyTypeMismatchError is panicked.
+// The result has the type of this dictionary, is not owned by any account,
+// and contains copies of the keys and values. Both dictionaries are unchanged.
+//
+func (v *DictionaryValue) Intersect(
+	interpreter *Interpreter,
+	locationRange func() LocationRange,
+	other *DictionaryValue,
+) *DictionaryValue {
+
+	v.checkSetOperationKeyType(locationRange, other)
+
+	var keysAndValues []Value
+
+	v.Iterate(func(key, value Value) (resume bool) {
+		if other.ContainsKey(interpreter, locationRange, key) {
+			keysAndValues = append(keysAndValues, key, value)
+		}
+		return true
+	})
+
+	return v.newSetOperationResult(interpreter, keysAndValues)
+}
+
+// Difference returns a new dictionary which contains the entries of this dictionary
+// whose keys are not in the other dictionary.
+//
+// The dictionaries must have the same key type, otherwise a DictionaryKeyTypeMismatchError is panicked.
+// The result has the type of this dictionary, is not owned by any account,
+// and contains copies of the keys and values. Both dictionaries are unchanged.
+//
+func (v *DictionaryValue) Difference(
+	interpreter *Interpreter,
+	locationRange func() LocationRange,
+	other *DictionaryValue,
+) *DictionaryValue {
+
+	v.checkSetOperationKeyType(locationRange, other)
+
+	var keysAndValues []Value
+
+	v.Iterate(func(key, value Value) (resume bool) {
+		if !other.ContainsKey(interpreter, locationRange, key) {
+			keysAndValues = append(keysAndValues, key, value)
+		}
+		return true
+	})
+
+	return v.newSetOperationResult(interpreter, keysAndValues)
+}
+
+func (v *DictionaryValue) checkSetOperationKeyType(locationRange func() LocationRange, other *DictionaryValue) {
+	if !v.Type.KeyType.Equal(other.Type.KeyType) {
+		panic(DictionaryKeyTypeMismatchError{
+			ExpectedType:  v.Type.KeyType,
+			ActualType:    other.Type.KeyType,
+			LocationRange: locationRange(),
+		})
+	}
+}
+
+func (v *DictionaryValue) newSetOperationResult(interpreter *Interpreter, keysAndValues []Value) *DictionaryValue {
+
+	// The keys and values are cloned, so the result
+	// does not share any slabs with the operands
+
+	for i, keyOrValue := range keysAndValues {
+		keysAndValues[i] = keyOrValue.Clone(interpreter)
+	}
+
+	return NewDictionaryValue(
+		interpreter,
+		v.Type,
+		keysAndValues...,
+	)
+}
@@ -0,0 +1,137 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/onflow/cadence/runtime/interpreter"
+)
+
+func TestDictionarySetOperations(t *testing.T) {
+
+	t.Parallel()
+
+	setType := DictionaryStaticType{
+		KeyType:   PrimitiveStaticTypeInt,
+		ValueType: PrimitiveStaticTypeVoid,
+	}
+
+	newSet := func(inter *Interpreter, keys ...int64) *DictionaryValue {
+		keysAndValues := make([]Value, 0, len(keys)*2)
+		for _, key := range keys {
+			keysAndValues = append(keysAndValues, NewIntValueFromInt64(key), VoidValue{})
+		}
+		return NewDictionaryValue(inter, setType, keysAndValues...)
+	}
+
+	setKeys := func(set *DictionaryValue) []int64 {
+		var keys []int64
+		set.Iterate(func(key, _ Value) (resume bool) {
+			keys = append(keys, key.(IntValue).BigInt.Int64())
+			return true
+		})
+		return keys
+	}
+
+	test := func(
+		name string,
+		operation func(a *DictionaryValue, inter *Interpreter, b *DictionaryValue) *DictionaryValue,
+		expected []int64,
+	) {
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			inter := newTestInterpreter(t)
+			storage := inter.Storage.(InMemoryStorage)
+
+			a := newSet(inter, 1, 2, 3, 4)
+			b := newSet(inter, 3, 4, 5)
+
+			slabCount := storage.Count()
+
+			result := operation(a, inter, b)
+
+			require.ElementsMatch(t, expected, setKeys(result))
+
+			// Removing the result does not affect the operands
+
+			result.DeepRemove(inter)
+			err := storage.Remove(result.StorageID())
+			require.NoError(t, err)
+
+			require.Equal(t, slabCount, storage.Count())
+
+			require.ElementsMatch(t, []int64{1, 2, 3, 4}, setKeys(a))
+			require.ElementsMatch(t, []int64{3, 4, 5}, setKeys(b))
+		})
+	}
+
+	test(
+		"union",
+		func(a *DictionaryValue, inter *Interpreter, b *DictionaryValue) *DictionaryValue {
+			return a.Union(inter, ReturnEmptyLocationRange, b)
+		},
+		[]int64{1, 2, 3, 4, 5},
+	)
+
+	test(
+		"intersection",
+		func(a *DictionaryValue, inter *Interpreter, b *DictionaryValue) *DictionaryValue {
+			return a.Intersect(inter, ReturnEmptyLocationRange, b)
+		},
+		[]int64{3, 4},
+	)
+
+	test(
+		"difference",
+		func(a *DictionaryValue, inter *Interpreter, b *DictionaryValue) *DictionaryValue {
+			return a.Difference(inter, ReturnEmptyLocationRange, b)
+		},
+		[]int64{1, 2},
+	)
+
+	t.Run("key type mismatch", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		a := newSet(inter, 1)
+		b := NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeUInt8,
+				ValueType: PrimitiveStaticTypeVoid,
+			},
+			UInt8Value(1), VoidValue{},
+		)
+
+		func() {
+			defer func() {
+				require.IsType(t, DictionaryKeyTypeMismatchError{}, recover())
+			}()
+
+			a.Intersect(inter, ReturnEmptyLocationRange, b)
+		}()
+	})
+}
@@ -0,0 +1,212 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// FirstDifference compares the two given values and returns the path to the first mismatch,
+// along with the mismatched values at that path.
+//
+// Containers are descended into in the same order as they are walked (see Value.Walk):
+// Fields of composites are appended to the path as `.name`,
+// elements of arrays as `[index]`, and entries of dictionaries as `[key]`.
+// Optionals are descended into without extending the path.
+//
+// If a child is only present in one of the values, the other leaf is nil.
+// Leaves are compared using DeepEqual.
+//
+func FirstDifference(interpreter *Interpreter, a, b Value) (path string, aLeaf, bLeaf Value, differ bool) {
+	return firstDifference(interpreter, "", a, b)
+}
+
+func firstDifference(
+	interpreter *Interpreter,
+	path string,
+	a, b Value,
+) (
+	string,
+	Value,
+	Value,
+	bool,
+) {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return "", nil, nil, false
+		}
+		return path, a, b, true
+	}
+
+	switch a := a.(type) {
+	case *SomeValue:
+		if b, ok := b.(*SomeValue); ok {
+			return firstDifference(interpreter, path, a.Value, b.Value)
+		}
+
+	case *CompositeValue:
+		if b, ok := b.(*CompositeValue); ok && a.Kind != common.CompositeKindEnum {
+			return firstCompositeDifference(interpreter, path, a, b)
+		}
+
+	case *ArrayValue:
+		if b, ok := b.(*ArrayValue); ok {
+			return firstArrayDifference(interpreter, path, a, b)
+		}
+
+	case *DictionaryValue:
+		if b, ok := b.(*DictionaryValue); ok {
+			return firstDictionaryDifference(interpreter, path, a, b)
+		}
+	}
+
+	if DeepEqual(interpreter, a, b) {
+		return "", nil, nil, false
+	}
+
+	return path, a, b, true
+}
+
+func firstCompositeDifference(
+	interpreter *Interpreter,
+	path string,
+	a, b *CompositeValue,
+) (
+	resultPath string,
+	aLeaf, bLeaf Value,
+	differ bool,
+) {
+	if !a.StaticType().Equal(b.StaticType()) || a.Kind != b.Kind {
+		return path, a, b, true
+	}
+
+	fieldPath := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	a.ForEachField(func(name string, aField Value) {
+		if differ {
+			return
+		}
+
+		bField := b.GetField(interpreter, ReturnEmptyLocationRange, name)
+		resultPath, aLeaf, bLeaf, differ =
+			firstDifference(interpreter, fieldPath(name), aField, bField)
+	})
+	if differ {
+		return
+	}
+
+	b.ForEachField(func(name string, bField Value) {
+		if differ {
+			return
+		}
+
+		if a.GetField(interpreter, ReturnEmptyLocationRange, name) == nil {
+			resultPath, aLeaf, bLeaf, differ = fieldPath(name), nil, bField, true
+		}
+	})
+
+	return
+}
+
+func firstArrayDifference(
+	interpreter *Interpreter,
+	path string,
+	a, b *ArrayValue,
+) (
+	string,
+	Value,
+	Value,
+	bool,
+) {
+	if !a.Type.Equal(b.Type) {
+		return path, a, b, true
+	}
+
+	aCount := a.Count()
+	bCount := b.Count()
+
+	count := aCount
+	if bCount > count {
+		count = bCount
+	}
+
+	for i := 0; i < count; i++ {
+		elementPath := fmt.Sprintf("%s[%d]", path, i)
+
+		var aElement, bElement Value
+		if i < aCount {
+			aElement = a.Get(interpreter, ReturnEmptyLocationRange, i)
+		}
+		if i < bCount {
+			bElement = b.Get(interpreter, ReturnEmptyLocationRange, i)
+		}
+
+		resultPath, aLeaf, bLeaf, differ :=
+			firstDifference(interpreter, elementPath, aElement, bElement)
+		if differ {
+			return resultPath, aLeaf, bLeaf, true
+		}
+	}
+
+	return "", nil, nil, false
+}
+
+func firstDictionaryDifference(
+	interpreter *Interpreter,
+	path string,
+	a, b *DictionaryValue,
+) (
+	resultPath string,
+	aLeaf, bLeaf Value,
+	differ bool,
+) {
+	if !a.Type.Equal(b.Type) {
+		return path, a, b, true
+	}
+
+	entryPath := func(key Value) string {
+		return fmt.Sprintf("%s[%s]", path, key)
+	}
+
+	a.Iterate(func(key, aValue Value) (resume bool) {
+		bValue, _ := b.Get(interpreter, ReturnEmptyLocationRange, key)
+		resultPath, aLeaf, bLeaf, differ =
+			firstDifference(interpreter, entryPath(key), aValue, bValue)
+		return !differ
+	})
+	if differ {
+		return
+	}
+
+	b.Iterate(func(key, bValue Value) (resume bool) {
+		if !bool(a.ContainsKey(interpreter, ReturnEmptyLocationRange, key)) {
+			resultPath, aLeaf, bLeaf, differ = entryPath(key), nil, bValue, true
+		}
+		return !differ
+	})
+
+	return
+}
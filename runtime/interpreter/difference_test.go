@@ -0,0 +1,157 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestFirstDifference(t *testing.T) {
+
+	t.Parallel()
+
+	dictionaryType := DictionaryStaticType{
+		KeyType:   PrimitiveStaticTypeString,
+		ValueType: PrimitiveStaticTypeInt,
+	}
+
+	newValue := func(inter *Interpreter, second int64, extraKey bool) Value {
+
+		keysAndValues := []Value{
+			NewStringValue("a"), NewIntValueFromInt64(1),
+		}
+		if extraKey {
+			keysAndValues = append(keysAndValues, NewStringValue("b"), NewIntValueFromInt64(2))
+		}
+
+		inner := NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Inner",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name: "values",
+					Value: NewArrayValue(
+						inter,
+						VariableSizedStaticType{
+							Type: PrimitiveStaticTypeInt,
+						},
+						common.Address{},
+						NewIntValueFromInt64(1),
+						NewIntValueFromInt64(second),
+						NewIntValueFromInt64(3),
+					),
+				},
+				{
+					Name:  "counts",
+					Value: NewDictionaryValue(inter, dictionaryType, keysAndValues...),
+				},
+			},
+			common.Address{},
+		)
+
+		return NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Outer",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name:  "inner",
+					Value: NewSomeValueNonCopying(inner),
+				},
+			},
+			common.Address{},
+		)
+	}
+
+	t.Run("equal", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		path, aLeaf, bLeaf, differ := FirstDifference(
+			inter,
+			newValue(inter, 2, false),
+			newValue(inter, 2, false),
+		)
+		require.False(t, differ)
+		require.Empty(t, path)
+		require.Nil(t, aLeaf)
+		require.Nil(t, bLeaf)
+	})
+
+	t.Run("nested array element", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		path, aLeaf, bLeaf, differ := FirstDifference(
+			inter,
+			newValue(inter, 2, false),
+			newValue(inter, 5, false),
+		)
+		require.True(t, differ)
+		require.Equal(t, "inner.values[1]", path)
+		require.Equal(t, NewIntValueFromInt64(2), aLeaf)
+		require.Equal(t, NewIntValueFromInt64(5), bLeaf)
+	})
+
+	t.Run("missing dictionary entry", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		path, aLeaf, bLeaf, differ := FirstDifference(
+			inter,
+			newValue(inter, 2, false),
+			newValue(inter, 2, true),
+		)
+		require.True(t, differ)
+		require.Equal(t, `inner.counts["b"]`, path)
+		require.Nil(t, aLeaf)
+		require.Equal(t, NewIntValueFromInt64(2), bLeaf)
+	})
+
+	t.Run("different types", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		a := NewStringValue("a")
+		b := NewIntValueFromInt64(1)
+
+		path, aLeaf, bLeaf, differ := FirstDifference(inter, a, b)
+		require.True(t, differ)
+		require.Empty(t, path)
+		require.Equal(t, a, aLeaf)
+		require.Equal(t, b, bLeaf)
+	})
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/onflow/atree"
 
 	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
 )
 
 const cborTagSize = 2
@@ -101,7 +102,7 @@ const (
 	CBORTagTypeValue
 	_ // DO *NOT* REPLACE. Previously used for array values
 	CBORTagStringValue
-	_
+	CBORTagEmptyContainerValue
 	_
 	_
 	_
@@ -916,6 +917,46 @@ func (v TypeValue) Encode(e *atree.Encoder) error {
 	return EncodeStaticType(e.CBOR, v.Type)
 }
 
+// NOTE: NEVER change, only add/increment; ensure uint64
+const (
+	// encodedEmptyContainerStorageIDFieldKey uint64 = 0
+	// encodedEmptyContainerTypeFieldKey      uint64 = 1
+
+	// !!! *WARNING* !!!
+	//
+	// encodedEmptyContainerLength MUST be updated when new element is added.
+	// It is used to verify encoded empty container length during decoding.
+	encodedEmptyContainerLength = 2
+)
+
+// Encode encodes EmptyContainerStorable as
+// cbor.Tag{
+//			Number: CBORTagEmptyContainerValue,
+//			Content: []interface{}{
+//				encodedEmptyContainerStorageIDFieldKey: atree.StorageIDStorable(v.StorageID),
+//				encodedEmptyContainerTypeFieldKey:      StaticType(v.Type),
+//			},
+// }
+func (s EmptyContainerStorable) Encode(e *atree.Encoder) error {
+	// Encode tag number and array head
+	err := e.CBOR.EncodeRawBytes([]byte{
+		// tag number
+		0xd8, CBORTagEmptyContainerValue,
+		// array, 2 items follow
+		0x82,
+	})
+	if err != nil {
+		return err
+	}
+	// Encode storage ID at array index encodedEmptyContainerStorageIDFieldKey
+	err = atree.StorageIDStorable(s.StorageID).Encode(e)
+	if err != nil {
+		return err
+	}
+	// Encode type at array index encodedEmptyContainerTypeFieldKey
+	return EncodeStaticType(e.CBOR, s.Type)
+}
+
 func StaticTypeToBytes(t StaticType) (cbor.RawMessage, error) {
 	var buf bytes.Buffer
 	enc := CBOREncMode.NewStreamEncoder(&buf)
@@ -1319,3 +1360,68 @@ func (c compositeTypeInfo) Equal(o atree.TypeInfo) bool {
 		c.qualifiedIdentifier == other.qualifiedIdentifier &&
 		c.kind == other.kind
 }
+
+// EnumKeyBytes returns the canonical encoding of the enum value, when used as a dictionary key:
+//
+// cborArray{
+//		Location(v.Location),
+//		string(v.QualifiedIdentifier),
+//		v.rawValue,
+// }
+//
+// Enum values of the same type and with equal raw values have the same encoding,
+// independent of how the values were constructed or the order of their fields.
+//
+func (v *CompositeValue) EnumKeyBytes(interpreter *Interpreter) ([]byte, error) {
+	if v.Kind != common.CompositeKindEnum {
+		return nil, fmt.Errorf(
+			"cannot encode enum key: %s is not an enum",
+			v.TypeID(),
+		)
+	}
+
+	rawValue, ok := v.GetField(
+		interpreter,
+		ReturnEmptyLocationRange,
+		sema.EnumRawValueFieldName,
+	).(atree.Storable)
+	if !ok {
+		return nil, fmt.Errorf(
+			"cannot encode enum key: %s has no raw value",
+			v.TypeID(),
+		)
+	}
+
+	var buf bytes.Buffer
+	enc := atree.NewEncoder(&buf, CBOREncMode)
+
+	err := enc.CBOR.EncodeRawBytes([]byte{
+		// array, 3 items follow
+		0x83,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = encodeLocation(enc.CBOR, v.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.CBOR.EncodeString(v.QualifiedIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rawValue.Encode(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.CBOR.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
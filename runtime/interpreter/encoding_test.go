@@ -22,7 +22,9 @@ import (
 	"math"
 	"math/big"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/onflow/atree"
@@ -3824,3 +3826,446 @@ func TestEncodeDecodeStaticType(t *testing.T) {
 		require.Equal(t, ty, actualType)
 	})
 }
+
+// BenchmarkDecodeHeterogeneousArray measures loading a large array of mixed element types.
+//
+// NOTE: Each encoded element is already tagged with a CBOR tag identifying its concrete type,
+// so decoding never needs to infer the types of the elements of an `[AnyStruct]` array.
+//
+func BenchmarkDecodeHeterogeneousArray(b *testing.B) {
+
+	const elementCount = 10_000
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(b, err)
+
+	elements := make([]Value, elementCount)
+	for i := range elements {
+		switch i % 4 {
+		case 0:
+			elements[i] = NewIntValueFromInt64(int64(i))
+		case 1:
+			elements[i] = NewStringValue(strings.Repeat("x", i%32))
+		case 2:
+			elements[i] = BoolValue(i%3 == 0)
+		case 3:
+			elements[i] = NewUFix64ValueWithInteger(uint64(i))
+		}
+	}
+
+	array := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeAnyStruct,
+		},
+		common.Address{0x1},
+		elements...,
+	)
+
+	encoded, err := storage.Encode()
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		decodedStorage := NewInMemoryStorage()
+
+		err := decodedStorage.Load(encoded)
+		require.NoError(b, err)
+
+		slab, ok, err := decodedStorage.Retrieve(array.StorageID())
+		require.NoError(b, err)
+		require.True(b, ok)
+
+		decodedArray := StoredValue(slab, decodedStorage).(*ArrayValue)
+
+		count := 0
+		decodedArray.Iterate(func(_ Value) (resume bool) {
+			count++
+			return true
+		})
+		require.Equal(b, elementCount, count)
+	}
+}
+
+func TestDecodeSizeLimits(t *testing.T) {
+
+	t.Parallel()
+
+	decode := func(decodeStorable atree.StorableDecoder, encoded []byte) error {
+		decoder := CBORDecMode.NewByteStreamDecoder(encoded)
+		_, err := decodeStorable(decoder, atree.StorageIDUndefined)
+		return err
+	}
+
+	t.Run("oversized bool", func(t *testing.T) {
+
+		t.Parallel()
+
+		// NOTE: The CBOR encoding of a boolean is always a single byte,
+		// so lower the limit to make it oversized
+
+		decodeStorable := NewStorableDecoder(
+			WithDecodeSizeLimits(map[StorableKind]uint64{
+				StorableKindBool: 0,
+			}),
+		)
+
+		err := decode(
+			decodeStorable,
+			[]byte{
+				// true
+				0xf5,
+			},
+		)
+		require.Equal(t,
+			SuspiciousStorableError{
+				Kind:  StorableKindBool,
+				Size:  1,
+				Limit: 0,
+			},
+			err,
+		)
+
+		// Other kinds are not limited
+
+		err = decode(
+			decodeStorable,
+			[]byte{
+				// null
+				0xf6,
+			},
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("not limited by default", func(t *testing.T) {
+
+		t.Parallel()
+
+		err := decode(
+			DecodeStorable,
+			[]byte{
+				// true
+				0xf5,
+			},
+		)
+		require.NoError(t, err)
+
+		// The encoding of a large integer is far larger than the recommended limits of other kinds
+
+		value := NewIntValueFromBigInt(new(big.Int).Lsh(big.NewInt(1), 40000))
+
+		encoded, err := atree.Encode(value, CBOREncMode)
+		require.NoError(t, err)
+		require.Greater(t, len(encoded), 4<<10)
+
+		decoder := CBORDecMode.NewByteStreamDecoder(encoded)
+		decoded, err := DecodeStorable(decoder, atree.StorageIDUndefined)
+		require.NoError(t, err)
+		require.Equal(t, value, decoded)
+
+		// Numbers are also not limited by the recommended limits
+
+		err = decode(
+			NewStorableDecoder(WithDecodeSizeLimits(RecommendedDecodeSizeLimits())),
+			encoded,
+		)
+		require.NoError(t, err)
+	})
+
+	t.Run("oversized void", func(t *testing.T) {
+
+		t.Parallel()
+
+		// The content of a void value is skipped when decoding,
+		// so a crafted void value may contain arbitrary data
+
+		encoded := []byte{
+			// tag
+			0xd8, CBORTagVoidValue,
+			// byte string, 1024 bytes follow
+			0x59, 0x04, 0x00,
+		}
+		encoded = append(encoded, make([]byte, 1024)...)
+
+		err := decode(
+			NewStorableDecoder(WithDecodeSizeLimits(RecommendedDecodeSizeLimits())),
+			encoded,
+		)
+
+		var suspiciousErr SuspiciousStorableError
+		require.ErrorAs(t, err, &suspiciousErr)
+		require.Equal(t, StorableKindVoid, suspiciousErr.Kind)
+	})
+
+	t.Run("limits are copied", func(t *testing.T) {
+
+		t.Parallel()
+
+		limits := map[StorableKind]uint64{
+			StorableKindBool: 0,
+		}
+
+		decodeStorable := NewStorableDecoder(WithDecodeSizeLimits(limits))
+
+		// Modifying the given limits and the recommended limits does not affect decoders
+
+		limits[StorableKindBool] = 16
+		RecommendedDecodeSizeLimits()[StorableKindNil] = 0
+
+		err := decode(
+			decodeStorable,
+			[]byte{
+				// true
+				0xf5,
+			},
+		)
+		var suspiciousErr SuspiciousStorableError
+		require.ErrorAs(t, err, &suspiciousErr)
+
+		err = decode(
+			NewStorableDecoder(WithDecodeSizeLimits(RecommendedDecodeSizeLimits())),
+			[]byte{
+				// null
+				0xf6,
+			},
+		)
+		require.NoError(t, err)
+	})
+}
+
+func TestEnumKeyBytes(t *testing.T) {
+
+	t.Parallel()
+
+	newComposite := func(inter *Interpreter, kind common.CompositeKind, rawValue Value) *CompositeValue {
+		return NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"E",
+			kind,
+			[]CompositeField{
+				{
+					Name:  sema.EnumRawValueFieldName,
+					Value: rawValue,
+				},
+			},
+			common.Address{},
+		)
+	}
+
+	t.Run("enum", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		encoded, err := newComposite(inter, common.CompositeKindEnum, UInt8Value(1)).
+			EnumKeyBytes(inter)
+		require.NoError(t, err)
+
+		require.Equal(t,
+			[]byte{
+				// array, 3 items follow
+				0x83,
+				// tag
+				0xd8, CBORTagStringLocation,
+				// UTF-8 string, 4 bytes follow
+				0x64,
+				// t, e, s, t
+				0x74, 0x65, 0x73, 0x74,
+				// UTF-8 string, 1 byte follows
+				0x61,
+				// E
+				0x45,
+				// tag
+				0xd8, CBORTagUInt8Value,
+				// positive integer 1
+				0x1,
+			},
+			encoded,
+		)
+
+		// An equal enum, owned by an account, has the same encoding
+
+		owned := newComposite(inter, common.CompositeKindEnum, UInt8Value(1)).
+			Transfer(inter, ReturnEmptyLocationRange, atree.Address(testOwner), false, nil).(*CompositeValue)
+
+		ownedEncoded, err := owned.EnumKeyBytes(inter)
+		require.NoError(t, err)
+		require.Equal(t, encoded, ownedEncoded)
+	})
+
+	t.Run("not an enum", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		_, err := newComposite(inter, common.CompositeKindStructure, UInt8Value(1)).
+			EnumKeyBytes(inter)
+		require.Error(t, err)
+	})
+}
+
+func TestDecodeStorableStandalone(t *testing.T) {
+
+	t.Parallel()
+
+	encode := func(t *testing.T, storable atree.Storable) []byte {
+		encoded, err := atree.Encode(storable, CBOREncMode)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	t.Run("integer", func(t *testing.T) {
+
+		t.Parallel()
+
+		value, err := DecodeStorableStandalone(
+			encode(t, NewIntValueFromInt64(42)),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, NewIntValueFromInt64(42), value)
+	})
+
+	t.Run("string", func(t *testing.T) {
+
+		t.Parallel()
+
+		value, err := DecodeStorableStandalone(
+			encode(t, NewStringValue("test")),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, NewStringValue("test"), value)
+	})
+
+	t.Run("optional", func(t *testing.T) {
+
+		t.Parallel()
+
+		value, err := DecodeStorableStandalone(
+			encode(t, SomeStorable{
+				Storable: UInt8Value(1),
+			}),
+		)
+		require.NoError(t, err)
+
+		require.Equal(t,
+			NewSomeValueNonCopying(UInt8Value(1)),
+			value,
+		)
+	})
+
+	t.Run("separate slab", func(t *testing.T) {
+
+		t.Parallel()
+
+		storageID := atree.NewStorageID(
+			atree.Address(testOwner),
+			atree.StorageIndex{0, 0, 0, 0, 0, 0, 0, 1},
+		)
+
+		_, err := DecodeStorableStandalone(
+			encode(t, atree.StorageIDStorable(storageID)),
+		)
+		require.Equal(t,
+			StandaloneDecodingError{
+				StorageID: storageID,
+			},
+			err,
+		)
+	})
+}
+
+func TestCodecProfiler(t *testing.T) {
+
+	// NOTE: The profiler is process-wide, so this test is not run in parallel
+
+	type observation struct {
+		op    string
+		bytes int
+	}
+
+	var observations []observation
+
+	SetCodecProfiler(func(op string, bytes int, dur time.Duration) {
+		require.GreaterOrEqual(t, dur, time.Duration(0))
+		observations = append(observations, observation{
+			op:    op,
+			bytes: bytes,
+		})
+	})
+	defer SetCodecProfiler(nil)
+
+	value := NewStringValue("test")
+
+	encoded, err := EncodeStorable(value)
+	require.NoError(t, err)
+
+	size, err := StorableSize(value)
+	require.NoError(t, err)
+
+	decoder := CBORDecMode.NewByteStreamDecoder(encoded)
+	decoded, err := DecodeStorable(decoder, atree.StorageIDUndefined)
+	require.NoError(t, err)
+
+	require.Equal(t, value, decoded)
+
+	require.Equal(t,
+		[]observation{
+			{
+				op:    CodecOperationEncode,
+				bytes: len(encoded),
+			},
+			{
+				op:    CodecOperationSize,
+				bytes: int(size),
+			},
+			{
+				op:    CodecOperationDecode,
+				bytes: len(encoded),
+			},
+		},
+		observations,
+	)
+}
+
+func TestCodecProfilerConcurrentSet(t *testing.T) {
+
+	// NOTE: The profiler is process-wide, so this test is not run in parallel
+
+	defer SetCodecProfiler(nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			SetCodecProfiler(func(op string, bytes int, dur time.Duration) {})
+			SetCodecProfiler(nil)
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			_, err := EncodeStorable(NewStringValue("test"))
+			require.NoError(t, err)
+		}()
+	}
+
+	wg.Wait()
+}
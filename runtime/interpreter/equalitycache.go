@@ -0,0 +1,250 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"container/list"
+
+	"github.com/onflow/atree"
+)
+
+type equalityCacheKey struct {
+	a, b atree.StorageID
+}
+
+func newEqualityCacheKey(a, b atree.StorageID) equalityCacheKey {
+	// Equality is symmetric, so order the pair
+	if b.Compare(a) < 0 {
+		a, b = b, a
+	}
+	return equalityCacheKey{a: a, b: b}
+}
+
+// DefaultEqualityCacheMaxEntries is the maximum number of results memoized by an equality cache
+// created with NewEqualityCache.
+//
+const DefaultEqualityCacheMaxEntries = 10_000
+
+// EqualityCache memoizes the results of DeepEqual for pairs of stored container values,
+// keyed by the pair of their storage IDs.
+//
+// A result is invalidated when one of the two containers, or any container nested in them, is mutated,
+// as a mutation of a nested value also changes the result for its parents.
+// The results for other pairs are kept.
+//
+// The number of memoized results is bounded:
+// when the cache is full, the least recently used result is evicted.
+//
+type EqualityCache struct {
+	results map[equalityCacheKey]*equalityCacheEntry
+	// recentlyUsed are the keys of the results, least recently used first
+	recentlyUsed *list.List
+	// dependents are the keys of the results which depend on the container with the storage ID
+	dependents map[atree.StorageID]map[equalityCacheKey]struct{}
+	maxEntries int
+	hits       int
+	misses     int
+}
+
+type equalityCacheEntry struct {
+	equal bool
+	// dependencies are the storage IDs of the containers the result depends on
+	dependencies []atree.StorageID
+	element      *list.Element
+}
+
+// NewEqualityCache returns a new equality cache,
+// which memoizes at most DefaultEqualityCacheMaxEntries results.
+//
+func NewEqualityCache() *EqualityCache {
+	return NewEqualityCacheWithMaxEntries(DefaultEqualityCacheMaxEntries)
+}
+
+// NewEqualityCacheWithMaxEntries returns a new equality cache,
+// which memoizes at most the given number of results.
+//
+func NewEqualityCacheWithMaxEntries(maxEntries int) *EqualityCache {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+
+	return &EqualityCache{
+		results:      map[equalityCacheKey]*equalityCacheEntry{},
+		recentlyUsed: list.New(),
+		dependents:   map[atree.StorageID]map[equalityCacheKey]struct{}{},
+		maxEntries:   maxEntries,
+	}
+}
+
+func (c *EqualityCache) get(a, b atree.StorageID) (equal bool, ok bool) {
+	entry, ok := c.results[newEqualityCacheKey(a, b)]
+	if !ok {
+		c.misses++
+		return false, false
+	}
+
+	c.hits++
+	c.recentlyUsed.MoveToBack(entry.element)
+
+	return entry.equal, true
+}
+
+func (c *EqualityCache) set(storage atree.SlabStorage, a, b atree.StorageID, equal bool) {
+	key := newEqualityCacheKey(a, b)
+
+	// The dependencies of an existing result may have changed, so replace it
+
+	c.evict(key)
+
+	for len(c.results) >= c.maxEntries {
+		leastRecentlyUsed := c.recentlyUsed.Front().Value.(equalityCacheKey)
+		c.evict(leastRecentlyUsed)
+	}
+
+	entry := &equalityCacheEntry{
+		equal:   equal,
+		element: c.recentlyUsed.PushBack(key),
+	}
+	c.results[key] = entry
+
+	addDependency := func(storageID atree.StorageID) {
+		entry.dependencies = append(entry.dependencies, storageID)
+
+		keys, ok := c.dependents[storageID]
+		if !ok {
+			keys = map[equalityCacheKey]struct{}{}
+			c.dependents[storageID] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	walkReferencedSlabs(storage, a, addDependency)
+	if b != a {
+		walkReferencedSlabs(storage, b, addDependency)
+	}
+}
+
+// evict removes the memoized result with the given key, if any,
+// and removes the key from the dependents of all its dependencies.
+//
+func (c *EqualityCache) evict(key equalityCacheKey) {
+	entry, ok := c.results[key]
+	if !ok {
+		return
+	}
+
+	delete(c.results, key)
+	c.recentlyUsed.Remove(entry.element)
+
+	for _, storageID := range entry.dependencies {
+		keys := c.dependents[storageID]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.dependents, storageID)
+		}
+	}
+}
+
+// Invalidate removes the memoized results which depend on the container with the given storage ID.
+//
+func (c *EqualityCache) Invalidate(storageID atree.StorageID) {
+	keys, ok := c.dependents[storageID]
+	if !ok {
+		return
+	}
+
+	// NOTE: ranging over the map is safe, as the order of evictions does not matter
+	for key := range keys { //nolint:maprangecheck
+		c.evict(key)
+	}
+}
+
+// Len returns the number of memoized results.
+//
+func (c *EqualityCache) Len() int {
+	return len(c.results)
+}
+
+// Stats returns the number of lookups that were answered from the cache,
+// and the number of lookups that required a full comparison.
+//
+func (c *EqualityCache) Stats() (hits int, misses int) {
+	return c.hits, c.misses
+}
+
+// storedStorageID returns the storage ID of the given value,
+// if it is a container value that is stored in an account.
+//
+func storedStorageID(value Value) (atree.StorageID, bool) {
+	var storageID atree.StorageID
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		storageID = value.StorageID()
+	case *DictionaryValue:
+		storageID = value.StorageID()
+	case *CompositeValue:
+		storageID = value.StorageID()
+	default:
+		return atree.StorageID{}, false
+	}
+
+	if storageID.Address == (atree.Address{}) {
+		return atree.StorageID{}, false
+	}
+
+	return storageID, true
+}
+
+// walkReferencedSlabs calls the given function for the given storage ID,
+// and the storage IDs of all slabs referenced by the slab, recursively.
+//
+// The slabs are not decoded into values, and slabs which are not allocated are skipped.
+//
+func walkReferencedSlabs(storage atree.SlabStorage, storageID atree.StorageID, f func(atree.StorageID)) {
+	visited := map[atree.StorageID]struct{}{}
+
+	var visit func(storable atree.Storable)
+	visit = func(storable atree.Storable) {
+		if storageID, ok := referencedStorageID(storable); ok {
+			if _, ok := visited[storageID]; ok {
+				return
+			}
+			visited[storageID] = struct{}{}
+
+			f(storageID)
+
+			slab, ok, err := storage.Retrieve(storageID)
+			if err != nil {
+				panic(ExternalError{err})
+			}
+			if !ok {
+				return
+			}
+
+			storable = slab
+		}
+
+		for _, child := range storable.ChildStorables() {
+			visit(child)
+		}
+	}
+
+	visit(atree.StorageIDStorable(storageID))
+}
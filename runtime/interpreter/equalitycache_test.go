@@ -0,0 +1,319 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func newEqualityCacheTestInterpreter(tb testing.TB, cache *EqualityCache) *Interpreter {
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+		WithEqualityCache(cache),
+	)
+	require.NoError(tb, err)
+
+	return inter
+}
+
+func newIntArray(inter *Interpreter, address common.Address, count int, value int64) *ArrayValue {
+	elements := make([]Value, count)
+	for i := range elements {
+		elements[i] = NewIntValueFromInt64(value)
+	}
+
+	return NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		},
+		address,
+		elements...,
+	)
+}
+
+func TestEqualityCache(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	t.Run("repeated comparison", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		a := newIntArray(inter, address, 10, 1)
+		b := newIntArray(inter, address, 10, 1)
+
+		require.True(t, DeepEqual(inter, a, b))
+		require.True(t, DeepEqual(inter, b, a))
+		require.True(t, DeepEqual(inter, a, b))
+
+		hits, misses := cache.Stats()
+		require.Equal(t, 2, hits)
+		require.Equal(t, 1, misses)
+	})
+
+	t.Run("mutation", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		a := newIntArray(inter, address, 10, 1)
+		b := newIntArray(inter, address, 10, 1)
+
+		require.True(t, DeepEqual(inter, a, b))
+
+		a.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+
+		require.False(t, DeepEqual(inter, a, b))
+
+		hits, misses := cache.Stats()
+		require.Equal(t, 0, hits)
+		require.Equal(t, 2, misses)
+	})
+
+	t.Run("nested mutation", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		arrayType := VariableSizedStaticType{
+			Type: VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+		}
+
+		a := NewArrayValue(inter, arrayType, address, newIntArray(inter, address, 2, 1))
+		b := NewArrayValue(inter, arrayType, address, newIntArray(inter, address, 2, 1))
+
+		require.True(t, DeepEqual(inter, a, b))
+
+		inner := a.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+		inner.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.False(t, DeepEqual(inter, a, b))
+	})
+
+	t.Run("unrelated mutation", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		a := newIntArray(inter, address, 10, 1)
+		b := newIntArray(inter, address, 10, 1)
+		c := newIntArray(inter, address, 10, 1)
+
+		require.True(t, DeepEqual(inter, a, b))
+
+		c.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+
+		require.True(t, DeepEqual(inter, a, b))
+
+		hits, misses := cache.Stats()
+		require.Equal(t, 1, hits)
+		require.Equal(t, 1, misses)
+	})
+
+	t.Run("invalidation", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		a := newIntArray(inter, address, 10, 1)
+		b := newIntArray(inter, address, 10, 1)
+		c := newIntArray(inter, address, 10, 1)
+
+		require.True(t, DeepEqual(inter, a, b))
+		require.True(t, DeepEqual(inter, a, c))
+		require.Equal(t, 2, cache.Len())
+
+		// Only the results which depend on the mutated container are removed
+
+		b.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+		require.Equal(t, 1, cache.Len())
+
+		a.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+		require.Equal(t, 0, cache.Len())
+
+		// Mutating a container whose results were all removed is a no-op
+
+		c.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+		require.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCacheWithMaxEntries(2)
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		a := newIntArray(inter, address, 10, 1)
+		b := newIntArray(inter, address, 10, 1)
+		c := newIntArray(inter, address, 10, 1)
+
+		require.True(t, DeepEqual(inter, a, b))
+		require.True(t, DeepEqual(inter, a, c))
+
+		// Using the result for a and b makes the result for a and c the least recently used
+
+		require.True(t, DeepEqual(inter, a, b))
+
+		require.True(t, DeepEqual(inter, b, c))
+		require.Equal(t, 2, cache.Len())
+
+		hits, misses := cache.Stats()
+		require.Equal(t, 1, hits)
+		require.Equal(t, 3, misses)
+
+		// The result for a and c was evicted
+
+		require.True(t, DeepEqual(inter, a, b))
+		require.True(t, DeepEqual(inter, a, c))
+
+		hits, misses = cache.Stats()
+		require.Equal(t, 2, hits)
+		require.Equal(t, 4, misses)
+		require.Equal(t, 2, cache.Len())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+		storage := inter.Storage.(InMemoryStorage)
+
+		a := newIntArray(inter, address, 0, 0)
+		b := newIntArray(inter, address, 0, 0)
+
+		// Comparing empty containers does not allocate their root slabs
+
+		require.True(t, DeepEqual(inter, a, b))
+		require.Equal(t, 0, storage.Count())
+
+		a.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(1))
+
+		require.False(t, DeepEqual(inter, a, b))
+	})
+
+	t.Run("not stored", func(t *testing.T) {
+
+		t.Parallel()
+
+		cache := NewEqualityCache()
+		inter := newEqualityCacheTestInterpreter(t, cache)
+
+		a := newIntArray(inter, common.Address{}, 10, 1)
+		b := newIntArray(inter, common.Address{}, 10, 1)
+
+		require.True(t, DeepEqual(inter, a, b))
+		require.True(t, DeepEqual(inter, a, b))
+
+		hits, misses := cache.Stats()
+		require.Equal(t, 0, hits)
+		require.Equal(t, 0, misses)
+	})
+}
+
+// BenchmarkSortWithEqualityCache sorts large stored arrays, many of which are equal.
+// The reported traversals are the number of full structural comparisons.
+//
+func BenchmarkSortWithEqualityCache(b *testing.B) {
+
+	const valueCount = 100
+	const elementCount = 1_000
+
+	address := common.Address{0x1}
+
+	run := func(b *testing.B, cached bool) {
+		var cache *EqualityCache
+		if cached {
+			cache = NewEqualityCache()
+		}
+
+		inter := newEqualityCacheTestInterpreter(b, cache)
+
+		values := make([]*ArrayValue, valueCount)
+		keys := make(map[*ArrayValue]int64, valueCount)
+		for i := range values {
+			key := int64(i % 4)
+			values[i] = newIntArray(inter, address, elementCount, key)
+			keys[values[i]] = key
+		}
+
+		comparisons := 0
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			sorted := make([]*ArrayValue, len(values))
+			copy(sorted, values)
+
+			sort.SliceStable(sorted, func(i, j int) bool {
+				comparisons++
+				if DeepEqual(inter, sorted[i], sorted[j]) {
+					return false
+				}
+				return keys[sorted[i]] < keys[sorted[j]]
+			})
+		}
+
+		traversals := comparisons
+		if cache != nil {
+			_, traversals = cache.Stats()
+		}
+
+		b.ReportMetric(float64(traversals)/float64(b.N), "traversals/op")
+	}
+
+	b.Run("without cache", func(b *testing.B) {
+		run(b, false)
+	})
+
+	b.Run("with cache", func(b *testing.B) {
+		run(b, true)
+	})
+}
@@ -0,0 +1,211 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// EqualToDepth compares the given values like DeepEqual,
+// but only examines the values up to the given depth.
+//
+// Like for SizeByDepth, the given values are at depth 0, the children of a container
+// (array, dictionary, or composite) are one level deeper than the container,
+// and optionals do not introduce a new level.
+//
+// If the values were found to be different, equal is false, and the result is conclusive.
+// If no differences were found, equal is true, and the result is only conclusive
+// if no children of containers at the maximum depth had to be skipped.
+// An inconclusive result can be used as a pre-filter before calling DeepEqual.
+//
+func EqualToDepth(interpreter *Interpreter, a, b Value, maxDepth int) (equal bool, conclusive bool) {
+	return equalToDepth(interpreter, a, b, 0, maxDepth)
+}
+
+func equalToDepth(interpreter *Interpreter, a, b Value, depth, maxDepth int) (equal bool, conclusive bool) {
+	if a == nil || b == nil {
+		return a == nil && b == nil, true
+	}
+
+	switch a := a.(type) {
+	case *SomeValue:
+		otherSome, ok := b.(*SomeValue)
+		if !ok {
+			return false, true
+		}
+
+		return equalToDepth(interpreter, a.Value, otherSome.Value, depth, maxDepth)
+
+	case *ArrayValue:
+		otherArray, ok := b.(*ArrayValue)
+		if !ok {
+			return false, true
+		}
+
+		count := a.Count()
+
+		if count != otherArray.Count() ||
+			!staticTypesEqual(a.Type, otherArray.Type) {
+
+			return false, true
+		}
+
+		if count == 0 {
+			return true, true
+		}
+
+		if depth >= maxDepth {
+			return true, false
+		}
+
+		conclusive = true
+
+		for i := 0; i < count; i++ {
+			childEqual, childConclusive := equalToDepth(
+				interpreter,
+				a.Get(interpreter, ReturnEmptyLocationRange, i),
+				otherArray.Get(interpreter, ReturnEmptyLocationRange, i),
+				depth+1,
+				maxDepth,
+			)
+			if !childEqual {
+				return false, true
+			}
+
+			conclusive = conclusive && childConclusive
+		}
+
+		return true, conclusive
+
+	case *DictionaryValue:
+		otherDictionary, ok := b.(*DictionaryValue)
+		if !ok {
+			return false, true
+		}
+
+		count := a.Count()
+
+		if count != otherDictionary.Count() ||
+			!a.Type.Equal(otherDictionary.Type) {
+
+			return false, true
+		}
+
+		if count == 0 {
+			return true, true
+		}
+
+		if depth >= maxDepth {
+			return true, false
+		}
+
+		equal = true
+		conclusive = true
+
+		a.Iterate(func(key, value Value) (resume bool) {
+			// Do NOT use an iterator for the other dictionary,
+			// the iteration order may be different (see DictionaryValue.Equal)
+			otherValue, ok := otherDictionary.Get(interpreter, ReturnEmptyLocationRange, key)
+			if !ok {
+				equal = false
+				return false
+			}
+
+			childEqual, childConclusive := equalToDepth(interpreter, value, otherValue, depth+1, maxDepth)
+			if !childEqual {
+				equal = false
+				return false
+			}
+
+			conclusive = conclusive && childConclusive
+			return true
+		})
+
+		if !equal {
+			return false, true
+		}
+
+		return true, conclusive
+
+	case *CompositeValue:
+		if a.Kind == common.CompositeKindEnum {
+			return deepEqual(interpreter, a, b), true
+		}
+
+		otherComposite, ok := b.(*CompositeValue)
+		if !ok {
+			return false, true
+		}
+
+		count := a.dictionary.Count()
+
+		if !a.StaticType().Equal(otherComposite.StaticType()) ||
+			a.Kind != otherComposite.Kind ||
+			count != otherComposite.dictionary.Count() {
+
+			return false, true
+		}
+
+		if count == 0 {
+			return true, true
+		}
+
+		if depth >= maxDepth {
+			return true, false
+		}
+
+		equal = true
+		conclusive = true
+
+		a.ForEachField(func(name string, value Value) {
+			if !equal {
+				return
+			}
+
+			// Do NOT use an iterator for the other composite,
+			// the iteration order may be different (see CompositeValue.Equal)
+			otherValue := otherComposite.GetField(interpreter, ReturnEmptyLocationRange, name)
+
+			childEqual, childConclusive := equalToDepth(interpreter, value, otherValue, depth+1, maxDepth)
+			if !childEqual {
+				equal = false
+				return
+			}
+
+			conclusive = conclusive && childConclusive
+		})
+
+		if !equal {
+			return false, true
+		}
+
+		return true, conclusive
+
+	default:
+		return deepEqual(interpreter, a, b), true
+	}
+}
+
+func staticTypesEqual(a, b StaticType) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Equal(b)
+}
@@ -0,0 +1,151 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+)
+
+func TestEqualToDepth(t *testing.T) {
+
+	t.Parallel()
+
+	// newNestedArray returns the array [[[innermost]]],
+	// i.e. the integer is at depth 3
+
+	newNestedArray := func(inter *Interpreter, innermost int64) *ArrayValue {
+		var value Value = NewIntValueFromInt64(innermost)
+		var staticType StaticType = PrimitiveStaticTypeInt
+
+		for i := 0; i < 3; i++ {
+			arrayType := VariableSizedStaticType{
+				Type: staticType,
+			}
+			value = NewArrayValue(
+				inter,
+				arrayType,
+				common.Address{},
+				value,
+			)
+			staticType = arrayType
+		}
+
+		return value.(*ArrayValue)
+	}
+
+	t.Run("different at depth 3", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		a := newNestedArray(inter, 1)
+		b := newNestedArray(inter, 2)
+
+		equal, conclusive := EqualToDepth(inter, a, b, 2)
+		require.True(t, equal)
+		require.False(t, conclusive)
+
+		equal, conclusive = EqualToDepth(inter, a, b, 3)
+		require.False(t, equal)
+		require.True(t, conclusive)
+	})
+
+	t.Run("equal", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		a := newNestedArray(inter, 1)
+		b := newNestedArray(inter, 1)
+
+		equal, conclusive := EqualToDepth(inter, a, b, 2)
+		require.True(t, equal)
+		require.False(t, conclusive)
+
+		equal, conclusive = EqualToDepth(inter, a, b, 3)
+		require.True(t, equal)
+		require.True(t, conclusive)
+	})
+
+	t.Run("different at depth 1", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		a := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(1),
+		)
+
+		b := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(2),
+		)
+
+		equal, conclusive := EqualToDepth(inter, a, b, 0)
+		require.True(t, equal)
+		require.False(t, conclusive)
+
+		equal, conclusive = EqualToDepth(inter, a, b, 2)
+		require.False(t, equal)
+		require.True(t, conclusive)
+	})
+
+	t.Run("different dictionary value", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		newDictionary := func(value int64) *DictionaryValue {
+			return NewDictionaryValue(
+				inter,
+				DictionaryStaticType{
+					KeyType:   PrimitiveStaticTypeString,
+					ValueType: PrimitiveStaticTypeInt,
+				},
+				NewStringValue("a"), NewIntValueFromInt64(value),
+			)
+		}
+
+		equal, conclusive := EqualToDepth(inter, newDictionary(1), newDictionary(2), 1)
+		require.False(t, equal)
+		require.True(t, conclusive)
+
+		equal, conclusive = EqualToDepth(inter, newDictionary(1), newDictionary(1), 1)
+		require.True(t, equal)
+		require.True(t, conclusive)
+	})
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/onflow/atree"
+
 	"github.com/onflow/cadence/runtime/ast"
 	"github.com/onflow/cadence/runtime/common"
 	"github.com/onflow/cadence/runtime/sema"
@@ -365,6 +367,36 @@ func (e StringIndexOutOfBoundsError) Error() string {
 	)
 }
 
+// InvalidSliceIndexError
+//
+type InvalidSliceIndexError struct {
+	FromIndex int
+	UpToIndex int
+	LocationRange
+}
+
+func (e InvalidSliceIndexError) Error() string {
+	return fmt.Sprintf(
+		"invalid slice indices: from index %d is greater than up-to index %d",
+		e.FromIndex,
+		e.UpToIndex,
+	)
+}
+
+// InvalidChunkSizeError
+//
+type InvalidChunkSizeError struct {
+	Size int
+	LocationRange
+}
+
+func (e InvalidChunkSizeError) Error() string {
+	return fmt.Sprintf(
+		"invalid chunk size: expected a positive size, got %d",
+		e.Size,
+	)
+}
+
 // EventEmissionUnavailableError
 //
 type EventEmissionUnavailableError struct {
@@ -389,12 +421,21 @@ func (e UUIDUnavailableError) Error() string {
 //
 type TypeLoadingError struct {
 	TypeID common.TypeID
+	// Err is the error returned by the composite type resolver, if any
+	Err error
 }
 
 func (e TypeLoadingError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("failed to load type: %s: %s", e.TypeID, e.Err)
+	}
 	return fmt.Sprintf("failed to load type: %s", e.TypeID)
 }
 
+func (e TypeLoadingError) Unwrap() error {
+	return e.Err
+}
+
 // EncodingUnsupportedValueError
 //
 type EncodingUnsupportedValueError struct {
@@ -498,6 +539,37 @@ func (e ContainerMutationError) Error() string {
 	)
 }
 
+// NonHashableKeyError
+//
+type NonHashableKeyError struct {
+	Value Value
+	LocationRange
+}
+
+func (e NonHashableKeyError) Error() string {
+	return fmt.Sprintf(
+		"cannot use non-hashable value as dictionary key: %s",
+		e.Value,
+	)
+}
+
+// DictionaryKeyTypeMismatchError is reported when a set operation
+// is performed on dictionaries with different key types
+//
+type DictionaryKeyTypeMismatchError struct {
+	ExpectedType StaticType
+	ActualType   StaticType
+	LocationRange
+}
+
+func (e DictionaryKeyTypeMismatchError) Error() string {
+	return fmt.Sprintf(
+		"dictionary key type mismatch: expected `%s`, got `%s`",
+		e.ExpectedType,
+		e.ActualType,
+	)
+}
+
 // NonStorableValueError
 //
 type NonStorableValueError struct {
@@ -536,3 +608,396 @@ func (e *InterfaceMissingLocationError) Error() string {
 		e.QualifiedIdentifier,
 	)
 }
+
+// NonInlinableStorableError is reported when a storage only allows inline storables,
+// but a storable is too large to be inlined
+//
+type NonInlinableStorableError struct {
+	Size          uint32
+	MaxInlineSize uint64
+}
+
+func (e NonInlinableStorableError) Error() string {
+	return fmt.Sprintf(
+		"cannot inline storable of size %d: exceeds maximum inline size %d",
+		e.Size,
+		e.MaxInlineSize,
+	)
+}
+
+// ReferencedValueError is reported when a stored value is removed
+// while it is still the target of links, i.e. capabilities
+//
+type ReferencedValueError struct {
+	Address common.Address
+	Key     string
+	Count   int
+}
+
+func (e ReferencedValueError) Error() string {
+	return fmt.Sprintf(
+		"cannot remove value %s in account %s: value is still referenced by %d capabilities",
+		e.Key,
+		e.Address,
+		e.Count,
+	)
+}
+
+// ImportResolutionError is reported when the import location handler
+// fails to resolve a location
+//
+type ImportResolutionError struct {
+	Location common.Location
+	Err      error
+}
+
+func (e ImportResolutionError) Error() string {
+	return fmt.Sprintf(
+		"failed to resolve import of location %s: %s",
+		e.Location,
+		e.Err,
+	)
+}
+
+func (e ImportResolutionError) Unwrap() error {
+	return e.Err
+}
+
+// ReadOnlyValueMutationError is reported when a read-only value,
+// e.g. a value borrowed from storage, is mutated
+//
+type ReadOnlyValueMutationError struct {
+	LocationRange
+}
+
+func (e ReadOnlyValueMutationError) Error() string {
+	return "cannot mutate read-only value"
+}
+
+// StringLengthLimitExceededError is reported when a string value
+// exceeds the configured maximum length
+//
+type StringLengthLimitExceededError struct {
+	Length int
+	Limit  int
+}
+
+func (e StringLengthLimitExceededError) Error() string {
+	return fmt.Sprintf(
+		"string length limit exceeded: %d bytes, limit is %d bytes",
+		e.Length,
+		e.Limit,
+	)
+}
+
+// StandaloneDecodingError is returned by DecodeStorableStandalone
+// when the decoded value is stored in a separate slab,
+// which cannot be loaded without a storage
+//
+type StandaloneDecodingError struct {
+	StorageID atree.StorageID
+}
+
+func (e StandaloneDecodingError) Error() string {
+	return fmt.Sprintf(
+		"cannot decode value standalone: value is stored in separate slab %s",
+		e.StorageID,
+	)
+}
+
+// SuspiciousStorableError is returned when a decoded storable
+// is encoded with more bytes than the configured limit for its kind
+//
+type SuspiciousStorableError struct {
+	Kind  StorableKind
+	Size  uint64
+	Limit uint64
+}
+
+func (e SuspiciousStorableError) Error() string {
+	return fmt.Sprintf(
+		"suspicious storable: %s encoded with %d bytes, limit is %d bytes",
+		e.Kind,
+		e.Size,
+		e.Limit,
+	)
+}
+
+// UnmarshalError is returned when a composite field
+// cannot be unmarshaled into a Go value
+//
+type UnmarshalError struct {
+	FieldName string
+	Err       error
+}
+
+func (e UnmarshalError) Error() string {
+	return fmt.Sprintf(
+		"cannot unmarshal field `%s`: %s",
+		e.FieldName,
+		e.Err,
+	)
+}
+
+func (e UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+// MissingCompositeFieldError is returned when a composite value is constructed
+// without a value for a field declared by its type
+//
+type MissingCompositeFieldError struct {
+	TypeID    common.TypeID
+	FieldName string
+}
+
+func (e MissingCompositeFieldError) Error() string {
+	return fmt.Sprintf(
+		"missing value for field `%s` of type `%s`",
+		e.FieldName,
+		e.TypeID,
+	)
+}
+
+// UnknownCompositeFieldError is returned when a composite value is constructed
+// with a value for a field which is not declared by its type,
+// or when a composite value is patched with a value for a field it does not have
+//
+type UnknownCompositeFieldError struct {
+	TypeID    common.TypeID
+	FieldName string
+}
+
+func (e UnknownCompositeFieldError) Error() string {
+	return fmt.Sprintf(
+		"type `%s` has no field `%s`",
+		e.TypeID,
+		e.FieldName,
+	)
+}
+
+// DuplicateDictionaryKeyError is reported when a dictionary is constructed
+// with the same key more than once
+//
+type DuplicateDictionaryKeyError struct {
+	Key Value
+}
+
+func (e DuplicateDictionaryKeyError) Error() string {
+	return fmt.Sprintf(
+		"duplicate dictionary key: %s",
+		e.Key,
+	)
+}
+
+// ValueTypeMismatchError is reported when a value inserted into a dictionary
+// is not a subtype of the value type of the dictionary, see WithValueTypeCheck
+//
+type ValueTypeMismatchError struct {
+	Key          Value
+	ExpectedType sema.Type
+	ActualType   sema.Type
+	LocationRange
+}
+
+func (e ValueTypeMismatchError) Error() string {
+	return fmt.Sprintf(
+		"invalid value for key %s: expected a subtype of '%s', found '%s'",
+		e.Key,
+		e.ExpectedType.QualifiedString(),
+		e.ActualType.QualifiedString(),
+	)
+}
+
+// ReadOnlyStorageError is reported when a read-only storage view,
+// e.g. a snapshot of a storage, is written to
+//
+type ReadOnlyStorageError struct{}
+
+func (e ReadOnlyStorageError) Error() string {
+	return "cannot write to read-only storage view"
+}
+
+// GoValueConversionError is returned when a Go value
+// cannot be converted to or from an interpreter value
+//
+type GoValueConversionError struct {
+	Path string
+	Err  error
+}
+
+func (e GoValueConversionError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("cannot convert value: %s", e.Err)
+	}
+	return fmt.Sprintf(
+		"cannot convert value at `%s`: %s",
+		e.Path,
+		e.Err,
+	)
+}
+
+func (e GoValueConversionError) Unwrap() error {
+	return e.Err
+}
+
+// TypeConsistencyError is returned when a value nested in a container
+// does not conform to the type declared by the container
+//
+type TypeConsistencyError struct {
+	Path         string
+	ExpectedType sema.Type
+	ActualType   sema.Type
+}
+
+func (e TypeConsistencyError) Error() string {
+	return fmt.Sprintf(
+		"value at `%s` has type `%s`, which does not conform to the declared type `%s`",
+		e.Path,
+		e.ActualType.QualifiedString(),
+		e.ExpectedType.QualifiedString(),
+	)
+}
+
+// SlabLimitExceededError is returned when a slab is stored in a storage
+// which already holds the maximum number of slabs, see WithMaxSlabs
+//
+type SlabLimitExceededError struct {
+	Limit int
+}
+
+func (e SlabLimitExceededError) Error() string {
+	return fmt.Sprintf(
+		"slab limit exceeded: storage already holds the maximum of %d slabs",
+		e.Limit,
+	)
+}
+
+// TransferOwnershipError is reported when a container nested in the result of a transfer
+// is not owned by the account the value was transferred to, see WithTransferOwnershipCheck
+//
+type TransferOwnershipError struct {
+	Path          string
+	ExpectedOwner common.Address
+	ActualOwner   common.Address
+}
+
+func (e TransferOwnershipError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "transferred value"
+	}
+	return fmt.Sprintf(
+		"`%s` is owned by %s, expected %s",
+		path,
+		e.ActualOwner,
+		e.ExpectedOwner,
+	)
+}
+
+// TransferBudgetExceededError is reported when a transfer would allocate
+// more slabs than the transfer slab budget allows, see WithTransferSlabBudget
+//
+type TransferBudgetExceededError struct {
+	Budget int
+}
+
+func (e TransferBudgetExceededError) Error() string {
+	return fmt.Sprintf(
+		"transfer exceeds slab budget of %d",
+		e.Budget,
+	)
+}
+
+// StorageOperationError is reported when reading or writing a value in storage fails.
+// It records the account storage key, if any, and the storage ID of the slab
+// which was operated on, if any, and wraps the underlying error
+//
+type StorageOperationError struct {
+	Key       *StorageKey
+	StorageID atree.StorageID
+	Err       error
+}
+
+func (e StorageOperationError) Error() string {
+	var context []string
+	if e.Key != nil {
+		context = append(context, fmt.Sprintf("key %s.%s", e.Key.Address, e.Key.Key))
+	}
+	if e.StorageID != atree.StorageIDUndefined {
+		context = append(context, fmt.Sprintf("storage ID %s", e.StorageID))
+	}
+
+	if len(context) == 0 {
+		return fmt.Sprintf("storage operation failed: %s", e.Err)
+	}
+
+	return fmt.Sprintf(
+		"storage operation failed (%s): %s",
+		strings.Join(context, ", "),
+		e.Err,
+	)
+}
+
+func (e StorageOperationError) Unwrap() error {
+	return e.Err
+}
+
+// IntegrityError is reported by InMemoryStorage.VerifyIntegrity
+// for a value stored under the given account storage key which is corrupted.
+// It records the storage ID of the affected slab, if any, and wraps the underlying error
+//
+type IntegrityError struct {
+	Address   common.Address
+	Key       string
+	StorageID atree.StorageID
+	Err       error
+}
+
+func (e IntegrityError) Error() string {
+	if e.StorageID == atree.StorageIDUndefined {
+		return fmt.Sprintf(
+			"invalid value stored under key %s.%s: %s",
+			e.Address,
+			e.Key,
+			e.Err,
+		)
+	}
+
+	return fmt.Sprintf(
+		"invalid value stored under key %s.%s (storage ID %s): %s",
+		e.Address,
+		e.Key,
+		e.StorageID,
+		e.Err,
+	)
+}
+
+func (e IntegrityError) Unwrap() error {
+	return e.Err
+}
+
+// ContentHashNotEnabledError is returned when the content hash of a dictionary is requested,
+// but it is not enabled, see DictionaryValue.EnableContentHash
+//
+type ContentHashNotEnabledError struct{}
+
+func (ContentHashNotEnabledError) Error() string {
+	return "content hash is not enabled"
+}
+
+// ContentHashEncodingError is reported when a dictionary entry cannot be added to
+// or removed from the content hash of the dictionary, because it cannot be encoded as canonical JSON
+//
+type ContentHashEncodingError struct {
+	Err error
+	LocationRange
+}
+
+func (e ContentHashEncodingError) Error() string {
+	return fmt.Sprintf("cannot update content hash: %s", e.Err.Error())
+}
+
+func (e ContentHashEncodingError) Unwrap() error {
+	return e.Err
+}
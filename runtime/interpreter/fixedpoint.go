@@ -0,0 +1,152 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/onflow/cadence/runtime/errors"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// RoundingMode determines how the result of a fixed-point operation
+// is rounded if it cannot be represented exactly.
+//
+type RoundingMode uint8
+
+const (
+	// RoundTruncate rounds towards zero
+	RoundTruncate RoundingMode = iota
+	// RoundHalfUp rounds to the nearest value, and ties away from zero
+	RoundHalfUp
+	// RoundHalfEven rounds to the nearest value, and ties to the value with an even last digit
+	RoundHalfEven
+)
+
+// DivFix64 divides a by b, rounding the result using the given rounding mode.
+//
+// In contrast to Fix64Value.Div, the result is rounded explicitly,
+// and errors are returned instead of panicking:
+// a DivisionByZeroError if b is zero, and an OverflowError or UnderflowError
+// if the result is not in the range of Fix64.
+//
+func DivFix64(interpreter *Interpreter, a, b Fix64Value, mode RoundingMode) (Fix64Value, error) {
+	if b == 0 {
+		return 0, DivisionByZeroError{}
+	}
+
+	numerator := new(big.Int).SetInt64(int64(a))
+	numerator.Mul(numerator, sema.Fix64FactorBig)
+
+	denominator := new(big.Int).SetInt64(int64(b))
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+	if remainder.Sign() != 0 {
+		switch mode {
+		case RoundTruncate:
+			// The quotient is already truncated
+
+		case RoundHalfUp, RoundHalfEven:
+			// Compare the remainder with half of the denominator
+
+			doubledRemainder := new(big.Int).Abs(remainder)
+			doubledRemainder.Lsh(doubledRemainder, 1)
+
+			comparison := doubledRemainder.CmpAbs(denominator)
+
+			roundAway := comparison > 0 ||
+				(comparison == 0 &&
+					(mode == RoundHalfUp || quotient.Bit(0) == 1))
+
+			if roundAway {
+				if numerator.Sign() == denominator.Sign() {
+					quotient.Add(quotient, big.NewInt(1))
+				} else {
+					quotient.Sub(quotient, big.NewInt(1))
+				}
+			}
+
+		default:
+			panic(errors.NewUnreachableError())
+		}
+	}
+
+	if quotient.Cmp(minInt64Big) < 0 {
+		return 0, UnderflowError{}
+	} else if quotient.Cmp(maxInt64Big) > 0 {
+		return 0, OverflowError{}
+	}
+
+	return Fix64Value(quotient.Int64()), nil
+}
+
+// fix64BasisPointFactor is the number of fixed-point units in a basis point, i.e. 0.0001
+//
+const fix64BasisPointFactor = sema.Fix64Factor / 10_000
+
+// UFix64FromBasisPoints returns the UFix64 value for the given number of basis points,
+// e.g. 250 basis points are 0.025 (2.5%).
+//
+// If the value is not in the range of UFix64, i.e. the basis points exceed
+// the maximum UFix64 value times 10,000, the function panics with an OverflowError.
+//
+func UFix64FromBasisPoints(bp uint64) UFix64Value {
+	if bp > math.MaxUint64/fix64BasisPointFactor {
+		panic(OverflowError{})
+	}
+
+	return UFix64Value(bp * fix64BasisPointFactor)
+}
+
+// ToBasisPoints returns the number of basis points of the value, e.g. 250 for 0.025 (2.5%).
+//
+// Fractions of a basis point cannot be represented and are truncated,
+// e.g. 0.00012345 results in 1 basis point.
+//
+func (v UFix64Value) ToBasisPoints() uint64 {
+	return uint64(v) / fix64BasisPointFactor
+}
+
+// Fix64FromBasisPoints returns the Fix64 value for the given number of basis points,
+// e.g. -250 basis points are -0.025 (-2.5%).
+//
+// If the value is not in the range of Fix64, the function panics with an OverflowError or UnderflowError.
+//
+func Fix64FromBasisPoints(bp int64) Fix64Value {
+	if bp > math.MaxInt64/fix64BasisPointFactor {
+		panic(OverflowError{})
+	}
+
+	if bp < math.MinInt64/fix64BasisPointFactor {
+		panic(UnderflowError{})
+	}
+
+	return Fix64Value(bp * fix64BasisPointFactor)
+}
+
+// ToBasisPoints returns the number of basis points of the value, e.g. -250 for -0.025 (-2.5%).
+//
+// Fractions of a basis point cannot be represented and are truncated towards zero,
+// e.g. -0.00012345 results in -1 basis point.
+//
+func (v Fix64Value) ToBasisPoints() int64 {
+	return int64(v) / fix64BasisPointFactor
+}
@@ -0,0 +1,160 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+func TestDivFix64WithRoundingMode(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	const one = Fix64Value(sema.Fix64Factor)
+
+	type test struct {
+		a, b                       Fix64Value
+		truncate, halfUp, halfEven Fix64Value
+	}
+
+	tests := map[string]test{
+		// 0.00000002|5
+		"half, even quotient": {5, 2 * one, 2, 3, 2},
+		// 0.00000003|5
+		"half, odd quotient": {7, 2 * one, 3, 4, 4},
+		// -0.00000002|5
+		"negative half": {-5, 2 * one, -2, -3, -2},
+		// -0.00000003|5
+		"negative divisor": {7, -2 * one, -3, -4, -4},
+		// 0.33333333|33
+		"below half": {one, 3 * one, 33333333, 33333333, 33333333},
+		// 0.66666666|67
+		"above half": {2 * one, 3 * one, 66666666, 66666667, 66666667},
+		// 0.5
+		"exact": {one, 2 * one, one / 2, one / 2, one / 2},
+	}
+
+	for name, test := range tests {
+
+		for mode, expected := range map[RoundingMode]Fix64Value{
+			RoundTruncate: test.truncate,
+			RoundHalfUp:   test.halfUp,
+			RoundHalfEven: test.halfEven,
+		} {
+			result, err := DivFix64(inter, test.a, test.b, mode)
+			require.NoError(t, err)
+			assert.Equal(t, expected, result, "%s, mode %d", name, mode)
+		}
+	}
+
+	t.Run("division by zero", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := DivFix64(inter, one, 0, RoundHalfEven)
+		require.Equal(t, DivisionByZeroError{}, err)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := DivFix64(inter, math.MaxInt64, one/2, RoundTruncate)
+		require.Equal(t, OverflowError{}, err)
+	})
+
+	t.Run("underflow", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := DivFix64(inter, math.MinInt64, one/2, RoundTruncate)
+		require.Equal(t, UnderflowError{}, err)
+	})
+}
+
+func TestFixedPointBasisPoints(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("UFix64", func(t *testing.T) {
+
+		t.Parallel()
+
+		for bp, expected := range map[uint64]UFix64Value{
+			// 0%
+			0: 0,
+			// 0.01%
+			1: 1_0000,
+			// 2.5%
+			250: 2_500000,
+			// 100%
+			10_000: 1_00000000,
+		} {
+			assert.Equal(t, expected, UFix64FromBasisPoints(bp))
+			assert.Equal(t, bp, expected.ToBasisPoints())
+		}
+
+		// Fractions of basis points are truncated
+
+		assert.Equal(t, uint64(1), UFix64Value(1_2345).ToBasisPoints())
+
+		assert.Equal(t,
+			uint64(math.MaxUint64/10_000),
+			UFix64FromBasisPoints(math.MaxUint64/10_000).ToBasisPoints(),
+		)
+
+		assert.PanicsWithValue(t, OverflowError{}, func() {
+			UFix64FromBasisPoints(math.MaxUint64/10_000 + 1)
+		})
+	})
+
+	t.Run("Fix64", func(t *testing.T) {
+
+		t.Parallel()
+
+		for bp, expected := range map[int64]Fix64Value{
+			250:  2_500000,
+			-250: -2_500000,
+		} {
+			assert.Equal(t, expected, Fix64FromBasisPoints(bp))
+			assert.Equal(t, bp, expected.ToBasisPoints())
+		}
+
+		// Fractions of basis points are truncated towards zero
+
+		assert.Equal(t, int64(-1), Fix64Value(-1_2345).ToBasisPoints())
+
+		assert.PanicsWithValue(t, OverflowError{}, func() {
+			Fix64FromBasisPoints(math.MaxInt64/10_000 + 1)
+		})
+
+		assert.PanicsWithValue(t, UnderflowError{}, func() {
+			Fix64FromBasisPoints(math.MinInt64/10_000 - 1)
+		})
+	})
+}
@@ -0,0 +1,475 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// ToGoValue converts the given value to a plain Go value:
+//
+//   - Signed integers are converted to int64, unsigned integers to uint64
+//   - Strings are converted to string, booleans to bool
+//   - Arrays are converted to []interface{}
+//   - Dictionaries with string keys are converted to map[string]interface{}
+//   - Optionals are converted to their inner value, or nil
+//
+// If the value, or a nested value, cannot be converted,
+// a GoValueConversionError is returned.
+//
+func ToGoValue(interpreter *Interpreter, value Value) (interface{}, error) {
+	return toGoValue(interpreter, "", value)
+}
+
+// StoredArrayToGoSlice converts the elements of the given array in the range [from, to)
+// to plain Go values, like ToGoValue.
+//
+// If the range is out of bounds, an ArrayIndexOutOfBoundsError is returned.
+// If an element cannot be converted, a GoValueConversionError is returned,
+// with a path that includes the index of the element.
+//
+func StoredArrayToGoSlice(interpreter *Interpreter, v *ArrayValue, from, to int) ([]interface{}, error) {
+	count := v.Count()
+
+	if from < 0 || from > count {
+		return nil, ArrayIndexOutOfBoundsError{
+			Index: from,
+			Size:  count,
+		}
+	}
+
+	if to < from || to > count {
+		return nil, ArrayIndexOutOfBoundsError{
+			Index: to,
+			Size:  count,
+		}
+	}
+
+	result := make([]interface{}, 0, to-from)
+
+	for index := from; index < to; index++ {
+		element := v.Get(interpreter, ReturnEmptyLocationRange, index)
+
+		goElement, err := toGoValue(
+			interpreter,
+			fmt.Sprintf("[%d]", index),
+			element,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, goElement)
+	}
+
+	return result, nil
+}
+
+func toGoValue(interpreter *Interpreter, path string, value Value) (interface{}, error) {
+	switch value := value.(type) {
+	case NilValue:
+		return nil, nil
+
+	case *SomeValue:
+		return toGoValue(interpreter, path, value.Value)
+
+	case BoolValue:
+		return bool(value), nil
+
+	case *StringValue:
+		return value.Str, nil
+
+	case IntegerValue:
+		integer, ok := integerValueToBigInt(value)
+		if !ok {
+			break
+		}
+
+		if isUnsignedIntegerValue(value) {
+			if !integer.IsUint64() {
+				return nil, GoValueConversionError{
+					Path: path,
+					Err:  fmt.Errorf("integer %s does not fit into uint64", integer),
+				}
+			}
+			return integer.Uint64(), nil
+		}
+
+		if !integer.IsInt64() {
+			return nil, GoValueConversionError{
+				Path: path,
+				Err:  fmt.Errorf("integer %s does not fit into int64", integer),
+			}
+		}
+		return integer.Int64(), nil
+
+	case *ArrayValue:
+		result := make([]interface{}, 0, value.Count())
+
+		var err error
+		value.Iterate(func(element Value) (resume bool) {
+			var goElement interface{}
+			goElement, err = toGoValue(
+				interpreter,
+				fmt.Sprintf("%s[%d]", path, len(result)),
+				element,
+			)
+			if err != nil {
+				return false
+			}
+
+			result = append(result, goElement)
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+
+	case *DictionaryValue:
+		result := make(map[string]interface{}, value.Count())
+
+		var err error
+		value.Iterate(func(key, value Value) (resume bool) {
+			stringKey, ok := key.(*StringValue)
+			if !ok {
+				err = GoValueConversionError{
+					Path: path,
+					Err:  fmt.Errorf("unsupported dictionary key type `%s`", key.StaticType()),
+				}
+				return false
+			}
+
+			var goValue interface{}
+			goValue, err = toGoValue(
+				interpreter,
+				fmt.Sprintf("%s[%s]", path, stringKey.Str),
+				value,
+			)
+			if err != nil {
+				return false
+			}
+
+			result[stringKey.Str] = goValue
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return result, nil
+	}
+
+	return nil, GoValueConversionError{
+		Path: path,
+		Err:  fmt.Errorf("unsupported value of type `%s`", value.StaticType()),
+	}
+}
+
+func isUnsignedIntegerValue(value IntegerValue) bool {
+	switch value.(type) {
+	case UIntValue, UInt8Value, UInt16Value, UInt32Value, UInt64Value, UInt128Value, UInt256Value,
+		Word8Value, Word16Value, Word32Value, Word64Value:
+		return true
+	default:
+		return false
+	}
+}
+
+// FromGoValue converts the given plain Go value to a value of the given static type,
+// i.e. it is the inverse of ToGoValue:
+//
+//   - Integers are converted from int64, uint64, or int, if they are in the range of the type
+//   - String is converted from string, Bool from bool
+//   - Optionals are converted from nil, or from a Go value for the inner type
+//   - Arrays are converted from []interface{}
+//   - Dictionaries with String keys are converted from map[string]interface{}
+//
+// Arrays and dictionaries are owned by the given address.
+//
+// If the Go value, or a nested Go value, does not match the static type,
+// a GoValueConversionError is returned.
+//
+func FromGoValue(
+	interpreter *Interpreter,
+	goValue interface{},
+	staticType StaticType,
+	owner common.Address,
+) (Value, error) {
+	return fromGoValue(interpreter, "", goValue, staticType, owner)
+}
+
+func fromGoValue(
+	interpreter *Interpreter,
+	path string,
+	goValue interface{},
+	staticType StaticType,
+	owner common.Address,
+) (Value, error) {
+
+	mismatch := func() error {
+		return GoValueConversionError{
+			Path: path,
+			Err:  fmt.Errorf("cannot convert %T to `%s`", goValue, staticType),
+		}
+	}
+
+	switch staticType := staticType.(type) {
+	case OptionalStaticType:
+		if goValue == nil {
+			return Nil(), nil
+		}
+
+		value, err := fromGoValue(interpreter, path, goValue, staticType.Type, owner)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSomeValueNonCopying(value), nil
+
+	case PrimitiveStaticType:
+		switch staticType {
+		case PrimitiveStaticTypeBool:
+			b, ok := goValue.(bool)
+			if !ok {
+				return nil, mismatch()
+			}
+			return BoolValue(b), nil
+
+		case PrimitiveStaticTypeString:
+			s, ok := goValue.(string)
+			if !ok {
+				return nil, mismatch()
+			}
+			return NewStringValue(s), nil
+		}
+
+		var integer *big.Int
+		switch goValue := goValue.(type) {
+		case int64:
+			integer = big.NewInt(goValue)
+		case int:
+			integer = big.NewInt(int64(goValue))
+		case uint64:
+			integer = new(big.Int).SetUint64(goValue)
+		default:
+			return nil, mismatch()
+		}
+
+		value, err := integerValueFromBigInt(integer, staticType)
+		if err != nil {
+			return nil, GoValueConversionError{
+				Path: path,
+				Err:  err,
+			}
+		}
+		return value, nil
+
+	case VariableSizedStaticType, ConstantSizedStaticType:
+		goElements, ok := goValue.([]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+
+		arrayType := staticType.(ArrayStaticType)
+
+		if constantSizedType, ok := arrayType.(ConstantSizedStaticType); ok &&
+			int64(len(goElements)) != constantSizedType.Size {
+
+			return nil, GoValueConversionError{
+				Path: path,
+				Err: fmt.Errorf(
+					"expected %d elements, got %d",
+					constantSizedType.Size,
+					len(goElements),
+				),
+			}
+		}
+
+		elements := make([]Value, len(goElements))
+		for i, goElement := range goElements {
+			element, err := fromGoValue(
+				interpreter,
+				fmt.Sprintf("%s[%d]", path, i),
+				goElement,
+				arrayType.ElementType(),
+				owner,
+			)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+
+		return NewArrayValue(interpreter, arrayType, owner, elements...), nil
+
+	case DictionaryStaticType:
+		if staticType.KeyType != PrimitiveStaticTypeString {
+			return nil, GoValueConversionError{
+				Path: path,
+				Err:  fmt.Errorf("unsupported dictionary key type `%s`", staticType.KeyType),
+			}
+		}
+
+		goEntries, ok := goValue.(map[string]interface{})
+		if !ok {
+			return nil, mismatch()
+		}
+
+		// Insert the entries in a deterministic order
+
+		keys := make([]string, 0, len(goEntries))
+		for key := range goEntries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		keysAndValues := make([]Value, 0, len(keys)*2)
+		for _, key := range keys {
+			value, err := fromGoValue(
+				interpreter,
+				fmt.Sprintf("%s[%s]", path, key),
+				goEntries[key],
+				staticType.ValueType,
+				owner,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			keysAndValues = append(keysAndValues, NewStringValue(key), value)
+		}
+
+		return NewDictionaryValueWithAddress(interpreter, staticType, owner, keysAndValues...), nil
+	}
+
+	return nil, GoValueConversionError{
+		Path: path,
+		Err:  fmt.Errorf("unsupported type `%s`", staticType),
+	}
+}
+
+// integerValueFromBigInt returns the value of the given integer type for the given integer,
+// or an error if the type is not an integer type, or the integer is out of its range.
+//
+func integerValueFromBigInt(integer *big.Int, staticType PrimitiveStaticType) (Value, error) {
+
+	var value func() Value
+
+	switch staticType {
+	case PrimitiveStaticTypeInt:
+		value = func() Value { return NewIntValueFromBigInt(integer) }
+	case PrimitiveStaticTypeInt8:
+		value = func() Value { return Int8Value(integer.Int64()) }
+	case PrimitiveStaticTypeInt16:
+		value = func() Value { return Int16Value(integer.Int64()) }
+	case PrimitiveStaticTypeInt32:
+		value = func() Value { return Int32Value(integer.Int64()) }
+	case PrimitiveStaticTypeInt64:
+		value = func() Value { return Int64Value(integer.Int64()) }
+	case PrimitiveStaticTypeInt128:
+		value = func() Value { return NewInt128ValueFromBigInt(integer) }
+	case PrimitiveStaticTypeInt256:
+		value = func() Value { return NewInt256ValueFromBigInt(integer) }
+	case PrimitiveStaticTypeUInt:
+		value = func() Value { return NewUIntValueFromBigInt(integer) }
+	case PrimitiveStaticTypeUInt8:
+		value = func() Value { return UInt8Value(integer.Uint64()) }
+	case PrimitiveStaticTypeUInt16:
+		value = func() Value { return UInt16Value(integer.Uint64()) }
+	case PrimitiveStaticTypeUInt32:
+		value = func() Value { return UInt32Value(integer.Uint64()) }
+	case PrimitiveStaticTypeUInt64:
+		value = func() Value { return UInt64Value(integer.Uint64()) }
+	case PrimitiveStaticTypeUInt128:
+		value = func() Value { return NewUInt128ValueFromBigInt(integer) }
+	case PrimitiveStaticTypeUInt256:
+		value = func() Value { return NewUInt256ValueFromBigInt(integer) }
+	case PrimitiveStaticTypeWord8:
+		value = func() Value { return Word8Value(integer.Uint64()) }
+	case PrimitiveStaticTypeWord16:
+		value = func() Value { return Word16Value(integer.Uint64()) }
+	case PrimitiveStaticTypeWord32:
+		value = func() Value { return Word32Value(integer.Uint64()) }
+	case PrimitiveStaticTypeWord64:
+		value = func() Value { return Word64Value(integer.Uint64()) }
+	default:
+		return nil, fmt.Errorf("unsupported type `%s`", staticType)
+	}
+
+	min, max := integerTypeBounds(staticType)
+
+	if (min != nil && integer.Cmp(min) < 0) ||
+		(max != nil && integer.Cmp(max) > 0) {
+
+		return nil, fmt.Errorf("integer %s is out of the range of `%s`", integer, staticType)
+	}
+
+	return value(), nil
+}
+
+// integerTypeBounds returns the minimum and maximum of the given integer type.
+// The bounds are nil if the type is unbounded in that direction.
+//
+func integerTypeBounds(staticType PrimitiveStaticType) (min, max *big.Int) {
+	switch staticType {
+	case PrimitiveStaticTypeInt8:
+		return sema.Int8TypeMinInt, sema.Int8TypeMaxInt
+	case PrimitiveStaticTypeInt16:
+		return sema.Int16TypeMinInt, sema.Int16TypeMaxInt
+	case PrimitiveStaticTypeInt32:
+		return sema.Int32TypeMinInt, sema.Int32TypeMaxInt
+	case PrimitiveStaticTypeInt64:
+		return sema.Int64TypeMinInt, sema.Int64TypeMaxInt
+	case PrimitiveStaticTypeInt128:
+		return sema.Int128TypeMinIntBig, sema.Int128TypeMaxIntBig
+	case PrimitiveStaticTypeInt256:
+		return sema.Int256TypeMinIntBig, sema.Int256TypeMaxIntBig
+	case PrimitiveStaticTypeUInt:
+		return sema.UIntTypeMin, nil
+	case PrimitiveStaticTypeUInt8:
+		return sema.UInt8TypeMinInt, sema.UInt8TypeMaxInt
+	case PrimitiveStaticTypeUInt16:
+		return sema.UInt16TypeMinInt, sema.UInt16TypeMaxInt
+	case PrimitiveStaticTypeUInt32:
+		return sema.UInt32TypeMinInt, sema.UInt32TypeMaxInt
+	case PrimitiveStaticTypeUInt64:
+		return sema.UInt64TypeMinInt, sema.UInt64TypeMaxInt
+	case PrimitiveStaticTypeUInt128:
+		return sema.UInt128TypeMinIntBig, sema.UInt128TypeMaxIntBig
+	case PrimitiveStaticTypeUInt256:
+		return sema.UInt256TypeMinIntBig, sema.UInt256TypeMaxIntBig
+	case PrimitiveStaticTypeWord8:
+		return sema.Word8TypeMinInt, sema.Word8TypeMaxInt
+	case PrimitiveStaticTypeWord16:
+		return sema.Word16TypeMinInt, sema.Word16TypeMaxInt
+	case PrimitiveStaticTypeWord32:
+		return sema.Word32TypeMinInt, sema.Word32TypeMaxInt
+	case PrimitiveStaticTypeWord64:
+		return sema.Word64TypeMinInt, sema.Word64TypeMaxInt
+	default:
+		return nil, nil
+	}
+}
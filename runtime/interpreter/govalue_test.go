@@ -0,0 +1,294 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestGoValueRoundTrip(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	owner := common.Address{0x1}
+
+	test := func(name string, staticType StaticType, newValue func() Value) {
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			value := newValue()
+
+			goValue, err := ToGoValue(inter, value)
+			require.NoError(t, err)
+
+			result, err := FromGoValue(inter, goValue, staticType, owner)
+			require.NoError(t, err)
+
+			utils.RequireValuesEqual(t, inter, value, result)
+		})
+	}
+
+	test("Bool", PrimitiveStaticTypeBool, func() Value {
+		return BoolValue(true)
+	})
+
+	test("String", PrimitiveStaticTypeString, func() Value {
+		return NewStringValue("hello")
+	})
+
+	test("Int8", PrimitiveStaticTypeInt8, func() Value {
+		return Int8Value(math.MinInt8)
+	})
+
+	test("UInt64", PrimitiveStaticTypeUInt64, func() Value {
+		return UInt64Value(math.MaxUint64)
+	})
+
+	test("Int", PrimitiveStaticTypeInt, func() Value {
+		return NewIntValueFromInt64(-42)
+	})
+
+	test("UInt256", PrimitiveStaticTypeUInt256, func() Value {
+		return NewUInt256ValueFromUint64(42)
+	})
+
+	test("Word16", PrimitiveStaticTypeWord16, func() Value {
+		return Word16Value(math.MaxUint16)
+	})
+
+	test("nil", OptionalStaticType{Type: PrimitiveStaticTypeInt}, func() Value {
+		return Nil()
+	})
+
+	test("some", OptionalStaticType{Type: PrimitiveStaticTypeInt}, func() Value {
+		return NewSomeValueNonCopying(NewIntValueFromInt64(1))
+	})
+
+	nestedType := DictionaryStaticType{
+		KeyType: PrimitiveStaticTypeString,
+		ValueType: VariableSizedStaticType{
+			Type: OptionalStaticType{
+				Type: PrimitiveStaticTypeUInt8,
+			},
+		},
+	}
+
+	test("nested", nestedType, func() Value {
+		return NewDictionaryValueWithAddress(
+			inter,
+			nestedType,
+			owner,
+			NewStringValue("a"),
+			NewArrayValue(
+				inter,
+				nestedType.ValueType.(VariableSizedStaticType),
+				owner,
+				NewSomeValueNonCopying(UInt8Value(1)),
+				Nil(),
+			),
+			NewStringValue("b"),
+			NewArrayValue(
+				inter,
+				nestedType.ValueType.(VariableSizedStaticType),
+				owner,
+			),
+		)
+	})
+
+	test("constant-sized", ConstantSizedStaticType{Type: PrimitiveStaticTypeBool, Size: 2}, func() Value {
+		return NewArrayValue(
+			inter,
+			ConstantSizedStaticType{Type: PrimitiveStaticTypeBool, Size: 2},
+			owner,
+			BoolValue(true),
+			BoolValue(false),
+		)
+	})
+}
+
+func TestFromGoValueErrors(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	test := func(name string, goValue interface{}, staticType StaticType, expectedPath string) {
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			_, err := FromGoValue(inter, goValue, staticType, common.Address{})
+
+			var conversionErr GoValueConversionError
+			require.True(t, errors.As(err, &conversionErr))
+			require.Equal(t, expectedPath, conversionErr.Path)
+		})
+	}
+
+	test("mismatch",
+		"1",
+		PrimitiveStaticTypeInt,
+		"",
+	)
+
+	test("nested mismatch",
+		map[string]interface{}{
+			"a": []interface{}{int64(1)},
+			"b": []interface{}{int64(1), "2"},
+		},
+		DictionaryStaticType{
+			KeyType: PrimitiveStaticTypeString,
+			ValueType: VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+		},
+		"[b][1]",
+	)
+
+	test("overflow",
+		[]interface{}{int64(256)},
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeUInt8,
+		},
+		"[0]",
+	)
+
+	test("negative unsigned",
+		int64(-1),
+		PrimitiveStaticTypeUInt,
+		"",
+	)
+
+	test("constant size",
+		[]interface{}{true},
+		ConstantSizedStaticType{
+			Type: PrimitiveStaticTypeBool,
+			Size: 2,
+		},
+		"",
+	)
+
+	t.Run("out of Go range", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := ToGoValue(inter, NewUInt256ValueFromBigInt(sema.UInt256TypeMaxIntBig))
+
+		var conversionErr GoValueConversionError
+		require.True(t, errors.As(err, &conversionErr))
+	})
+}
+
+func TestStoredArrayToGoSlice(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	const count = 100
+
+	elements := make([]Value, count)
+	for i := range elements {
+		elements[i] = NewIntValueFromInt64(int64(i))
+	}
+
+	array := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		},
+		common.Address{0x1},
+		elements...,
+	)
+
+	mixedArray := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeAnyStruct,
+		},
+		common.Address{},
+		NewIntValueFromInt64(1),
+		NewAddressValue(common.Address{0x1}),
+	)
+
+	t.Run("range", func(t *testing.T) {
+
+		t.Parallel()
+
+		result, err := StoredArrayToGoSlice(inter, array, 10, 15)
+		require.NoError(t, err)
+
+		require.Equal(t,
+			[]interface{}{
+				int64(10),
+				int64(11),
+				int64(12),
+				int64(13),
+				int64(14),
+			},
+			result,
+		)
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+
+		t.Parallel()
+
+		result, err := StoredArrayToGoSlice(inter, array, count, count)
+		require.NoError(t, err)
+		require.Empty(t, result)
+	})
+
+	t.Run("out of bounds", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := StoredArrayToGoSlice(inter, array, 10, count+1)
+		require.Equal(t,
+			ArrayIndexOutOfBoundsError{
+				Index: count + 1,
+				Size:  count,
+			},
+			err,
+		)
+	})
+
+	t.Run("non-convertible element", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := StoredArrayToGoSlice(inter, mixedArray, 0, 2)
+
+		var conversionErr GoValueConversionError
+		require.True(t, errors.As(err, &conversionErr))
+		require.Equal(t, "[1]", conversionErr.Path)
+	})
+}
@@ -20,6 +20,8 @@ package interpreter
 
 import (
 	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
 )
 
 // HashableValue is an immutable value that can be hashed
@@ -29,6 +31,20 @@ type HashableValue interface {
 	HashInput(interpreter *Interpreter, getLocationRange func() LocationRange, scratch []byte) []byte
 }
 
+// IsHashableValue returns true if the given value can be used as a dictionary key,
+// i.e. it is a hashable value. Composite values are only hashable if they are enums.
+//
+func IsHashableValue(value Value) bool {
+	switch value := value.(type) {
+	case *CompositeValue:
+		return value.Kind == common.CompositeKindEnum
+	case HashableValue:
+		return true
+	default:
+		return false
+	}
+}
+
 func newHashInputProvider(interpreter *Interpreter, getLocationRange func() LocationRange) atree.HashInputProvider {
 	return func(value atree.Value, scratch []byte) ([]byte, error) {
 		hashInput := MustConvertStoredValue(value).(HashableValue).
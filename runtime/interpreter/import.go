@@ -48,3 +48,13 @@ type InterpreterImport struct {
 }
 
 func (InterpreterImport) isImport() {}
+
+// FailedImport is returned by an import location handler
+// when the location cannot be resolved.
+// The interpreter reports it as an ImportResolutionError.
+//
+type FailedImport struct {
+	Err error
+}
+
+func (FailedImport) isImport() {}
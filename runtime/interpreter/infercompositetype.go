@@ -0,0 +1,139 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sort"
+
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// InferCompositeType returns a composite type for the given composite value,
+// which is inferred from the actual values of its fields,
+// e.g. to display stored composites whose contract is not available.
+//
+// Each field of the composite is declared as a public constant field member.
+// If all types referenced by a field's value are available, see ReferencedTypes,
+// the field's type is the static type of the value, where the element type of arrays
+// is inferred from their elements, see ArrayValue.InferElementType.
+// Otherwise, the types of nested composites and optionals are inferred recursively,
+// and other values fall back to AnyStruct.
+//
+func InferCompositeType(interpreter *Interpreter, value *CompositeValue) *sema.CompositeType {
+
+	compositeType := &sema.CompositeType{
+		Location:   value.Location,
+		Identifier: value.QualifiedIdentifier,
+		Kind:       value.Kind,
+		Members:    sema.NewStringMemberOrderedMap(),
+	}
+
+	fields := map[string]Value{}
+	value.ForEachField(func(name string, value Value) {
+		fields[name] = value
+	})
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldType := inferFieldType(interpreter, fields[name])
+
+		compositeType.Members.Set(
+			name,
+			sema.NewPublicConstantFieldMember(
+				compositeType,
+				name,
+				fieldType,
+				"",
+			),
+		)
+	}
+
+	compositeType.Fields = names
+
+	return compositeType
+}
+
+func inferFieldType(interpreter *Interpreter, value Value) sema.Type {
+
+	if referencedTypesAvailable(interpreter, value) {
+		if array, ok := value.(*ArrayValue); ok {
+			elementType := interpreter.MustConvertStaticToSemaType(array.InferElementType(interpreter))
+
+			if constantSizedType, ok := array.Type.(ConstantSizedStaticType); ok {
+				return &sema.ConstantSizedType{
+					Type: elementType,
+					Size: constantSizedType.Size,
+				}
+			}
+
+			return &sema.VariableSizedType{
+				Type: elementType,
+			}
+		}
+
+		return interpreter.MustConvertStaticToSemaType(value.StaticType())
+	}
+
+	switch value := value.(type) {
+	case *CompositeValue:
+		return InferCompositeType(interpreter, value)
+
+	case *SomeValue:
+		return &sema.OptionalType{
+			Type: inferFieldType(interpreter, value.Value),
+		}
+
+	default:
+		return sema.AnyStructType
+	}
+}
+
+// referencedTypesAvailable returns true if all types referenced by the given value
+// can be converted to sema types.
+//
+func referencedTypesAvailable(interpreter *Interpreter, value Value) bool {
+	for _, staticType := range ReferencedTypes(interpreter, value) {
+		if !interpreter.staticTypeAvailable(staticType) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// staticTypeAvailable returns true if the given static type can be converted to a sema type.
+//
+// NOTE: Loading the program of a location which cannot be imported panics,
+// so the conversion is attempted and panics are treated as the type being unavailable.
+//
+func (interpreter *Interpreter) staticTypeAvailable(staticType StaticType) (available bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			available = false
+		}
+	}()
+
+	_, err := interpreter.ConvertStaticToSemaType(staticType)
+	return err == nil
+}
@@ -0,0 +1,122 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestInferCompositeType(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	// The types of the composites are not available
+
+	inner := NewCompositeValue(
+		inter,
+		utils.TestLocation,
+		"Outer.Inner",
+		common.CompositeKindStructure,
+		[]CompositeField{
+			{
+				Name:  "name",
+				Value: NewStringValue("inner"),
+			},
+		},
+		common.Address{},
+	)
+
+	outer := NewCompositeValue(
+		inter,
+		utils.TestLocation,
+		"Outer",
+		common.CompositeKindResource,
+		[]CompositeField{
+			{
+				Name:  "id",
+				Value: UInt64Value(1),
+			},
+			{
+				Name: "values",
+				Value: NewArrayValue(
+					inter,
+					VariableSizedStaticType{
+						Type: PrimitiveStaticTypeAnyStruct,
+					},
+					common.Address{},
+					UInt8Value(1),
+					UInt8Value(2),
+				),
+			},
+			{
+				Name:  "inner",
+				Value: NewSomeValueNonCopying(inner),
+			},
+		},
+		common.Address{},
+	)
+
+	compositeType := InferCompositeType(inter, outer)
+
+	assert.Equal(t, utils.TestLocation, compositeType.Location)
+	assert.Equal(t, "Outer", compositeType.QualifiedIdentifier())
+	assert.Equal(t, common.CompositeKindResource, compositeType.Kind)
+
+	require.Equal(t, 3, compositeType.Members.Len())
+	require.Equal(t, []string{"id", "inner", "values"}, compositeType.Fields)
+
+	fieldType := func(compositeType *sema.CompositeType, name string) sema.Type {
+		member, ok := compositeType.Members.Get(name)
+		require.True(t, ok)
+		return member.TypeAnnotation.Type
+	}
+
+	assert.Equal(t, sema.UInt64Type, fieldType(compositeType, "id"))
+
+	// The element type of the array is inferred from its elements
+
+	assert.Equal(t,
+		&sema.VariableSizedType{
+			Type: sema.UInt8Type,
+		},
+		fieldType(compositeType, "values"),
+	)
+
+	// The type of the nested composite is inferred recursively
+
+	innerOptionalType, ok := fieldType(compositeType, "inner").(*sema.OptionalType)
+	require.True(t, ok)
+
+	innerType, ok := innerOptionalType.Type.(*sema.CompositeType)
+	require.True(t, ok)
+
+	assert.Equal(t, "Outer.Inner", innerType.QualifiedIdentifier())
+	require.Equal(t, 1, innerType.Members.Len())
+	assert.Equal(t, sema.StringType, fieldType(innerType, "name"))
+}
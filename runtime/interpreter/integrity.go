@@ -0,0 +1,149 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	goRuntime "runtime"
+	"sort"
+
+	"github.com/onflow/atree"
+)
+
+// VerifyIntegrity checks all values stored in the storage, and returns the problems found,
+// at most one per account storage key, sorted by address and key.
+//
+// For each stored value, all slabs referenced by the value, directly or indirectly,
+// must exist and must decode from their encoding, and all nested values must conform
+// to the types declared by their containers, see ValidateTypeConsistency.
+//
+// If key hashing is enabled, the keys of the errors are the hashed keys.
+//
+func (i InMemoryStorage) VerifyIntegrity(interpreter *Interpreter) []IntegrityError {
+
+	storageKeys := make([]StorageKey, 0, len(i.AccountStorage))
+	for storageKey := range i.AccountStorage {
+		storageKeys = append(storageKeys, storageKey)
+	}
+
+	sort.Slice(storageKeys, func(i, j int) bool {
+		return storageKeys[i].IsLess(storageKeys[j])
+	})
+
+	var result []IntegrityError
+
+	for _, storageKey := range storageKeys {
+		storable := i.AccountStorage[storageKey]
+
+		storageID, err := i.verifySlabs(storable)
+		if err == nil {
+			err = verifyStoredValue(interpreter, StoredValue(storable, i))
+		}
+
+		if err != nil {
+			result = append(result, IntegrityError{
+				Address:   storageKey.Address,
+				Key:       storageKey.Key,
+				StorageID: storageID,
+				Err:       err,
+			})
+		}
+	}
+
+	return result
+}
+
+// verifySlabs checks that all slabs referenced by the given storable exist,
+// and that they decode from their encoding.
+// If a slab is invalid, its storage ID is returned along with the error.
+//
+func (i InMemoryStorage) verifySlabs(storable atree.Storable) (atree.StorageID, error) {
+
+	var walk func(storable atree.Storable) (atree.StorageID, error)
+	walk = func(storable atree.Storable) (atree.StorageID, error) {
+		// The root slab of an empty container might not be allocated
+
+		unallocated, err := isUnallocatedEmptyContainer(storable, i)
+		if err != nil || unallocated {
+			return atree.StorageIDUndefined, err
+		}
+
+		storageID, ok := referencedStorageID(storable)
+		if !ok {
+			for _, child := range storable.ChildStorables() {
+				storageID, err := walk(child)
+				if err != nil {
+					return storageID, err
+				}
+			}
+			return atree.StorageIDUndefined, nil
+		}
+
+		slab, ok, err := i.Retrieve(storageID)
+		if err != nil {
+			return storageID, err
+		}
+		if !ok {
+			return storageID, atree.NewSlabNotFoundErrorf(storageID, "slab not found")
+		}
+
+		data, err := EncodeStorable(slab)
+		if err != nil {
+			return storageID, err
+		}
+
+		_, err = atree.DecodeSlab(storageID, data, CBORDecMode, DecodeStorable, DecodeTypeInfo)
+		if err != nil {
+			return storageID, err
+		}
+
+		for _, child := range slab.ChildStorables() {
+			storageID, err := walk(child)
+			if err != nil {
+				return storageID, err
+			}
+		}
+
+		return atree.StorageIDUndefined, nil
+	}
+
+	return walk(storable)
+}
+
+// verifyStoredValue checks that all values nested in the given stored value
+// conform to the types declared by their containers.
+// Panics caused by the corrupted value are returned as errors.
+//
+func verifyStoredValue(interpreter *Interpreter, value Value) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch r := r.(type) {
+			case goRuntime.Error:
+				// Don't recover Go's panics
+				panic(r)
+			case error:
+				err = r
+			default:
+				err = fmt.Errorf("%s", r)
+			}
+		}
+	}()
+
+	return ValidateTypeConsistency(interpreter, value)
+}
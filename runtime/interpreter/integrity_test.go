@@ -0,0 +1,107 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestStorageVerifyIntegrity(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	newStorage := func(t *testing.T) (InMemoryStorage, *Interpreter, *ArrayValue) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		inner := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			address,
+			NewStringValue("a"),
+			NewStringValue("b"),
+		)
+
+		outer := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: VariableSizedStaticType{
+					Type: PrimitiveStaticTypeString,
+				},
+			},
+			address,
+			inner,
+		)
+
+		storage.WriteValue(inter, address, "nested", NewSomeValueNonCopying(outer))
+		storage.WriteValue(inter, address, "simple", NewSomeValueNonCopying(UInt8Value(1)))
+
+		inner = outer.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+
+		return storage, inter, inner
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter, _ := newStorage(t)
+
+		require.Empty(t, storage.VerifyIntegrity(inter))
+	})
+
+	t.Run("missing child slab", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter, inner := newStorage(t)
+
+		err := storage.Remove(inner.StorageID())
+		require.NoError(t, err)
+
+		integrityErrors := storage.VerifyIntegrity(inter)
+		require.Len(t, integrityErrors, 1)
+
+		integrityErr := integrityErrors[0]
+		require.Equal(t, address, integrityErr.Address)
+		require.Equal(t, "nested", integrityErr.Key)
+		require.Equal(t, inner.StorageID(), integrityErr.StorageID)
+
+		var slabNotFoundErr *atree.SlabNotFoundError
+		require.True(t, errors.As(integrityErr, &slabNotFoundErr))
+	})
+}
@@ -124,6 +124,14 @@ type OnRecordTraceFunc func(
 	logs []opentracing.LogRecord,
 )
 
+// OnOperationTraceFunc is a function that is triggered after a value operation was performed.
+//
+type OnOperationTraceFunc func(
+	operation string,
+	kind string,
+	duration time.Duration,
+)
+
 // InjectedCompositeFieldsHandlerFunc is a function that handles storage reads.
 //
 type InjectedCompositeFieldsHandlerFunc func(
@@ -149,6 +157,23 @@ type ImportLocationHandlerFunc func(
 	location common.Location,
 ) Import
 
+// ImportErrorHandlerFunc is a function that is called when an import location
+// could not be resolved, before the failure is reported as an ImportResolutionError.
+//
+type ImportErrorHandlerFunc func(
+	location common.Location,
+	err error,
+)
+
+// CompositeTypeResolverFunc is a function that supplies the type of a composite
+// with the given location and qualified identifier,
+// if the type is not declared in a loaded program, e.g. when loading arbitrary storage.
+//
+type CompositeTypeResolverFunc func(
+	location common.Location,
+	qualifiedIdentifier string,
+) (*sema.CompositeType, error)
+
 // PublicAccountHandlerFunc is a function that handles retrieving a public account at a given address.
 // The account returned must be of type `PublicAccount`.
 //
@@ -281,6 +306,9 @@ type Interpreter struct {
 	activations                    *VariableActivations
 	Globals                        GlobalVariables
 	allInterpreters                map[common.LocationID]*Interpreter
+	equalityCache                  *EqualityCache
+	sharedContainers               map[atree.StorageID][]*sharedCopy
+	transferProgress               *transferProgress
 	typeCodes                      TypeCodes
 	Transactions                   []*HostFunctionValue
 	Storage                        Storage
@@ -293,6 +321,8 @@ type Interpreter struct {
 	injectedCompositeFieldsHandler InjectedCompositeFieldsHandlerFunc
 	contractValueHandler           ContractValueHandlerFunc
 	importLocationHandler          ImportLocationHandlerFunc
+	importErrorHandler             ImportErrorHandlerFunc
+	compositeTypeResolver          CompositeTypeResolverFunc
 	publicAccountHandler           PublicAccountHandlerFunc
 	uuidHandler                    UUIDHandlerFunc
 	PublicKeyValidationHandler     PublicKeyValidationHandlerFunc
@@ -307,6 +337,22 @@ type Interpreter struct {
 	atreeValueValidationEnabled    bool
 	atreeStorageValidationEnabled  bool
 	tracingEnabled                 bool
+	duplicateKeyCheckEnabled       bool
+	valueTypeCheckEnabled          bool
+	valuePoolEnabled               bool
+	// containerHooks is nil if no container hook is enabled
+	containerHooks *containerHooks
+	// checkingTransferOwnership is true while the outermost transfer
+	// of a transfer ownership check is performed
+	checkingTransferOwnership bool
+	// checkingTransferSlabBudget is true while the outermost transfer
+	// of a transfer slab budget check is performed
+	checkingTransferSlabBudget bool
+	// maxStringLength is 0 if the length of string values is not limited
+	maxStringLength int
+	// transferAddressMap is nil unless the interpreter is dedicated to
+	// a copy which rewrites addresses, see CopyValueRemap
+	transferAddressMap map[common.Address]common.Address
 }
 
 type Option func(*Interpreter) error
@@ -432,6 +478,26 @@ func WithImportLocationHandler(handler ImportLocationHandlerFunc) Option {
 	}
 }
 
+// WithImportErrorHandler returns an interpreter option which sets the given function
+// as the function that is called when the import of a location fails.
+//
+func WithImportErrorHandler(handler ImportErrorHandlerFunc) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetImportErrorHandler(handler)
+		return nil
+	}
+}
+
+// WithCompositeTypeResolver returns an interpreter option which sets the given function
+// as the function that supplies the types of composites which are not declared in a loaded program.
+//
+func WithCompositeTypeResolver(resolver CompositeTypeResolverFunc) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetCompositeTypeResolver(resolver)
+		return nil
+	}
+}
+
 // WithPublicAccountHandlerFunc returns an interpreter option which sets the given function
 // as the function that is used to handle public accounts.
 //
@@ -520,6 +586,29 @@ func WithAllInterpreters(allInterpreters map[common.LocationID]*Interpreter) Opt
 	}
 }
 
+// WithEqualityCache returns an interpreter option which sets the cache
+// that is used to memoize the results of DeepEqual for stored values.
+//
+func WithEqualityCache(cache *EqualityCache) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetEqualityCache(cache)
+		return nil
+	}
+}
+
+// WithMaxStringLength returns an interpreter option which sets
+// the maximum length of string values, in bytes. Zero means unlimited.
+//
+// The limit applies to the strings the interpreter constructs.
+// Decoded strings are limited by the decoder, see WithMaxDecodedStringLength.
+//
+func WithMaxStringLength(length int) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetMaxStringLength(length)
+		return nil
+	}
+}
+
 // WithAtreeValueValidationEnabled returns an interpreter option which sets
 // the atree validation option.
 //
@@ -530,6 +619,88 @@ func WithAtreeValueValidationEnabled(enabled bool) Option {
 	}
 }
 
+// WithOperationTracer returns an interpreter option which sets
+// the given function as the operation tracer.
+//
+// The tracer is called after each get, insert, remove, transfer, and deepRemove operation
+// of an array, dictionary, or composite value, with the kind of the value and the duration of the operation.
+// Nested operations, e.g. the transfers of the elements of a transferred array, are traced before the enclosing operation.
+//
+func WithOperationTracer(tracer OnOperationTraceFunc) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetOperationTracer(tracer)
+		return nil
+	}
+}
+
+// WithValuePool returns an interpreter option which sets
+// if the Go-level wrappers of array and dictionary values,
+// which are only used temporarily, e.g. the elements of a container
+// while it is transferred, cloned, compared, or deep removed, are recycled.
+//
+// Value pooling reduces the number of allocations in bulk workloads,
+// and has no effect on the behaviour of the interpreter.
+//
+func WithValuePool(enabled bool) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetValuePool(enabled)
+		return nil
+	}
+}
+
+// WithTransferOwnershipCheck returns an interpreter option which sets
+// if the result of a transfer of a container is checked to be owned by the target account,
+// including all nested containers.
+//
+// This is a debugging aid: a container which is owned by another account
+// results in a panic with a TransferOwnershipError.
+//
+func WithTransferOwnershipCheck(enabled bool) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetTransferOwnershipCheck(enabled)
+		return nil
+	}
+}
+
+// WithTransferSlabBudget returns an interpreter option which limits
+// the number of slabs a transfer of a container may allocate, including all nested containers.
+//
+// This bounds the cost of transferring untrusted values: a transfer which exceeds the budget
+// is aborted with a TransferBudgetExceededError, and rolled back, so no partial state is left behind.
+// The slabs are counted while they are allocated, if the storage supports it (see SlabAllocationCountingStorage),
+// otherwise the number of slabs of the transferred value is used as an estimate.
+// A budget less than or equal to 0 disables the limit.
+//
+func WithTransferSlabBudget(n int) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetTransferSlabBudget(n)
+		return nil
+	}
+}
+
+// WithDuplicateKeyCheck returns an interpreter option which sets
+// if dictionary construction checks the given keys for duplicates.
+//
+func WithDuplicateKeyCheck(enabled bool) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetDuplicateKeyCheck(enabled)
+		return nil
+	}
+}
+
+// WithValueTypeCheck returns an interpreter option which sets
+// if values inserted into dictionaries are checked to conform to the value type of the dictionary.
+//
+// A value which does not conform results in a panic with a ValueTypeMismatchError,
+// which names the key the value was inserted for.
+//
+func WithValueTypeCheck(enabled bool) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.SetValueTypeCheck(enabled)
+		return nil
+	}
+}
+
 // WithAtreeStorageValidationEnabled returns an interpreter option which sets
 // the atree validation option.
 //
@@ -559,6 +730,38 @@ func withTypeCodes(typeCodes TypeCodes) Option {
 	}
 }
 
+// withSharedContainers returns an interpreter option which sets the shared copies
+// of the containers which share their slabs with the copy, by storage ID (see TransferShared).
+//
+func withSharedContainers(sharedContainers map[atree.StorageID][]*sharedCopy) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.sharedContainers = sharedContainers
+		return nil
+	}
+}
+
+// withContainerHooks returns an interpreter option which sets the container hooks,
+// i.e. the transfer checks, the operation tracer, and the content hash updates.
+//
+func withContainerHooks(hooks *containerHooks) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.containerHooks = hooks
+		return nil
+	}
+}
+
+// withTransferAddressMap returns an interpreter option which sets the map
+// which is used to rewrite the addresses of transferred address values and capabilities.
+//
+// It is only set for the dedicated interpreter of a copy, see CopyValueRemap.
+//
+func withTransferAddressMap(addressMap map[common.Address]common.Address) Option {
+	return func(interpreter *Interpreter) error {
+		interpreter.transferAddressMap = addressMap
+		return nil
+	}
+}
+
 // Create a base-activation so that it can be reused across all interpreters.
 //
 var baseActivation = func() *VariableActivation {
@@ -583,6 +786,7 @@ func NewInterpreter(program *Program, location common.Location, options ...Optio
 
 	defaultOptions := []Option{
 		WithAllInterpreters(map[common.LocationID]*Interpreter{}),
+		withSharedContainers(map[atree.StorageID][]*sharedCopy{}),
 		withTypeCodes(TypeCodes{
 			CompositeCodes:       map[sema.TypeID]CompositeTypeCode{},
 			InterfaceCodes:       map[sema.TypeID]WrapperCode{},
@@ -643,6 +847,14 @@ func (interpreter *Interpreter) SetOnRecordTraceHandler(function OnRecordTraceFu
 	interpreter.onRecordTrace = function
 }
 
+// SetOperationTracer sets the function that is triggered after a value operation was performed.
+//
+func (interpreter *Interpreter) SetOperationTracer(tracer OnOperationTraceFunc) {
+	interpreter.updateContainerHooks(func(hooks *containerHooks) {
+		hooks.onOperationTrace = tracer
+	})
+}
+
 // SetStorage sets the value that is used for storage operations.
 func (interpreter *Interpreter) SetStorage(storage Storage) {
 	interpreter.Storage = storage
@@ -667,6 +879,19 @@ func (interpreter *Interpreter) SetImportLocationHandler(function ImportLocation
 	interpreter.importLocationHandler = function
 }
 
+// SetImportErrorHandler sets the function that is called when the import of a location fails.
+//
+func (interpreter *Interpreter) SetImportErrorHandler(function ImportErrorHandlerFunc) {
+	interpreter.importErrorHandler = function
+}
+
+// SetCompositeTypeResolver sets the function that supplies the types of composites
+// which are not declared in a loaded program.
+//
+func (interpreter *Interpreter) SetCompositeTypeResolver(resolver CompositeTypeResolverFunc) {
+	interpreter.compositeTypeResolver = resolver
+}
+
 // SetPublicAccountHandler sets the function that is used to handle accounts.
 //
 func (interpreter *Interpreter) SetPublicAccountHandler(function PublicAccountHandlerFunc) {
@@ -715,6 +940,12 @@ func (interpreter *Interpreter) SetExitHandler(function ExitHandlerFunc) {
 	interpreter.ExitHandler = function
 }
 
+// SetEqualityCache sets the cache that is used to memoize the results of DeepEqual for stored values.
+//
+func (interpreter *Interpreter) SetEqualityCache(cache *EqualityCache) {
+	interpreter.equalityCache = cache
+}
+
 // SetAllInterpreters sets the given map of interpreters as the map of all interpreters.
 //
 func (interpreter *Interpreter) SetAllInterpreters(allInterpreters map[common.LocationID]*Interpreter) {
@@ -733,6 +964,66 @@ func (interpreter *Interpreter) SetAtreeValueValidationEnabled(enabled bool) {
 	interpreter.atreeValueValidationEnabled = enabled
 }
 
+// SetDuplicateKeyCheck sets if dictionary construction checks the given keys for duplicates.
+//
+func (interpreter *Interpreter) SetDuplicateKeyCheck(enabled bool) {
+	interpreter.duplicateKeyCheckEnabled = enabled
+}
+
+// SetValueTypeCheck sets if values inserted into dictionaries are checked to conform to the value type.
+//
+func (interpreter *Interpreter) SetValueTypeCheck(enabled bool) {
+	interpreter.valueTypeCheckEnabled = enabled
+}
+
+// SetValuePool sets if temporarily used array and dictionary values are recycled.
+//
+func (interpreter *Interpreter) SetValuePool(enabled bool) {
+	interpreter.valuePoolEnabled = enabled
+}
+
+// SetTransferOwnershipCheck sets if the results of transfers are checked to be owned by the target account.
+//
+func (interpreter *Interpreter) SetTransferOwnershipCheck(enabled bool) {
+	interpreter.updateContainerHooks(func(hooks *containerHooks) {
+		hooks.transferOwnershipCheck = enabled
+	})
+}
+
+// SetMaxStringLength sets the maximum length of string values, in bytes.
+// Zero means unlimited, which is the default.
+//
+func (interpreter *Interpreter) SetMaxStringLength(length int) {
+	if length < 0 {
+		length = 0
+	}
+	interpreter.maxStringLength = length
+}
+
+// checkStringLength panics with a StringLengthLimitExceededError
+// if the given length of a string exceeds the maximum length of string values.
+//
+func (interpreter *Interpreter) checkStringLength(length int) {
+	if interpreter.maxStringLength > 0 && length > interpreter.maxStringLength {
+		panic(StringLengthLimitExceededError{
+			Length: length,
+			Limit:  interpreter.maxStringLength,
+		})
+	}
+}
+
+// SetTransferSlabBudget sets the maximum number of slabs a transfer may allocate.
+// A budget less than or equal to 0 disables the limit.
+//
+func (interpreter *Interpreter) SetTransferSlabBudget(n int) {
+	if n < 0 {
+		n = 0
+	}
+	interpreter.updateContainerHooks(func(hooks *containerHooks) {
+		hooks.transferSlabBudget = n
+	})
+}
+
 // SetAtreeStorageValidationEnabled sets the atree storage validation option.
 //
 func (interpreter *Interpreter) SetAtreeStorageValidationEnabled(enabled bool) {
@@ -1184,10 +1475,10 @@ func (interpreter *Interpreter) visitFunctionBody(
 		if ret, ok := result.(functionReturn); ok {
 			returnValue = ret.Value
 		} else {
-			returnValue = VoidValue{}
+			returnValue = Void()
 		}
 	} else {
-		returnValue = VoidValue{}
+		returnValue = Void()
 	}
 
 	// If there is a return type, declare the constant `result`.
@@ -1724,7 +2015,7 @@ func EnumConstructorFunction(
 
 			caseValue, ok := lookupTable[string(rawValueArgumentBigEndianBytes)]
 			if !ok {
-				return NilValue{}
+				return Nil()
 			}
 
 			return NewSomeValueNonCopying(caseValue)
@@ -2437,6 +2728,16 @@ func (interpreter *Interpreter) ensureLoadedWithLocationHandler(
 
 		return subInterpreter
 
+	case FailedImport:
+		if interpreter.importErrorHandler != nil {
+			interpreter.importErrorHandler(location, imported.Err)
+		}
+
+		panic(ImportResolutionError{
+			Location: location,
+			Err:      imported.Err,
+		})
+
 	default:
 		panic(errors.NewUnreachableError())
 	}
@@ -2462,11 +2763,20 @@ func (interpreter *Interpreter) NewSubInterpreter(
 		WithInjectedCompositeFieldsHandler(interpreter.injectedCompositeFieldsHandler),
 		WithContractValueHandler(interpreter.contractValueHandler),
 		WithImportLocationHandler(interpreter.importLocationHandler),
+		WithImportErrorHandler(interpreter.importErrorHandler),
+		WithCompositeTypeResolver(interpreter.compositeTypeResolver),
 		WithUUIDHandler(interpreter.uuidHandler),
 		WithAllInterpreters(interpreter.allInterpreters),
+		WithEqualityCache(interpreter.equalityCache),
 		WithAtreeValueValidationEnabled(interpreter.atreeValueValidationEnabled),
 		WithAtreeStorageValidationEnabled(interpreter.atreeStorageValidationEnabled),
+		WithDuplicateKeyCheck(interpreter.duplicateKeyCheckEnabled),
+		WithValueTypeCheck(interpreter.valueTypeCheckEnabled),
+		WithValuePool(interpreter.valuePoolEnabled),
+		withContainerHooks(interpreter.containerHooks),
+		WithMaxStringLength(interpreter.maxStringLength),
 		withTypeCodes(interpreter.typeCodes),
+		withSharedContainers(interpreter.sharedContainers),
 		WithPublicAccountHandlerFunc(interpreter.publicAccountHandler),
 		WithPublicKeyValidationHandler(interpreter.PublicKeyValidationHandler),
 		WithSignatureVerificationHandler(interpreter.SignatureVerificationHandler),
@@ -2496,6 +2806,23 @@ func (interpreter *Interpreter) writeStored(storageAddress common.Address, key s
 	interpreter.Storage.WriteValue(interpreter, storageAddress, key, value)
 }
 
+// removeStored removes the value stored under the given key.
+//
+// Storages which may not remove values when nil is written, e.g. see WithExplicitNil,
+// remove the value explicitly.
+//
+func (interpreter *Interpreter) removeStored(storageAddress common.Address, key string) {
+	if storage, ok := interpreter.Storage.(valueRemovingStorage); ok {
+		err := storage.RemoveValue(interpreter, storageAddress, key, false)
+		if err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	interpreter.writeStored(storageAddress, key, Nil())
+}
+
 type valueConverterDeclaration struct {
 	name    string
 	convert func(Value) Value
@@ -2750,7 +3077,7 @@ func init() {
 				// if the given key is not a valid dictionary key, it wouldn't make sense to create this type
 				if keyType == nil ||
 					!sema.IsValidDictionaryKeyType(invocation.Interpreter.MustConvertStaticToSemaType(keyType)) {
-					return NilValue{}
+					return Nil()
 				}
 
 				return NewSomeValueNonCopying(TypeValue{
@@ -2771,7 +3098,7 @@ func init() {
 
 				composite, err := lookupComposite(invocation.Interpreter, typeID)
 				if err != nil {
-					return NilValue{}
+					return Nil()
 				}
 
 				return NewSomeValueNonCopying(TypeValue{
@@ -2791,7 +3118,7 @@ func init() {
 
 				interfaceType, err := lookupInterface(invocation.Interpreter, typeID)
 				if err != nil {
-					return NilValue{}
+					return Nil()
 				}
 
 				return NewSomeValueNonCopying(TypeValue{
@@ -2861,7 +3188,7 @@ func RestrictedTypeFunction(invocation Invocation) Value {
 	})
 
 	if !ok {
-		return NilValue{}
+		return Nil()
 	}
 
 	var semaType sema.Type
@@ -2873,7 +3200,7 @@ func RestrictedTypeFunction(invocation Invocation) Value {
 	case *SomeValue:
 		semaType, err = lookupComposite(invocation.Interpreter, typeID.Value.(*StringValue).Str)
 		if err != nil {
-			return NilValue{}
+			return Nil()
 		}
 	default:
 		panic(errors.NewUnreachableError())
@@ -2890,7 +3217,7 @@ func RestrictedTypeFunction(invocation Invocation) Value {
 
 	// if the restricted type would have failed to typecheck statically, we return nil
 	if !ok {
-		return NilValue{}
+		return Nil()
 	}
 	return NewSomeValueNonCopying(TypeValue{
 		Type: &RestrictedStaticType{
@@ -3028,7 +3355,7 @@ var runtimeTypeConstructors = []runtimeTypeConstructor{
 				// Capabilities must hold references
 				_, ok := ty.(ReferenceStaticType)
 				if !ok {
-					return NilValue{}
+					return Nil()
 				}
 				return NewSomeValueNonCopying(
 					TypeValue{
@@ -3109,6 +3436,7 @@ var stringFunction = func() Value {
 			func(invocation Invocation) Value {
 				argument := invocation.Arguments[0].(*ArrayValue)
 				bytes, _ := ByteArrayValueToByteSlice(argument)
+				invocation.Interpreter.checkStringLength(hex.EncodedLen(len(bytes)))
 				return NewStringValue(hex.EncodeToString(bytes))
 			},
 			sema.StringTypeEncodeHexFunctionType,
@@ -3409,7 +3737,7 @@ func (interpreter *Interpreter) authAccountSaveFunction(addressValue AddressValu
 				NewSomeValueNonCopying(value),
 			)
 
-			return VoidValue{}
+			return Void()
 		},
 		sema.AuthAccountTypeSaveFunctionType,
 	)
@@ -3453,7 +3781,7 @@ func (interpreter *Interpreter) authAccountReadFunction(addressValue AddressValu
 
 				dynamicType := value.Value.DynamicType(interpreter, SeenReferences{})
 				if !interpreter.IsSubType(dynamicType, ty) {
-					return NilValue{}
+					return Nil()
 				}
 
 				inter := invocation.Interpreter
@@ -3473,7 +3801,7 @@ func (interpreter *Interpreter) authAccountReadFunction(addressValue AddressValu
 				// Remove the value from storage,
 				// but only if the type check succeeded.
 				if clear {
-					interpreter.writeStored(address, key, NilValue{})
+					interpreter.removeStored(address, key)
 				}
 
 				return transferredValue
@@ -3518,7 +3846,7 @@ func (interpreter *Interpreter) authAccountBorrowFunction(addressValue AddressVa
 			// and performs a dynamic type check
 
 			if reference.ReferencedValue(interpreter) == nil {
-				return NilValue{}
+				return Nil()
 			}
 
 			return NewSomeValueNonCopying(reference)
@@ -3546,7 +3874,7 @@ func (interpreter *Interpreter) authAccountLinkFunction(addressValue AddressValu
 			newCapabilityKey := PathToStorageKey(newCapabilityPath)
 
 			if interpreter.storedValueExists(address, newCapabilityKey) {
-				return NilValue{}
+				return Nil()
 			}
 
 			// Write new value
@@ -3599,7 +3927,7 @@ func (interpreter *Interpreter) accountGetLinkTargetFunction(addressValue Addres
 
 				link, ok := value.Value.(LinkValue)
 				if !ok {
-					return NilValue{}
+					return Nil()
 				}
 
 				return NewSomeValueNonCopying(link.TargetPath)
@@ -3626,10 +3954,10 @@ func (interpreter *Interpreter) authAccountUnlinkFunction(addressValue AddressVa
 			interpreter.writeStored(
 				address,
 				capabilityKey,
-				NilValue{},
+				Nil(),
 			)
 
-			return VoidValue{}
+			return Void()
 		},
 		sema.AuthAccountTypeUnlinkFunctionType,
 	)
@@ -3670,7 +3998,7 @@ func (interpreter *Interpreter) capabilityBorrowFunction(
 			}
 
 			if targetStorageKey == "" {
-				return NilValue{}
+				return Nil()
 			}
 
 			reference := &StorageReferenceValue{
@@ -3685,7 +4013,7 @@ func (interpreter *Interpreter) capabilityBorrowFunction(
 			// and performs a dynamic type check
 
 			if reference.ReferencedValue(interpreter) == nil {
-				return NilValue{}
+				return Nil()
 			}
 
 			return NewSomeValueNonCopying(reference)
@@ -3882,18 +4210,49 @@ func (interpreter *Interpreter) GetCompositeType(
 		return interpreter.getNativeCompositeType(qualifiedIdentifier)
 	}
 
-	return interpreter.getUserCompositeType(location, typeID)
+	return interpreter.getUserCompositeType(location, qualifiedIdentifier, typeID)
 }
 
-func (interpreter *Interpreter) getUserCompositeType(location common.Location, typeID common.TypeID) (*sema.CompositeType, error) {
-	elaboration := interpreter.getElaboration(location)
-	if elaboration == nil {
+// getUserCompositeType returns the composite type with the given location and type ID
+// from the elaboration of the program at the location.
+//
+// If the type is not declared in the program, or the program cannot be loaded,
+// because no import location handler is set, the type is resolved
+// using the composite type resolver, if any.
+//
+func (interpreter *Interpreter) getUserCompositeType(
+	location common.Location,
+	qualifiedIdentifier string,
+	typeID common.TypeID,
+) (*sema.CompositeType, error) {
+
+	var elaboration *sema.Elaboration
+	if interpreter.importLocationHandler != nil ||
+		interpreter.allInterpreters[location.ID()] != nil {
+
+		elaboration = interpreter.getElaboration(location)
+	}
+
+	if elaboration != nil {
+		ty := elaboration.CompositeTypes[typeID]
+		if ty != nil {
+			return ty, nil
+		}
+	}
+
+	if interpreter.compositeTypeResolver == nil {
 		return nil, TypeLoadingError{
 			TypeID: typeID,
 		}
 	}
 
-	ty := elaboration.CompositeTypes[typeID]
+	ty, err := interpreter.compositeTypeResolver(location, qualifiedIdentifier)
+	if err != nil {
+		return nil, TypeLoadingError{
+			TypeID: typeID,
+			Err:    err,
+		}
+	}
 	if ty == nil {
 		return nil, TypeLoadingError{
 			TypeID: typeID,
@@ -4062,6 +4421,28 @@ func (interpreter *Interpreter) checkContainerMutation(
 	}
 }
 
+// checkDictionaryValueType panics with a ValueTypeMismatchError
+// if the given value, inserted for the given key, is not a subtype of the given value type.
+//
+func (interpreter *Interpreter) checkDictionaryValueType(
+	valueType StaticType,
+	key Value,
+	value Value,
+	getLocationRange func() LocationRange,
+) {
+	expectedType := interpreter.MustConvertStaticToSemaType(valueType)
+	actualType := value.DynamicType(interpreter, SeenReferences{})
+
+	if !interpreter.IsSubType(actualType, expectedType) {
+		panic(ValueTypeMismatchError{
+			Key:           key,
+			ExpectedType:  expectedType,
+			ActualType:    interpreter.MustConvertStaticToSemaType(value.StaticType()),
+			LocationRange: getLocationRange(),
+		})
+	}
+}
+
 func (interpreter *Interpreter) checkResourceNotDestroyed(value Value, getLocationRange func() LocationRange) {
 	resourceKindedValue, ok := value.(ResourceKindedValue)
 	if !ok || !resourceKindedValue.IsDestroyed() {
@@ -4074,18 +4455,41 @@ func (interpreter *Interpreter) checkResourceNotDestroyed(value Value, getLocati
 }
 
 func (interpreter *Interpreter) RemoveReferencedSlab(storable atree.Storable) {
-	storageIDStorable, ok := storable.(atree.StorageIDStorable)
-	if !ok {
+	var storageID atree.StorageID
+
+	switch storable := storable.(type) {
+	case atree.StorageIDStorable:
+		storageID = atree.StorageID(storable)
+
+	case EmptyContainerStorable:
+		unallocated, err := isUnallocatedEmptyContainer(storable, interpreter.Storage)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+		if unallocated {
+			return
+		}
+		storageID = storable.StorageID
+
+	default:
 		return
 	}
 
-	storageID := atree.StorageID(storageIDStorable)
 	err := interpreter.Storage.Remove(storageID)
 	if err != nil {
 		panic(ExternalError{err})
 	}
 }
 
+// invalidateEqualityCache must be called whenever a container value is mutated,
+// with the storage ID of the mutated container.
+//
+func (interpreter *Interpreter) invalidateEqualityCache(storageID atree.StorageID) {
+	if interpreter.equalityCache != nil {
+		interpreter.equalityCache.Invalidate(storageID)
+	}
+}
+
 func (interpreter *Interpreter) maybeValidateAtreeValue(v atree.Value) {
 	if interpreter.atreeValueValidationEnabled {
 		interpreter.ValidateAtreeValue(v)
@@ -336,7 +336,7 @@ func (interpreter *Interpreter) VisitBoolExpression(expression *ast.BoolExpressi
 }
 
 func (interpreter *Interpreter) VisitNilExpression(_ *ast.NilExpression) ast.Repr {
-	return NilValue{}
+	return Nil()
 }
 
 func (interpreter *Interpreter) VisitIntegerExpression(expression *ast.IntegerExpression) ast.Repr {
@@ -436,6 +436,7 @@ func (interpreter *Interpreter) VisitFixedPointExpression(expression *ast.FixedP
 }
 
 func (interpreter *Interpreter) VisitStringExpression(expression *ast.StringExpression) ast.Repr {
+	interpreter.checkStringLength(len(expression.Value))
 	return NewStringValue(expression.Value)
 }
 
@@ -688,7 +689,7 @@ func (interpreter *Interpreter) VisitCastingExpression(expression *ast.CastingEx
 		switch expression.Operation {
 		case ast.OperationFailableCast:
 			if !isSubType {
-				return NilValue{}
+				return Nil()
 			}
 
 			return NewSomeValueNonCopying(value)
@@ -728,7 +729,7 @@ func (interpreter *Interpreter) VisitDestroyExpression(expression *ast.DestroyEx
 
 	value.(ResourceKindedValue).Destroy(interpreter, getLocationRange)
 
-	return VoidValue{}
+	return Void()
 }
 
 func (interpreter *Interpreter) VisitReferenceExpression(referenceExpression *ast.ReferenceExpression) ast.Repr {
@@ -60,7 +60,7 @@ func (interpreter *Interpreter) VisitReturnStatement(statement *ast.ReturnStatem
 
 	var value Value
 	if statement.Expression == nil {
-		value = VoidValue{}
+		value = Void()
 	} else {
 		value = interpreter.evalExpression(statement.Expression)
 
@@ -282,7 +282,7 @@ func (interpreter *Interpreter) VisitForStatement(statement *ast.ForStatement) a
 		nil,
 	)
 
-	iterator, err := transferredValue.(*ArrayValue).array.Iterator()
+	iterator, err := transferredValue.(*ArrayValue).atreeArray().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
@@ -21,10 +21,12 @@ package interpreter_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/onflow/cadence/runtime/common"
 	. "github.com/onflow/cadence/runtime/interpreter"
 	"github.com/onflow/cadence/runtime/sema"
 	"github.com/onflow/cadence/runtime/tests/utils"
@@ -157,3 +159,65 @@ func TestInterpreterBoxing(t *testing.T) {
 		})
 	}
 }
+
+func TestInterpreterOperationTracer(t *testing.T) {
+
+	t.Parallel()
+
+	type operation struct {
+		name string
+		kind string
+	}
+
+	var operations []operation
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(NewInMemoryStorage()),
+		WithOperationTracer(func(name string, kind string, duration time.Duration) {
+			require.GreaterOrEqual(t, int64(duration), int64(0))
+
+			operations = append(operations, operation{
+				name: name,
+				kind: kind,
+			})
+		}),
+	)
+	require.NoError(t, err)
+
+	dictionary := NewDictionaryValue(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: PrimitiveStaticTypeAnyStruct,
+		},
+	)
+
+	array := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		},
+		common.Address{},
+	)
+
+	require.Empty(t, operations)
+
+	dictionary.Insert(inter, ReturnEmptyLocationRange, NewStringValue("a"), array)
+	dictionary.Get(inter, ReturnEmptyLocationRange, NewStringValue("a"))
+	dictionary.Remove(inter, ReturnEmptyLocationRange, NewStringValue("a"))
+
+	require.Equal(t,
+		[]operation{
+			// The inserted array is transferred into the dictionary
+			{"transfer", "array"},
+			{"insert", "dictionary"},
+			{"get", "dictionary"},
+			// The removed array is transferred out of the dictionary
+			{"transfer", "array"},
+			{"remove", "dictionary"},
+		},
+		operations,
+	)
+}
@@ -33,6 +33,20 @@ const (
 	tracingTransferPrefix   = "transfer."
 )
 
+const (
+	tracingOperationGet        = "get"
+	tracingOperationInsert     = "insert"
+	tracingOperationRemove     = "remove"
+	tracingOperationTransfer   = "transfer"
+	tracingOperationDeepRemove = "deepRemove"
+)
+
+const (
+	tracingKindArray      = "array"
+	tracingKindDictionary = "dictionary"
+	tracingKindComposite  = "composite"
+)
+
 func (interpreter *Interpreter) reportFunctionTrace(functionName string, duration time.Duration) {
 	interpreter.onRecordTrace(interpreter, tracingFunctionPrefix+functionName, duration, nil)
 }
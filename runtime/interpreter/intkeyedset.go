@@ -0,0 +1,341 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"math/bits"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/errors"
+)
+
+// intKeyedSetMaxDenseKey is the exclusive upper bound for keys
+// which are stored in the bitset of an IntKeyedSet.
+// Adding a larger key falls back to a general dictionary.
+//
+const intKeyedSetMaxDenseKey = 1 << 16
+
+// IntKeyedSet is a set of unsigned integer keys, i.e. a dictionary of type `{K: Bool}`,
+// where each contained key maps to `true`.
+//
+// As long as all keys are small, membership is stored in a bitset.
+// Once a key is added which is too large, the set falls back to a general dictionary.
+//
+// The set presents as a dictionary value, see Dictionary.
+// The dictionary value is owned by the set, and must be removed using DeepRemove.
+//
+type IntKeyedSet struct {
+	keyType PrimitiveStaticType
+	bits    []uint64
+	count   int
+	dense   bool
+	// dictionary is the dictionary value the set presents as.
+	// It is nil until it is requested (see Dictionary), or until the set falls back to it.
+	// While the set is dense, it is kept in sync with the bitset.
+	dictionary *DictionaryValue
+}
+
+// NewIntKeyedSet returns a new empty set for the given key type,
+// which must be an unsigned integer type (`UInt8` to `UInt64`, or `Word8` to `Word64`).
+//
+func NewIntKeyedSet(_ *Interpreter, keyType PrimitiveStaticType) *IntKeyedSet {
+	switch keyType {
+	case PrimitiveStaticTypeUInt8,
+		PrimitiveStaticTypeUInt16,
+		PrimitiveStaticTypeUInt32,
+		PrimitiveStaticTypeUInt64,
+		PrimitiveStaticTypeWord8,
+		PrimitiveStaticTypeWord16,
+		PrimitiveStaticTypeWord32,
+		PrimitiveStaticTypeWord64:
+
+		return &IntKeyedSet{
+			keyType: keyType,
+			dense:   true,
+		}
+
+	default:
+		panic(errors.NewUnreachableError())
+	}
+}
+
+// Type returns the type of the dictionary the set presents as.
+//
+func (s *IntKeyedSet) Type() DictionaryStaticType {
+	return DictionaryStaticType{
+		KeyType:   s.keyType,
+		ValueType: PrimitiveStaticTypeBool,
+	}
+}
+
+// IsDense returns true if the membership of the set is stored in a bitset.
+//
+func (s *IntKeyedSet) IsDense() bool {
+	return s.dense
+}
+
+// BitsetSize returns the size of the bitset in bytes.
+//
+func (s *IntKeyedSet) BitsetSize() int {
+	return len(s.bits) * 8
+}
+
+func (s *IntKeyedSet) Count() int {
+	if !s.dense {
+		return s.dictionary.Count()
+	}
+	return s.count
+}
+
+// Add adds the given key to the set, and returns true if it was not contained yet.
+//
+// It panics with a ContainerMutationError if the key is not of the key type of the set.
+//
+func (s *IntKeyedSet) Add(interpreter *Interpreter, key Value) bool {
+	if !s.dense {
+		return s.addToDictionary(interpreter, key)
+	}
+
+	k, ok := s.keyToUint64(key)
+	if !ok {
+		panic(ContainerMutationError{
+			ExpectedType:  interpreter.MustConvertStaticToSemaType(s.keyType),
+			ActualType:    interpreter.MustConvertStaticToSemaType(key.StaticType()),
+			LocationRange: ReturnEmptyLocationRange(),
+		})
+	}
+
+	if k >= intKeyedSetMaxDenseKey {
+		s.fallBack(interpreter)
+		return s.addToDictionary(interpreter, key)
+	}
+
+	word, mask := k/64, uint64(1)<<(k%64)
+
+	for uint64(len(s.bits)) <= word {
+		s.bits = append(s.bits, 0)
+	}
+
+	if s.bits[word]&mask != 0 {
+		return false
+	}
+
+	s.bits[word] |= mask
+	s.count++
+
+	if s.dictionary != nil {
+		s.addToDictionary(interpreter, key)
+	}
+
+	return true
+}
+
+func (s *IntKeyedSet) addToDictionary(interpreter *Interpreter, key Value) bool {
+	existing := s.dictionary.Insert(interpreter, ReturnEmptyLocationRange, key, BoolValue(true))
+	_, existed := existing.(*SomeValue)
+	return !existed
+}
+
+// Remove removes the given key from the set, and returns true if it was contained.
+//
+func (s *IntKeyedSet) Remove(interpreter *Interpreter, key Value) bool {
+	if !s.dense {
+		return s.removeFromDictionary(interpreter, key)
+	}
+
+	k, ok := s.keyToUint64(key)
+	if !ok {
+		return false
+	}
+
+	word, mask := k/64, uint64(1)<<(k%64)
+
+	if word >= uint64(len(s.bits)) || s.bits[word]&mask == 0 {
+		return false
+	}
+
+	s.bits[word] &^= mask
+	s.count--
+
+	if s.dictionary != nil {
+		s.removeFromDictionary(interpreter, key)
+	}
+
+	return true
+}
+
+func (s *IntKeyedSet) removeFromDictionary(interpreter *Interpreter, key Value) bool {
+	existing := s.dictionary.Remove(interpreter, ReturnEmptyLocationRange, key)
+	_, existed := existing.(*SomeValue)
+	return existed
+}
+
+// Contains returns true if the given key is contained in the set.
+//
+func (s *IntKeyedSet) Contains(interpreter *Interpreter, key Value) bool {
+	if !s.dense {
+		return bool(s.dictionary.ContainsKey(interpreter, ReturnEmptyLocationRange, key))
+	}
+
+	k, ok := s.keyToUint64(key)
+	if !ok {
+		return false
+	}
+
+	word, mask := k/64, uint64(1)<<(k%64)
+
+	return word < uint64(len(s.bits)) && s.bits[word]&mask != 0
+}
+
+// Iterate calls the given function for each key in the set, with the value `true`,
+// like DictionaryValue.Iterate. Keys of a dense set are iterated in ascending order.
+//
+func (s *IntKeyedSet) Iterate(f func(key, value Value) (resume bool)) {
+	if !s.dense {
+		s.dictionary.Iterate(f)
+		return
+	}
+
+	for i, word := range s.bits {
+		for word != 0 {
+			bit := bits.TrailingZeros64(word)
+			word &^= 1 << bit
+
+			key := s.uint64ToKey(uint64(i)*64 + uint64(bit))
+			if !f(key, BoolValue(true)) {
+				return
+			}
+		}
+	}
+}
+
+// Dictionary returns the dictionary value the set presents as.
+//
+// The same dictionary value is returned on every call, and it reflects all later changes to the set.
+// It must not be mutated directly, and it is removed when the set is removed, see DeepRemove.
+//
+func (s *IntKeyedSet) Dictionary(interpreter *Interpreter) *DictionaryValue {
+	if s.dictionary != nil {
+		return s.dictionary
+	}
+
+	keysAndValues := make([]Value, 0, s.count*2)
+	s.Iterate(func(key, value Value) bool {
+		keysAndValues = append(keysAndValues, key, value)
+		return true
+	})
+
+	s.dictionary = NewDictionaryValue(interpreter, s.Type(), keysAndValues...)
+
+	return s.dictionary
+}
+
+// DeepRemove removes the dictionary value the set presents as, if any, including its slabs.
+// The set is empty afterwards.
+//
+func (s *IntKeyedSet) DeepRemove(interpreter *Interpreter) {
+	if s.dictionary != nil {
+		storageID, ok := containerRootStorageID(s.dictionary)
+
+		s.dictionary.DeepRemove(interpreter)
+
+		if ok {
+			interpreter.RemoveReferencedSlab(atree.StorageIDStorable(storageID))
+		}
+
+		s.dictionary = nil
+	}
+
+	s.bits = nil
+	s.count = 0
+	s.dense = true
+}
+
+func (s *IntKeyedSet) fallBack(interpreter *Interpreter) {
+	s.dictionary = s.Dictionary(interpreter)
+	s.bits = nil
+	s.count = 0
+	s.dense = false
+}
+
+// keyToUint64 returns the integer of the given key,
+// and false if the key is not of the key type of the set.
+//
+func (s *IntKeyedSet) keyToUint64(key Value) (uint64, bool) {
+	switch key := key.(type) {
+	case UInt8Value:
+		if s.keyType == PrimitiveStaticTypeUInt8 {
+			return uint64(key), true
+		}
+	case UInt16Value:
+		if s.keyType == PrimitiveStaticTypeUInt16 {
+			return uint64(key), true
+		}
+	case UInt32Value:
+		if s.keyType == PrimitiveStaticTypeUInt32 {
+			return uint64(key), true
+		}
+	case UInt64Value:
+		if s.keyType == PrimitiveStaticTypeUInt64 {
+			return uint64(key), true
+		}
+	case Word8Value:
+		if s.keyType == PrimitiveStaticTypeWord8 {
+			return uint64(key), true
+		}
+	case Word16Value:
+		if s.keyType == PrimitiveStaticTypeWord16 {
+			return uint64(key), true
+		}
+	case Word32Value:
+		if s.keyType == PrimitiveStaticTypeWord32 {
+			return uint64(key), true
+		}
+	case Word64Value:
+		if s.keyType == PrimitiveStaticTypeWord64 {
+			return uint64(key), true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *IntKeyedSet) uint64ToKey(k uint64) Value {
+	switch s.keyType {
+	case PrimitiveStaticTypeUInt8:
+		return UInt8Value(k)
+	case PrimitiveStaticTypeUInt16:
+		return UInt16Value(k)
+	case PrimitiveStaticTypeUInt32:
+		return UInt32Value(k)
+	case PrimitiveStaticTypeUInt64:
+		return UInt64Value(k)
+	case PrimitiveStaticTypeWord8:
+		return Word8Value(k)
+	case PrimitiveStaticTypeWord16:
+		return Word16Value(k)
+	case PrimitiveStaticTypeWord32:
+		return Word32Value(k)
+	case PrimitiveStaticTypeWord64:
+		return Word64Value(k)
+	default:
+		panic(errors.NewUnreachableError())
+	}
+}
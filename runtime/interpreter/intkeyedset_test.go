@@ -0,0 +1,233 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestIntKeyedSet(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("equivalent to dictionary", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		set := NewIntKeyedSet(inter, PrimitiveStaticTypeUInt32)
+		dictionary := NewDictionaryValue(inter, set.Type())
+
+		r := rand.New(rand.NewSource(42))
+
+		for i := 0; i < 2000; i++ {
+			key := UInt32Value(r.Intn(512))
+
+			if r.Intn(3) == 0 {
+				removed := set.Remove(inter, key)
+				_, existed := dictionary.Remove(inter, ReturnEmptyLocationRange, key).(*SomeValue)
+				require.Equal(t, existed, removed)
+			} else {
+				added := set.Add(inter, key)
+				_, existed := dictionary.Insert(inter, ReturnEmptyLocationRange, key, BoolValue(true)).(*SomeValue)
+				require.Equal(t, !existed, added)
+			}
+
+			require.Equal(t, dictionary.Count(), set.Count())
+		}
+
+		for key := 0; key < 512; key++ {
+			require.Equal(t,
+				bool(dictionary.ContainsKey(inter, ReturnEmptyLocationRange, UInt32Value(key))),
+				set.Contains(inter, UInt32Value(key)),
+			)
+		}
+
+		require.True(t, set.IsDense())
+
+		utils.RequireValuesEqual(t, inter, dictionary, set.Dictionary(inter))
+	})
+
+	t.Run("iteration order", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		set := NewIntKeyedSet(inter, PrimitiveStaticTypeUInt64)
+
+		for _, key := range []uint64{130, 3, 64, 0} {
+			set.Add(inter, UInt64Value(key))
+		}
+
+		var keys []Value
+		set.Iterate(func(key, value Value) bool {
+			assert.Equal(t, BoolValue(true), value)
+			keys = append(keys, key)
+			return true
+		})
+
+		require.Equal(t,
+			[]Value{
+				UInt64Value(0),
+				UInt64Value(3),
+				UInt64Value(64),
+				UInt64Value(130),
+			},
+			keys,
+		)
+	})
+
+	t.Run("sparse keys", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		set := NewIntKeyedSet(inter, PrimitiveStaticTypeUInt64)
+
+		require.True(t, set.Add(inter, UInt64Value(1)))
+		require.True(t, set.IsDense())
+
+		require.True(t, set.Add(inter, UInt64Value(1<<40)))
+		require.False(t, set.IsDense())
+		require.False(t, set.Add(inter, UInt64Value(1)))
+
+		require.Equal(t, 2, set.Count())
+		require.True(t, set.Contains(inter, UInt64Value(1)))
+		require.True(t, set.Contains(inter, UInt64Value(1<<40)))
+		require.False(t, set.Contains(inter, UInt64Value(2)))
+
+		require.True(t, set.Remove(inter, UInt64Value(1)))
+		require.Equal(t, 1, set.Count())
+	})
+
+	t.Run("reduced storage", func(t *testing.T) {
+
+		t.Parallel()
+
+		const count = 1024
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		set := NewIntKeyedSet(inter, PrimitiveStaticTypeUInt64)
+
+		keysAndValues := make([]Value, 0, count*2)
+		for key := 0; key < count; key++ {
+			set.Add(inter, UInt64Value(key))
+			keysAndValues = append(keysAndValues, UInt64Value(key), BoolValue(true))
+		}
+
+		_ = NewDictionaryValueWithAddress(
+			inter,
+			set.Type(),
+			common.Address{0x1},
+			keysAndValues...,
+		)
+
+		encoded, err := storage.Encode()
+		require.NoError(t, err)
+
+		dictionarySize := 0
+		for _, data := range encoded {
+			dictionarySize += len(data)
+		}
+
+		require.True(t, set.IsDense())
+		require.Equal(t, count/8, set.BitsetSize())
+		require.Less(t, set.BitsetSize()*10, dictionarySize)
+	})
+	t.Run("dictionary view", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		slabCount := storage.Count()
+
+		set := NewIntKeyedSet(inter, PrimitiveStaticTypeUInt16)
+		set.Add(inter, UInt16Value(1))
+
+		dictionary := set.Dictionary(inter)
+		require.Same(t, dictionary, set.Dictionary(inter))
+
+		// The view reflects later changes to the set
+
+		set.Add(inter, UInt16Value(2))
+		set.Remove(inter, UInt16Value(1))
+
+		require.True(t, set.IsDense())
+		require.Equal(t, 1, dictionary.Count())
+		require.True(t, bool(dictionary.ContainsKey(inter, ReturnEmptyLocationRange, UInt16Value(2))))
+
+		// Removing the set removes the view
+
+		set.DeepRemove(inter)
+
+		require.Equal(t, 0, set.Count())
+		require.Equal(t, slabCount, storage.Count())
+	})
+
+	t.Run("invalid key type", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		set := NewIntKeyedSet(inter, PrimitiveStaticTypeUInt32)
+
+		require.False(t, set.Contains(inter, UInt8Value(1)))
+		require.False(t, set.Remove(inter, UInt8Value(1)))
+
+		require.PanicsWithValue(t,
+			ContainerMutationError{
+				ExpectedType:  sema.UInt32Type,
+				ActualType:    sema.UInt8Type,
+				LocationRange: ReturnEmptyLocationRange(),
+			},
+			func() {
+				set.Add(inter, UInt8Value(1))
+			},
+		)
+	})
+}
@@ -0,0 +1,533 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/onflow/atree"
+)
+
+// lazyContainerRoot is the storage and the root slab storage ID of an empty array or dictionary,
+// whose root slab is not allocated yet.
+//
+// Many containers are only transiently empty, e.g. they are created and then discarded,
+// so the root slab is only allocated when the first element is added.
+// Count and Iterate do not allocate the root slab.
+//
+// The storage ID of the root slab is reserved when the container is created,
+// so the storage ID of the container is known without allocating the root slab,
+// and the container can be stored using the empty container sentinel (see EmptyContainerStorable).
+//
+// Once the container is stored, other values may load the same container from the sentinel,
+// and allocate the root slab. So the storage must be checked for the root slab
+// before it is allocated, or the container is assumed to be empty.
+//
+type lazyContainerRoot struct {
+	storage   atree.SlabStorage
+	storageID atree.StorageID
+	stored    bool
+}
+
+func newLazyContainerRoot(storage atree.SlabStorage, address atree.Address) *lazyContainerRoot {
+	storageID, err := storage.GenerateStorageID(address)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	return &lazyContainerRoot{
+		storage:   storage,
+		storageID: storageID,
+	}
+}
+
+// allocated returns true if the root slab was allocated,
+// i.e. the container was stored and the root slab was allocated through another value.
+//
+func (r *lazyContainerRoot) allocated() bool {
+	if !r.stored {
+		return false
+	}
+
+	_, ok, err := r.storage.Retrieve(r.storageID)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+	return ok
+}
+
+// reservedStorageIDStorage is the storage of a lazy container root,
+// which generates the reserved storage ID, so the root slab gets allocated with it.
+//
+type reservedStorageIDStorage struct {
+	atree.SlabStorage
+	storageID atree.StorageID
+}
+
+func (s reservedStorageIDStorage) GenerateStorageID(_ atree.Address) (atree.StorageID, error) {
+	return s.storageID, nil
+}
+
+// releaseRootSlab removes the root slab of an empty container,
+// and returns the lazy container root which replaces it.
+//
+// Only containers which are only referred to by the empty container sentinel may be released,
+// as the sentinel does not require the root slab to exist.
+// The root slab is not released if it is not a plain data slab, e.g. it refers to other slabs.
+//
+func releaseRootSlab(storage atree.SlabStorage, storageID atree.StorageID) *lazyContainerRoot {
+	slab, ok, err := storage.Retrieve(storageID)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+	if !ok || len(slab.ChildStorables()) > 0 {
+		return nil
+	}
+
+	err = storage.Remove(storageID)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	return &lazyContainerRoot{
+		storage:   storage,
+		storageID: storageID,
+		stored:    true,
+	}
+}
+
+// atreeArray returns the underlying atree array of the array value,
+// and allocates its root slab if it was not allocated yet.
+//
+func (v *ArrayValue) atreeArray() *atree.Array {
+	lazyRoot := v.lazyRoot
+	if lazyRoot == nil {
+		return v.array
+	}
+
+	if !lazyRoot.allocated() {
+		_, err := atree.NewArray(
+			reservedStorageIDStorage{
+				SlabStorage: lazyRoot.storage,
+				storageID:   lazyRoot.storageID,
+			},
+			lazyRoot.storageID.Address,
+			v.Type,
+		)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+	}
+
+	array, err := atree.NewArrayWithRootID(lazyRoot.storage, lazyRoot.storageID)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	v.array = array
+	v.lazyRoot = nil
+
+	return v.array
+}
+
+// hasRootSlab returns true if the root slab of the array is allocated.
+//
+// If the root slab was allocated through another value of the same stored array,
+// the value starts using it.
+//
+func (v *ArrayValue) hasRootSlab() bool {
+	if v.lazyRoot == nil {
+		return true
+	}
+
+	if !v.lazyRoot.allocated() {
+		return false
+	}
+
+	v.atreeArray()
+	return true
+}
+
+// releaseRootSlab releases the root slab of the array if it became empty,
+// and it is only referred to by the empty container sentinel.
+//
+func (v *ArrayValue) releaseRootSlab() {
+	if !v.sentinel || v.lazyRoot != nil || v.array.Count() > 0 {
+		return
+	}
+
+	lazyRoot := releaseRootSlab(v.array.Storage, v.array.StorageID())
+	if lazyRoot == nil {
+		return
+	}
+
+	v.array = nil
+	v.lazyRoot = lazyRoot
+}
+
+// storable returns the storable referring to the array.
+//
+// If the given storage stores empty container sentinels (see WithEmptyContainerSentinels),
+// an array which was created empty, and whose root slab is still unallocated,
+// is referred to by the empty container sentinel, if it fits into the given maximum inline size,
+// so its root slab is not allocated until the first element is added.
+// Otherwise, the root slab is allocated, and referred to by its storage ID.
+//
+func (v *ArrayValue) storable(storage atree.SlabStorage, maxInlineSize uint64) atree.Storable {
+	if v.sentinel &&
+		!v.hasRootSlab() &&
+		storesEmptyContainerSentinels(storage) {
+
+		storable := EmptyContainerStorable{
+			StorageID: v.StorageID(),
+			Type:      v.Type,
+		}
+		if uint64(storable.ByteSize()) <= maxInlineSize {
+			v.lazyRoot.stored = true
+			return storable
+		}
+	}
+
+	// The root slab is referred to by its storage ID,
+	// so it must not be released when the array becomes empty
+
+	v.sentinel = false
+
+	return atree.StorageIDStorable(v.atreeArray().StorageID())
+}
+
+// atreeMap returns the underlying atree map of the dictionary value,
+// and allocates its root slab if it was not allocated yet.
+//
+func (v *DictionaryValue) atreeMap() *atree.OrderedMap {
+	lazyRoot := v.lazyRoot
+	if lazyRoot == nil {
+		return v.dictionary
+	}
+
+	if !lazyRoot.allocated() {
+		_, err := atree.NewMap(
+			reservedStorageIDStorage{
+				SlabStorage: lazyRoot.storage,
+				storageID:   lazyRoot.storageID,
+			},
+			lazyRoot.storageID.Address,
+			atree.NewDefaultDigesterBuilder(),
+			v.Type,
+		)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+	}
+
+	dictionary, err := atree.NewMapWithRootID(
+		lazyRoot.storage,
+		lazyRoot.storageID,
+		atree.NewDefaultDigesterBuilder(),
+	)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	v.dictionary = dictionary
+	v.lazyRoot = nil
+
+	return v.dictionary
+}
+
+// hasRootSlab returns true if the root slab of the dictionary is allocated,
+// like ArrayValue.hasRootSlab.
+//
+func (v *DictionaryValue) hasRootSlab() bool {
+	if v.lazyRoot == nil {
+		return true
+	}
+
+	if !v.lazyRoot.allocated() {
+		return false
+	}
+
+	v.atreeMap()
+	return true
+}
+
+// releaseRootSlab releases the root slab of the dictionary if it became empty,
+// like ArrayValue.releaseRootSlab.
+//
+func (v *DictionaryValue) releaseRootSlab() {
+	if !v.sentinel || v.lazyRoot != nil || v.dictionary.Count() > 0 {
+		return
+	}
+
+	lazyRoot := releaseRootSlab(v.dictionary.Storage, v.dictionary.StorageID())
+	if lazyRoot == nil {
+		return
+	}
+
+	v.dictionary = nil
+	v.lazyRoot = lazyRoot
+}
+
+// storable returns the storable referring to the dictionary,
+// like ArrayValue.storable.
+//
+func (v *DictionaryValue) storable(storage atree.SlabStorage, maxInlineSize uint64) atree.Storable {
+	if v.sentinel &&
+		!v.hasRootSlab() &&
+		storesEmptyContainerSentinels(storage) {
+
+		storable := EmptyContainerStorable{
+			StorageID: v.StorageID(),
+			Type:      v.Type,
+		}
+		if uint64(storable.ByteSize()) <= maxInlineSize {
+			v.lazyRoot.stored = true
+			return storable
+		}
+	}
+
+	v.sentinel = false
+
+	return atree.StorageIDStorable(v.atreeMap().StorageID())
+}
+
+// EmptyContainerStorable is the storable of an array or dictionary which was created empty:
+// the empty container sentinel.
+//
+// It refers to the root slab of the container, like a storage ID storable,
+// but the root slab is only allocated when the first element is added (see lazyContainerRoot),
+// and it is removed again when the container becomes empty.
+// The storage ID of the root slab is reserved, so the storable stays valid
+// when the root slab is allocated through any value of the container.
+//
+type EmptyContainerStorable struct {
+	StorageID atree.StorageID
+	Type      StaticType
+}
+
+var _ atree.Storable = EmptyContainerStorable{}
+
+func (s EmptyContainerStorable) ByteSize() uint32 {
+	return mustStorableSize(s)
+}
+
+func (s EmptyContainerStorable) StoredValue(storage atree.SlabStorage) (atree.Value, error) {
+	lazyRoot := &lazyContainerRoot{
+		storage:   storage,
+		storageID: s.StorageID,
+		stored:    true,
+	}
+
+	switch staticType := s.Type.(type) {
+	case ArrayStaticType:
+		return &ArrayValue{
+			Type:     staticType,
+			lazyRoot: lazyRoot,
+			sentinel: true,
+		}, nil
+
+	case DictionaryStaticType:
+		return &DictionaryValue{
+			Type:     staticType,
+			lazyRoot: lazyRoot,
+			sentinel: true,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid empty container type: %s", s.Type)
+	}
+}
+
+// ChildStorables returns the storage ID storable of the root slab of the container,
+// which is allocated once the first element is added through a value loaded from the sentinel.
+//
+// NOTE: The root slab might not be allocated, see isUnallocatedEmptyContainer.
+//
+func (s EmptyContainerStorable) ChildStorables() []atree.Storable {
+	return []atree.Storable{
+		atree.StorageIDStorable(s.StorageID),
+	}
+}
+
+// referencedStorageID returns the storage ID of the slab the given storable refers to,
+// if it is a storage ID storable or an empty container sentinel.
+//
+// The slab referred to by an empty container sentinel might not be allocated.
+//
+func referencedStorageID(storable atree.Storable) (atree.StorageID, bool) {
+	switch storable := storable.(type) {
+	case atree.StorageIDStorable:
+		return atree.StorageID(storable), true
+
+	case EmptyContainerStorable:
+		return storable.StorageID, true
+
+	default:
+		return atree.StorageID{}, false
+	}
+}
+
+// isUnallocatedEmptyContainer returns true if the given storable is an empty container sentinel,
+// and the root slab of the container is not allocated.
+//
+func isUnallocatedEmptyContainer(storable atree.Storable, storage atree.SlabStorage) (bool, error) {
+	emptyContainerStorable, ok := storable.(EmptyContainerStorable)
+	if !ok {
+		return false, nil
+	}
+
+	_, ok, err := storage.Retrieve(emptyContainerStorable.StorageID)
+	if err != nil {
+		return false, err
+	}
+	return !ok, nil
+}
+
+// emptyContainerRootsStorage is a view of a slab storage for health checks.
+// It provides empty placeholder root slabs for the unallocated root slabs of empty container sentinels,
+// so the sentinels get checked like references to allocated root slabs.
+//
+type emptyContainerRootsStorage struct {
+	atree.SlabStorage
+	roots map[atree.StorageID]atree.Slab
+}
+
+var _ atree.SlabStorage = emptyContainerRootsStorage{}
+
+// withEmptyContainerRoots returns the given storage,
+// or a view of it which includes placeholder root slabs
+// for the empty container sentinels stored in slabs whose root slabs are not allocated.
+//
+func withEmptyContainerRoots(storage atree.SlabStorage) (atree.SlabStorage, error) {
+	roots := map[atree.StorageID]atree.Slab{}
+
+	slabIterator, err := storage.SlabIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		id, slab := slabIterator()
+		if id == atree.StorageIDUndefined {
+			break
+		}
+
+		childStorables := slab.ChildStorables()
+
+		for len(childStorables) > 0 {
+			var next []atree.Storable
+
+			for _, childStorable := range childStorables {
+				switch childStorable := childStorable.(type) {
+				case atree.StorageIDStorable:
+					continue
+
+				case EmptyContainerStorable:
+					unallocated, err := isUnallocatedEmptyContainer(childStorable, storage)
+					if err != nil {
+						return nil, err
+					}
+					if !unallocated {
+						continue
+					}
+
+					root, err := newEmptyContainerRootSlab(childStorable)
+					if err != nil {
+						return nil, err
+					}
+					roots[childStorable.StorageID] = root
+
+				default:
+					next = append(next, childStorable.ChildStorables()...)
+				}
+			}
+
+			childStorables = next
+		}
+	}
+
+	if len(roots) == 0 {
+		return storage, nil
+	}
+
+	return emptyContainerRootsStorage{
+		SlabStorage: storage,
+		roots:       roots,
+	}, nil
+}
+
+// newEmptyContainerRootSlab returns an empty root slab with the storage ID of the given sentinel.
+//
+// The slab is allocated in a separate storage, so the given sentinel's storage is not modified.
+//
+func newEmptyContainerRootSlab(storable EmptyContainerStorable) (atree.Slab, error) {
+	storage := NewInMemoryStorage()
+
+	_, err := atree.NewArray(
+		reservedStorageIDStorage{
+			SlabStorage: storage,
+			storageID:   storable.StorageID,
+		},
+		storable.StorageID.Address,
+		storable.Type,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	slab, _, err := storage.Retrieve(storable.StorageID)
+	if err != nil {
+		return nil, err
+	}
+	return slab, nil
+}
+
+func (s emptyContainerRootsStorage) Retrieve(id atree.StorageID) (atree.Slab, bool, error) {
+	if slab, ok := s.roots[id]; ok {
+		return slab, true, nil
+	}
+	return s.SlabStorage.Retrieve(id)
+}
+
+func (s emptyContainerRootsStorage) Count() int {
+	return s.SlabStorage.Count() + len(s.roots)
+}
+
+func (s emptyContainerRootsStorage) SlabIterator() (atree.SlabIterator, error) {
+	slabIterator, err := s.SlabStorage.SlabIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	roots := make([]atree.Slab, 0, len(s.roots))
+	for _, root := range s.roots { //nolint:maprangecheck
+		roots = append(roots, root)
+	}
+
+	return func() (atree.StorageID, atree.Slab) {
+		id, slab := slabIterator()
+		if id != atree.StorageIDUndefined || len(roots) == 0 {
+			return id, slab
+		}
+
+		slab = roots[0]
+		roots = roots[1:]
+		return slab.ID(), slab
+	}, nil
+}
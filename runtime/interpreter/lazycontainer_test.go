@@ -0,0 +1,315 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func newTestInterpreterWithEmptyContainerSentinels(t *testing.T) *Interpreter {
+
+	storage := NewInMemoryStorage(WithEmptyContainerSentinels(true))
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+		WithAtreeValueValidationEnabled(true),
+		WithAtreeStorageValidationEnabled(true),
+	)
+	require.NoError(t, err)
+
+	return inter
+}
+
+func TestLazyContainerRoot(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	t.Run("array", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+		storage := inter.Storage.(InMemoryStorage)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			address,
+		)
+
+		require.Equal(t, 0, storage.Count())
+
+		require.Equal(t, 0, array.Count())
+		array.Iterate(func(_ Value) (resume bool) {
+			require.Fail(t, "unexpected element")
+			return true
+		})
+		require.Equal(t, address, array.GetOwner())
+
+		require.Equal(t, 0, storage.Count())
+
+		array.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(1))
+		array.Insert(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.Equal(t, 1, storage.Count())
+		require.Equal(t, 2, array.Count())
+		require.Equal(t, address, array.GetOwner())
+
+		utils.RequireValuesEqual(t,
+			inter,
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeInt,
+				},
+				address,
+				NewIntValueFromInt64(2),
+				NewIntValueFromInt64(1),
+			),
+			array,
+		)
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+		storage := inter.Storage.(InMemoryStorage)
+
+		dictionaryType := DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: PrimitiveStaticTypeInt,
+		}
+
+		dictionary := NewDictionaryValueWithAddress(inter, dictionaryType, address)
+
+		require.Equal(t, 0, storage.Count())
+
+		require.Equal(t, 0, dictionary.Count())
+		dictionary.Iterate(func(_, _ Value) (resume bool) {
+			require.Fail(t, "unexpected entry")
+			return true
+		})
+		require.False(t, bool(dictionary.ContainsKey(inter, ReturnEmptyLocationRange, NewStringValue("a"))))
+		_, ok := dictionary.Get(inter, ReturnEmptyLocationRange, NewStringValue("a"))
+		require.False(t, ok)
+		require.Equal(t, address, dictionary.GetOwner())
+
+		require.Equal(t, 0, storage.Count())
+
+		dictionary.Insert(inter, ReturnEmptyLocationRange, NewStringValue("a"), NewIntValueFromInt64(1))
+
+		require.Equal(t, 1, storage.Count())
+		require.Equal(t, 1, dictionary.Count())
+		require.Equal(t, address, dictionary.GetOwner())
+
+		value, ok := dictionary.Get(inter, ReturnEmptyLocationRange, NewStringValue("a"))
+		require.True(t, ok)
+		utils.RequireValuesEqual(t, inter, NewIntValueFromInt64(1), value)
+	})
+
+	t.Run("stored", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreterWithEmptyContainerSentinels(t)
+		storage := inter.Storage.(InMemoryStorage)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+		)
+
+		// Neither transferring nor storing an empty container allocates its root slab,
+		// it is stored using the empty container sentinel
+
+		transferred := array.Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(address),
+			true,
+			nil,
+		).(*ArrayValue)
+
+		require.Equal(t, 0, storage.Count())
+
+		storage.WriteValue(
+			inter,
+			address,
+			"value",
+			NewSomeValueNonCopying(transferred),
+		)
+
+		require.Equal(t, 0, storage.Count())
+
+		value := storage.ReadValue(inter, address, "value")
+		require.IsType(t, &SomeValue{}, value)
+
+		storedArray := value.(*SomeValue).Value.(*ArrayValue)
+		require.Equal(t, 0, storedArray.Count())
+		require.Equal(t, address, storedArray.GetOwner())
+		require.Equal(t, transferred.StorageID(), storedArray.StorageID())
+
+		require.Equal(t, 0, storage.Count())
+
+		// The first insertion allocates the root slab with the reserved storage ID,
+		// so the stored sentinel refers to it
+
+		storedArray.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(1))
+
+		require.Equal(t, 1, storage.Count())
+		require.Equal(t, 1, transferred.Count())
+
+		value = storage.ReadValue(inter, address, "value")
+		require.Equal(t, 1, value.(*SomeValue).Value.(*ArrayValue).Count())
+
+		// Removing the last element releases the root slab again
+
+		storedArray.Remove(inter, ReturnEmptyLocationRange, 0)
+
+		require.Equal(t, 0, storage.Count())
+		require.Equal(t, 0, transferred.Count())
+		require.Equal(t, transferred.StorageID(), storedArray.StorageID())
+
+		require.Empty(t, storage.VerifyIntegrity(inter))
+	})
+
+	t.Run("stored after insertions", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreterWithEmptyContainerSentinels(t)
+		storage := inter.Storage.(InMemoryStorage)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			address,
+		)
+
+		for i := 0; i < 100; i++ {
+			array.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(int64(i)))
+		}
+
+		// The array is no longer empty, so it is stored using its storage ID,
+		// not the empty container sentinel
+
+		storable, err := array.Storable(storage, atree.Address(address), 1024)
+		require.NoError(t, err)
+		require.Equal(t, atree.StorageIDStorable(array.StorageID()), storable)
+
+		storage.WriteValue(inter, address, "value", NewSomeValueNonCopying(array))
+
+		require.NoError(t, storage.CheckHealth())
+
+		// Removing all elements does not turn the stored reference into a sentinel
+
+		for i := 0; i < 100; i++ {
+			array.RemoveLast(inter, ReturnEmptyLocationRange)
+		}
+
+		storable, err = array.Storable(storage, atree.Address(address), 1024)
+		require.NoError(t, err)
+		require.Equal(t, atree.StorageIDStorable(array.StorageID()), storable)
+
+		require.NoError(t, storage.CheckHealth())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+		storage := inter.Storage.(InMemoryStorage)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			address,
+		)
+
+		// Without empty container sentinels, storing allocates the root slab
+
+		storable, err := array.Storable(storage, atree.Address(address), 1024)
+		require.NoError(t, err)
+		require.Equal(t, atree.StorageIDStorable(array.StorageID()), storable)
+
+		require.Equal(t, 1, storage.Count())
+		require.NoError(t, storage.CheckHealth())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreterWithEmptyContainerSentinels(t)
+		storage := inter.Storage.(InMemoryStorage)
+
+		arrayType := VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		}
+
+		outer := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: arrayType,
+			},
+			address,
+			NewArrayValue(inter, arrayType, common.Address{}),
+		)
+
+		// Only the root slab of the outer array is allocated
+
+		require.Equal(t, 1, storage.Count())
+		require.Empty(t, storage.VerifyIntegrity(inter))
+		require.NoError(t, storage.CheckHealth())
+
+		inner := outer.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+		inner.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(1))
+
+		require.Equal(t, 2, storage.Count())
+		require.Equal(t, 1, outer.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue).Count())
+
+		outer.DeepRemove(inter)
+		inter.RemoveReferencedSlab(atree.StorageIDStorable(outer.StorageID()))
+
+		require.Equal(t, 0, storage.Count())
+	})
+}
@@ -0,0 +1,498 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// Containers are marshaled as CBOR arrays, which are never used
+// for the encoding of other storables:
+//
+//  - Arrays as [marshaledArray, static type, [elements...]]
+//  - Dictionaries as [marshaledDictionary, static type, [key, value, ...]],
+//    with the entries sorted by the encoding of their keys
+//  - Composites as [marshaledComposite, type info, [field name, field value, ...]],
+//    with the fields sorted by name
+//  - Optionals as [marshaledSome, inner value]
+//
+// All other values are marshaled like when they are stored.
+//
+const (
+	marshaledArray uint64 = iota
+	marshaledDictionary
+	marshaledComposite
+	marshaledSome
+)
+
+// MarshalValue encodes the given value, including all nested values,
+// into a single self-contained byte blob, independent of any storage.
+//
+// In contrast to storage encoding, nested containers are inlined and no storage IDs are encoded.
+// The encoding is deterministic: equal values have equal encodings.
+//
+func MarshalValue(interpreter *Interpreter, value Value) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := atree.NewEncoder(&buffer, CBOREncMode)
+
+	err := marshalValueTo(interpreter, encoder, value)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.CBOR.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func marshalValueTo(interpreter *Interpreter, encoder *atree.Encoder, value Value) error {
+	switch value := value.(type) {
+	case *ArrayValue:
+		err := encoder.CBOR.EncodeArrayHead(3)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeUint64(marshaledArray)
+		if err != nil {
+			return err
+		}
+
+		err = EncodeStaticType(encoder.CBOR, value.Type)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeArrayHead(uint64(value.Count()))
+		if err != nil {
+			return err
+		}
+
+		value.Iterate(func(element Value) (resume bool) {
+			err = marshalValueTo(interpreter, encoder, element)
+			return err == nil
+		})
+		return err
+
+	case *DictionaryValue:
+		type entry struct {
+			key   []byte
+			value Value
+		}
+
+		entries := make([]entry, 0, value.Count())
+
+		var err error
+		value.Iterate(func(key, value Value) (resume bool) {
+			var encodedKey []byte
+			encodedKey, err = MarshalValue(interpreter, key)
+			if err != nil {
+				return false
+			}
+
+			entries = append(entries, entry{
+				key:   encodedKey,
+				value: value,
+			})
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		sort.Slice(entries, func(i, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+
+		err = encoder.CBOR.EncodeArrayHead(3)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeUint64(marshaledDictionary)
+		if err != nil {
+			return err
+		}
+
+		err = EncodeStaticType(encoder.CBOR, value.Type)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeArrayHead(uint64(len(entries) * 2))
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			err = encoder.CBOR.EncodeRawBytes(entry.key)
+			if err != nil {
+				return err
+			}
+
+			err = marshalValueTo(interpreter, encoder, entry.value)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case *CompositeValue:
+		fields := map[string]Value{}
+		value.ForEachField(func(name string, value Value) {
+			fields[name] = value
+		})
+
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		err := encoder.CBOR.EncodeArrayHead(3)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeUint64(marshaledComposite)
+		if err != nil {
+			return err
+		}
+
+		typeInfo := compositeTypeInfo{
+			location:            value.Location,
+			qualifiedIdentifier: value.QualifiedIdentifier,
+			kind:                value.Kind,
+		}
+
+		err = typeInfo.Encode(encoder.CBOR)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeArrayHead(uint64(len(names) * 2))
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			err = encoder.CBOR.EncodeString(name)
+			if err != nil {
+				return err
+			}
+
+			err = marshalValueTo(interpreter, encoder, fields[name])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case *SomeValue:
+		err := encoder.CBOR.EncodeArrayHead(2)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.CBOR.EncodeUint64(marshaledSome)
+		if err != nil {
+			return err
+		}
+
+		return marshalValueTo(interpreter, encoder, value.Value)
+
+	default:
+		storable, err := value.Storable(interpreter.Storage, atree.Address{}, math.MaxUint64)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := storable.(NonStorable); ok {
+			return fmt.Errorf("cannot marshal value of type %T", value)
+		}
+
+		return storable.Encode(encoder)
+	}
+}
+
+// UnmarshalValue decodes a value which was encoded using MarshalValue.
+//
+// The decoded value is not owned by any account,
+// and is stored in a new in-memory storage.
+// Use UnmarshalValueWithInterpreter to decode the value
+// into the storage of an existing interpreter.
+//
+func UnmarshalValue(data []byte) (Value, error) {
+	inter, err := NewInterpreter(
+		nil,
+		nil,
+		WithStorage(NewInMemoryStorage()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalValueWithInterpreter(inter, data)
+}
+
+// UnmarshalValueWithInterpreter decodes a value which was encoded using MarshalValue
+// into the storage of the given interpreter.
+//
+// The decoded value is not owned by any account.
+//
+func UnmarshalValueWithInterpreter(interpreter *Interpreter, data []byte) (Value, error) {
+	decoder := CBORDecMode.NewByteStreamDecoder(data)
+
+	return unmarshalValueFrom(interpreter, decoder)
+}
+
+// DumpValue writes the given value to the given writer, using the format of MarshalValue,
+// e.g. to keep a value which caused a test failure as a fixture.
+//
+func DumpValue(interpreter *Interpreter, value Value, w io.Writer) error {
+	data, err := MarshalValue(interpreter, value)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// LoadDumpedValue reads a value which was written using DumpValue.
+//
+func LoadDumpedValue(r io.Reader) (Value, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return UnmarshalValue(data)
+}
+
+func unmarshalValueFrom(interpreter *Interpreter, decoder *cbor.StreamDecoder) (Value, error) {
+
+	nextType, err := decoder.NextType()
+	if err != nil {
+		return nil, err
+	}
+
+	if nextType != cbor.ArrayType {
+		storable, err := DecodeStorable(decoder, atree.StorageIDUndefined)
+		if err != nil {
+			return nil, err
+		}
+
+		return StoredValue(storable, interpreter.Storage), nil
+	}
+
+	size, err := decoder.DecodeArrayHead()
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := decoder.DecodeUint64()
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == marshaledSome {
+		if size != 2 {
+			return nil, fmt.Errorf("invalid marshaled optional: expected 2 elements, got %d", size)
+		}
+
+		value, err := unmarshalValueFrom(interpreter, decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSomeValueNonCopying(value), nil
+	}
+
+	if size != 3 {
+		return nil, fmt.Errorf("invalid marshaled container: expected 3 elements, got %d", size)
+	}
+
+	switch kind {
+	case marshaledArray:
+		staticType, err := decodeStaticType(decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		arrayType, ok := staticType.(ArrayStaticType)
+		if !ok {
+			return nil, fmt.Errorf("invalid marshaled array type: %s", staticType)
+		}
+
+		elements, err := unmarshalValuesFrom(interpreter, decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewArrayValue(interpreter, arrayType, common.Address{}, elements...), nil
+
+	case marshaledDictionary:
+		staticType, err := decodeStaticType(decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		dictionaryType, ok := staticType.(DictionaryStaticType)
+		if !ok {
+			return nil, fmt.Errorf("invalid marshaled dictionary type: %s", staticType)
+		}
+
+		keysAndValues, err := unmarshalValuesFrom(interpreter, decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(keysAndValues)%2 != 0 {
+			return nil, fmt.Errorf("invalid marshaled dictionary: odd number of keys and values")
+		}
+
+		return newUnmarshaledDictionaryValue(interpreter, dictionaryType, keysAndValues), nil
+
+	case marshaledComposite:
+		typeInfo, err := DecodeTypeInfo(decoder)
+		if err != nil {
+			return nil, err
+		}
+
+		compositeTypeInfo, ok := typeInfo.(compositeTypeInfo)
+		if !ok {
+			return nil, fmt.Errorf("invalid marshaled composite type info: %T", typeInfo)
+		}
+
+		fieldCount, err := decoder.DecodeArrayHead()
+		if err != nil {
+			return nil, err
+		}
+
+		if fieldCount%2 != 0 {
+			return nil, fmt.Errorf("invalid marshaled composite: odd number of field names and values")
+		}
+
+		fields := make([]CompositeField, fieldCount/2)
+		for i := range fields {
+			name, err := decoder.DecodeString()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := unmarshalValueFrom(interpreter, decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			fields[i] = CompositeField{
+				Name:  name,
+				Value: value,
+			}
+		}
+
+		return NewCompositeValue(
+			interpreter,
+			compositeTypeInfo.location,
+			compositeTypeInfo.qualifiedIdentifier,
+			compositeTypeInfo.kind,
+			fields,
+			common.Address{},
+		), nil
+
+	default:
+		return nil, fmt.Errorf("invalid marshaled container kind: %d", kind)
+	}
+}
+
+func unmarshalValuesFrom(interpreter *Interpreter, decoder *cbor.StreamDecoder) ([]Value, error) {
+	count, err := decoder.DecodeArrayHead()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]Value, count)
+	for i := range values {
+		values[i], err = unmarshalValueFrom(interpreter, decoder)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+// newUnmarshaledDictionaryValue returns a new unowned dictionary with the given keys and values.
+//
+// In contrast to NewDictionaryValue, the keys and values are not type-checked,
+// as they were marshaled from a valid dictionary, and the types of composites
+// are not available to the interpreter performing the unmarshaling.
+//
+func newUnmarshaledDictionaryValue(
+	interpreter *Interpreter,
+	dictionaryType DictionaryStaticType,
+	keysAndValues []Value,
+) *DictionaryValue {
+
+	dictionary, err := atree.NewMap(
+		interpreter.Storage,
+		atree.Address{},
+		atree.NewDefaultDigesterBuilder(),
+		dictionaryType,
+	)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	valueComparator := newValueComparator(interpreter, ReturnEmptyLocationRange)
+	hashInputProvider := newHashInputProvider(interpreter, ReturnEmptyLocationRange)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		_, err := dictionary.Set(
+			valueComparator,
+			hashInputProvider,
+			keysAndValues[i],
+			keysAndValues[i+1],
+		)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+	}
+
+	return &DictionaryValue{
+		Type:       dictionaryType,
+		dictionary: dictionary,
+	}
+}
@@ -0,0 +1,79 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+)
+
+// Materialize loads all slabs of the given value, including the slabs of all nested values,
+// so that subsequent accesses of the value do not read from storage anymore.
+//
+// Materializing an already materialized value does not read from storage.
+//
+func Materialize(interpreter *Interpreter, value Value) error {
+	switch value := value.(type) {
+	case *SomeValue:
+		return Materialize(interpreter, value.Value)
+
+	case *ArrayValue:
+		if !value.hasRootSlab() {
+			// The root slab was not allocated yet, so there is nothing to load
+			return nil
+		}
+		return value.array.Iterate(func(element atree.Value) (bool, error) {
+			return materializeStoredValue(interpreter, element)
+		})
+
+	case *DictionaryValue:
+		if !value.hasRootSlab() {
+			// The root slab was not allocated yet, so there is nothing to load
+			return nil
+		}
+		return value.dictionary.Iterate(func(key, value atree.Value) (bool, error) {
+			resume, err := materializeStoredValue(interpreter, key)
+			if err != nil || !resume {
+				return resume, err
+			}
+			return materializeStoredValue(interpreter, value)
+		})
+
+	case *CompositeValue:
+		return value.dictionary.Iterate(func(_, value atree.Value) (bool, error) {
+			return materializeStoredValue(interpreter, value)
+		})
+
+	default:
+		return nil
+	}
+}
+
+func materializeStoredValue(interpreter *Interpreter, storedValue atree.Value) (bool, error) {
+	value, err := ConvertStoredValue(storedValue)
+	if err != nil {
+		return false, err
+	}
+
+	err = Materialize(interpreter, value)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
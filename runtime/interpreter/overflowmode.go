@@ -0,0 +1,112 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// OverflowMode determines the result of an integer operation
+// if the result is not in the range of the integer type.
+//
+// NOTE: the modes are prefixed, as OverflowError is already the name of the overflow error type.
+//
+type OverflowMode uint8
+
+const (
+	// OverflowModeError returns an OverflowError or UnderflowError
+	OverflowModeError OverflowMode = iota
+	// OverflowModeWrap wraps the result around the range of the type, like the Word types
+	OverflowModeWrap
+	// OverflowModeSaturate clamps the result to the minimum or maximum of the type
+	OverflowModeSaturate
+)
+
+// AddNumberValuesWithMode adds a and b, handling results which are not in the range
+// of their type according to the given overflow mode.
+//
+// Both values must be integers of the same type. The given mode is applied to all integer types,
+// i.e. also to the Word types, which always wrap when added using NumberValue.Plus.
+//
+func AddNumberValuesWithMode(interpreter *Interpreter, a, b NumberValue, mode OverflowMode) (NumberValue, error) {
+
+	staticType, ok := a.StaticType().(PrimitiveStaticType)
+	if !ok || b.StaticType() != staticType {
+		return nil, fmt.Errorf(
+			"cannot add values of types `%s` and `%s`",
+			a.StaticType(),
+			b.StaticType(),
+		)
+	}
+
+	left, ok := integerValueToBigInt(a)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type `%s`", staticType)
+	}
+
+	right, ok := integerValueToBigInt(b)
+	if !ok {
+		return nil, fmt.Errorf("unsupported type `%s`", staticType)
+	}
+
+	sum := new(big.Int).Add(left, right)
+
+	min, max := integerTypeBounds(staticType)
+
+	overflow := max != nil && sum.Cmp(max) > 0
+	underflow := min != nil && sum.Cmp(min) < 0
+
+	if overflow || underflow {
+		switch mode {
+		case OverflowModeError:
+			if overflow {
+				return nil, OverflowError{}
+			}
+			return nil, UnderflowError{}
+
+		case OverflowModeWrap:
+			// Both bounds exist, the unbounded types Int and UInt cannot overflow resp. underflow
+
+			size := new(big.Int).Sub(max, min)
+			size.Add(size, big.NewInt(1))
+
+			sum.Sub(sum, min)
+			sum.Mod(sum, size)
+			sum.Add(sum, min)
+
+		case OverflowModeSaturate:
+			if overflow {
+				sum.Set(max)
+			} else {
+				sum.Set(min)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported overflow mode: %d", mode)
+		}
+	}
+
+	result, err := integerValueFromBigInt(sum, staticType)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(NumberValue), nil
+}
@@ -0,0 +1,124 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+func TestAddNumberValuesWithMode(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	maxUInt256 := NewUInt256ValueFromBigInt(sema.UInt256TypeMaxIntBig)
+
+	t.Run("UInt8", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := AddNumberValuesWithMode(inter, UInt8Value(255), UInt8Value(1), OverflowModeError)
+		require.Equal(t, OverflowError{}, err)
+
+		result, err := AddNumberValuesWithMode(inter, UInt8Value(255), UInt8Value(2), OverflowModeWrap)
+		require.NoError(t, err)
+		require.Equal(t, UInt8Value(1), result)
+
+		result, err = AddNumberValuesWithMode(inter, UInt8Value(255), UInt8Value(2), OverflowModeSaturate)
+		require.NoError(t, err)
+		require.Equal(t, UInt8Value(255), result)
+
+		// In range
+
+		for _, mode := range []OverflowMode{OverflowModeError, OverflowModeWrap, OverflowModeSaturate} {
+			result, err = AddNumberValuesWithMode(inter, UInt8Value(254), UInt8Value(1), mode)
+			require.NoError(t, err)
+			require.Equal(t, UInt8Value(255), result)
+		}
+	})
+
+	t.Run("UInt256", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := AddNumberValuesWithMode(inter, maxUInt256, NewUInt256ValueFromUint64(1), OverflowModeError)
+		require.Equal(t, OverflowError{}, err)
+
+		result, err := AddNumberValuesWithMode(inter, maxUInt256, NewUInt256ValueFromUint64(2), OverflowModeWrap)
+		require.NoError(t, err)
+		require.Equal(t, NewUInt256ValueFromUint64(1), result)
+
+		result, err = AddNumberValuesWithMode(inter, maxUInt256, NewUInt256ValueFromUint64(2), OverflowModeSaturate)
+		require.NoError(t, err)
+		require.Equal(t, maxUInt256, result)
+	})
+
+	t.Run("Int8 underflow", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := AddNumberValuesWithMode(inter, Int8Value(-128), Int8Value(-1), OverflowModeError)
+		require.Equal(t, UnderflowError{}, err)
+
+		result, err := AddNumberValuesWithMode(inter, Int8Value(-128), Int8Value(-1), OverflowModeWrap)
+		require.NoError(t, err)
+		require.Equal(t, Int8Value(127), result)
+
+		result, err = AddNumberValuesWithMode(inter, Int8Value(-128), Int8Value(-1), OverflowModeSaturate)
+		require.NoError(t, err)
+		require.Equal(t, Int8Value(-128), result)
+	})
+
+	t.Run("Int", func(t *testing.T) {
+
+		t.Parallel()
+
+		large := NewIntValueFromBigInt(new(big.Int).Lsh(big.NewInt(1), 300))
+
+		result, err := AddNumberValuesWithMode(inter, large, large, OverflowModeError)
+		require.NoError(t, err)
+		require.Equal(t,
+			NewIntValueFromBigInt(new(big.Int).Lsh(big.NewInt(1), 301)),
+			result,
+		)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := AddNumberValuesWithMode(inter, UInt8Value(1), UInt16Value(1), OverflowModeError)
+		require.Error(t, err)
+	})
+
+	t.Run("fixed-point", func(t *testing.T) {
+
+		t.Parallel()
+
+		_, err := AddNumberValuesWithMode(inter, UFix64Value(1), UFix64Value(1), OverflowModeError)
+		require.Error(t, err)
+	})
+}
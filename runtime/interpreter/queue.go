@@ -0,0 +1,177 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// queueCompactionThreshold is the minimum number of dequeued slots
+// at the front of a queue's array before they are removed.
+//
+const queueCompactionThreshold = 64
+
+// QueueValue is a first-in-first-out queue backed by an array.
+//
+// Elements are stored in an array with the optional of the element type as its element type.
+// Dequeuing an element replaces it with nil and advances the head index,
+// instead of removing the element from the front of the array, which would shift all elements.
+// Once at least half of the array consists of dequeued slots, they are removed in one batch.
+//
+// The queue is stored like any other array, see Array.
+// When the queue is loaded again using NewQueueValueFromArray,
+// the head index is restored from the number of dequeued slots.
+//
+type QueueValue struct {
+	array *ArrayValue
+	head  int
+}
+
+// NewQueueValue returns a new empty queue for elements of the given type, owned by the given account.
+//
+func NewQueueValue(interpreter *Interpreter, elementType StaticType, address common.Address) *QueueValue {
+	return &QueueValue{
+		array: NewArrayValue(
+			interpreter,
+			VariableSizedStaticType{
+				Type: OptionalStaticType{
+					Type: elementType,
+				},
+			},
+			address,
+		),
+	}
+}
+
+// NewQueueValueFromArray returns the queue backed by the given array,
+// which must have been obtained from QueueValue.Array.
+//
+func NewQueueValueFromArray(interpreter *Interpreter, array *ArrayValue) (*QueueValue, error) {
+	arrayType, ok := array.Type.(VariableSizedStaticType)
+	if !ok {
+		return nil, fmt.Errorf("invalid queue array type: `%s`", array.Type)
+	}
+
+	if _, ok := arrayType.Type.(OptionalStaticType); !ok {
+		return nil, fmt.Errorf("invalid queue array type: `%s`", array.Type)
+	}
+
+	head := 0
+	array.Iterate(func(element Value) (resume bool) {
+		if _, ok := element.(NilValue); !ok {
+			return false
+		}
+		head++
+		return true
+	})
+
+	return &QueueValue{
+		array: array,
+		head:  head,
+	}, nil
+}
+
+// Array returns the array backing the queue, e.g. to store the queue.
+//
+func (q *QueueValue) Array() *ArrayValue {
+	return q.array
+}
+
+// Count returns the number of elements in the queue.
+//
+func (q *QueueValue) Count() int {
+	return q.array.Count() - q.head
+}
+
+// Enqueue adds the given element to the back of the queue.
+//
+func (q *QueueValue) Enqueue(interpreter *Interpreter, getLocationRange func() LocationRange, element Value) {
+	q.array.Append(interpreter, getLocationRange, NewSomeValueNonCopying(element))
+}
+
+// Peek returns the element at the front of the queue, without removing it,
+// or nil if the queue is empty.
+//
+func (q *QueueValue) Peek(interpreter *Interpreter, getLocationRange func() LocationRange) OptionalValue {
+	if q.Count() == 0 {
+		return Nil()
+	}
+
+	return q.array.Get(interpreter, getLocationRange, q.head).(*SomeValue)
+}
+
+// Dequeue removes the element at the front of the queue and returns it, transferred out of the queue's account,
+// or returns nil if the queue is empty.
+//
+func (q *QueueValue) Dequeue(interpreter *Interpreter, getLocationRange func() LocationRange) OptionalValue {
+	if q.Count() == 0 {
+		return Nil()
+	}
+
+	array := q.array
+
+	array.checkMutable(getLocationRange)
+	array.unshare(interpreter)
+
+	storable, err := array.atreeArray().Set(uint64(q.head), Nil())
+	if err != nil {
+		panic(ExternalError{err})
+	}
+	interpreter.maybeValidateAtreeValue(array.array)
+	interpreter.invalidateEqualityCache(array.StorageID())
+
+	q.head++
+
+	value := StoredValue(storable, interpreter.Storage).Transfer(
+		interpreter,
+		getLocationRange,
+		atree.Address{},
+		true,
+		storable,
+	)
+
+	q.compact(interpreter)
+
+	return value.(*SomeValue)
+}
+
+// compact removes the dequeued slots at the front of the array,
+// once there are enough of them, and they make up at least half of the array.
+//
+func (q *QueueValue) compact(interpreter *Interpreter) {
+	if q.head < queueCompactionThreshold || q.head*2 < q.array.Count() {
+		return
+	}
+
+	// The dequeued slots are nil, so no slabs are referenced by them
+
+	for ; q.head > 0; q.head-- {
+		_, err := q.array.atreeArray().Remove(0)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+	}
+
+	interpreter.maybeValidateAtreeValue(q.array.array)
+	interpreter.invalidateEqualityCache(q.array.StorageID())
+}
@@ -0,0 +1,163 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestQueueValue(t *testing.T) {
+
+	t.Parallel()
+
+	newInterpreter := func(t *testing.T) (*Interpreter, InMemoryStorage) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		return inter, storage
+	}
+
+	address := common.Address{0x1}
+
+	t.Run("FIFO", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := newInterpreter(t)
+
+		queue := NewQueueValue(inter, PrimitiveStaticTypeUInt64, address)
+
+		require.Equal(t, Nil(), queue.Peek(inter, ReturnEmptyLocationRange))
+		require.Equal(t, Nil(), queue.Dequeue(inter, ReturnEmptyLocationRange))
+
+		const queueSize = 100
+		const operationCount = 10_000
+
+		for i := 0; i < queueSize; i++ {
+			queue.Enqueue(inter, ReturnEmptyLocationRange, UInt64Value(i))
+		}
+
+		slabCount := storage.Count()
+		maxSlabCount := slabCount
+
+		// Enqueue and dequeue many elements, keeping the size of the queue constant
+
+		next := uint64(0)
+
+		for i := queueSize; i < operationCount; i++ {
+			queue.Enqueue(inter, ReturnEmptyLocationRange, UInt64Value(i))
+
+			utils.RequireValuesEqual(
+				t,
+				inter,
+				NewSomeValueNonCopying(UInt64Value(next)),
+				queue.Peek(inter, ReturnEmptyLocationRange),
+			)
+			utils.RequireValuesEqual(
+				t,
+				inter,
+				NewSomeValueNonCopying(UInt64Value(next)),
+				queue.Dequeue(inter, ReturnEmptyLocationRange),
+			)
+			next++
+
+			require.Equal(t, queueSize, queue.Count())
+
+			if count := storage.Count(); count > maxSlabCount {
+				maxSlabCount = count
+			}
+		}
+
+		// The dequeued slots are removed, so the queue does not grow
+
+		require.LessOrEqual(t, maxSlabCount, slabCount+2)
+
+		for queue.Count() > 0 {
+			utils.RequireValuesEqual(
+				t,
+				inter,
+				NewSomeValueNonCopying(UInt64Value(next)),
+				queue.Dequeue(inter, ReturnEmptyLocationRange),
+			)
+			next++
+		}
+
+		require.Equal(t, uint64(operationCount), next)
+
+		require.NoError(t, storage.CheckHealth())
+	})
+
+	t.Run("array", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newInterpreter(t)
+
+		queue := NewQueueValue(inter, PrimitiveStaticTypeUInt64, address)
+
+		for i := 0; i < 10; i++ {
+			queue.Enqueue(inter, ReturnEmptyLocationRange, UInt64Value(i))
+		}
+
+		for i := 0; i < 3; i++ {
+			queue.Dequeue(inter, ReturnEmptyLocationRange)
+		}
+
+		restoredQueue, err := NewQueueValueFromArray(inter, queue.Array())
+		require.NoError(t, err)
+
+		require.Equal(t, 7, restoredQueue.Count())
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			NewSomeValueNonCopying(UInt64Value(3)),
+			restoredQueue.Dequeue(inter, ReturnEmptyLocationRange),
+		)
+	})
+
+	t.Run("invalid array", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeUInt64,
+			},
+			address,
+		)
+
+		_, err := NewQueueValueFromArray(inter, array)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,174 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+)
+
+// RawStorables calls the given function for each element of the array, in order,
+// with the storable of the element, without decoding it into a value.
+//
+// For example, a nested container is provided as the storage ID storable of its root slab.
+// Iteration stops when the function returns false.
+//
+func (v *ArrayValue) RawStorables(interpreter *Interpreter, f func(index int, storable atree.Storable) bool) {
+	if !v.hasRootSlab() {
+		return
+	}
+
+	index := 0
+
+	var visit func(storageID atree.StorageID) (resume bool)
+	visit = func(storageID atree.StorageID) (resume bool) {
+		slab := mustRetrieveSlab(interpreter.Storage, storageID)
+
+		if _, ok := slab.(*atree.ArrayMetaDataSlab); ok {
+			for _, child := range slab.ChildStorables() {
+				if !visit(atree.StorageID(child.(atree.StorageIDStorable))) {
+					return false
+				}
+			}
+			return true
+		}
+
+		for _, storable := range slab.ChildStorables() {
+			if !f(index, storable) {
+				return false
+			}
+			index++
+		}
+		return true
+	}
+
+	visit(v.StorageID())
+}
+
+// RawStorables calls the given function for each entry of the dictionary,
+// with the storables of the key and the value, without decoding them into values,
+// like ArrayValue.RawStorables.
+//
+func (v *DictionaryValue) RawStorables(interpreter *Interpreter, f func(key, value atree.Storable) bool) {
+	if !v.hasRootSlab() {
+		return
+	}
+
+	rawMapStorables(interpreter.Storage, v.StorageID(), f)
+}
+
+// RawStorables calls the given function for each field of the composite,
+// with the name and the storable of the field, without decoding it into a value,
+// like ArrayValue.RawStorables.
+//
+func (v *CompositeValue) RawStorables(interpreter *Interpreter, f func(name string, storable atree.Storable) bool) {
+	rawMapStorables(
+		interpreter.Storage,
+		v.StorageID(),
+		func(key, value atree.Storable) bool {
+			// Large field names are stored in separate slabs
+
+			name, ok := key.(stringAtreeValue)
+			if !ok {
+				storedKey, err := key.StoredValue(interpreter.Storage)
+				if err != nil {
+					panic(ExternalError{err})
+				}
+				name = storedKey.(stringAtreeValue)
+			}
+
+			return f(string(name), value)
+		},
+	)
+}
+
+// rawMapStorables calls the given function for each entry of the atree map
+// with the given root slab, with the storables of the key and the value.
+//
+func rawMapStorables(storage atree.SlabStorage, rootStorageID atree.StorageID, f func(key, value atree.Storable) bool) {
+
+	var visit func(storageID atree.StorageID) (resume bool)
+	visit = func(storageID atree.StorageID) (resume bool) {
+		slab := mustRetrieveSlab(storage, storageID)
+
+		if _, ok := slab.(*atree.MapMetaDataSlab); ok {
+			for _, child := range slab.ChildStorables() {
+				if !visit(atree.StorageID(child.(atree.StorageIDStorable))) {
+					return false
+				}
+			}
+			return true
+		}
+
+		// The storables of a data slab are the keys and values of the entries,
+		// interleaved with the storage IDs of external collision groups,
+		// which are data slabs that are not the root of a map
+
+		storables := slab.ChildStorables()
+
+		for i := 0; i < len(storables); i++ {
+			key := storables[i]
+
+			if isExternalCollisionGroup(storage, key) {
+				if !visit(atree.StorageID(key.(atree.StorageIDStorable))) {
+					return false
+				}
+				continue
+			}
+
+			i++
+			if !f(key, storables[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	visit(rootStorageID)
+}
+
+// isExternalCollisionGroup returns true if the given storable refers to
+// an external collision group of a map, i.e. a map data slab which is not a root slab.
+//
+func isExternalCollisionGroup(storage atree.SlabStorage, storable atree.Storable) bool {
+	storageIDStorable, ok := storable.(atree.StorageIDStorable)
+	if !ok {
+		return false
+	}
+
+	slab := mustRetrieveSlab(storage, atree.StorageID(storageIDStorable))
+	if _, ok := slab.(*atree.MapDataSlab); !ok {
+		return false
+	}
+
+	// Only root slabs have the extra data which is necessary to load the map
+
+	_, err := slab.StoredValue(storage)
+	return err != nil
+}
+
+func mustRetrieveSlab(storage atree.SlabStorage, storageID atree.StorageID) atree.Slab {
+	slab, ok, err := storage.Retrieve(storageID)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+	if !ok {
+		panic(ExternalError{atree.NewSlabNotFoundErrorf(storageID, "slab not found")})
+	}
+	return slab
+}
@@ -0,0 +1,188 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestRawStorables(t *testing.T) {
+
+	t.Parallel()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(NewInMemoryStorage()),
+	)
+	require.NoError(t, err)
+
+	owner := common.Address{0x1}
+
+	// Many elements, so the containers consist of multiple slabs
+
+	const count = 1000
+
+	t.Run("array", func(t *testing.T) {
+
+		elements := make([]Value, count)
+		expectedSize := 0
+		for i := range elements {
+			element := UInt64Value(i)
+			elements[i] = element
+			expectedSize += int(element.ByteSize())
+		}
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeUInt64,
+			},
+			owner,
+			elements...,
+		)
+
+		size := 0
+		expectedIndex := 0
+
+		array.RawStorables(inter, func(index int, storable atree.Storable) bool {
+			require.Equal(t, expectedIndex, index)
+			require.Equal(t, UInt64Value(index), storable)
+			expectedIndex++
+
+			size += int(storable.ByteSize())
+			return true
+		})
+
+		require.Equal(t, count, expectedIndex)
+		require.Equal(t, expectedSize, size)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeUInt64,
+			},
+			owner,
+			UInt64Value(1),
+			UInt64Value(2),
+		)
+
+		calls := 0
+		array.RawStorables(inter, func(_ int, _ atree.Storable) bool {
+			calls++
+			return false
+		})
+
+		require.Equal(t, 1, calls)
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+
+		keysAndValues := make([]Value, 0, count*2)
+		expectedSize := 0
+		for i := 0; i < count; i++ {
+			key := NewStringValue(fmt.Sprintf("key%d", i))
+			value := UInt64Value(i)
+			keysAndValues = append(keysAndValues, key, value)
+			expectedSize += int(key.ByteSize() + value.ByteSize())
+		}
+
+		dictionary := NewDictionaryValueWithAddress(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeUInt64,
+			},
+			owner,
+			keysAndValues...,
+		)
+
+		size := 0
+		entries := 0
+
+		dictionary.RawStorables(inter, func(key, value atree.Storable) bool {
+			entries++
+			size += int(key.ByteSize() + value.ByteSize())
+			return true
+		})
+
+		require.Equal(t, count, entries)
+		require.Equal(t, expectedSize, size)
+	})
+
+	t.Run("composite", func(t *testing.T) {
+
+		nested := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeUInt64,
+			},
+			common.Address{},
+			UInt64Value(1),
+		)
+
+		composite := NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Test",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name:  "a",
+					Value: UInt64Value(1),
+				},
+				{
+					Name:  "b",
+					Value: nested,
+				},
+			},
+			owner,
+		)
+
+		storables := map[string]atree.Storable{}
+
+		composite.RawStorables(inter, func(name string, storable atree.Storable) bool {
+			storables[name] = storable
+			return true
+		})
+
+		// The nested container is not decoded
+
+		nestedStorageID := composite.GetField(inter, ReturnEmptyLocationRange, "b").(*ArrayValue).StorageID()
+
+		require.Equal(t,
+			map[string]atree.Storable{
+				"a": UInt64Value(1),
+				"b": atree.StorageIDStorable(nestedStorageID),
+			},
+			storables,
+		)
+	})
+}
@@ -0,0 +1,100 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+// ReferencedTypes returns the distinct static types the given value references,
+// in the order they are first encountered.
+//
+// The value is walked in depth-first order. For each value its static type is collected,
+// as well as the types it refers to, e.g. the borrow type of a capability,
+// the type of a link, or the type of a type value.
+// Types are also decomposed, e.g. the element type of an array type is collected,
+// even if the array is empty.
+//
+func ReferencedTypes(_ *Interpreter, value Value) []StaticType {
+	collector := &referencedTypesCollector{}
+	WalkValue(collector, value)
+	return collector.types
+}
+
+type referencedTypesCollector struct {
+	types []StaticType
+}
+
+var _ ValueWalker = &referencedTypesCollector{}
+
+func (c *referencedTypesCollector) WalkValue(value Value) ValueWalker {
+	if value == nil {
+		return nil
+	}
+
+	c.addType(value.StaticType())
+
+	switch value := value.(type) {
+	case *CapabilityValue:
+		c.addType(value.BorrowType)
+	case LinkValue:
+		c.addType(value.Type)
+	case TypeValue:
+		c.addType(value.Type)
+	}
+
+	return c
+}
+
+func (c *referencedTypesCollector) addType(staticType StaticType) {
+	if staticType == nil {
+		return
+	}
+
+	for _, existing := range c.types {
+		if existing.Equal(staticType) {
+			return
+		}
+	}
+
+	c.types = append(c.types, staticType)
+
+	switch staticType := staticType.(type) {
+	case VariableSizedStaticType:
+		c.addType(staticType.Type)
+
+	case ConstantSizedStaticType:
+		c.addType(staticType.Type)
+
+	case DictionaryStaticType:
+		c.addType(staticType.KeyType)
+		c.addType(staticType.ValueType)
+
+	case OptionalStaticType:
+		c.addType(staticType.Type)
+
+	case *RestrictedStaticType:
+		c.addType(staticType.Type)
+		for _, restriction := range staticType.Restrictions {
+			c.addType(restriction)
+		}
+
+	case ReferenceStaticType:
+		c.addType(staticType.Type)
+
+	case CapabilityStaticType:
+		c.addType(staticType.BorrowType)
+	}
+}
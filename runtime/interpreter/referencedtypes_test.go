@@ -0,0 +1,123 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestReferencedTypes(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	resourceType := NewCompositeStaticType(utils.TestLocation, "R")
+
+	arrayType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeInt,
+	}
+
+	emptyArrayType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeCharacter,
+	}
+
+	dictionaryType := DictionaryStaticType{
+		KeyType:   PrimitiveStaticTypeString,
+		ValueType: PrimitiveStaticTypeBool,
+	}
+
+	borrowType := ReferenceStaticType{
+		Type: resourceType,
+	}
+
+	value := NewCompositeValue(
+		inter,
+		utils.TestLocation,
+		"S",
+		common.CompositeKindStructure,
+		[]CompositeField{
+			{
+				Name: "array",
+				Value: NewArrayValue(
+					inter,
+					arrayType,
+					common.Address{},
+					NewIntValueFromInt64(1),
+				),
+			},
+			{
+				Name: "emptyArray",
+				Value: NewArrayValue(
+					inter,
+					emptyArrayType,
+					common.Address{},
+				),
+			},
+			{
+				Name: "dictionary",
+				Value: NewDictionaryValue(
+					inter,
+					dictionaryType,
+					NewStringValue("a"),
+					BoolValue(true),
+				),
+			},
+			{
+				Name: "capability",
+				Value: &CapabilityValue{
+					Address: AddressValue{0x1},
+					Path: PathValue{
+						Domain:     common.PathDomainPublic,
+						Identifier: "r",
+					},
+					BorrowType: borrowType,
+				},
+			},
+		},
+		common.Address{},
+	)
+
+	require.ElementsMatch(t,
+		[]StaticType{
+			NewCompositeStaticType(utils.TestLocation, "S"),
+			arrayType,
+			PrimitiveStaticTypeInt,
+			emptyArrayType,
+			PrimitiveStaticTypeCharacter,
+			dictionaryType,
+			PrimitiveStaticTypeString,
+			PrimitiveStaticTypeBool,
+			CapabilityStaticType{
+				BorrowType: borrowType,
+			},
+			borrowType,
+			resourceType,
+			PrimitiveStaticTypeAddress,
+			PrimitiveStaticTypePublicPath,
+		},
+		ReferencedTypes(inter, value),
+	)
+}
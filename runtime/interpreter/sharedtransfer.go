@@ -0,0 +1,478 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+)
+
+// sharedCopy is a copy of a value which was created using TransferShared,
+// and which shares the slabs of the value it was transferred from.
+//
+// The containers of the source value are tracked by storage ID,
+// and the containers retrieved from the copy are tracked by value,
+// so that the slabs can be duplicated once either side is mutated (see unshare).
+//
+type sharedCopy struct {
+	// interpreter is the interpreter which tracks the shared slabs,
+	// and which duplicates them when the copy is stored (see Storable)
+	interpreter *Interpreter
+	address     atree.Address
+	root    sharedValue
+	// storageIDs are the storage IDs of all containers of the source value
+	storageIDs []atree.StorageID
+	// values are the container values of the copy, i.e. the root
+	// and all containers retrieved from it, by the storage ID of the shared container
+	values map[atree.StorageID][]sharedValue
+}
+
+// sharedValue is a container value which may belong to a shared copy.
+//
+type sharedValue interface {
+	Value
+	// sharedCopy returns the shared copy the value belongs to,
+	// or nil if the value does not share any slabs
+	sharedCopy() *sharedCopy
+	setSharedCopy(sharedCopy *sharedCopy)
+	// sharedStorageID returns the storage ID of the container of the value.
+	// The value must not be a lazily allocated container
+	sharedStorageID() atree.StorageID
+	// setContainer replaces the container of the value
+	// with the container of the given value of the same kind
+	setContainer(value Value)
+	// unshare must be called before the value is mutated.
+	// It duplicates the slabs of the shared copy the value belongs to, if any,
+	// and of all shared copies of the value
+	unshare(interpreter *Interpreter)
+}
+
+// TransferShared transfers the given value to the given address, like Transfer without removal,
+// but shares the slabs of non-resource arrays and dictionaries copy-on-write.
+//
+// The slabs are only duplicated once the shared copy, a container retrieved from it,
+// or a container of the transferred value is mutated, removed, or transferred with removal.
+// Only the shared copies of the mutated container are duplicated.
+//
+// The sharing is only tracked by the interpreter, so a shared copy, or a container retrieved from it,
+// is also duplicated when it is transferred, or stored, e.g. written to storage or inserted into a container.
+//
+// All other values are transferred using Transfer.
+//
+func TransferShared(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	value Value,
+	address atree.Address,
+) Value {
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		// An empty array without a root slab is transferred without copying slabs anyway
+
+		if value.hasRootSlab() && value.NeedsStoreTo(address) && !value.IsResourceKinded(interpreter) {
+			shared := &ArrayValue{
+				Type:             value.Type,
+				semaType:         value.semaType,
+				isResourceKinded: value.isResourceKinded,
+				array:            value.array,
+			}
+			interpreter.shareContainers(value, shared, address)
+			return shared
+		}
+
+	case *DictionaryValue:
+		// An empty dictionary without a root slab is transferred without copying slabs anyway
+
+		if value.hasRootSlab() && value.NeedsStoreTo(address) && !value.IsResourceKinded(interpreter) {
+			shared := &DictionaryValue{
+				Type:             value.Type,
+				semaType:         value.semaType,
+				isResourceKinded: value.isResourceKinded,
+				dictionary:       value.dictionary,
+			}
+			interpreter.shareContainers(value, shared, address)
+			return shared
+		}
+	}
+
+	return value.Transfer(interpreter, getLocationRange, address, false, nil)
+}
+
+// IsShared returns true if the given value was transferred using TransferShared,
+// or was retrieved from such a value, and still shares the slabs of the transferred value.
+//
+func IsShared(value Value) bool {
+	sharedValue, ok := value.(sharedValue)
+	return ok && sharedValue.sharedCopy() != nil
+}
+
+// unshareForStorage duplicates the shared slabs of the given shared copy, if any,
+// before a storable of one of its values is produced:
+// the storable would otherwise refer to the slabs of the source value.
+//
+func unshareForStorage(sharedCopy *sharedCopy) {
+	if sharedCopy == nil {
+		return
+	}
+
+	sharedCopy.unshare(sharedCopy.interpreter)
+}
+
+// shareContainers records that the given copy shares the slabs of the given source value.
+//
+func (interpreter *Interpreter) shareContainers(source Value, root sharedValue, address atree.Address) {
+	sharedCopy := &sharedCopy{
+		interpreter: interpreter,
+		address:     address,
+		root:        root,
+		values:      map[atree.StorageID][]sharedValue{},
+	}
+
+	walkContainers(source, func(container sharedValue) {
+		storageID := container.sharedStorageID()
+		sharedCopy.storageIDs = append(sharedCopy.storageIDs, storageID)
+		interpreter.sharedContainers[storageID] = append(
+			interpreter.sharedContainers[storageID],
+			sharedCopy,
+		)
+	})
+
+	sharedCopy.add(root)
+}
+
+// add records that the given value belongs to the shared copy.
+//
+func (c *sharedCopy) add(value sharedValue) {
+	value.setSharedCopy(c)
+	storageID := value.sharedStorageID()
+	c.values[storageID] = append(c.values[storageID], value)
+}
+
+// share returns the given value, which was retrieved from a container of the shared copy,
+// as a value of the shared copy.
+//
+func (c *sharedCopy) share(value Value) Value {
+	switch value := value.(type) {
+	case sharedValue:
+		c.add(value)
+	case *SomeValue:
+		c.share(value.Value)
+	}
+	return value
+}
+
+// unshareCopies duplicates the slabs of all shared copies of the container with the given storage ID.
+//
+func (interpreter *Interpreter) unshareCopies(storageID atree.StorageID) {
+	for {
+		sharedCopies := interpreter.sharedContainers[storageID]
+		if len(sharedCopies) == 0 {
+			return
+		}
+		sharedCopies[0].unshare(interpreter)
+	}
+}
+
+// unshare duplicates the shared slabs of the copy,
+// and replaces the containers of all values of the copy with the duplicated containers.
+// The copy owns the duplicated slabs.
+//
+func (c *sharedCopy) unshare(interpreter *Interpreter) {
+	c.release(interpreter)
+
+	copied := c.root.Transfer(interpreter, ReturnEmptyLocationRange, c.address, false, nil)
+
+	remaining := 0
+	for _, values := range c.values {
+		remaining += len(values)
+	}
+
+	// Determine the duplicated containers of all values first,
+	// as the shared containers must be traversed before they are replaced
+
+	var replacements []sharedContainerReplacement
+	collectSharedContainerReplacements(
+		interpreter,
+		c.root,
+		copied,
+		c.values,
+		&remaining,
+		&replacements,
+	)
+
+	for _, replacement := range replacements {
+		replacement.value.setContainer(replacement.copied)
+	}
+
+	c.values = nil
+}
+
+// release stops the tracking of the shared slabs of the copy.
+// The values of the copy do not belong to it anymore.
+//
+func (c *sharedCopy) release(interpreter *Interpreter) {
+	for _, storageID := range c.storageIDs {
+		sharedCopies := interpreter.sharedContainers[storageID]
+		for i, sharedCopy := range sharedCopies {
+			if sharedCopy == c {
+				sharedCopies = append(sharedCopies[:i], sharedCopies[i+1:]...)
+				break
+			}
+		}
+		if len(sharedCopies) == 0 {
+			delete(interpreter.sharedContainers, storageID)
+		} else {
+			interpreter.sharedContainers[storageID] = sharedCopies
+		}
+	}
+	c.storageIDs = nil
+
+	for _, values := range c.values {
+		for _, value := range values {
+			value.setSharedCopy(nil)
+		}
+	}
+}
+
+type sharedContainerReplacement struct {
+	value  sharedValue
+	copied Value
+}
+
+// collectSharedContainerReplacements determines the replacements of the containers of the given values,
+// which are nested in the shared value, i.e. the containers at the same position in the copied value.
+//
+func collectSharedContainerReplacements(
+	interpreter *Interpreter,
+	shared Value,
+	copied Value,
+	values map[atree.StorageID][]sharedValue,
+	remaining *int,
+	replacements *[]sharedContainerReplacement,
+) {
+	for {
+		sharedSome, ok := shared.(*SomeValue)
+		if !ok {
+			break
+		}
+		shared = sharedSome.Value
+		copied = copied.(*SomeValue).Value
+	}
+
+	sharedContainer, ok := shared.(sharedValue)
+	if !ok {
+		return
+	}
+
+	for _, value := range values[sharedContainer.sharedStorageID()] {
+		*replacements = append(
+			*replacements,
+			sharedContainerReplacement{
+				value:  value,
+				copied: copied,
+			},
+		)
+		*remaining--
+	}
+
+	switch shared := shared.(type) {
+	case *ArrayValue:
+		copied := copied.(*ArrayValue)
+		count := shared.Count()
+		for i := 0; i < count && *remaining > 0; i++ {
+			collectSharedContainerReplacements(
+				interpreter,
+				shared.Get(interpreter, ReturnEmptyLocationRange, i),
+				copied.Get(interpreter, ReturnEmptyLocationRange, i),
+				values,
+				remaining,
+				replacements,
+			)
+		}
+
+	case *DictionaryValue:
+		copied := copied.(*DictionaryValue)
+		shared.Iterate(func(key, value Value) (resume bool) {
+			copiedValue, _ := copied.Get(interpreter, ReturnEmptyLocationRange, key)
+			collectSharedContainerReplacements(
+				interpreter,
+				value,
+				copiedValue,
+				values,
+				remaining,
+				replacements,
+			)
+			return *remaining > 0
+		})
+
+	case *CompositeValue:
+		copied := copied.(*CompositeValue)
+		shared.ForEachField(func(name string, value Value) {
+			if *remaining == 0 {
+				return
+			}
+			collectSharedContainerReplacements(
+				interpreter,
+				value,
+				copied.GetField(interpreter, ReturnEmptyLocationRange, name),
+				values,
+				remaining,
+				replacements,
+			)
+		})
+	}
+}
+
+// walkContainers calls the given function for the given value, if it is a container,
+// and for all containers nested in it.
+//
+func walkContainers(value Value, f func(container sharedValue)) {
+	for {
+		someValue, ok := value.(*SomeValue)
+		if !ok {
+			break
+		}
+		value = someValue.Value
+	}
+
+	container, ok := value.(sharedValue)
+	if !ok {
+		return
+	}
+
+	f(container)
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		value.Iterate(func(element Value) (resume bool) {
+			walkContainers(element, f)
+			return true
+		})
+
+	case *DictionaryValue:
+		value.Iterate(func(_, value Value) (resume bool) {
+			walkContainers(value, f)
+			return true
+		})
+
+	case *CompositeValue:
+		value.ForEachField(func(_ string, value Value) {
+			walkContainers(value, f)
+		})
+	}
+}
+
+// ArrayValue
+
+func (v *ArrayValue) sharedCopy() *sharedCopy {
+	return v.shared
+}
+
+func (v *ArrayValue) setSharedCopy(sharedCopy *sharedCopy) {
+	v.shared = sharedCopy
+}
+
+func (v *ArrayValue) sharedStorageID() atree.StorageID {
+	return v.StorageID()
+}
+
+func (v *ArrayValue) setContainer(value Value) {
+	other := value.(*ArrayValue)
+	v.array = other.array
+	v.lazyRoot = other.lazyRoot
+	v.sentinel = other.sentinel
+}
+
+func (v *ArrayValue) unshare(interpreter *Interpreter) {
+	if v.shared != nil {
+		v.shared.unshare(interpreter)
+		return
+	}
+
+	if len(interpreter.sharedContainers) == 0 {
+		return
+	}
+
+	interpreter.unshareCopies(v.StorageID())
+}
+
+// DictionaryValue
+
+func (v *DictionaryValue) sharedCopy() *sharedCopy {
+	return v.shared
+}
+
+func (v *DictionaryValue) setSharedCopy(sharedCopy *sharedCopy) {
+	v.shared = sharedCopy
+}
+
+func (v *DictionaryValue) sharedStorageID() atree.StorageID {
+	return v.StorageID()
+}
+
+func (v *DictionaryValue) setContainer(value Value) {
+	other := value.(*DictionaryValue)
+	v.dictionary = other.dictionary
+	v.lazyRoot = other.lazyRoot
+	v.sentinel = other.sentinel
+}
+
+func (v *DictionaryValue) unshare(interpreter *Interpreter) {
+	if v.shared != nil {
+		v.shared.unshare(interpreter)
+		return
+	}
+
+	if len(interpreter.sharedContainers) == 0 {
+		return
+	}
+
+	interpreter.unshareCopies(v.StorageID())
+}
+
+// CompositeValue
+
+func (v *CompositeValue) sharedCopy() *sharedCopy {
+	return v.shared
+}
+
+func (v *CompositeValue) setSharedCopy(sharedCopy *sharedCopy) {
+	v.shared = sharedCopy
+}
+
+func (v *CompositeValue) sharedStorageID() atree.StorageID {
+	return v.dictionary.StorageID()
+}
+
+func (v *CompositeValue) setContainer(value Value) {
+	v.dictionary = value.(*CompositeValue).dictionary
+}
+
+func (v *CompositeValue) unshare(interpreter *Interpreter) {
+	if v.shared != nil {
+		v.shared.unshare(interpreter)
+		return
+	}
+
+	if len(interpreter.sharedContainers) == 0 {
+		return
+	}
+
+	interpreter.unshareCopies(v.dictionary.StorageID())
+}
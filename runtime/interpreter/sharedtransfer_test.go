@@ -0,0 +1,391 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestTransferShared(t *testing.T) {
+
+	t.Parallel()
+
+	sourceAddress := common.Address{0x1}
+	targetAddress := common.Address{0x2}
+
+	newTestInterpreterWithStorage := func(t *testing.T) (*Interpreter, InMemoryStorage) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		return inter, storage
+	}
+
+	t.Run("array, shared until write", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := newTestInterpreterWithStorage(t)
+
+		source := newIntArray(inter, sourceAddress, 1000, 1)
+
+		slabCount := len(storage.Slabs)
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		require.True(t, IsShared(shared))
+		require.Equal(t, slabCount, len(storage.Slabs))
+		require.Equal(t, source.StorageID(), shared.StorageID())
+		require.Equal(t, targetAddress, shared.GetOwner())
+
+		copied := source.Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(targetAddress),
+			false,
+			nil,
+		)
+
+		utils.RequireValuesEqual(t, inter, copied, shared)
+		require.Equal(t, copied.String(), shared.String())
+
+		slabCount = len(storage.Slabs)
+
+		shared.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.False(t, IsShared(shared))
+		require.Greater(t, len(storage.Slabs), slabCount)
+		require.NotEqual(t, source.StorageID(), shared.StorageID())
+		require.Equal(t, targetAddress, shared.GetOwner())
+
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(1),
+			source.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(2),
+			shared.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+	})
+
+	t.Run("array, write to source", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newTestInterpreterWithStorage(t)
+
+		source := newIntArray(inter, sourceAddress, 10, 1)
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		require.True(t, IsShared(shared))
+
+		source.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.False(t, IsShared(shared))
+
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(2),
+			source.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(1),
+			shared.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+	})
+
+	t.Run("nested array", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newTestInterpreterWithStorage(t)
+
+		source := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: VariableSizedStaticType{
+					Type: PrimitiveStaticTypeInt,
+				},
+			},
+			sourceAddress,
+			newIntArray(inter, common.Address{}, 2, 1),
+		)
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		inner := shared.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+
+		// Reading a nested container does not duplicate the slabs
+
+		require.True(t, IsShared(shared))
+		require.True(t, IsShared(inner))
+		require.Equal(t, targetAddress, inner.GetOwner())
+
+		inner.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.False(t, IsShared(shared))
+		require.False(t, IsShared(inner))
+
+		sourceInner := source.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(1),
+			sourceInner.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+
+		sharedInner := shared.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+		require.Equal(t, inner.StorageID(), sharedInner.StorageID())
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(2),
+			sharedInner.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+	})
+
+	t.Run("nested array, write to source", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newTestInterpreterWithStorage(t)
+
+		source := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: VariableSizedStaticType{
+					Type: PrimitiveStaticTypeInt,
+				},
+			},
+			sourceAddress,
+			newIntArray(inter, common.Address{}, 2, 1),
+		)
+
+		// The nested array is retrieved before the transfer
+
+		sourceInner := source.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		inner := shared.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+
+		sourceInner.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.False(t, IsShared(shared))
+		require.False(t, IsShared(inner))
+
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(1),
+			inner.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(2),
+			sourceInner.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+	})
+
+	t.Run("only copies of mutated value are duplicated", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newTestInterpreterWithStorage(t)
+
+		source1 := newIntArray(inter, sourceAddress, 2, 1)
+		source2 := newIntArray(inter, sourceAddress, 2, 1)
+
+		shared1 := TransferShared(inter, ReturnEmptyLocationRange, source1, atree.Address(targetAddress))
+		shared2 := TransferShared(inter, ReturnEmptyLocationRange, source2, atree.Address(targetAddress))
+
+		source1.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.False(t, IsShared(shared1))
+		require.True(t, IsShared(shared2))
+	})
+
+	t.Run("remove", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := newTestInterpreterWithStorage(t)
+
+		source := newIntArray(inter, sourceAddress, 1000, 1)
+
+		slabCount := len(storage.Slabs)
+
+		// A shared copy does not own any slabs
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		shared.DeepRemove(inter)
+
+		require.False(t, IsShared(shared))
+		require.Equal(t, slabCount, len(storage.Slabs))
+		require.Equal(t, 1000, source.Count())
+
+		// The duplicated slabs are owned by the copy
+
+		shared = TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		shared.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(2))
+
+		require.Greater(t, len(storage.Slabs), slabCount)
+
+		shared.DeepRemove(inter)
+		inter.RemoveReferencedSlab(atree.StorageIDStorable(shared.StorageID()))
+
+		require.Equal(t, slabCount, len(storage.Slabs))
+		require.Equal(t, 1000, source.Count())
+	})
+
+	t.Run("stored", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := newTestInterpreterWithStorage(t)
+
+		source := newIntArray(inter, sourceAddress, 1000, 1)
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*ArrayValue)
+
+		// Storing the shared copy duplicates the shared slabs,
+		// as the sharing is not tracked in storage
+
+		storage.WriteValue(inter, targetAddress, "copy", NewSomeValueNonCopying(shared))
+
+		require.False(t, IsShared(shared))
+		require.NotEqual(t, source.StorageID(), shared.StorageID())
+
+		source.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+
+		source.DeepRemove(inter)
+		inter.RemoveReferencedSlab(atree.StorageIDStorable(source.StorageID()))
+
+		stored := storage.ReadValue(inter, targetAddress, "copy").(*SomeValue).Value.(*ArrayValue)
+
+		require.Equal(t, targetAddress, stored.GetOwner())
+		require.Equal(t, 1000, stored.Count())
+
+		utils.RequireValuesEqual(t, inter,
+			NewIntValueFromInt64(1),
+			stored.Get(inter, ReturnEmptyLocationRange, 999),
+		)
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := newTestInterpreterWithStorage(t)
+
+		source := NewDictionaryValueWithAddress(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeInt,
+			},
+			sourceAddress,
+			NewStringValue("a"), NewIntValueFromInt64(1),
+			NewStringValue("b"), NewIntValueFromInt64(2),
+		)
+
+		slabCount := len(storage.Slabs)
+
+		shared := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			source,
+			atree.Address(targetAddress),
+		).(*DictionaryValue)
+
+		require.True(t, IsShared(shared))
+		require.Equal(t, slabCount, len(storage.Slabs))
+
+		value, ok := shared.Get(inter, ReturnEmptyLocationRange, NewStringValue("b"))
+		require.True(t, ok)
+		utils.RequireValuesEqual(t, inter, NewIntValueFromInt64(2), value)
+
+		shared.Insert(inter, ReturnEmptyLocationRange, NewStringValue("c"), NewIntValueFromInt64(3))
+
+		require.False(t, IsShared(shared))
+		require.Equal(t, 3, shared.Count())
+		require.Equal(t, 2, source.Count())
+		require.Equal(t, targetAddress, shared.GetOwner())
+	})
+
+	t.Run("other values", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newTestInterpreterWithStorage(t)
+
+		source := newIntArray(inter, sourceAddress, 2, 1)
+
+		result := TransferShared(
+			inter,
+			ReturnEmptyLocationRange,
+			NewSomeValueNonCopying(source),
+			atree.Address(targetAddress),
+		)
+
+		require.False(t, IsShared(result))
+	})
+}
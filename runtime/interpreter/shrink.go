@@ -0,0 +1,229 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+// ShrinkValue reduces the given value to a smaller value for which the given predicate still holds,
+// e.g. to reduce a randomly generated value which triggers a failure to a minimal reproducing value.
+//
+// The value is shrunk by repeatedly removing elements of arrays, entries of dictionaries,
+// and fields of composites, and by shortening strings, including nested values,
+// as long as the predicate holds for the smaller value. Shrinking is deterministic.
+//
+// The given value is not modified. The returned value is owned by no account.
+// Resources are not shrunk.
+//
+func ShrinkValue(interpreter *Interpreter, value Value, stillFails func(Value) bool) Value {
+	current := value
+
+	for {
+		var next Value
+
+		interpreter.shrinkCandidates(current, func(candidate Value) (accept bool) {
+			if stillFails(candidate) {
+				next = candidate
+				return true
+			}
+
+			interpreter.removeShrinkCandidate(candidate)
+			return false
+		})
+
+		if next == nil {
+			return current
+		}
+
+		if current != value {
+			interpreter.removeShrinkCandidate(current)
+		}
+
+		current = next
+	}
+}
+
+// shrinkCandidates calls the given function with the candidates for shrinking the given value,
+// until the function accepts a candidate. It returns true if a candidate was accepted.
+//
+// Each candidate is a new value, which is owned by no account,
+// and which the function is responsible for.
+//
+func (interpreter *Interpreter) shrinkCandidates(value Value, f func(candidate Value) (accept bool)) bool {
+	if value.IsResourceKinded(interpreter) {
+		return false
+	}
+
+	switch value := value.(type) {
+	case *SomeValue:
+		return interpreter.shrinkCandidates(value.Value, func(candidate Value) bool {
+			return f(NewSomeValueNonCopying(candidate))
+		})
+
+	case *StringValue:
+		runes := []rune(value.Str)
+		count := len(runes)
+
+		var lengths []int
+		switch {
+		case count == 0:
+			return false
+		case count == 1:
+			lengths = []int{0}
+		default:
+			lengths = []int{0, count / 2, count - 1}
+		}
+
+		for _, length := range lengths {
+			if f(NewStringValue(string(runes[:length]))) {
+				return true
+			}
+		}
+
+		return false
+
+	case *ArrayValue:
+		count := value.Count()
+
+		// Remove elements
+
+		for index := 0; index < count; index++ {
+			candidate := CopyValue(interpreter, value).(*ArrayValue)
+			removed := candidate.Remove(interpreter, ReturnEmptyLocationRange, index)
+			interpreter.removeShrinkCandidate(removed)
+
+			if f(candidate) {
+				return true
+			}
+		}
+
+		// Shrink elements
+
+		for index := 0; index < count; index++ {
+			element := value.Get(interpreter, ReturnEmptyLocationRange, index)
+
+			accepted := interpreter.shrinkCandidates(element, func(elementCandidate Value) bool {
+				candidate := CopyValue(interpreter, value).(*ArrayValue)
+				candidate.Set(interpreter, ReturnEmptyLocationRange, index, elementCandidate)
+				interpreter.removeShrinkCandidate(elementCandidate)
+
+				return f(candidate)
+			})
+			if accepted {
+				return true
+			}
+		}
+
+		return false
+
+	case *DictionaryValue:
+		var keys []Value
+		value.Iterate(func(key, _ Value) (resume bool) {
+			keys = append(keys, key)
+			return true
+		})
+
+		// Remove entries
+
+		for _, key := range keys {
+			candidate := CopyValue(interpreter, value).(*DictionaryValue)
+			removed := candidate.Remove(interpreter, ReturnEmptyLocationRange, key)
+			interpreter.removeShrinkCandidate(removed)
+
+			if f(candidate) {
+				return true
+			}
+		}
+
+		// Shrink values
+
+		for _, key := range keys {
+			entryValue, _ := value.Get(interpreter, ReturnEmptyLocationRange, key)
+
+			accepted := interpreter.shrinkCandidates(entryValue, func(valueCandidate Value) bool {
+				candidate := CopyValue(interpreter, value).(*DictionaryValue)
+				existing := candidate.Insert(
+					interpreter,
+					ReturnEmptyLocationRange,
+					CopyValue(interpreter, key),
+					valueCandidate,
+				)
+				interpreter.removeShrinkCandidate(existing)
+				interpreter.removeShrinkCandidate(valueCandidate)
+
+				return f(candidate)
+			})
+			if accepted {
+				return true
+			}
+		}
+
+		return false
+
+	case *CompositeValue:
+		var names []string
+		value.ForEachField(func(name string, _ Value) {
+			names = append(names, name)
+		})
+
+		// Remove fields
+
+		for _, name := range names {
+			candidate := CopyValue(interpreter, value).(*CompositeValue)
+			removed := candidate.RemoveMember(interpreter, ReturnEmptyLocationRange, name)
+			interpreter.removeShrinkCandidate(removed)
+
+			if f(candidate) {
+				return true
+			}
+		}
+
+		// Shrink fields
+
+		for _, name := range names {
+			field := value.GetField(interpreter, ReturnEmptyLocationRange, name)
+
+			accepted := interpreter.shrinkCandidates(field, func(fieldCandidate Value) bool {
+				candidate := CopyValue(interpreter, value).(*CompositeValue)
+				candidate.SetMember(interpreter, ReturnEmptyLocationRange, name, fieldCandidate)
+				interpreter.removeShrinkCandidate(fieldCandidate)
+
+				return f(candidate)
+			})
+			if accepted {
+				return true
+			}
+		}
+
+		return false
+
+	default:
+		return false
+	}
+}
+
+// removeShrinkCandidate removes the given candidate, which is owned by no account, from storage.
+//
+func (interpreter *Interpreter) removeShrinkCandidate(value Value) {
+	switch value := value.(type) {
+	case *SomeValue:
+		interpreter.removeShrinkCandidate(value.Value)
+	case nil:
+		return
+	default:
+		interpreter.removeUnownedContainer(value)
+	}
+}
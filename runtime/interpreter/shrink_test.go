@@ -0,0 +1,127 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestShrinkValue(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	newStringArray := func(elements ...Value) *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			common.Address{},
+			elements...,
+		)
+	}
+
+	// ["ab", ["abcdefgh", "xyz"], {"a": "hello"}]
+
+	value := newStringArray(
+		NewStringValue("ab"),
+		newStringArray(
+			NewStringValue("abcdefgh"),
+			NewStringValue("xyz"),
+		),
+		NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeString,
+			},
+			NewStringValue("a"),
+			NewStringValue("hello"),
+		),
+	)
+
+	// Fails if any string is longer than 3 characters
+
+	var hasLongString func(value Value) bool
+	hasLongString = func(value Value) bool {
+		if str, ok := value.(*StringValue); ok && len(str.Str) > 3 {
+			return true
+		}
+
+		found := false
+		value.Walk(func(child Value) {
+			if !found && hasLongString(child) {
+				found = true
+			}
+		})
+		return found
+	}
+
+	require.True(t, hasLongString(value))
+
+	slabCount := storage.Count()
+
+	shrunk := ShrinkValue(inter, value, hasLongString)
+
+	// All rejected candidates were removed,
+	// only the slabs of the shrunk value, an array and a dictionary, were added
+
+	require.Equal(t, slabCount+2, storage.Count())
+	require.NoError(t, storage.CheckHealth())
+
+	// Elements are removed before they are shrunk,
+	// so the long string in the dictionary is the one which is trimmed,
+	// to the shortest candidate which still fails
+
+	utils.RequireValuesEqual(
+		t,
+		inter,
+		newStringArray(
+			NewDictionaryValue(
+				inter,
+				DictionaryStaticType{
+					KeyType:   PrimitiveStaticTypeString,
+					ValueType: PrimitiveStaticTypeString,
+				},
+				NewStringValue("a"),
+				NewStringValue("hell"),
+			),
+		),
+		shrunk,
+	)
+
+	// The given value is unchanged
+
+	require.Equal(t, 3, value.Count())
+}
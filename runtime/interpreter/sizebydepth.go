@@ -0,0 +1,78 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"math"
+
+	"github.com/onflow/atree"
+)
+
+// SizeByDepth returns the approximate number of bytes of the given value,
+// attributed to the nesting level at which they occur.
+//
+// The root value is at depth 0, and the children of a container
+// (array, dictionary, or composite) are one level deeper than the container.
+// Optionals do not introduce a new level.
+//
+// Only the inline encoded sizes of non-container values are attributed,
+// e.g. the keys and values of a dictionary, or the field values of a composite.
+// The overhead of the containers themselves (slab headers, field names, etc.) is not included.
+//
+func SizeByDepth(interpreter *Interpreter, value Value) map[int]uint64 {
+	sizes := map[int]uint64{}
+
+	var walk func(value Value, depth int)
+	walk = func(value Value, depth int) {
+		switch value.(type) {
+		case *ArrayValue, *DictionaryValue, *CompositeValue:
+			value.Walk(func(child Value) {
+				walk(child, depth+1)
+			})
+
+		case *SomeValue:
+			value.Walk(func(child Value) {
+				walk(child, depth)
+			})
+
+		default:
+			sizes[depth] += uint64(inlineByteSize(interpreter, value))
+		}
+	}
+
+	walk(value, 0)
+
+	return sizes
+}
+
+// inlineByteSize returns the size of the given non-container value when it is inlined,
+// or 0 if the value is not storable.
+//
+func inlineByteSize(interpreter *Interpreter, value Value) uint32 {
+	storable, err := value.Storable(interpreter.Storage, atree.Address{}, math.MaxUint64)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	if _, ok := storable.(NonStorable); ok {
+		return 0
+	}
+
+	return storable.ByteSize()
+}
@@ -0,0 +1,112 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+)
+
+func TestSizeByDepth(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	byteSize := func(values ...Value) (size uint64) {
+		for _, value := range values {
+			storable, err := value.Storable(inter.Storage, atree.Address{}, math.MaxUint64)
+			require.NoError(t, err)
+			size += uint64(storable.ByteSize())
+		}
+		return
+	}
+
+	t.Run("leaf", func(t *testing.T) {
+
+		t.Parallel()
+
+		value := NewStringValue("hello")
+
+		require.Equal(t,
+			map[int]uint64{
+				0: byteSize(value),
+			},
+			SizeByDepth(inter, value),
+		)
+	})
+
+	t.Run("nested", func(t *testing.T) {
+
+		t.Parallel()
+
+		// [1, [2, 3], {"a": true}, nil, Some(4)]
+
+		value := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			common.Address{},
+			UInt8Value(1),
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeUInt8,
+				},
+				common.Address{},
+				UInt8Value(2),
+				UInt8Value(3),
+			),
+			NewDictionaryValue(
+				inter,
+				DictionaryStaticType{
+					KeyType:   PrimitiveStaticTypeString,
+					ValueType: PrimitiveStaticTypeBool,
+				},
+				NewStringValue("a"), BoolValue(true),
+			),
+			NilValue{},
+			NewSomeValueNonCopying(UInt8Value(4)),
+		)
+
+		require.Equal(t,
+			map[int]uint64{
+				1: byteSize(
+					UInt8Value(1),
+					NilValue{},
+					UInt8Value(4),
+				),
+				2: byteSize(
+					UInt8Value(2),
+					UInt8Value(3),
+					NewStringValue("a"),
+					BoolValue(true),
+				),
+			},
+			SizeByDepth(inter, value),
+		)
+	})
+}
@@ -22,6 +22,8 @@ import (
 	"bytes"
 	"fmt"
 	"math"
+	"sort"
+	"time"
 
 	"github.com/fxamacker/cbor/v2"
 	"github.com/onflow/atree"
@@ -32,9 +34,27 @@ import (
 )
 
 func StoredValue(storable atree.Storable, storage atree.SlabStorage) Value {
+	return storedValue(nil, storable, storage)
+}
+
+// storedValue returns the value of the given storable, like StoredValue.
+// If loading the value fails, a StorageOperationError is panicked,
+// which includes the given account storage key, if any,
+// and the storage ID of the storable, if it refers to a slab.
+//
+func storedValue(key *StorageKey, storable atree.Storable, storage atree.SlabStorage) Value {
 	storedValue, err := storable.StoredValue(storage)
 	if err != nil {
-		panic(err)
+		var storageID atree.StorageID
+		if storageIDStorable, ok := storable.(atree.StorageIDStorable); ok {
+			storageID = atree.StorageID(storageIDStorable)
+		}
+
+		panic(StorageOperationError{
+			Key:       key,
+			StorageID: storageID,
+			Err:       err,
+		})
 	}
 
 	return MustConvertStoredValue(storedValue)
@@ -106,11 +126,181 @@ func (k StorageKey) IsLess(o StorageKey) bool {
 type InMemoryStorage struct {
 	*atree.BasicSlabStorage
 	AccountStorage map[StorageKey]atree.Storable
+	keyHasher      func(string) string
+	dirty          map[atree.StorageID]struct{}
+	inlineOnly     bool
+	// referenceCounts is nil if capability reference tracking is disabled
+	referenceCounts map[StorageKey]int
+	// deterministicOrder determines if dictionaries are encoded canonically, see WithDeterministicOrder
+	deterministicOrder bool
+	// storageIDAllocator is nil if the default allocator is used
+	storageIDAllocator func(address atree.Address) atree.StorageID
+	// maxSlabs is 0 if the number of slabs is not limited
+	maxSlabs int
+	// readReturnsCopy determines if ReadValue returns copies of stored values
+	readReturnsCopy bool
+	// explicitNil determines if WriteValue stores nil instead of removing the value
+	explicitNil bool
+	// emptyContainerSentinels determines if empty containers are stored
+	// using the empty container sentinel, see EmptyContainerStorable
+	emptyContainerSentinels bool
+	// slabAllocations is shared by copies of the storage, see SetSlabAllocationCounter
+	slabAllocations *slabAllocationCounterHolder
+}
+
+// slabAllocationCounterHolder holds the slab allocation counter of an in-memory storage,
+// so it can be set through any copy of the storage.
+//
+type slabAllocationCounterHolder struct {
+	// counter is nil if slab allocations are not counted
+	counter *SlabAllocationCounter
 }
 
 var _ Storage = InMemoryStorage{}
 
-func NewInMemoryStorage() InMemoryStorage {
+// InMemoryStorageOption is an option for an in-memory storage.
+//
+type InMemoryStorageOption func(*InMemoryStorage)
+
+// WithKeyHashing returns an in-memory storage option which hashes
+// all storage keys using the given function before they are used
+// as keys of the account storage.
+//
+// NOTE: No reverse mapping is kept, i.e. the keys of the account storage
+// are the hashed keys, and the original keys cannot be recovered from them.
+//
+func WithKeyHashing(hasher func(string) string) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.keyHasher = hasher
+	}
+}
+
+// WithInlineOnly returns an in-memory storage option which determines
+// if large immutable storables are always inlined, instead of being stored in separate slabs.
+//
+// This reduces the number of slabs for small storages.
+// Storables which exceed the maximum inline size result in a NonInlinableStorableError.
+//
+func WithInlineOnly(inlineOnly bool) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.inlineOnly = inlineOnly
+	}
+}
+
+// WithCapabilityReferenceTracking returns an in-memory storage option which determines
+// if the storage tracks how many links, i.e. capabilities, target each stored value.
+//
+// If enabled, values which are the target of a link cannot be removed or overwritten,
+// unless the removal is forced using RemoveValue.
+//
+func WithCapabilityReferenceTracking(enabled bool) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		if enabled {
+			storage.referenceCounts = make(map[StorageKey]int)
+		} else {
+			storage.referenceCounts = nil
+		}
+	}
+}
+
+// WithDeterministicOrder returns an in-memory storage option which determines
+// if the storage of dictionaries only depends on their entries,
+// and not on the order in which entries were inserted and removed.
+//
+// If enabled, EncodeOrdered encodes each dictionary canonically,
+// so that dictionaries with the same entries encode identically.
+// Mutations do not rebuild the dictionary, its slabs are split and merged as usual.
+//
+// The storage IDs of removed slabs are never reused,
+// so stale values and references never refer to unrelated slabs.
+//
+// NOTE: Values of entries which are stored in separate slabs, e.g. nested containers,
+// keep the storage IDs they were allocated when they were created.
+//
+func WithDeterministicOrder(enabled bool) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.deterministicOrder = enabled
+	}
+}
+
+// WithStorageIDAllocator returns an in-memory storage option which sets
+// the function that allocates the storage IDs of new slabs.
+//
+// By default, the storage IDs are allocated by the underlying atree slab storage.
+// The allocator must never return the same storage ID twice.
+//
+func WithStorageIDAllocator(allocator func(address atree.Address) atree.StorageID) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.storageIDAllocator = allocator
+	}
+}
+
+// WithEmptyContainerSentinels returns an in-memory storage option which determines
+// if arrays and dictionaries which were created empty, and are still empty, are stored
+// using the empty container sentinel, without allocating their root slab (see EmptyContainerStorable).
+//
+// The sentinel is a storage format extension, so it is disabled by default,
+// see rfcs/0003-empty-container-sentinel.md.
+//
+func WithEmptyContainerSentinels(enabled bool) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.emptyContainerSentinels = enabled
+	}
+}
+
+// WithMaxSlabs returns an in-memory storage option which limits
+// the total number of slabs the storage holds to the given number.
+//
+// Storing a new slab which would exceed the limit results in a SlabLimitExceededError.
+// Removed slabs no longer count towards the limit.
+// A limit less than or equal to 0 disables the limit.
+//
+func WithMaxSlabs(n int) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		if n < 0 {
+			n = 0
+		}
+		storage.maxSlabs = n
+	}
+}
+
+// WithReadReturnsCopy returns an in-memory storage option which determines
+// if ReadValue returns a deep copy of the stored value (see CopyValue),
+// instead of a value backed by the storage.
+//
+// Mutating a copy does not affect the stored value, i.e. writes to the copy are not persisted.
+// By default, the stored value is returned, which avoids copying.
+//
+// The copy is allocated in the storage, and the caller owns it:
+// it must be removed when it is no longer needed, e.g. using DeepRemoveAll,
+// otherwise its slabs are never freed.
+//
+// Resources are never copied, as a copy would duplicate the resource:
+// ReadValue returns stored resources backed by the storage, like when disabled.
+//
+// NOTE: When enabled, ReadValue requires an interpreter.
+//
+func WithReadReturnsCopy(enabled bool) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.readReturnsCopy = enabled
+	}
+}
+
+// WithExplicitNil returns an in-memory storage option which determines
+// if writing nil using WriteValue stores an explicit nil, instead of removing the stored value.
+//
+// If enabled, reading an explicit nil returns an optional containing nil,
+// which distinguishes it from an absent value, which is read as nil.
+// Values are still removed by RemoveValue.
+// By default, writing nil removes the stored value.
+//
+func WithExplicitNil(enabled bool) InMemoryStorageOption {
+	return func(storage *InMemoryStorage) {
+		storage.explicitNil = enabled
+	}
+}
+
+func NewInMemoryStorage(options ...InMemoryStorageOption) InMemoryStorage {
 	slabStorage := atree.NewBasicSlabStorage(
 		CBOREncMode,
 		CBORDecMode,
@@ -118,9 +308,387 @@ func NewInMemoryStorage() InMemoryStorage {
 		DecodeTypeInfo,
 	)
 
-	return InMemoryStorage{
+	storage := InMemoryStorage{
 		BasicSlabStorage: slabStorage,
 		AccountStorage:   make(map[StorageKey]atree.Storable),
+		dirty:            make(map[atree.StorageID]struct{}),
+		slabAllocations:  &slabAllocationCounterHolder{},
+	}
+
+	for _, option := range options {
+		option(&storage)
+	}
+
+	return storage
+}
+
+// Clone returns an independent copy of the storage, e.g. for speculative execution:
+// writes to the clone do not affect this storage, and vice versa.
+//
+// The stored storables are immutable and are shared.
+// Slabs are mutated in place, so they are copied by encoding and decoding them.
+// The clone has the same options as this storage.
+//
+func (i InMemoryStorage) Clone() InMemoryStorage {
+	slabStorage := atree.NewBasicSlabStorage(
+		CBOREncMode,
+		CBORDecMode,
+		DecodeStorable,
+		DecodeTypeInfo,
+	)
+
+	encoded, err := i.BasicSlabStorage.Encode()
+	if err != nil {
+		panic(err)
+	}
+
+	err = slabStorage.Load(encoded)
+	if err != nil {
+		panic(err)
+	}
+
+	// The storage indices of the slab storage cannot be copied,
+	// so advance the indices of the clone past the indices of all slabs,
+	// to avoid allocating the storage ID of an existing slab
+
+	if i.storageIDAllocator == nil {
+		maxIndices := map[atree.Address]atree.StorageIndex{}
+		for storageID := range slabStorage.Slabs {
+			maxIndex := maxIndices[storageID.Address]
+			if bytes.Compare(storageID.Index[:], maxIndex[:]) > 0 {
+				maxIndices[storageID.Address] = storageID.Index
+			}
+		}
+
+		for address, maxIndex := range maxIndices {
+			for {
+				storageID, err := slabStorage.GenerateStorageID(address)
+				if err != nil {
+					panic(err)
+				}
+				if storageID.Index == maxIndex {
+					break
+				}
+			}
+		}
+	}
+
+	accountStorage := make(map[StorageKey]atree.Storable, len(i.AccountStorage))
+	for key, storable := range i.AccountStorage {
+		accountStorage[key] = storable
+	}
+
+	dirty := make(map[atree.StorageID]struct{}, len(i.dirty))
+	for storageID := range i.dirty {
+		dirty[storageID] = struct{}{}
+	}
+
+	clone := i
+	clone.BasicSlabStorage = slabStorage
+	clone.AccountStorage = accountStorage
+	clone.dirty = dirty
+	clone.slabAllocations = &slabAllocationCounterHolder{}
+
+	if i.referenceCounts != nil {
+		clone.referenceCounts = make(map[StorageKey]int, len(i.referenceCounts))
+		for key, count := range i.referenceCounts {
+			clone.referenceCounts[key] = count
+		}
+	}
+
+	return clone
+}
+
+func (i InMemoryStorage) Store(id atree.StorageID, slab atree.Slab) error {
+	if i.maxSlabs > 0 {
+		// Overwriting an existing slab does not increase the number of slabs
+		if _, ok := i.Slabs[id]; !ok && len(i.Slabs) >= i.maxSlabs {
+			return SlabLimitExceededError{
+				Limit: i.maxSlabs,
+			}
+		}
+	}
+
+	err := i.BasicSlabStorage.Store(id, slab)
+	if err != nil {
+		return err
+	}
+	i.dirty[id] = struct{}{}
+	return nil
+}
+
+func (i InMemoryStorage) Remove(id atree.StorageID) error {
+	err := i.BasicSlabStorage.Remove(id)
+	if err != nil {
+		return err
+	}
+	i.dirty[id] = struct{}{}
+	return nil
+}
+
+func (i InMemoryStorage) GenerateStorageID(address atree.Address) (atree.StorageID, error) {
+	var storageID atree.StorageID
+	if i.storageIDAllocator != nil {
+		storageID = i.storageIDAllocator(address)
+	} else {
+		var err error
+		storageID, err = i.BasicSlabStorage.GenerateStorageID(address)
+		if err != nil {
+			return atree.StorageID{}, err
+		}
+	}
+
+	if i.slabAllocations != nil && i.slabAllocations.counter != nil {
+		err := i.slabAllocations.counter.Allocate(storageID)
+		if err != nil {
+			return atree.StorageID{}, err
+		}
+	}
+
+	return storageID, nil
+}
+
+// SetSlabAllocationCounter sets the counter which counts the slabs allocated by the storage,
+// or stops counting if the counter is nil, see SlabAllocationCountingStorage.
+//
+func (i InMemoryStorage) SetSlabAllocationCounter(counter *SlabAllocationCounter) {
+	i.slabAllocations.counter = counter
+}
+
+// DeterministicOrder returns true if the encoding of dictionaries
+// only depends on their entries, see WithDeterministicOrder.
+//
+func (i InMemoryStorage) DeterministicOrder() bool {
+	return i.deterministicOrder
+}
+
+type valueRemovingStorage interface {
+	RemoveValue(interpreter *Interpreter, address common.Address, key string, force bool) error
+}
+
+// EmptyContainerSentinels returns true if empty containers are stored
+// using the empty container sentinel, see WithEmptyContainerSentinels.
+//
+func (i InMemoryStorage) EmptyContainerSentinels() bool {
+	return i.emptyContainerSentinels
+}
+
+type emptyContainerSentinelStorage interface {
+	EmptyContainerSentinels() bool
+}
+
+// storesEmptyContainerSentinels returns true if the given storage stores empty containers
+// using the empty container sentinel.
+//
+// Storages which do not opt in, e.g. the account storage of the runtime,
+// always allocate the root slab of a stored container.
+//
+func storesEmptyContainerSentinels(storage atree.SlabStorage) bool {
+	sentinelStorage, ok := storage.(emptyContainerSentinelStorage)
+	return ok && sentinelStorage.EmptyContainerSentinels()
+}
+
+// EncodeDirty returns the encoded slabs which were stored or removed
+// since the storage was created, or since the last call to ClearDirty.
+//
+// Removed slabs are included with a nil encoding.
+//
+func (i InMemoryStorage) EncodeDirty() (map[atree.StorageID][]byte, error) {
+	result := make(map[atree.StorageID][]byte, len(i.dirty))
+
+	for id := range i.dirty {
+		slab, ok, err := i.BasicSlabStorage.Retrieve(id)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			result[id] = nil
+			continue
+		}
+
+		data, err := EncodeStorable(slab)
+		if err != nil {
+			return nil, err
+		}
+		result[id] = data
+	}
+
+	return result, nil
+}
+
+// EncodedSlab is an encoded slab and its storage ID.
+//
+type EncodedSlab struct {
+	ID    atree.StorageID
+	Bytes []byte
+}
+
+// EncodeOrdered returns all encoded slabs, sorted by storage ID.
+//
+// If deterministic order is enabled, each atree map, e.g. a dictionary,
+// is encoded as one canonical slab with the storage ID of its root slab,
+// see encodeCanonicalMap, and the other slabs of the map are omitted.
+//
+func (i InMemoryStorage) EncodeOrdered() ([]EncodedSlab, error) {
+	var slabs map[atree.StorageID][]byte
+	var err error
+	if i.DeterministicOrder() {
+		slabs, err = i.encodeCanonical()
+	} else {
+		slabs, err = i.Encode()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]EncodedSlab, 0, len(slabs))
+	for id, data := range slabs {
+		result = append(result, EncodedSlab{
+			ID:    id,
+			Bytes: data,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID.Compare(result[j].ID) < 0
+	})
+
+	return result, nil
+}
+
+// encodeCanonical encodes all slabs, like Encode,
+// but encodes atree maps canonically.
+//
+func (i InMemoryStorage) encodeCanonical() (map[atree.StorageID][]byte, error) {
+	result := make(map[atree.StorageID][]byte, len(i.Slabs))
+
+	for id, slab := range i.Slabs {
+		var data []byte
+		var err error
+
+		if mapSlab, ok := slab.(atree.MapSlab); ok {
+			// Only root slabs have extra data.
+			// The other slabs of the map are encoded as part of the root slab
+
+			extraData := mapSlab.ExtraData()
+			if extraData == nil {
+				continue
+			}
+
+			data, err = i.encodeCanonicalMap(id, extraData)
+		} else {
+			data, err = atree.Encode(slab, CBOREncMode)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		result[id] = data
+	}
+
+	return result, nil
+}
+
+// encodeCanonicalMap encodes the atree map with the given root slab
+// as an array of the type info, the count, the seed, and the entries,
+// with the keys and values interleaved, and sorted by the encoding of the key.
+//
+// The layout of the slabs of a map depends on the order in which the entries
+// were inserted and removed, whereas this encoding only depends on the entries.
+//
+func (i InMemoryStorage) encodeCanonicalMap(rootStorageID atree.StorageID, extraData *atree.MapExtraData) ([]byte, error) {
+
+	type entry struct {
+		key   []byte
+		value []byte
+	}
+
+	var entries []entry
+	var err error
+
+	rawMapStorables(
+		i,
+		rootStorageID,
+		func(key, value atree.Storable) bool {
+			var e entry
+
+			e.key, err = atree.Encode(key, CBOREncMode)
+			if err != nil {
+				return false
+			}
+
+			e.value, err = atree.Encode(value, CBOREncMode)
+			if err != nil {
+				return false
+			}
+
+			entries = append(entries, e)
+			return true
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].key, entries[j].key) < 0
+	})
+
+	var buf bytes.Buffer
+	enc := CBOREncMode.NewStreamEncoder(&buf)
+
+	err = enc.EncodeArrayHead(4)
+	if err != nil {
+		return nil, err
+	}
+
+	err = extraData.TypeInfo.Encode(enc)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.EncodeUint64(extraData.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.EncodeUint64(extraData.Seed)
+	if err != nil {
+		return nil, err
+	}
+
+	err = enc.EncodeArrayHead(uint64(len(entries) * 2))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		err = enc.EncodeRawBytes(e.key)
+		if err != nil {
+			return nil, err
+		}
+
+		err = enc.EncodeRawBytes(e.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = enc.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ClearDirty resets the set of stored or removed slabs
+// tracked for EncodeDirty.
+//
+func (i InMemoryStorage) ClearDirty() {
+	for id := range i.dirty {
+		delete(i.dirty, id)
 	}
 }
 
@@ -144,28 +712,91 @@ func DecodeTypeInfo(dec *cbor.StreamDecoder) (atree.TypeInfo, error) {
 	}
 }
 
-func (i InMemoryStorage) ValueExists(_ *Interpreter, address common.Address, key string) bool {
-	storageKey := StorageKey{
+// storageKey returns the account storage key for the given address and key,
+// hashing the key if key hashing is enabled.
+//
+func (i InMemoryStorage) storageKey(address common.Address, key string) StorageKey {
+	if i.keyHasher != nil {
+		key = i.keyHasher(key)
+	}
+
+	return StorageKey{
 		Address: address,
 		Key:     key,
 	}
+}
+
+func (i InMemoryStorage) ValueExists(_ *Interpreter, address common.Address, key string) bool {
+	storageKey := i.storageKey(address, key)
 	_, ok := i.AccountStorage[storageKey]
 	return ok
 }
 
-func (i InMemoryStorage) ReadValue(_ *Interpreter, address common.Address, key string) OptionalValue {
-	storageKey := StorageKey{
-		Address: address,
-		Key:     key,
+func (i InMemoryStorage) ReadValue(interpreter *Interpreter, address common.Address, key string) OptionalValue {
+	storageKey := i.storageKey(address, key)
+
+	storable, ok := i.AccountStorage[storageKey]
+	if !ok {
+		return Nil()
+	}
+
+	value := storedValue(&storageKey, storable, i)
+
+	if i.readReturnsCopy && !value.IsResourceKinded(interpreter) {
+		value = CopyValue(interpreter, value)
 	}
 
+	return NewSomeValueNonCopying(value)
+}
+
+// Borrow returns the value stored under the given key, without transferring it.
+// The returned value is read-only: mutating it, or any value nested in it, panics.
+//
+func (i InMemoryStorage) Borrow(_ *Interpreter, address common.Address, key string) (Value, bool) {
+	storageKey := i.storageKey(address, key)
+
 	storable, ok := i.AccountStorage[storageKey]
 	if !ok {
-		return NilValue{}
+		return nil, false
+	}
+
+	return ReadOnlyValue(storedValue(&storageKey, storable, i)), true
+}
+
+// ReadMany returns the values stored under the given keys, in the same order, like ReadValue.
+// Keys under which no value is stored result in nil.
+//
+// Each stored value is only loaded once, even if its key is given more than once.
+//
+func (i InMemoryStorage) ReadMany(interpreter *Interpreter, keys []StorageKey) []OptionalValue {
+	values := make([]OptionalValue, len(keys))
+
+	loaded := make(map[StorageKey]OptionalValue, len(keys))
+
+	for index, key := range keys {
+		value, ok := loaded[key]
+		if !ok {
+			value = i.ReadValue(interpreter, key.Address, key.Key)
+			loaded[key] = value
+		}
+
+		values[index] = value
 	}
 
-	storedValue := StoredValue(storable, i)
-	return NewSomeValueNonCopying(storedValue)
+	return values
+}
+
+// ReadOr returns the value stored under the given key, like ReadValue, but not wrapped in an optional.
+// If no value is stored under the key, the given fallback value is returned as-is,
+// i.e. it is not transferred or stored.
+//
+func (i InMemoryStorage) ReadOr(interpreter *Interpreter, address common.Address, key string, fallback Value) Value {
+	switch value := i.ReadValue(interpreter, address, key).(type) {
+	case *SomeValue:
+		return value.Value
+	default:
+		return fallback
+	}
 }
 
 func (i InMemoryStorage) WriteValue(
@@ -174,15 +805,108 @@ func (i InMemoryStorage) WriteValue(
 	key string,
 	value OptionalValue,
 ) {
-	storageKey := StorageKey{
-		Address: address,
-		Key:     key,
+	if _, ok := value.(NilValue); ok && i.explicitNil {
+		value = NewSomeValueNonCopying(value)
 	}
 
+	err := i.writeValue(interpreter, address, key, value, false)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RemoveValue removes the value stored under the given key, if any.
+//
+// If capability reference tracking is enabled and the value is the target of a link,
+// a ReferencedValueError is returned, unless the removal is forced.
+//
+func (i InMemoryStorage) RemoveValue(
+	interpreter *Interpreter,
+	address common.Address,
+	key string,
+	force bool,
+) error {
+	return i.writeValue(interpreter, address, key, Nil(), force)
+}
+
+// Swap exchanges the values stored under the two given keys of the given account.
+// The stored values are not decoded, only their storables are exchanged.
+// If no value is stored under one of the keys, the other value is moved.
+//
+// If capability reference tracking is enabled and a key which is the target of a link
+// would no longer have a value stored under it, a ReferencedValueError is returned.
+//
+func (i InMemoryStorage) Swap(address common.Address, keyA, keyB string) error {
+	storageKeyA := i.storageKey(address, keyA)
+	storageKeyB := i.storageKey(address, keyB)
+
+	storableA, okA := i.AccountStorage[storageKeyA]
+	storableB, okB := i.AccountStorage[storageKeyB]
+
+	if i.referenceCounts != nil {
+		if okA && !okB {
+			if count := i.referenceCounts[storageKeyA]; count > 0 {
+				return ReferencedValueError{
+					Address: address,
+					Key:     keyA,
+					Count:   count,
+				}
+			}
+		}
+		if okB && !okA {
+			if count := i.referenceCounts[storageKeyB]; count > 0 {
+				return ReferencedValueError{
+					Address: address,
+					Key:     keyB,
+					Count:   count,
+				}
+			}
+		}
+	}
+
+	if okB {
+		i.AccountStorage[storageKeyA] = storableB
+	} else {
+		delete(i.AccountStorage, storageKeyA)
+	}
+
+	if okA {
+		i.AccountStorage[storageKeyB] = storableA
+	} else {
+		delete(i.AccountStorage, storageKeyB)
+	}
+
+	return nil
+}
+
+func (i InMemoryStorage) writeValue(
+	interpreter *Interpreter,
+	address common.Address,
+	key string,
+	value OptionalValue,
+	force bool,
+) error {
+	storageKey := i.storageKey(address, key)
+
 	// Remove existing, if any
 
 	if existingStorable, ok := i.AccountStorage[storageKey]; ok {
-		StoredValue(existingStorable, i).DeepRemove(interpreter)
+		existingValue := storedValue(&storageKey, existingStorable, i)
+
+		if i.referenceCounts != nil {
+			count := i.referenceCounts[storageKey]
+			if count > 0 && !force {
+				return ReferencedValueError{
+					Address: address,
+					Key:     key,
+					Count:   count,
+				}
+			}
+
+			i.trackLinkReference(address, existingValue, -1)
+		}
+
+		existingValue.DeepRemove(interpreter)
 		interpreter.RemoveReferencedSlab(existingStorable)
 	}
 
@@ -202,18 +926,106 @@ func (i InMemoryStorage) WriteValue(
 			math.MaxUint64,
 		)
 		if err != nil {
-			panic(err)
+			return StorageOperationError{
+				Key: &storageKey,
+				Err: err,
+			}
 		}
 		i.AccountStorage[storageKey] = storable
 
+		if i.referenceCounts != nil {
+			i.trackLinkReference(address, value.Value, 1)
+		}
+
 	case NilValue:
 		// Remove entry
 		delete(i.AccountStorage, storageKey)
 	}
+
+	return nil
 }
 
+// trackLinkReference adjusts the reference count of the target of the given value
+// by the given delta, if the value is a link.
+//
+func (i InMemoryStorage) trackLinkReference(address common.Address, value Value, delta int) {
+	link, ok := value.(LinkValue)
+	if !ok {
+		return
+	}
+
+	targetKey := i.storageKey(address, PathToStorageKey(link.TargetPath))
+
+	count := i.referenceCounts[targetKey] + delta
+	if count > 0 {
+		i.referenceCounts[targetKey] = count
+	} else {
+		delete(i.referenceCounts, targetKey)
+	}
+}
+
+// CapabilityReferenceCount returns the number of links which target
+// the value stored under the given key.
+// The count is always zero if capability reference tracking is disabled.
+//
+func (i InMemoryStorage) CapabilityReferenceCount(address common.Address, key string) int {
+	return i.referenceCounts[i.storageKey(address, key)]
+}
+
+// UsageByAccount returns the total size of the encoded slabs owned by each account.
+//
+// Slabs which are not owned by an account, i.e. which have an undefined address, are ignored.
+//
+func (i InMemoryStorage) UsageByAccount() (map[common.Address]uint64, error) {
+	slabs, err := i.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[common.Address]uint64{}
+
+	for id, slab := range slabs {
+		if id.Address == atree.AddressUndefined {
+			continue
+		}
+
+		address := common.Address(id.Address)
+		result[address] += uint64(len(slab))
+	}
+
+	return result, nil
+}
+
+// SizeUnder returns the total size of all slabs, when encoded with the given encoding mode,
+// e.g. to compare the size of the storage under different encoding modes.
+//
+// The slabs are only encoded to determine the size, the storage is not modified.
+//
+func (i InMemoryStorage) SizeUnder(enc cbor.EncMode) (int, error) {
+	size := 0
+
+	for _, slab := range i.Slabs {
+		data, err := atree.Encode(slab, enc)
+		if err != nil {
+			return 0, err
+		}
+		size += len(data)
+	}
+
+	return size, nil
+}
+
+// CheckHealth checks the health of the slabs in the storage.
+//
+// The unallocated root slabs of empty container sentinels are checked as empty root slabs.
+//
 func (i InMemoryStorage) CheckHealth() error {
-	_, err := atree.CheckStorageHealth(i, -1)
+	storage, err := withEmptyContainerRoots(i)
+	if err != nil {
+		return err
+	}
+
+	_, err = atree.CheckStorageHealth(storage, -1)
 	return err
 }
 
@@ -236,6 +1048,21 @@ func mustStorableSize(storable atree.Storable) uint32 {
 }
 
 func StorableSize(storable atree.Storable) (uint32, error) {
+	profiler := loadCodecProfiler()
+	if profiler == nil {
+		return storableSize(storable)
+	}
+
+	start := time.Now()
+
+	size, err := storableSize(storable)
+
+	profiler(CodecOperationSize, int(size), time.Since(start))
+
+	return size, err
+}
+
+func storableSize(storable atree.Storable) (uint32, error) {
 	var writer writeCounter
 	enc := atree.NewEncoder(&writer, CBOREncMode)
 
@@ -261,6 +1088,10 @@ func StorableSize(storable atree.Storable) (uint32, error) {
 // if it it can be inlined, or else stores it in a separate slab
 // and returns a StorageIDStorable.
 //
+// If the storage is an in-memory storage which only allows inline storables,
+// storables up to and including the maximum inline size are inlined,
+// and an error is returned for larger storables.
+//
 func maybeLargeImmutableStorable(
 	storable atree.Storable,
 	storage atree.SlabStorage,
@@ -271,7 +1102,19 @@ func maybeLargeImmutableStorable(
 	error,
 ) {
 
-	if uint64(storable.ByteSize()) < maxInlineSize {
+	size := storable.ByteSize()
+
+	if inMemoryStorage, ok := storage.(InMemoryStorage); ok && inMemoryStorage.inlineOnly {
+		if uint64(size) > maxInlineSize {
+			return nil, NonInlinableStorableError{
+				Size:          size,
+				MaxInlineSize: maxInlineSize,
+			}
+		}
+		return storable, nil
+	}
+
+	if uint64(size) < maxInlineSize {
 		return storable, nil
 	}
 
@@ -19,8 +19,15 @@
 package interpreter_test
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
 	"testing"
 
+	"github.com/fxamacker/cbor/v2"
 	"github.com/onflow/atree"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -123,12 +130,12 @@ func TestArrayStorage(t *testing.T) {
 
 		require.NotEqual(t, atree.StorageIDUndefined, value.StorageID())
 
-		// array + composite
-		require.Equal(t, 2, storage.BasicSlabStorage.Count())
+		// composite, the root slab of the empty array is not allocated yet
+		require.Equal(t, 1, storage.BasicSlabStorage.Count())
 
 		_, ok, err := storage.BasicSlabStorage.Retrieve(value.StorageID())
 		require.NoError(t, err)
-		require.True(t, ok)
+		require.False(t, ok)
 
 		require.False(t, bool(value.Contains(nil, nil, element)))
 
@@ -244,11 +251,12 @@ func TestDictionaryStorage(t *testing.T) {
 
 		require.NotEqual(t, atree.StorageIDUndefined, value.StorageID())
 
-		require.Equal(t, 1, storage.BasicSlabStorage.Count())
+		// the root slab of the empty dictionary is not allocated yet
+		require.Equal(t, 0, storage.BasicSlabStorage.Count())
 
 		_, ok, err := storage.BasicSlabStorage.Retrieve(value.StorageID())
 		require.NoError(t, err)
-		require.True(t, ok)
+		require.False(t, ok)
 
 		entryKey := NewStringValue("test")
 		entryValue := BoolValue(true)
@@ -397,11 +405,12 @@ func TestDictionaryStorage(t *testing.T) {
 
 		require.NotEqual(t, atree.StorageIDUndefined, value.StorageID())
 
-		require.Equal(t, 1, storage.BasicSlabStorage.Count())
+		// the root slab of the empty dictionary is not allocated yet
+		require.Equal(t, 0, storage.BasicSlabStorage.Count())
 
 		_, ok, err := storage.BasicSlabStorage.Retrieve(value.StorageID())
 		require.NoError(t, err)
-		require.True(t, ok)
+		require.False(t, ok)
 
 		value.Insert(
 			inter,
@@ -477,3 +486,1684 @@ func TestStorageOverwriteAndRemove(t *testing.T) {
 
 	assert.Len(t, storage.Slabs, 0)
 }
+
+func TestStorageKeyHashing(t *testing.T) {
+
+	t.Parallel()
+
+	hasher := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+
+	storage := NewInMemoryStorage(WithKeyHashing(hasher))
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+	key := strings.Repeat("test", 100)
+
+	require.False(t, storage.ValueExists(inter, address, key))
+
+	storage.WriteValue(
+		inter,
+		address,
+		key,
+		NewSomeValueNonCopying(NewStringValue("value")),
+	)
+
+	require.True(t, storage.ValueExists(inter, address, key))
+
+	// The account storage contains the hashed key only
+
+	require.Len(t, storage.AccountStorage, 1)
+
+	_, ok := storage.AccountStorage[StorageKey{
+		Address: address,
+		Key:     hasher(key),
+	}]
+	require.True(t, ok)
+
+	value := storage.ReadValue(inter, address, key)
+	require.IsType(t, &SomeValue{}, value)
+
+	RequireValuesEqual(
+		t,
+		inter,
+		NewStringValue("value"),
+		value.(*SomeValue).Value,
+	)
+
+	storage.WriteValue(inter, address, key, NilValue{})
+
+	require.False(t, storage.ValueExists(inter, address, key))
+	require.Empty(t, storage.AccountStorage)
+}
+
+func TestStorageEncodeDirty(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	newArray := func() *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			address,
+			NewStringValue("test"),
+		)
+	}
+
+	array1 := newArray()
+	array2 := newArray()
+
+	dirty, err := storage.EncodeDirty()
+	require.NoError(t, err)
+	require.Len(t, dirty, 2)
+
+	storage.ClearDirty()
+
+	dirty, err = storage.EncodeDirty()
+	require.NoError(t, err)
+	require.Empty(t, dirty)
+
+	// Mutating a value only marks its slabs dirty
+
+	array1.Append(inter, ReturnEmptyLocationRange, BoolValue(true))
+
+	dirty, err = storage.EncodeDirty()
+	require.NoError(t, err)
+	require.Len(t, dirty, 1)
+	require.Contains(t, dirty, array1.StorageID())
+	require.NotNil(t, dirty[array1.StorageID()])
+
+	storage.ClearDirty()
+
+	// Removing a value marks its slabs as deleted
+
+	array2.DeepRemove(inter)
+	err = storage.Remove(array2.StorageID())
+	require.NoError(t, err)
+
+	dirty, err = storage.EncodeDirty()
+	require.NoError(t, err)
+	require.Len(t, dirty, 1)
+	require.Contains(t, dirty, array2.StorageID())
+	require.Nil(t, dirty[array2.StorageID()])
+}
+
+func TestStorageInlineOnly(t *testing.T) {
+
+	t.Parallel()
+
+	// Find the largest string which can be inlined into an array
+
+	var largestInlinableString string
+	for length := 1; ; length++ {
+		str := strings.Repeat("x", length)
+		if uint64(NewStringValue(str).ByteSize()) > atree.MaxInlineArrayElementSize {
+			break
+		}
+		largestInlinableString = str
+	}
+
+	newArray := func(t *testing.T, storage InMemoryStorage, str string) (array *ArrayValue, err error) {
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			if r := recover(); r != nil {
+				externalError, ok := r.(ExternalError)
+				require.True(t, ok)
+				err = externalError.Recovered.(error)
+			}
+		}()
+
+		array = NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			common.Address{0x1},
+			NewStringValue("test"),
+			NewStringValue(str),
+		)
+		return array, nil
+	}
+
+	t.Run("default", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage()
+
+		_, err := newArray(t, storage, largestInlinableString)
+		require.NoError(t, err)
+
+		require.Equal(t, 2, storage.Count())
+	})
+
+	t.Run("inline only", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage(WithInlineOnly(true))
+
+		array, err := newArray(t, storage, largestInlinableString)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, storage.Count())
+		require.Equal(t, 2, array.Count())
+	})
+
+	t.Run("inline only, too large", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage(WithInlineOnly(true))
+
+		_, err := newArray(t, storage, largestInlinableString+"x")
+		require.Error(t, err)
+		require.ErrorAs(t, err, &NonInlinableStorableError{})
+	})
+}
+
+func TestStorageUsageByAccount(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address1 := common.Address{0x1}
+	address2 := common.Address{0x2}
+
+	newArray := func(address common.Address, count int) {
+		elements := make([]Value, count)
+		for i := range elements {
+			elements[i] = NewStringValue(strings.Repeat("x", 100))
+		}
+
+		NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			address,
+			elements...,
+		)
+	}
+
+	newArray(address1, 1)
+	newArray(address2, 100)
+
+	// Not owned by any account
+	newArray(common.Address{}, 1)
+
+	usage, err := storage.UsageByAccount()
+	require.NoError(t, err)
+
+	require.Len(t, usage, 2)
+	require.NotZero(t, usage[address1])
+	require.NotZero(t, usage[address2])
+	require.Greater(t, usage[address2], usage[address1])
+
+	slabs, err := storage.Encode()
+	require.NoError(t, err)
+
+	var total uint64
+	for id, slab := range slabs {
+		if id.Address == atree.AddressUndefined {
+			continue
+		}
+		total += uint64(len(slab))
+	}
+
+	require.Equal(t, total, usage[address1]+usage[address2])
+}
+
+func TestStorageSizeUnder(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	const count = 100
+
+	elements := make([]Value, count)
+	for i := 0; i < count; i++ {
+		elements[i] = NewUInt256ValueFromUint64(uint64(i))
+	}
+
+	_ = NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeUInt256,
+		},
+		common.Address{0x1},
+		elements...,
+	)
+
+	encoded, err := storage.Encode()
+	require.NoError(t, err)
+
+	var encodedSize int
+	for _, data := range encoded {
+		encodedSize += len(data)
+	}
+
+	size, err := storage.SizeUnder(CBOREncMode)
+	require.NoError(t, err)
+	require.Equal(t, encodedSize, size)
+
+	otherSize, err := storage.SizeUnder(CBOREncMode)
+	require.NoError(t, err)
+	require.Equal(t, size, otherSize)
+
+	// Encoding big integers in their shortest form results in a smaller size
+
+	shortestOptions := cbor.CanonicalEncOptions()
+	shortestOptions.BigIntConvert = cbor.BigIntConvertShortest
+	shortestEncMode, err := shortestOptions.EncMode()
+	require.NoError(t, err)
+
+	shortestSize, err := storage.SizeUnder(shortestEncMode)
+	require.NoError(t, err)
+	require.Less(t, shortestSize, size)
+
+	// The storage is not modified
+
+	reencoded, err := storage.Encode()
+	require.NoError(t, err)
+	require.Equal(t, encoded, reencoded)
+}
+
+func TestStorageCapabilityReferenceTracking(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	targetPath := PathValue{
+		Domain:     common.PathDomainStorage,
+		Identifier: "target",
+	}
+	targetKey := PathToStorageKey(targetPath)
+
+	linkPath := PathValue{
+		Domain:     common.PathDomainPublic,
+		Identifier: "link",
+	}
+	linkKey := PathToStorageKey(linkPath)
+
+	setup := func(t *testing.T) (*Interpreter, InMemoryStorage) {
+
+		storage := NewInMemoryStorage(WithCapabilityReferenceTracking(true))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		storage.WriteValue(
+			inter,
+			address,
+			targetKey,
+			NewSomeValueNonCopying(NewStringValue("test")),
+		)
+
+		require.Equal(t, 0, storage.CapabilityReferenceCount(address, targetKey))
+
+		storage.WriteValue(
+			inter,
+			address,
+			linkKey,
+			NewSomeValueNonCopying(
+				LinkValue{
+					TargetPath: targetPath,
+					Type:       PrimitiveStaticTypeString,
+				},
+			),
+		)
+
+		require.Equal(t, 1, storage.CapabilityReferenceCount(address, targetKey))
+
+		return inter, storage
+	}
+
+	t.Run("referenced, refused", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		err := storage.RemoveValue(inter, address, targetKey, false)
+		require.ErrorAs(t, err, &ReferencedValueError{})
+
+		require.PanicsWithValue(t,
+			ReferencedValueError{
+				Address: address,
+				Key:     targetKey,
+				Count:   1,
+			},
+			func() {
+				storage.WriteValue(inter, address, targetKey, NilValue{})
+			},
+		)
+
+		require.True(t, storage.ValueExists(inter, address, targetKey))
+	})
+
+	t.Run("referenced, forced", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		err := storage.RemoveValue(inter, address, targetKey, true)
+		require.NoError(t, err)
+
+		require.False(t, storage.ValueExists(inter, address, targetKey))
+	})
+
+	t.Run("unlinked", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		storage.WriteValue(inter, address, linkKey, NilValue{})
+
+		require.Equal(t, 0, storage.CapabilityReferenceCount(address, targetKey))
+
+		err := storage.RemoveValue(inter, address, targetKey, false)
+		require.NoError(t, err)
+
+		require.False(t, storage.ValueExists(inter, address, targetKey))
+	})
+}
+
+func TestStorageEncodeOrdered(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			common.Address{byte(10 - i)},
+			NewStringValue(strings.Repeat("x", i)),
+		)
+	}
+
+	slabs1, err := storage.EncodeOrdered()
+	require.NoError(t, err)
+	require.Len(t, slabs1, 10)
+
+	slabs2, err := storage.EncodeOrdered()
+	require.NoError(t, err)
+
+	require.Equal(t, slabs1, slabs2)
+
+	for i := 1; i < len(slabs1); i++ {
+		require.Equal(t, -1, slabs1[i-1].ID.Compare(slabs1[i].ID))
+	}
+}
+
+func TestStorageBorrow(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	setup := func(t *testing.T) (*Interpreter, InMemoryStorage) {
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		innerType := VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		}
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: innerType,
+			},
+			address,
+			NewArrayValue(
+				inter,
+				innerType,
+				address,
+				NewIntValueFromInt64(1),
+				NewIntValueFromInt64(2),
+			),
+		)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(array))
+
+		return inter, storage
+	}
+
+	requireUntouched := func(t *testing.T, inter *Interpreter, storage InMemoryStorage) {
+		value := storage.ReadValue(inter, address, "test").(*SomeValue).Value.(*ArrayValue)
+		require.Equal(t, 1, value.Count())
+
+		inner := value.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+		require.Equal(t, 2, inner.Count())
+		require.Equal(t,
+			NewIntValueFromInt64(1),
+			inner.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+	}
+
+	t.Run("missing", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		value, ok := storage.Borrow(inter, address, "missing")
+		require.False(t, ok)
+		require.Nil(t, value)
+	})
+
+	t.Run("read", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		value, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		array := value.(*ArrayValue)
+		require.True(t, array.IsReadOnly())
+		require.Equal(t, 1, array.Count())
+
+		inner := array.Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+		require.True(t, inner.IsReadOnly())
+		require.Equal(t, 2, inner.Count())
+
+		requireUntouched(t, inter, storage)
+	})
+
+	t.Run("mutation", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		value, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		array := value.(*ArrayValue)
+
+		func() {
+			defer func() {
+				require.IsType(t, ReadOnlyValueMutationError{}, recover())
+			}()
+
+			array.Remove(inter, ReturnEmptyLocationRange, 0)
+		}()
+
+		requireUntouched(t, inter, storage)
+	})
+
+	t.Run("nested mutation", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		value, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		inner := value.(*ArrayValue).Get(inter, ReturnEmptyLocationRange, 0).(*ArrayValue)
+
+		func() {
+			defer func() {
+				require.IsType(t, ReadOnlyValueMutationError{}, recover())
+			}()
+
+			inner.Set(inter, ReturnEmptyLocationRange, 0, NewIntValueFromInt64(3))
+		}()
+
+		requireUntouched(t, inter, storage)
+	})
+
+	t.Run("iterated children", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		value, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		array := value.(*ArrayValue)
+
+		var children []Value
+		array.Iterate(func(element Value) (resume bool) {
+			children = append(children, element)
+			return true
+		})
+		array.Walk(func(element Value) {
+			children = append(children, element)
+		})
+		require.Len(t, children, 2)
+
+		for _, child := range children {
+			inner := child.(*ArrayValue)
+			require.True(t, inner.IsReadOnly())
+
+			require.PanicsWithValue(t,
+				ReadOnlyValueMutationError{},
+				func() {
+					inner.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(3))
+				},
+			)
+		}
+
+		requireUntouched(t, inter, storage)
+	})
+
+	t.Run("deep remove", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		value, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		require.PanicsWithValue(t,
+			ReadOnlyValueMutationError{},
+			func() {
+				value.DeepRemove(inter)
+			},
+		)
+
+		err := DeepRemoveAll(inter, storage, []Value{value})
+		require.Equal(t, ReadOnlyValueMutationError{}, err)
+
+		requireUntouched(t, inter, storage)
+	})
+
+	t.Run("composite", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		composite := NewCompositeValue(
+			inter,
+			TestLocation,
+			"Test",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name: "array",
+					Value: NewArrayValue(
+						inter,
+						VariableSizedStaticType{
+							Type: PrimitiveStaticTypeInt,
+						},
+						common.Address{},
+						NewIntValueFromInt64(1),
+					),
+				},
+			},
+			address,
+		)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(composite))
+
+		value, ok := storage.Borrow(inter, address, "test")
+		require.True(t, ok)
+
+		borrowed := value.(*CompositeValue)
+
+		require.PanicsWithValue(t,
+			ReadOnlyValueMutationError{},
+			func() {
+				borrowed.RemoveField(inter, ReturnEmptyLocationRange, "array")
+			},
+		)
+
+		borrowed.ForEachField(func(_ string, fieldValue Value) {
+			array := fieldValue.(*ArrayValue)
+			require.True(t, array.IsReadOnly())
+
+			require.PanicsWithValue(t,
+				ReadOnlyValueMutationError{},
+				func() {
+					array.Remove(inter, ReturnEmptyLocationRange, 0)
+				},
+			)
+		})
+
+		field := composite.GetField(inter, ReturnEmptyLocationRange, "array").(*ArrayValue)
+		require.False(t, field.IsReadOnly())
+		require.Equal(t, 1, field.Count())
+	})
+}
+
+func TestStorageDeterministicOrder(t *testing.T) {
+
+	t.Parallel()
+
+	const count = 300
+
+	address := common.Address{0x1}
+
+	dictionaryType := DictionaryStaticType{
+		KeyType:   PrimitiveStaticTypeString,
+		ValueType: PrimitiveStaticTypeInt,
+	}
+
+	build := func(t *testing.T, seed int64) []EncodedSlab {
+
+		storage := NewInMemoryStorage(WithDeterministicOrder(true))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		dictionary := NewDictionaryValueWithAddress(inter, dictionaryType, address)
+
+		r := rand.New(rand.NewSource(seed))
+
+		for _, i := range r.Perm(count) {
+			key := NewStringValue(fmt.Sprintf("key%d", i))
+
+			// Insert some entries which are removed again, and overwrite others
+
+			switch r.Intn(3) {
+			case 0:
+				removedKey := NewStringValue(fmt.Sprintf("removed%d", i))
+				dictionary.Insert(inter, ReturnEmptyLocationRange, removedKey, NewIntValueFromInt64(0))
+				dictionary.Remove(inter, ReturnEmptyLocationRange, removedKey)
+
+			case 1:
+				dictionary.Insert(inter, ReturnEmptyLocationRange, key, NewIntValueFromInt64(0))
+			}
+
+			dictionary.Insert(inter, ReturnEmptyLocationRange, key, NewIntValueFromInt64(int64(i)))
+		}
+
+		require.Equal(t, count, dictionary.Count())
+
+		// The dictionary spans multiple slabs
+
+		require.Greater(t, storage.Count(), 2)
+
+		encoded, err := storage.EncodeOrdered()
+		require.NoError(t, err)
+
+		return encoded
+	}
+
+	expected := build(t, 0)
+
+	// The dictionary is encoded as one canonical slab
+
+	require.Len(t, expected, 1)
+
+	for seed := int64(1); seed <= 3; seed++ {
+		require.Equal(t, expected, build(t, seed))
+	}
+
+	t.Run("storage IDs are not reused", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage(WithDeterministicOrder(true))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		removed := NewDictionaryValueWithAddress(inter, dictionaryType, address)
+		removedStorageID := removed.StorageID()
+
+		removed.DeepRemove(inter)
+		inter.RemoveReferencedSlab(atree.StorageIDStorable(removedStorageID))
+
+		dictionary := NewDictionaryValueWithAddress(inter, dictionaryType, address)
+		require.NotEqual(t, removedStorageID, dictionary.StorageID())
+	})
+}
+
+func TestStorageIDAllocator(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	newCounterAllocator := func() func(address atree.Address) atree.StorageID {
+		counters := map[atree.Address]uint64{}
+
+		return func(address atree.Address) atree.StorageID {
+			counters[address]++
+
+			var index atree.StorageIndex
+			binary.BigEndian.PutUint64(index[:], 0x100+counters[address])
+			return atree.NewStorageID(address, index)
+		}
+	}
+
+	build := func(t *testing.T) ([]EncodedSlab, atree.StorageID) {
+		storage := NewInMemoryStorage(
+			WithStorageIDAllocator(newCounterAllocator()),
+		)
+
+		inter, err := NewInterpreter(
+			nil,
+			TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		// Large strings are stored in separate slabs
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			address,
+			NewStringValue(strings.Repeat("a", 2048)),
+			NewStringValue(strings.Repeat("b", 2048)),
+		)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(array))
+
+		encoded, err := storage.EncodeOrdered()
+		require.NoError(t, err)
+
+		return encoded, array.StorageID()
+	}
+
+	expected, arrayID := build(t)
+
+	require.Len(t, expected, 3)
+
+	var firstIndex atree.StorageIndex
+	binary.BigEndian.PutUint64(firstIndex[:], 0x101)
+	require.Equal(t, atree.NewStorageID(atree.Address(address), firstIndex), arrayID)
+
+	for _, slab := range expected {
+		index := binary.BigEndian.Uint64(slab.ID.Index[:])
+		require.Greater(t, index, uint64(0x100))
+	}
+
+	for i := 0; i < 3; i++ {
+		encoded, _ := build(t)
+		require.Equal(t, expected, encoded)
+	}
+}
+
+func TestStorageReadView(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	storage.WriteValue(
+		inter,
+		address,
+		"array",
+		NewSomeValueNonCopying(
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeAnyStruct,
+				},
+				address,
+				NewStringValue("first"),
+			),
+		),
+	)
+
+	view := storage.ReadView()
+
+	// Write to the base storage after the snapshot was taken
+
+	array := storage.ReadValue(inter, address, "array").(*SomeValue).Value.(*ArrayValue)
+	array.Append(inter, ReturnEmptyLocationRange, NewStringValue("second"))
+
+	storage.WriteValue(
+		inter,
+		address,
+		"other",
+		NewSomeValueNonCopying(NewStringValue("other")),
+	)
+
+	require.Equal(t, 2, array.Count())
+	require.True(t, storage.ValueExists(inter, address, "other"))
+
+	// The view still returns the old data
+
+	require.False(t, view.ValueExists(inter, address, "other"))
+	require.Equal(t, Nil(), view.ReadValue(inter, address, "other"))
+
+	viewValue := view.ReadValue(inter, address, "array")
+	require.IsType(t, &SomeValue{}, viewValue)
+
+	viewArray := viewValue.(*SomeValue).Value.(*ArrayValue)
+	require.Equal(t, 1, viewArray.Count())
+
+	RequireValuesEqual(
+		t,
+		inter,
+		NewStringValue("first"),
+		viewArray.Get(inter, ReturnEmptyLocationRange, 0),
+	)
+
+	require.NoError(t, view.CheckHealth())
+
+	// Writing through the view is disallowed
+
+	require.PanicsWithValue(t,
+		ReadOnlyStorageError{},
+		func() {
+			view.WriteValue(inter, address, "other", Nil())
+		},
+	)
+
+	require.Panics(t, func() {
+		viewArray.Append(inter, ReturnEmptyLocationRange, NewStringValue("second"))
+	})
+
+	require.Equal(t, 1, view.Count())
+}
+
+func TestStorageReadOr(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	storage.WriteValue(
+		inter,
+		address,
+		"present",
+		NewSomeValueNonCopying(NewStringValue("stored")),
+	)
+
+	newFallback := func() *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			common.Address{},
+			NewStringValue("fallback"),
+		)
+	}
+
+	t.Run("present", func(t *testing.T) {
+
+		fallback := newFallback()
+
+		value := storage.ReadOr(inter, address, "present", fallback)
+
+		RequireValuesEqual(t, inter, NewStringValue("stored"), value)
+	})
+
+	t.Run("absent", func(t *testing.T) {
+
+		fallback := newFallback()
+
+		slabCount := storage.Count()
+
+		value := storage.ReadOr(inter, address, "absent", fallback)
+
+		// The fallback is returned as-is, it is neither transferred nor stored
+
+		require.Same(t, fallback, value)
+		require.Equal(t, common.Address{}, fallback.GetOwner())
+		require.Equal(t, slabCount, storage.Count())
+		require.False(t, storage.ValueExists(inter, address, "absent"))
+	})
+}
+
+func TestStorageMaxSlabs(t *testing.T) {
+
+	t.Parallel()
+
+	const maxSlabs = 5
+
+	storage := NewInMemoryStorage(WithMaxSlabs(maxSlabs))
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	newArray := func(i int) *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			address,
+			NewStringValue(fmt.Sprintf("value%d", i)),
+		)
+	}
+
+	// Each array is stored in a single slab
+
+	tryWrite := func(i int) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				if externalErr, ok := r.(ExternalError); ok {
+					r = externalErr.Recovered
+				}
+				err = r.(error)
+			}
+		}()
+
+		storage.WriteValue(
+			inter,
+			address,
+			fmt.Sprintf("array%d", i),
+			NewSomeValueNonCopying(newArray(i)),
+		)
+
+		return nil
+	}
+
+	for i := 0; i < maxSlabs; i++ {
+		require.NoError(t, tryWrite(i))
+	}
+
+	require.Equal(t, maxSlabs, storage.Count())
+
+	err = tryWrite(maxSlabs)
+	var slabLimitErr SlabLimitExceededError
+	require.ErrorAs(t, err, &slabLimitErr)
+	require.Equal(t, maxSlabs, slabLimitErr.Limit)
+
+	// Reads still work
+
+	for i := 0; i < maxSlabs; i++ {
+		array := storage.ReadValue(inter, address, fmt.Sprintf("array%d", i)).(*SomeValue).Value.(*ArrayValue)
+		RequireValuesEqual(
+			t,
+			inter,
+			NewStringValue(fmt.Sprintf("value%d", i)),
+			array.Get(inter, ReturnEmptyLocationRange, 0),
+		)
+	}
+
+	// Removing a value frees the slab budget
+
+	err = storage.RemoveValue(inter, address, "array0", false)
+	require.NoError(t, err)
+
+	require.NoError(t, tryWrite(maxSlabs))
+}
+
+func TestStorageReadMany(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address1 := common.Address{0x1}
+	address2 := common.Address{0x2}
+
+	storage.WriteValue(inter, address1, "a", NewSomeValueNonCopying(NewStringValue("a")))
+	storage.WriteValue(inter, address2, "b", NewSomeValueNonCopying(NewStringValue("b")))
+
+	values := storage.ReadMany(
+		inter,
+		[]StorageKey{
+			{Address: address1, Key: "missing"},
+			{Address: address1, Key: "a"},
+			{Address: address1, Key: "b"},
+			{Address: address2, Key: "b"},
+			{Address: address1, Key: "a"},
+		},
+	)
+
+	require.Len(t, values, 5)
+
+	require.Equal(t, Nil(), values[0])
+	RequireValuesEqual(t, inter, NewSomeValueNonCopying(NewStringValue("a")), values[1])
+	require.Equal(t, Nil(), values[2])
+	RequireValuesEqual(t, inter, NewSomeValueNonCopying(NewStringValue("b")), values[3])
+	RequireValuesEqual(t, inter, NewSomeValueNonCopying(NewStringValue("a")), values[4])
+
+	require.Empty(t, storage.ReadMany(inter, nil))
+}
+
+type failingStorable struct {
+	err error
+}
+
+var _ atree.Storable = failingStorable{}
+
+func (s failingStorable) Encode(_ *atree.Encoder) error {
+	return s.err
+}
+
+func (failingStorable) ByteSize() uint32 {
+	return 1
+}
+
+func (s failingStorable) StoredValue(_ atree.SlabStorage) (atree.Value, error) {
+	return nil, s.err
+}
+
+func (failingStorable) ChildStorables() []atree.Storable {
+	return nil
+}
+
+func TestStorageOperationError(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	newStorage := func(t *testing.T) (InMemoryStorage, *Interpreter) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		return storage, inter
+	}
+
+	recoverStorageOperationError := func(t *testing.T, f func()) (storageErr StorageOperationError) {
+		defer func() {
+			r := recover()
+			require.IsType(t, StorageOperationError{}, r)
+			storageErr = r.(StorageOperationError)
+		}()
+
+		f()
+
+		return
+	}
+
+	t.Run("failing storable", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter := newStorage(t)
+
+		injectedErr := fmt.Errorf("injected failure")
+
+		storageKey := StorageKey{
+			Address: address,
+			Key:     "value",
+		}
+		storage.AccountStorage[storageKey] = failingStorable{err: injectedErr}
+
+		storageErr := recoverStorageOperationError(t, func() {
+			storage.ReadValue(inter, address, "value")
+		})
+
+		require.Equal(t, &storageKey, storageErr.Key)
+		require.Equal(t, atree.StorageIDUndefined, storageErr.StorageID)
+		require.ErrorIs(t, storageErr, injectedErr)
+		require.Contains(t, storageErr.Error(), "value")
+
+		// Overwriting the value loads the existing value, which fails
+
+		storageErr = recoverStorageOperationError(t, func() {
+			storage.WriteValue(inter, address, "value", NewSomeValueNonCopying(NewStringValue("new")))
+		})
+
+		require.Equal(t, &storageKey, storageErr.Key)
+		require.ErrorIs(t, storageErr, injectedErr)
+	})
+
+	t.Run("missing slab", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter := newStorage(t)
+
+		storageID := atree.StorageID{
+			Address: atree.Address(address),
+			Index:   atree.StorageIndex{0, 0, 0, 0, 0, 0, 0, 42},
+		}
+
+		storageKey := StorageKey{
+			Address: address,
+			Key:     "value",
+		}
+		storage.AccountStorage[storageKey] = atree.StorageIDStorable(storageID)
+
+		storageErr := recoverStorageOperationError(t, func() {
+			storage.ReadValue(inter, address, "value")
+		})
+
+		require.Equal(t, &storageKey, storageErr.Key)
+		require.Equal(t, storageID, storageErr.StorageID)
+		require.Contains(t, storageErr.Error(), storageID.String())
+	})
+}
+
+func TestStorageSwap(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	setup := func(t *testing.T, options ...InMemoryStorageOption) (*Interpreter, InMemoryStorage) {
+
+		storage := NewInMemoryStorage(options...)
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		return inter, storage
+	}
+
+	newArray := func(inter *Interpreter) *ArrayValue {
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			common.Address{},
+			NewStringValue("a"),
+			NewStringValue("b"),
+		)
+	}
+
+	t.Run("both present", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		storage.WriteValue(inter, address, "a", NewSomeValueNonCopying(newArray(inter)))
+		storage.WriteValue(inter, address, "b", NewSomeValueNonCopying(NewStringValue("test")))
+
+		expectedArray := newArray(inter)
+
+		slabCount := storage.Count()
+
+		err := storage.Swap(address, "a", "b")
+		require.NoError(t, err)
+
+		RequireValuesEqual(t,
+			inter,
+			NewSomeValueNonCopying(NewStringValue("test")),
+			storage.ReadValue(inter, address, "a"),
+		)
+		RequireValuesEqual(t,
+			inter,
+			NewSomeValueNonCopying(expectedArray),
+			storage.ReadValue(inter, address, "b"),
+		)
+
+		// No slabs are created or removed
+
+		require.Equal(t, slabCount, storage.Count())
+	})
+
+	t.Run("one absent", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t)
+
+		storage.WriteValue(inter, address, "a", NewSomeValueNonCopying(newArray(inter)))
+
+		err := storage.Swap(address, "a", "b")
+		require.NoError(t, err)
+
+		require.False(t, storage.ValueExists(inter, address, "a"))
+		RequireValuesEqual(t,
+			inter,
+			NewSomeValueNonCopying(newArray(inter)),
+			storage.ReadValue(inter, address, "b"),
+		)
+
+		// Swapping back moves the value back
+
+		err = storage.Swap(address, "a", "b")
+		require.NoError(t, err)
+
+		require.True(t, storage.ValueExists(inter, address, "a"))
+		require.False(t, storage.ValueExists(inter, address, "b"))
+	})
+
+	t.Run("referenced", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := setup(t, WithCapabilityReferenceTracking(true))
+
+		targetPath := PathValue{
+			Domain:     common.PathDomainStorage,
+			Identifier: "target",
+		}
+		targetKey := PathToStorageKey(targetPath)
+
+		storage.WriteValue(inter, address, targetKey, NewSomeValueNonCopying(NewStringValue("test")))
+		storage.WriteValue(
+			inter,
+			address,
+			"link",
+			NewSomeValueNonCopying(
+				LinkValue{
+					TargetPath: targetPath,
+					Type:       PrimitiveStaticTypeString,
+				},
+			),
+		)
+
+		err := storage.Swap(address, targetKey, "other")
+		require.Equal(t,
+			ReferencedValueError{
+				Address: address,
+				Key:     targetKey,
+				Count:   1,
+			},
+			err,
+		)
+
+		require.True(t, storage.ValueExists(inter, address, targetKey))
+		require.False(t, storage.ValueExists(inter, address, "other"))
+	})
+}
+
+func TestStorageReadReturnsCopy(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	test := func(t *testing.T, readReturnsCopy bool) int {
+
+		storage := NewInMemoryStorage(WithReadReturnsCopy(readReturnsCopy))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(1),
+			NewIntValueFromInt64(2),
+		).Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(address),
+			true,
+			nil,
+		)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(array))
+
+		// Mutate the read value
+
+		readArray := storage.ReadValue(inter, address, "test").(*SomeValue).Value.(*ArrayValue)
+
+		readArray.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(3))
+
+		// Read the stored value again
+
+		storedArray := storage.ReadValue(inter, address, "test").(*SomeValue).Value.(*ArrayValue)
+
+		return storedArray.Count()
+	}
+
+	t.Run("copy", func(t *testing.T) {
+
+		t.Parallel()
+
+		// The stored value is unchanged
+
+		require.Equal(t, 2, test(t, true))
+	})
+
+	t.Run("reference", func(t *testing.T) {
+
+		t.Parallel()
+
+		// The stored value is mutated
+
+		require.Equal(t, 3, test(t, false))
+	})
+
+	t.Run("copy owned by caller", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage(WithReadReturnsCopy(true))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(1),
+		).Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(address),
+			true,
+			nil,
+		)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(array))
+
+		slabCount := storage.Count()
+
+		// Each copy allocates slabs, which are freed when the caller removes the copy
+
+		copies := make([]Value, 0, 10)
+		for i := 0; i < 10; i++ {
+			copies = append(
+				copies,
+				storage.ReadValue(inter, address, "test").(*SomeValue).Value,
+			)
+		}
+
+		require.Greater(t, storage.Count(), slabCount)
+
+		err = DeepRemoveAll(inter, storage, copies)
+		require.NoError(t, err)
+
+		require.Equal(t, slabCount, storage.Count())
+	})
+
+	t.Run("resource", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage(WithReadReturnsCopy(true))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		resource := NewCompositeValue(
+			inter,
+			TestLocation,
+			"R",
+			common.CompositeKindResource,
+			nil,
+			address,
+		)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(resource))
+
+		slabCount := storage.Count()
+
+		// Resources are not copied, the stored resource is returned
+
+		readResource := storage.ReadValue(inter, address, "test").(*SomeValue).Value.(*CompositeValue)
+
+		require.Equal(t, resource.StorageID(), readResource.StorageID())
+		require.Equal(t, address, readResource.GetOwner())
+		require.Equal(t, slabCount, storage.Count())
+	})
+}
+
+func TestStorageClone(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	array := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		},
+		common.Address{},
+		NewIntValueFromInt64(1),
+		NewIntValueFromInt64(2),
+	).Transfer(
+		inter,
+		ReturnEmptyLocationRange,
+		atree.Address(address),
+		true,
+		nil,
+	)
+
+	storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(array))
+
+	slabCount := storage.Count()
+
+	clone := storage.Clone()
+
+	require.Equal(t, slabCount, clone.Count())
+
+	cloneInter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(clone),
+	)
+	require.NoError(t, err)
+
+	// Mutate the clone.
+	// Appending many elements allocates new slabs
+
+	clonedArray := clone.ReadValue(cloneInter, address, "test").(*SomeValue).Value.(*ArrayValue)
+
+	const count = 1000
+
+	for i := 0; i < count; i++ {
+		clonedArray.Append(cloneInter, ReturnEmptyLocationRange, NewIntValueFromInt64(int64(i)))
+	}
+
+	clone.WriteValue(cloneInter, address, "other", NewSomeValueNonCopying(NewStringValue("other")))
+
+	require.Greater(t, clone.Count(), slabCount)
+	require.NoError(t, clone.CheckHealth())
+
+	// The original is unchanged
+
+	require.Equal(t, slabCount, storage.Count())
+	require.NoError(t, storage.CheckHealth())
+
+	storedArray := storage.ReadValue(inter, address, "test").(*SomeValue).Value.(*ArrayValue)
+	require.Equal(t, 2, storedArray.Count())
+
+	require.False(t, storage.ValueExists(inter, address, "other"))
+
+	clonedArray = clone.ReadValue(cloneInter, address, "test").(*SomeValue).Value.(*ArrayValue)
+	require.Equal(t, count+2, clonedArray.Count())
+}
+
+func TestStorageExplicitNil(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	newStorage := func(t *testing.T, explicitNil bool) (InMemoryStorage, *Interpreter) {
+		storage := NewInMemoryStorage(WithExplicitNil(explicitNil))
+
+		inter, err := NewInterpreter(
+			nil,
+			common.AddressLocation{},
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		storage.WriteValue(inter, address, "test", NewSomeValueNonCopying(NewIntValueFromInt64(1)))
+
+		return storage, inter
+	}
+
+	t.Run("explicit nil", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter := newStorage(t, true)
+
+		storage.WriteValue(inter, address, "test", Nil())
+
+		// The written nil is distinguished from an absent value
+
+		require.True(t, storage.ValueExists(inter, address, "test"))
+		require.Equal(t,
+			NewSomeValueNonCopying(Nil()),
+			storage.ReadValue(inter, address, "test"),
+		)
+
+		require.Equal(t, Nil(), storage.ReadValue(inter, address, "absent"))
+
+		// Removing the value removes the explicit nil
+
+		err := storage.RemoveValue(inter, address, "test", false)
+		require.NoError(t, err)
+
+		require.False(t, storage.ValueExists(inter, address, "test"))
+		require.Equal(t, Nil(), storage.ReadValue(inter, address, "test"))
+	})
+
+	t.Run("remove on nil", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, inter := newStorage(t, false)
+
+		storage.WriteValue(inter, address, "test", Nil())
+
+		require.False(t, storage.ValueExists(inter, address, "test"))
+		require.Equal(t, Nil(), storage.ReadValue(inter, address, "test"))
+	})
+}
@@ -0,0 +1,129 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// StorageReadView is a read-only, point-in-time view of an in-memory storage,
+// see InMemoryStorage.ReadView.
+//
+// Reading values through the view is unaffected by later writes to the storage.
+// Writing through the view fails with a ReadOnlyStorageError,
+// and values read from the view are read-only.
+//
+type StorageReadView struct {
+	storage InMemoryStorage
+}
+
+var _ Storage = StorageReadView{}
+
+// ReadView returns a read-only snapshot of the current contents of the storage.
+//
+// The account storage entries are captured by reference, as storables are immutable.
+// Slabs are mutated in place by later writes, so they are captured in encoded form
+// and decoded into a separate slab storage.
+//
+func (i InMemoryStorage) ReadView() StorageReadView {
+	slabs, err := i.Encode()
+	if err != nil {
+		panic(err)
+	}
+
+	snapshot := InMemoryStorage{
+		BasicSlabStorage: atree.NewBasicSlabStorage(
+			CBOREncMode,
+			CBORDecMode,
+			DecodeStorable,
+			DecodeTypeInfo,
+		),
+		AccountStorage:  make(map[StorageKey]atree.Storable, len(i.AccountStorage)),
+		keyHasher:       i.keyHasher,
+		dirty:           make(map[atree.StorageID]struct{}),
+		slabAllocations: &slabAllocationCounterHolder{},
+	}
+
+	err = snapshot.Load(slabs)
+	if err != nil {
+		panic(err)
+	}
+
+	for key, storable := range i.AccountStorage {
+		snapshot.AccountStorage[key] = storable
+	}
+
+	return StorageReadView{
+		storage: snapshot,
+	}
+}
+
+func (v StorageReadView) Store(_ atree.StorageID, _ atree.Slab) error {
+	return ReadOnlyStorageError{}
+}
+
+func (v StorageReadView) Retrieve(id atree.StorageID) (atree.Slab, bool, error) {
+	return v.storage.Retrieve(id)
+}
+
+func (v StorageReadView) Remove(_ atree.StorageID) error {
+	return ReadOnlyStorageError{}
+}
+
+func (v StorageReadView) GenerateStorageID(_ atree.Address) (atree.StorageID, error) {
+	return atree.StorageID{}, ReadOnlyStorageError{}
+}
+
+func (v StorageReadView) Count() int {
+	return v.storage.Count()
+}
+
+func (v StorageReadView) SlabIterator() (atree.SlabIterator, error) {
+	return v.storage.SlabIterator()
+}
+
+func (v StorageReadView) ValueExists(interpreter *Interpreter, address common.Address, key string) bool {
+	return v.storage.ValueExists(interpreter, address, key)
+}
+
+func (v StorageReadView) ReadValue(_ *Interpreter, address common.Address, key string) OptionalValue {
+	storageKey := v.storage.storageKey(address, key)
+
+	storable, ok := v.storage.AccountStorage[storageKey]
+	if !ok {
+		return Nil()
+	}
+
+	// Values read from the view are read-only,
+	// so mutations are rejected before they reach the snapshot's slabs
+
+	storedValue := ReadOnlyValue(StoredValue(storable, v))
+	return NewSomeValueNonCopying(storedValue)
+}
+
+func (v StorageReadView) WriteValue(_ *Interpreter, _ common.Address, _ string, _ OptionalValue) {
+	panic(ReadOnlyStorageError{})
+}
+
+func (v StorageReadView) CheckHealth() error {
+	_, err := atree.CheckStorageHealth(v, -1)
+	return err
+}
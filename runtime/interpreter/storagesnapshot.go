@@ -0,0 +1,220 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/onflow/atree"
+)
+
+// StorageSnapshot is a point-in-time record of the values stored in an in-memory storage,
+// see InMemoryStorage.Snapshot.
+//
+type StorageSnapshot struct {
+	entries map[StorageKey]storageSnapshotEntry
+}
+
+type storageSnapshotEntry struct {
+	storable atree.Storable
+	// encoded is the encoding of the storable,
+	// followed by the encodings of all slabs reachable from it, in storage ID order
+	encoded []byte
+}
+
+// Snapshot records the values currently stored in the storage,
+// including the contents of all slabs of the values.
+//
+// Snapshots taken at different times can be compared using DiffSnapshots.
+//
+func (i InMemoryStorage) Snapshot() (StorageSnapshot, error) {
+	entries := make(map[StorageKey]storageSnapshotEntry, len(i.AccountStorage))
+
+	for storageKey, storable := range i.AccountStorage {
+		encoded, err := i.encodeWithSlabs(storable)
+		if err != nil {
+			return StorageSnapshot{}, err
+		}
+
+		entries[storageKey] = storageSnapshotEntry{
+			storable: storable,
+			encoded:  encoded,
+		}
+	}
+
+	return StorageSnapshot{
+		entries: entries,
+	}, nil
+}
+
+// encodeWithSlabs returns the encoding of the given storable,
+// followed by the encodings of all slabs reachable from it, in storage ID order.
+//
+func (i InMemoryStorage) encodeWithSlabs(storable atree.Storable) ([]byte, error) {
+	var buf bytes.Buffer
+
+	encoded, err := EncodeStorable(storable)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(encoded)
+
+	slabs := map[atree.StorageID][]byte{}
+
+	var visit func(storable atree.Storable) error
+	visit = func(storable atree.Storable) error {
+		if storageID, ok := referencedStorageID(storable); ok {
+			if _, ok := slabs[storageID]; ok {
+				return nil
+			}
+
+			slab, ok, err := i.Retrieve(storageID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+
+			encoded, err := EncodeStorable(slab)
+			if err != nil {
+				return err
+			}
+			slabs[storageID] = encoded
+
+			storable = slab
+		}
+
+		for _, child := range storable.ChildStorables() {
+			err := visit(child)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err = visit(storable)
+	if err != nil {
+		return nil, err
+	}
+
+	storageIDs := make([]atree.StorageID, 0, len(slabs))
+	for storageID := range slabs {
+		storageIDs = append(storageIDs, storageID)
+	}
+	sort.Slice(storageIDs, func(i, j int) bool {
+		return storageIDs[i].Compare(storageIDs[j]) < 0
+	})
+
+	for _, storageID := range storageIDs {
+		buf.Write(slabs[storageID])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// StorageChangeKind is the kind of a change of a stored value between two snapshots.
+//
+type StorageChangeKind uint
+
+const (
+	StorageChangeKindUnknown StorageChangeKind = iota
+	StorageChangeKindAdded
+	StorageChangeKindRemoved
+	StorageChangeKindModified
+)
+
+// StorageChange is a change of the value stored under a key between two snapshots.
+//
+// OldStorable is nil for additions, NewStorable is nil for removals.
+//
+type StorageChange struct {
+	Key         StorageKey
+	Kind        StorageChangeKind
+	OldStorable atree.Storable
+	NewStorable atree.Storable
+}
+
+// OldValue decodes the old storable of the change, see DecodeStorableStandalone.
+//
+func (c StorageChange) OldValue() (Value, error) {
+	if c.OldStorable == nil {
+		return nil, nil
+	}
+	return standaloneStoredValue(c.OldStorable)
+}
+
+// NewValue decodes the new storable of the change, see DecodeStorableStandalone.
+//
+func (c StorageChange) NewValue() (Value, error) {
+	if c.NewStorable == nil {
+		return nil, nil
+	}
+	return standaloneStoredValue(c.NewStorable)
+}
+
+// DiffSnapshots returns the changes of the stored values from snapshot a to snapshot b,
+// ordered by key.
+//
+// A value is modified if its storable, or the content of any of its slabs, changed.
+//
+func DiffSnapshots(a, b StorageSnapshot) []StorageChange {
+	var changes []StorageChange
+
+	for storageKey, oldEntry := range a.entries {
+		newEntry, ok := b.entries[storageKey]
+		switch {
+		case !ok:
+			changes = append(changes, StorageChange{
+				Key:         storageKey,
+				Kind:        StorageChangeKindRemoved,
+				OldStorable: oldEntry.storable,
+			})
+
+		case !bytes.Equal(oldEntry.encoded, newEntry.encoded):
+			changes = append(changes, StorageChange{
+				Key:         storageKey,
+				Kind:        StorageChangeKindModified,
+				OldStorable: oldEntry.storable,
+				NewStorable: newEntry.storable,
+			})
+		}
+	}
+
+	for storageKey, newEntry := range b.entries {
+		if _, ok := a.entries[storageKey]; ok {
+			continue
+		}
+
+		changes = append(changes, StorageChange{
+			Key:         storageKey,
+			Kind:        StorageChangeKindAdded,
+			NewStorable: newEntry.storable,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key.IsLess(changes[j].Key)
+	})
+
+	return changes
+}
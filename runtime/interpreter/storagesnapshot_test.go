@@ -0,0 +1,150 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		common.AddressLocation{},
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	write := func(key string, value Value) {
+		storage.WriteValue(
+			inter,
+			address,
+			key,
+			NewSomeValueNonCopying(
+				value.Transfer(
+					inter,
+					ReturnEmptyLocationRange,
+					atree.Address(address),
+					true,
+					nil,
+				),
+			),
+		)
+	}
+
+	write("unchanged", NewStringValue("same"))
+	write("modified", NewIntValueFromInt64(1))
+	write("removed", BoolValue(true))
+	write(
+		"container",
+		NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(1),
+		),
+	)
+
+	before, err := storage.Snapshot()
+	require.NoError(t, err)
+
+	// No changes between snapshots of the same state
+
+	unchanged, err := storage.Snapshot()
+	require.NoError(t, err)
+	require.Empty(t, DiffSnapshots(before, unchanged))
+
+	// Mutate
+
+	write("modified", NewIntValueFromInt64(2))
+	write("added", NewStringValue("new"))
+
+	err = storage.RemoveValue(inter, address, "removed", false)
+	require.NoError(t, err)
+
+	// Mutate the array in place, its storable stays the same
+
+	array := storage.ReadValue(inter, address, "container").(*SomeValue).Value.(*ArrayValue)
+	array.Append(inter, ReturnEmptyLocationRange, NewIntValueFromInt64(2))
+
+	after, err := storage.Snapshot()
+	require.NoError(t, err)
+
+	changes := DiffSnapshots(before, after)
+
+	type change struct {
+		key  string
+		kind StorageChangeKind
+	}
+
+	actual := make([]change, len(changes))
+	for i, c := range changes {
+		require.Equal(t, address, c.Key.Address)
+		actual[i] = change{
+			key:  c.Key.Key,
+			kind: c.Kind,
+		}
+	}
+
+	require.Equal(t,
+		[]change{
+			{key: "added", kind: StorageChangeKindAdded},
+			{key: "container", kind: StorageChangeKindModified},
+			{key: "modified", kind: StorageChangeKindModified},
+			{key: "removed", kind: StorageChangeKindRemoved},
+		},
+		actual,
+	)
+
+	// Old and new values of modifications can be decoded
+
+	modification := changes[2]
+
+	oldValue, err := modification.OldValue()
+	require.NoError(t, err)
+	require.Equal(t, NewIntValueFromInt64(1), oldValue)
+
+	newValue, err := modification.NewValue()
+	require.NoError(t, err)
+	require.Equal(t, NewIntValueFromInt64(2), newValue)
+
+	// Additions have no old value, removals have no new value
+
+	oldValue, err = changes[0].OldValue()
+	require.NoError(t, err)
+	require.Nil(t, oldValue)
+
+	newValue, err = changes[3].NewValue()
+	require.NoError(t, err)
+	require.Nil(t, newValue)
+}
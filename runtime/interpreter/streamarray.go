@@ -0,0 +1,67 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+)
+
+// StreamStoredArray calls the given function for each element of the array stored
+// with the given root storage ID, in order, until the function returns false.
+//
+// In contrast to loading the array using StoredValue, no ArrayValue is created,
+// and each element is only converted when it is passed to the function,
+// so the elements can be processed one at a time.
+//
+func StreamStoredArray(
+	storage atree.SlabStorage,
+	id atree.StorageID,
+	f func(index int, element Value) bool,
+) error {
+
+	array, err := atree.NewArrayWithRootID(storage, id)
+	if err != nil {
+		return err
+	}
+
+	iterator, err := array.Iterator()
+	if err != nil {
+		return err
+	}
+
+	for index := 0; ; index++ {
+		element, err := iterator.Next()
+		if err != nil {
+			return err
+		}
+
+		if element == nil {
+			return nil
+		}
+
+		converted, err := ConvertStoredValue(element)
+		if err != nil {
+			return err
+		}
+
+		if !f(index, converted) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestStreamStoredArray(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	const elementCount = 10_000
+
+	values := make([]Value, elementCount)
+	for i := range values {
+		values[i] = UInt64Value(i)
+	}
+
+	array := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeUInt64,
+		},
+		common.Address{0x1},
+		values...,
+	)
+
+	// The array is stored in many slabs
+
+	require.Greater(t, storage.Count(), 1)
+
+	t.Run("all", func(t *testing.T) {
+
+		count := 0
+
+		err := StreamStoredArray(
+			storage,
+			array.StorageID(),
+			func(index int, element Value) bool {
+				require.Equal(t, count, index)
+				require.Equal(t, UInt64Value(index), element)
+				count++
+				return true
+			},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, elementCount, count)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+
+		count := 0
+
+		err := StreamStoredArray(
+			storage,
+			array.StorageID(),
+			func(index int, element Value) bool {
+				count++
+				return index < 9
+			},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t, 10, count)
+	})
+
+	t.Run("unknown storage ID", func(t *testing.T) {
+
+		err := StreamStoredArray(
+			storage,
+			atree.StorageID{
+				Address: atree.Address{0x2},
+				Index:   atree.StorageIndex{0x1},
+			},
+			func(index int, element Value) bool {
+				return true
+			},
+		)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,305 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"bytes"
+
+	"github.com/onflow/atree"
+)
+
+// SlabAllocationCounter counts the slabs allocated by a storage, up to a budget,
+// see SlabAllocationCountingStorage.
+//
+type SlabAllocationCounter struct {
+	budget int
+	// allocated are the storage IDs of the allocated slabs, in allocation order
+	allocated []atree.StorageID
+	// exceeded is true if an allocation was rejected, as it exceeded the budget
+	exceeded bool
+}
+
+// Allocate records the allocation of the slab with the given storage ID.
+//
+// A TransferBudgetExceededError is returned if the allocation exceeds the budget.
+//
+func (c *SlabAllocationCounter) Allocate(storageID atree.StorageID) error {
+	if len(c.allocated) >= c.budget {
+		c.exceeded = true
+		return TransferBudgetExceededError{
+			Budget: c.budget,
+		}
+	}
+
+	c.allocated = append(c.allocated, storageID)
+	return nil
+}
+
+// SlabAllocationCountingStorage is implemented by storages which report
+// the storage IDs they generate for new slabs to a slab allocation counter,
+// so transfers can be limited to a slab budget, see WithTransferSlabBudget.
+//
+// When a counter is set, generating a storage ID fails with the error returned by the counter.
+//
+type SlabAllocationCountingStorage interface {
+	SetSlabAllocationCounter(counter *SlabAllocationCounter)
+}
+
+// budgetedTransfer transfers the given value, and aborts the transfer
+// if it allocates more slabs than the given transfer slab budget allows.
+//
+// The slabs are counted while they are allocated, see SlabAllocationCountingStorage.
+// If the budget is exceeded, the transfer is rolled back: the slabs it allocated are removed,
+// and the slabs of the value it removed or emptied are restored, so no partial state is left behind.
+//
+// If the storage does not count slab allocations, the number of slabs the value is stored in
+// is used as an estimate of the number of slabs the transfer allocates,
+// and the transfer is rejected before it is performed if the estimate exceeds the budget.
+//
+// Nested transfers performed as part of the transfer are not checked separately.
+//
+func (interpreter *Interpreter) budgetedTransfer(
+	value Value,
+	getLocationRange func() LocationRange,
+	address atree.Address,
+	remove bool,
+	storable atree.Storable,
+	budget int,
+) Value {
+
+	// A resource which is not moved to another account is not copied
+
+	copies := value.NeedsStoreTo(address) || !value.IsResourceKinded(interpreter)
+
+	countingStorage, counts := interpreter.Storage.(SlabAllocationCountingStorage)
+
+	if copies && !counts && interpreter.exceedsSlabCount(value, budget) {
+		panic(TransferBudgetExceededError{
+			Budget: budget,
+		})
+	}
+
+	interpreter.checkingTransferSlabBudget = true
+	defer func() {
+		interpreter.checkingTransferSlabBudget = false
+	}()
+
+	if !copies || !counts {
+		return value.Transfer(interpreter, getLocationRange, address, remove, storable)
+	}
+
+	// The slabs which a removing transfer may remove or empty are the slabs of the value.
+	// Shared copies of the value are unshared before, so their slabs are not rolled back
+
+	var valueSlabs map[atree.StorageID][]byte
+	if remove {
+		interpreter.unshareContainerSlabs(value)
+		valueSlabs = interpreter.encodeContainerSlabs(value)
+	}
+
+	counter := &SlabAllocationCounter{
+		budget: budget,
+	}
+
+	countingStorage.SetSlabAllocationCounter(counter)
+	defer func() {
+		countingStorage.SetSlabAllocationCounter(nil)
+
+		r := recover()
+		if !counter.exceeded {
+			if r != nil {
+				panic(r)
+			}
+			return
+		}
+
+		interpreter.rollBackTransfer(counter.allocated, valueSlabs)
+
+		panic(TransferBudgetExceededError{
+			Budget: budget,
+		})
+	}()
+
+	return value.Transfer(interpreter, getLocationRange, address, remove, storable)
+}
+
+// unshareContainerSlabs unshares the given container value, if it is a shared copy,
+// and all shared copies of the containers it is stored in, see TransferShared.
+//
+func (interpreter *Interpreter) unshareContainerSlabs(value Value) {
+	for {
+		someValue, ok := value.(*SomeValue)
+		if !ok {
+			break
+		}
+		value = someValue.Value
+	}
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		value.unshare(interpreter)
+	case *DictionaryValue:
+		value.unshare(interpreter)
+	case *CompositeValue:
+		value.unshare(interpreter)
+	}
+
+	if len(interpreter.sharedContainers) == 0 {
+		return
+	}
+
+	storageID, ok := containerRootStorageID(value)
+	if !ok {
+		return
+	}
+
+	walkReferencedSlabs(interpreter.Storage, storageID, interpreter.unshareCopies)
+}
+
+// encodeContainerSlabs returns the encodings of all slabs the given container value is stored in,
+// including the slabs of all nested containers.
+//
+func (interpreter *Interpreter) encodeContainerSlabs(value Value) map[atree.StorageID][]byte {
+	storageID, ok := containerRootStorageID(value)
+	if !ok {
+		return nil
+	}
+
+	slabs := map[atree.StorageID][]byte{}
+
+	walkReferencedSlabs(interpreter.Storage, storageID, func(storageID atree.StorageID) {
+		slab, ok, err := interpreter.Storage.Retrieve(storageID)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+		if !ok {
+			return
+		}
+
+		data, err := atree.Encode(slab, CBOREncMode)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+
+		slabs[storageID] = data
+	})
+
+	return slabs
+}
+
+// rollBackTransfer rolls back an aborted transfer:
+// it removes the given slabs allocated by the transfer,
+// and restores the given slabs of the transferred value, if they were removed or modified.
+//
+// Slabs which are unchanged are kept, so values which refer to them stay valid.
+//
+func (interpreter *Interpreter) rollBackTransfer(allocated []atree.StorageID, valueSlabs map[atree.StorageID][]byte) {
+	storage := interpreter.Storage
+
+	for _, storageID := range allocated {
+		_, ok, err := storage.Retrieve(storageID)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+		if !ok {
+			continue
+		}
+
+		err = storage.Remove(storageID)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+	}
+
+	// NOTE: ranging over the map is safe, as the restored slabs are independent
+	for storageID, data := range valueSlabs { //nolint:maprangecheck
+		slab, ok, err := storage.Retrieve(storageID)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+
+		if ok {
+			currentData, err := atree.Encode(slab, CBOREncMode)
+			if err != nil {
+				panic(ExternalError{err})
+			}
+			if bytes.Equal(currentData, data) {
+				continue
+			}
+		}
+
+		slab, err = atree.DecodeSlab(storageID, data, CBORDecMode, DecodeStorable, DecodeTypeInfo)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+
+		err = storage.Store(storageID, slab)
+		if err != nil {
+			panic(ExternalError{err})
+		}
+	}
+}
+
+// exceedsSlabCount returns true if the given value is stored in more than the given number of slabs,
+// including the slabs of all nested containers.
+//
+// At most the given number of slabs are read.
+//
+func (interpreter *Interpreter) exceedsSlabCount(value Value, n int) bool {
+	storageID, ok := containerRootStorageID(value)
+	if !ok {
+		return false
+	}
+
+	visited := map[atree.StorageID]struct{}{}
+
+	var visit func(storable atree.Storable) bool
+	visit = func(storable atree.Storable) bool {
+		if storageID, ok := referencedStorageID(storable); ok {
+			if _, ok := visited[storageID]; ok {
+				return false
+			}
+
+			slab, ok, err := interpreter.Storage.Retrieve(storageID)
+			if err != nil {
+				panic(ExternalError{err})
+			}
+			if !ok {
+				return false
+			}
+
+			visited[storageID] = struct{}{}
+			if len(visited) > n {
+				return true
+			}
+
+			storable = slab
+		}
+
+		for _, child := range storable.ChildStorables() {
+			if visit(child) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return visit(atree.StorageIDStorable(storageID))
+}
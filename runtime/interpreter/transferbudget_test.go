@@ -0,0 +1,189 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestTransferSlabBudget(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	owner := common.Address{0x1}
+	newOwner := common.Address{0x2}
+
+	const count = 1000
+
+	elements := make([]Value, count)
+	for i := range elements {
+		elements[i] = UInt64Value(i)
+	}
+
+	value := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeUInt64,
+		},
+		owner,
+		elements...,
+	)
+
+	slabCount := storage.Count()
+	require.Greater(t, slabCount, 1)
+
+	t.Run("exceeded", func(t *testing.T) {
+
+		inter.SetTransferSlabBudget(slabCount - 1)
+
+		require.PanicsWithValue(t,
+			TransferBudgetExceededError{
+				Budget: slabCount - 1,
+			},
+			func() {
+				value.Transfer(
+					inter,
+					ReturnEmptyLocationRange,
+					atree.Address(newOwner),
+					true,
+					nil,
+				)
+			},
+		)
+
+		// The storage and the value are unchanged
+
+		require.Equal(t, slabCount, storage.Count())
+		require.NoError(t, storage.CheckHealth())
+
+		require.Equal(t, owner, value.GetOwner())
+		require.Equal(t, count, value.Count())
+	})
+
+	t.Run("within budget", func(t *testing.T) {
+
+		inter.SetTransferSlabBudget(slabCount)
+
+		transferred := value.Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(newOwner),
+			false,
+			nil,
+		).(*ArrayValue)
+
+		require.Equal(t, newOwner, transferred.GetOwner())
+		require.Equal(t, count, transferred.Count())
+
+		// The transfer allocated at most as many slabs as the budget allows
+
+		require.LessOrEqual(t, storage.Count()-slabCount, slabCount)
+	})
+	t.Run("rolled back", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		// Each nested array is stored in its own slab
+
+		value := newNestedArrayValue(inter, 50).Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address(owner),
+			true,
+			nil,
+		).(*ArrayValue)
+
+		slabCount := storage.Count()
+		require.Greater(t, slabCount, 50)
+
+		encoded, err := storage.Encode()
+		require.NoError(t, err)
+
+		// The transfer is aborted after some nested arrays were already moved and removed
+
+		inter.SetTransferSlabBudget(10)
+
+		require.PanicsWithValue(t,
+			TransferBudgetExceededError{
+				Budget: 10,
+			},
+			func() {
+				value.Transfer(
+					inter,
+					ReturnEmptyLocationRange,
+					atree.Address(newOwner),
+					true,
+					nil,
+				)
+			},
+		)
+
+		// The storage is rolled back
+
+		require.Equal(t, slabCount, storage.Count())
+		require.NoError(t, storage.CheckHealth())
+
+		rolledBackEncoded, err := storage.Encode()
+		require.NoError(t, err)
+		require.Equal(t, encoded, rolledBackEncoded)
+
+		require.Equal(t, owner, value.GetOwner())
+		require.Equal(t, 50, value.Count())
+
+		inter.SetTransferSlabBudget(0)
+
+		utils.RequireValuesEqual(
+			t,
+			inter,
+			newNestedArrayValue(inter, 50),
+			value.Transfer(
+				inter,
+				ReturnEmptyLocationRange,
+				atree.Address{},
+				false,
+				nil,
+			),
+		)
+	})
+}
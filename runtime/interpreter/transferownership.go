@@ -0,0 +1,106 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// checkedTransfer transfers the given value, and checks that the result,
+// including all nested containers, is owned by the given address.
+//
+// Nested transfers performed as part of the transfer are not checked separately.
+//
+func (interpreter *Interpreter) checkedTransfer(
+	value Value,
+	getLocationRange func() LocationRange,
+	address atree.Address,
+	remove bool,
+	storable atree.Storable,
+) Value {
+
+	interpreter.checkingTransferOwnership = true
+	result := func() Value {
+		defer func() {
+			interpreter.checkingTransferOwnership = false
+		}()
+
+		return value.Transfer(interpreter, getLocationRange, address, remove, storable)
+	}()
+
+	checkOwnership("", result, common.Address(address))
+
+	return result
+}
+
+// checkOwnership panics with a TransferOwnershipError if the given value,
+// or a container nested in it, is not owned by the given owner.
+//
+func checkOwnership(path string, value Value, owner common.Address) {
+
+	check := func(actualOwner common.Address) {
+		if actualOwner != owner {
+			panic(TransferOwnershipError{
+				Path:          path,
+				ExpectedOwner: owner,
+				ActualOwner:   actualOwner,
+			})
+		}
+	}
+
+	switch value := value.(type) {
+	case *SomeValue:
+		checkOwnership(path, value.Value, owner)
+
+	case *ArrayValue:
+		check(value.GetOwner())
+
+		index := 0
+		value.Iterate(func(element Value) (resume bool) {
+			checkOwnership(fmt.Sprintf("%s[%d]", path, index), element, owner)
+			index++
+			return true
+		})
+
+	case *DictionaryValue:
+		check(value.GetOwner())
+
+		value.Iterate(func(key, value Value) (resume bool) {
+			entryPath := fmt.Sprintf("%s[%s]", path, key)
+			checkOwnership(entryPath, key, owner)
+			checkOwnership(entryPath, value, owner)
+			return true
+		})
+
+	case *CompositeValue:
+		check(value.GetOwner())
+
+		value.ForEachField(func(name string, value Value) {
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			checkOwnership(fieldPath, value, owner)
+		})
+	}
+}
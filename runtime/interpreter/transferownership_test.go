@@ -0,0 +1,122 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestTransferOwnershipCheck(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	elaboration := sema.NewElaboration()
+	elaboration.CompositeTypes[testCompositeValueType.ID()] = testCompositeValueType
+
+	inter, err := NewInterpreter(
+		&Program{
+			Elaboration: elaboration,
+		},
+		utils.TestLocation,
+		WithStorage(storage),
+		WithTransferOwnershipCheck(true),
+	)
+	require.NoError(t, err)
+
+	owner := common.Address{0x1}
+	newOwner := common.Address{0x2}
+
+	// [{"a": Test(values: [1])}, Some([2])]
+
+	value := NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: PrimitiveStaticTypeAnyStruct,
+		},
+		owner,
+		NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeAnyStruct,
+			},
+			NewStringValue("a"),
+			NewCompositeValue(
+				inter,
+				utils.TestLocation,
+				"Test",
+				common.CompositeKindStructure,
+				[]CompositeField{
+					{
+						Name: "values",
+						Value: NewArrayValue(
+							inter,
+							VariableSizedStaticType{
+								Type: PrimitiveStaticTypeUInt8,
+							},
+							common.Address{},
+							UInt8Value(1),
+						),
+					},
+				},
+				common.Address{},
+			),
+		),
+		NewSomeValueNonCopying(
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeUInt8,
+				},
+				common.Address{},
+				UInt8Value(2),
+			),
+		),
+	)
+
+	transferred := value.Transfer(
+		inter,
+		ReturnEmptyLocationRange,
+		atree.Address(newOwner),
+		true,
+		nil,
+	).(*ArrayValue)
+
+	require.Equal(t, newOwner, transferred.GetOwner())
+
+	dictionary := transferred.Get(inter, ReturnEmptyLocationRange, 0).(*DictionaryValue)
+	require.Equal(t, newOwner, dictionary.GetOwner())
+
+	composite, ok := dictionary.Get(inter, ReturnEmptyLocationRange, NewStringValue("a"))
+	require.True(t, ok)
+	require.Equal(t, newOwner, composite.(*CompositeValue).GetOwner())
+
+	optional := transferred.Get(inter, ReturnEmptyLocationRange, 1).(*SomeValue)
+	require.Equal(t, newOwner, optional.Value.(*ArrayValue).GetOwner())
+}
@@ -0,0 +1,118 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/atree"
+)
+
+// TransferProgressFunc is a function that is called during TransferWithProgress.
+// Processed is the number of elements, entries, and fields transferred so far,
+// total is the number of elements, entries, and fields of the transferred value,
+// including nested values.
+//
+type TransferProgressFunc func(processed, total int)
+
+type transferProgress struct {
+	onProgress TransferProgressFunc
+	interval   int
+	processed  int
+	total      int
+}
+
+// TransferWithProgress transfers the given value like Transfer,
+// and reports the progress of the transfer to the given function.
+//
+// The function is called every interval processed elements, entries, and fields
+// (every time if interval is less than or equal to 1), and a final time once the transfer completed.
+//
+func TransferWithProgress(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	value Value,
+	address atree.Address,
+	remove bool,
+	storable atree.Storable,
+	onProgress TransferProgressFunc,
+	interval int,
+) Value {
+
+	progress := &transferProgress{
+		onProgress: onProgress,
+		interval:   interval,
+		total:      transferElementCount(value),
+	}
+
+	previous := interpreter.transferProgress
+	interpreter.transferProgress = progress
+	defer func() {
+		interpreter.transferProgress = previous
+	}()
+
+	result := value.Transfer(interpreter, getLocationRange, address, remove, storable)
+
+	onProgress(progress.total, progress.total)
+
+	return result
+}
+
+func (interpreter *Interpreter) reportTransferProgress() {
+	progress := interpreter.transferProgress
+	if progress == nil {
+		return
+	}
+
+	progress.processed++
+
+	if progress.processed >= progress.total {
+		// The final progress is reported once the transfer completed
+		return
+	}
+
+	if progress.interval <= 1 || progress.processed%progress.interval == 0 {
+		progress.onProgress(progress.processed, progress.total)
+	}
+}
+
+// transferElementCount returns the number of elements of arrays,
+// entries of dictionaries, and fields of composites in the given value.
+//
+func transferElementCount(value Value) int {
+	count := 0
+
+	var walk func(value Value)
+	walk = func(value Value) {
+		switch value := value.(type) {
+		case *ArrayValue:
+			count += value.Count()
+		case *DictionaryValue:
+			count += value.Count()
+		case *CompositeValue:
+			value.ForEachField(func(_ string, _ Value) {
+				count++
+			})
+		}
+
+		value.Walk(walk)
+	}
+
+	walk(value)
+
+	return count
+}
@@ -0,0 +1,145 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestTransferWithProgress(t *testing.T) {
+
+	t.Parallel()
+
+	targetAddress := atree.Address{0x1}
+
+	type progress struct {
+		processed, total int
+	}
+
+	t.Run("dictionary", func(t *testing.T) {
+
+		t.Parallel()
+
+		const entryCount = 1000
+
+		inter := newTestInterpreter(t)
+
+		keysAndValues := make([]Value, 0, entryCount*2)
+		for i := 0; i < entryCount; i++ {
+			keysAndValues = append(
+				keysAndValues,
+				NewStringValue(fmt.Sprintf("key%d", i)),
+				NewIntValueFromInt64(int64(i)),
+			)
+		}
+
+		dictionary := NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeInt,
+			},
+			keysAndValues...,
+		)
+
+		var reports []progress
+
+		result := TransferWithProgress(
+			inter,
+			ReturnEmptyLocationRange,
+			dictionary,
+			targetAddress,
+			false,
+			nil,
+			func(processed, total int) {
+				reports = append(reports, progress{processed, total})
+			},
+			100,
+		)
+
+		require.Len(t, reports, 10)
+		for i, report := range reports[:9] {
+			require.Equal(t, progress{(i + 1) * 100, entryCount}, report)
+		}
+		require.Equal(t, progress{entryCount, entryCount}, reports[9])
+
+		expected := dictionary.Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			targetAddress,
+			false,
+			nil,
+		)
+
+		utils.RequireValuesEqual(t, inter, expected, result)
+		require.Equal(t, common.Address(targetAddress), result.(*DictionaryValue).GetOwner())
+	})
+
+	t.Run("nested", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: VariableSizedStaticType{
+					Type: PrimitiveStaticTypeInt,
+				},
+			},
+			common.Address{},
+			newIntArray(inter, common.Address{}, 3, 1),
+			newIntArray(inter, common.Address{}, 2, 1),
+		)
+
+		var reports []progress
+
+		_ = TransferWithProgress(
+			inter,
+			ReturnEmptyLocationRange,
+			array,
+			targetAddress,
+			false,
+			nil,
+			func(processed, total int) {
+				reports = append(reports, progress{processed, total})
+			},
+			0,
+		)
+
+		// 2 outer elements, 3 + 2 inner elements
+
+		require.Len(t, reports, 7)
+		require.Equal(t, progress{7, 7}, reports[6])
+
+		for i, report := range reports[:6] {
+			require.Equal(t, 7, report.total)
+			require.Equal(t, i+1, report.processed)
+		}
+	})
+}
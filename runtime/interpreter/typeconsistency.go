@@ -0,0 +1,138 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+// ValidateTypeConsistency checks that all values nested in the given value
+// conform to the types declared by their containers: the elements of arrays
+// to the element type, the keys and values of dictionaries to the key and value type,
+// and the fields of composites to the field types declared by the composite type.
+//
+// In contrast to ConformsToDynamicType, the values are checked against the types
+// declared by the containers themselves, e.g. to detect corrupted storage.
+//
+// The first value which does not conform is reported in a TypeConsistencyError,
+// with its path, e.g. `[1].balances[a]`.
+//
+func ValidateTypeConsistency(interpreter *Interpreter, value Value) error {
+	return validateTypeConsistency(interpreter, "", value)
+}
+
+func validateTypeConsistency(interpreter *Interpreter, path string, value Value) error {
+
+	check := func(path string, expectedType sema.Type, value Value) error {
+		actualType := value.DynamicType(interpreter, SeenReferences{})
+
+		if !interpreter.IsSubType(actualType, expectedType) {
+			return TypeConsistencyError{
+				Path:         path,
+				ExpectedType: expectedType,
+				ActualType:   interpreter.MustConvertStaticToSemaType(value.StaticType()),
+			}
+		}
+
+		return validateTypeConsistency(interpreter, path, value)
+	}
+
+	var err error
+
+	switch value := value.(type) {
+	case *SomeValue:
+		return validateTypeConsistency(interpreter, path, value.Value)
+
+	case *ArrayValue:
+		elementType, convertErr := interpreter.ConvertStaticToSemaType(value.Type.ElementType())
+		if convertErr != nil {
+			return convertErr
+		}
+
+		index := 0
+		value.Iterate(func(element Value) (resume bool) {
+			err = check(
+				fmt.Sprintf("%s[%d]", path, index),
+				elementType,
+				element,
+			)
+			index++
+			return err == nil
+		})
+
+	case *DictionaryValue:
+		keyType, convertErr := interpreter.ConvertStaticToSemaType(value.Type.KeyType)
+		if convertErr != nil {
+			return convertErr
+		}
+
+		valueType, convertErr := interpreter.ConvertStaticToSemaType(value.Type.ValueType)
+		if convertErr != nil {
+			return convertErr
+		}
+
+		value.Iterate(func(key, value Value) (resume bool) {
+			entryPath := fmt.Sprintf("%s[%s]", path, key)
+
+			err = check(entryPath, keyType, key)
+			if err != nil {
+				return false
+			}
+
+			err = check(entryPath, valueType, value)
+			return err == nil
+		})
+
+	case *CompositeValue:
+		compositeType, typeErr := interpreter.GetCompositeType(
+			value.Location,
+			value.QualifiedIdentifier,
+			value.TypeID(),
+		)
+		if typeErr != nil {
+			return typeErr
+		}
+
+		for _, fieldName := range compositeType.Fields {
+			member, ok := compositeType.Members.Get(fieldName)
+			if !ok {
+				continue
+			}
+
+			fieldValue := value.GetField(interpreter, ReturnEmptyLocationRange, fieldName)
+			if fieldValue == nil {
+				continue
+			}
+
+			fieldPath := fieldName
+			if path != "" {
+				fieldPath = path + "." + fieldName
+			}
+
+			err = check(fieldPath, member.TypeAnnotation.Type, fieldValue)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return err
+}
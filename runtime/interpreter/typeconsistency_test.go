@@ -0,0 +1,120 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+)
+
+func TestValidateTypeConsistency(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	newDictionary := func() *DictionaryValue {
+		return NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeAnyStruct,
+			},
+			NewStringValue("a"),
+			NewArrayValue(
+				inter,
+				VariableSizedStaticType{
+					Type: PrimitiveStaticTypeAnyStruct,
+				},
+				common.Address{},
+				NewStringValue("x"),
+				NewIntValueFromInt64(1),
+			),
+		)
+	}
+
+	t.Run("consistent", func(t *testing.T) {
+
+		t.Parallel()
+
+		require.NoError(t, ValidateTypeConsistency(inter, newDictionary()))
+	})
+
+	t.Run("corrupted key", func(t *testing.T) {
+
+		t.Parallel()
+
+		dictionary := newDictionary()
+
+		// Simulate a corrupted dictionary, which declares Int keys,
+		// but contains a String key
+
+		dictionary.Type = DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeInt,
+			ValueType: PrimitiveStaticTypeAnyStruct,
+		}
+
+		require.Equal(t,
+			TypeConsistencyError{
+				Path:         `["a"]`,
+				ExpectedType: sema.IntType,
+				ActualType:   sema.StringType,
+			},
+			ValidateTypeConsistency(inter, dictionary),
+		)
+	})
+
+	t.Run("corrupted nested element", func(t *testing.T) {
+
+		t.Parallel()
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeAnyStruct,
+			},
+			common.Address{},
+			NewStringValue("x"),
+			NewIntValueFromInt64(1),
+		)
+
+		// Simulate a corrupted array, which declares String elements,
+		// but contains an Int element
+
+		array.Type = VariableSizedStaticType{
+			Type: PrimitiveStaticTypeString,
+		}
+
+		value := NewSomeValueNonCopying(array)
+
+		require.Equal(t,
+			TypeConsistencyError{
+				Path:         `[1]`,
+				ExpectedType: sema.StringType,
+				ActualType:   sema.IntType,
+			},
+			ValidateTypeConsistency(inter, value),
+		)
+	})
+}
@@ -0,0 +1,233 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+)
+
+// UnmarshalTag is the struct field tag which names the composite field
+// a Go struct field is unmarshaled from.
+//
+const UnmarshalTag = "cadence"
+
+var bigIntType = reflect.TypeOf((*big.Int)(nil))
+
+// UnmarshalComposite stores the fields of the given composite value
+// in the struct pointed to by out.
+//
+// Only struct fields with a `cadence:"fieldName"` tag are set.
+// Booleans, strings, and integers are converted to Go booleans, strings,
+// and integers (or *big.Int). Nested composites are unmarshaled into structs
+// or pointers to structs, arrays into slices, and dictionaries into maps.
+// Optionals are unwrapped, and nil leaves the Go value unchanged.
+//
+// If a field is missing, or cannot be converted, an UnmarshalError is returned.
+//
+func UnmarshalComposite(interpreter *Interpreter, value *CompositeValue, out interface{}) error {
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("cannot unmarshal into %T: expected non-nil pointer to struct", out)
+	}
+
+	return unmarshalComposite(interpreter, "", value, target.Elem())
+}
+
+func unmarshalComposite(interpreter *Interpreter, path string, value *CompositeValue, target reflect.Value) error {
+	targetType := target.Type()
+
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+
+		name, ok := field.Tag.Lookup(UnmarshalTag)
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+
+		fieldValue := value.GetField(interpreter, ReturnEmptyLocationRange, name)
+		if fieldValue == nil {
+			return UnmarshalError{
+				FieldName: fieldPath,
+				Err:       fmt.Errorf("missing field"),
+			}
+		}
+
+		err := unmarshalValue(interpreter, fieldPath, fieldValue, target.Field(i))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unmarshalValue(interpreter *Interpreter, path string, value Value, target reflect.Value) error {
+
+	switch value := value.(type) {
+	case *SomeValue:
+		return unmarshalValue(interpreter, path, value.Value, target)
+
+	case NilValue:
+		return nil
+	}
+
+	mismatch := func() error {
+		return UnmarshalError{
+			FieldName: path,
+			Err: fmt.Errorf(
+				"cannot convert value of type `%s` to %s",
+				value.StaticType(),
+				target.Type(),
+			),
+		}
+	}
+
+	if target.Type() == bigIntType {
+		integer, ok := integerValueToBigInt(value)
+		if !ok {
+			return mismatch()
+		}
+		target.Set(reflect.ValueOf(integer))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Bool:
+		boolValue, ok := value.(BoolValue)
+		if !ok {
+			return mismatch()
+		}
+		target.SetBool(bool(boolValue))
+
+	case reflect.String:
+		stringValue, ok := value.(*StringValue)
+		if !ok {
+			return mismatch()
+		}
+		target.SetString(stringValue.Str)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer, ok := integerValueToBigInt(value)
+		if !ok || !integer.IsInt64() || target.OverflowInt(integer.Int64()) {
+			return mismatch()
+		}
+		target.SetInt(integer.Int64())
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		integer, ok := integerValueToBigInt(value)
+		if !ok || !integer.IsUint64() || target.OverflowUint(integer.Uint64()) {
+			return mismatch()
+		}
+		target.SetUint(integer.Uint64())
+
+	case reflect.Struct:
+		composite, ok := value.(*CompositeValue)
+		if !ok {
+			return mismatch()
+		}
+		return unmarshalComposite(interpreter, path, composite, target)
+
+	case reflect.Ptr:
+		if target.Type().Elem().Kind() != reflect.Struct {
+			return mismatch()
+		}
+		composite, ok := value.(*CompositeValue)
+		if !ok {
+			return mismatch()
+		}
+		element := reflect.New(target.Type().Elem())
+		err := unmarshalComposite(interpreter, path, composite, element.Elem())
+		if err != nil {
+			return err
+		}
+		target.Set(element)
+
+	case reflect.Slice:
+		array, ok := value.(*ArrayValue)
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(target.Type(), array.Count(), array.Count())
+		for i := 0; i < array.Count(); i++ {
+			element := array.Get(interpreter, ReturnEmptyLocationRange, i)
+			elementPath := fmt.Sprintf("%s[%d]", path, i)
+			err := unmarshalValue(interpreter, elementPath, element, slice.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+
+	case reflect.Map:
+		dictionary, ok := value.(*DictionaryValue)
+		if !ok {
+			return mismatch()
+		}
+		mapType := target.Type()
+		result := reflect.MakeMapWithSize(mapType, dictionary.Count())
+		var err error
+		dictionary.Iterate(func(key, value Value) (resume bool) {
+			entryPath := fmt.Sprintf("%s[%s]", path, key)
+			goKey := reflect.New(mapType.Key()).Elem()
+			err = unmarshalValue(interpreter, entryPath, key, goKey)
+			if err != nil {
+				return false
+			}
+			goValue := reflect.New(mapType.Elem()).Elem()
+			err = unmarshalValue(interpreter, entryPath, value, goValue)
+			if err != nil {
+				return false
+			}
+			result.SetMapIndex(goKey, goValue)
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		target.Set(result)
+
+	default:
+		return mismatch()
+	}
+
+	return nil
+}
+
+func integerValueToBigInt(value Value) (*big.Int, bool) {
+	switch value := value.(type) {
+	case BigNumberValue:
+		if _, ok := value.(IntegerValue); !ok {
+			return nil, false
+		}
+		return value.ToBigInt(), true
+
+	case IntegerValue:
+		return big.NewInt(int64(value.ToInt())), true
+
+	default:
+		return nil, false
+	}
+}
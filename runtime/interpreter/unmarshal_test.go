@@ -0,0 +1,235 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestUnmarshalComposite(t *testing.T) {
+
+	t.Parallel()
+
+	type Inner struct {
+		Name   string  `cadence:"name"`
+		Values []uint8 `cadence:"values"`
+	}
+
+	type Outer struct {
+		ID       uint64           `cadence:"id"`
+		Balance  *big.Int         `cadence:"balance"`
+		Enabled  bool             `cadence:"enabled"`
+		Inner    Inner            `cadence:"inner"`
+		Optional *Inner           `cadence:"optional"`
+		Counts   map[string]int32 `cadence:"counts"`
+		Ignored  string
+	}
+
+	newInner := func(inter *Interpreter, name string) *CompositeValue {
+		return NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Inner",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name:  "name",
+					Value: NewStringValue(name),
+				},
+				{
+					Name: "values",
+					Value: NewArrayValue(
+						inter,
+						VariableSizedStaticType{
+							Type: PrimitiveStaticTypeUInt8,
+						},
+						common.Address{},
+						UInt8Value(1),
+						UInt8Value(2),
+					),
+				},
+			},
+			common.Address{},
+		)
+	}
+
+	newOuter := func(inter *Interpreter, id Value) *CompositeValue {
+		return NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Outer",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name:  "id",
+					Value: id,
+				},
+				{
+					Name:  "balance",
+					Value: NewUInt256ValueFromUint64(1000),
+				},
+				{
+					Name:  "enabled",
+					Value: BoolValue(true),
+				},
+				{
+					Name:  "inner",
+					Value: newInner(inter, "a"),
+				},
+				{
+					Name:  "optional",
+					Value: NewSomeValueNonCopying(newInner(inter, "b")),
+				},
+				{
+					Name: "counts",
+					Value: NewDictionaryValue(
+						inter,
+						DictionaryStaticType{
+							KeyType:   PrimitiveStaticTypeString,
+							ValueType: PrimitiveStaticTypeInt32,
+						},
+						NewStringValue("x"), Int32Value(-3),
+					),
+				},
+			},
+			common.Address{},
+		)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		var outer Outer
+		err := UnmarshalComposite(inter, newOuter(inter, UInt64Value(42)), &outer)
+		require.NoError(t, err)
+
+		require.Equal(t,
+			Outer{
+				ID:      42,
+				Balance: big.NewInt(1000),
+				Enabled: true,
+				Inner: Inner{
+					Name:   "a",
+					Values: []uint8{1, 2},
+				},
+				Optional: &Inner{
+					Name:   "b",
+					Values: []uint8{1, 2},
+				},
+				Counts: map[string]int32{
+					"x": -3,
+				},
+			},
+			outer,
+		)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		var outer Outer
+		err := UnmarshalComposite(inter, newOuter(inter, NewStringValue("42")), &outer)
+
+		var unmarshalErr UnmarshalError
+		require.True(t, errors.As(err, &unmarshalErr))
+		require.Equal(t, "id", unmarshalErr.FieldName)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		var out struct {
+			Values []int8 `cadence:"values"`
+		}
+		value := NewCompositeValue(
+			inter,
+			utils.TestLocation,
+			"Test",
+			common.CompositeKindStructure,
+			[]CompositeField{
+				{
+					Name: "values",
+					Value: NewArrayValue(
+						inter,
+						VariableSizedStaticType{
+							Type: PrimitiveStaticTypeInt,
+						},
+						common.Address{},
+						NewIntValueFromInt64(1),
+						NewIntValueFromInt64(1000),
+					),
+				},
+			},
+			common.Address{},
+		)
+
+		err := UnmarshalComposite(inter, value, &out)
+
+		var unmarshalErr UnmarshalError
+		require.True(t, errors.As(err, &unmarshalErr))
+		require.Equal(t, "values[1]", unmarshalErr.FieldName)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		var out struct {
+			Inner struct {
+				Missing string `cadence:"missing"`
+			} `cadence:"inner"`
+		}
+
+		err := UnmarshalComposite(inter, newOuter(inter, UInt64Value(1)), &out)
+
+		var unmarshalErr UnmarshalError
+		require.True(t, errors.As(err, &unmarshalErr))
+		require.Equal(t, "inner.missing", unmarshalErr.FieldName)
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		var outer Outer
+		err := UnmarshalComposite(inter, newOuter(inter, UInt64Value(1)), outer)
+		require.Error(t, err)
+	})
+}
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"reflect"
 	"strings"
 	"time"
 
@@ -149,6 +150,119 @@ func newValueComparator(interpreter *Interpreter, getLocationRange func() Locati
 	}
 }
 
+// DeepEqual returns true if the two given values are deeply equal.
+//
+// Enum values are equal if they have the same location, qualified identifier, kind, and raw value.
+// Other equatable values are compared using Equal,
+// and non-equatable values are compared structurally.
+//
+// If the interpreter has an equality cache, the results for stored container values are memoized.
+//
+func DeepEqual(interpreter *Interpreter, a, b Value) bool {
+	cache := interpreter.equalityCache
+	if cache != nil {
+		storageID, ok := storedStorageID(a)
+		if ok {
+			otherStorageID, ok := storedStorageID(b)
+			if ok {
+				if equal, ok := cache.get(storageID, otherStorageID); ok {
+					return equal
+				}
+
+				equal := deepEqual(interpreter, a, b)
+				cache.set(interpreter.Storage, storageID, otherStorageID, equal)
+				return equal
+			}
+		}
+	}
+
+	return deepEqual(interpreter, a, b)
+}
+
+func deepEqual(interpreter *Interpreter, a, b Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	if enum, ok := a.(*CompositeValue); ok && enum.Kind == common.CompositeKindEnum {
+		otherEnum, ok := b.(*CompositeValue)
+		if !ok ||
+			enum.Kind != otherEnum.Kind ||
+			enum.QualifiedIdentifier != otherEnum.QualifiedIdentifier ||
+			!common.LocationsMatch(enum.Location, otherEnum.Location) {
+
+			return false
+		}
+
+		return DeepEqual(
+			interpreter,
+			enum.GetField(interpreter, ReturnEmptyLocationRange, sema.EnumRawValueFieldName),
+			otherEnum.GetField(interpreter, ReturnEmptyLocationRange, sema.EnumRawValueFieldName),
+		)
+	}
+
+	if equatableValue, ok := a.(EquatableValue); ok {
+		return equatableValue.Equal(interpreter, ReturnEmptyLocationRange, b)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// EqualUnwrappingOptional returns true if the two given values are deeply equal,
+// after unwrapping a single layer of SomeValue on either side.
+//
+// This allows comparing the result of a storage read, which is optional,
+// against a non-optional value.
+//
+func EqualUnwrappingOptional(interpreter *Interpreter, a, b Value) bool {
+	if someValue, ok := a.(*SomeValue); ok {
+		a = someValue.Value
+	}
+
+	if someValue, ok := b.(*SomeValue); ok {
+		b = someValue.Value
+	}
+
+	return DeepEqual(interpreter, a, b)
+}
+
+// ReadOnlyValue flags the given value as read-only, if it is a container value,
+// and returns it. Mutating a read-only value, or any value nested in it, panics.
+//
+// The values nested in a read-only value are read-only when they are handed out,
+// e.g. by Get, GetField, Iterate, Walk, and ForEachField.
+//
+func ReadOnlyValue(value Value) Value {
+	switch value := value.(type) {
+	case *ArrayValue:
+		value.readOnly = true
+	case *DictionaryValue:
+		value.readOnly = true
+	case *CompositeValue:
+		value.readOnly = true
+	case *SomeValue:
+		value.Value = ReadOnlyValue(value.Value)
+	}
+	return value
+}
+
+// isReadOnlyValue returns true if the given value, or the value it optionally wraps,
+// is a read-only container value, see ReadOnlyValue.
+//
+func isReadOnlyValue(value Value) bool {
+	switch value := value.(type) {
+	case *ArrayValue:
+		return value.readOnly
+	case *DictionaryValue:
+		return value.readOnly
+	case *CompositeValue:
+		return value.readOnly
+	case *SomeValue:
+		return isReadOnlyValue(value.Value)
+	}
+	return false
+}
+
 // ResourceKindedValue
 
 type ResourceKindedValue interface {
@@ -351,6 +465,17 @@ func (v TypeValue) HashInput(interpreter *Interpreter, _ func() LocationRange, s
 
 type VoidValue struct{}
 
+// VoidValueSingleton is the void value.
+// Void values are immutable and carry no data, so this value can be shared.
+//
+var VoidValueSingleton = VoidValue{}
+
+// Void returns the void value.
+//
+func Void() VoidValue {
+	return VoidValueSingleton
+}
+
 var _ Value = VoidValue{}
 var _ atree.Storable = VoidValue{}
 var _ EquatableValue = VoidValue{}
@@ -648,13 +773,28 @@ func (v *StringValue) Concat(other *StringValue) Value {
 	return NewStringValue(sb.String())
 }
 
-func (v *StringValue) Slice(from IntValue, to IntValue, getLocationRange func() LocationRange) Value {
-	fromIndex := from.ToInt()
+// Slice returns the substring of the characters (grapheme clusters)
+// from the given start index (inclusive) up to the given end index (exclusive).
+//
+// It panics if an index is out of bounds, or if the start index is greater than the end index.
+//
+func (v *StringValue) Slice(
+	_ *Interpreter,
+	getLocationRange func() LocationRange,
+	fromIndex int,
+	toIndex int,
+) *StringValue {
 	v.checkBoundsInclusiveLength(fromIndex, getLocationRange)
-
-	toIndex := to.ToInt()
 	v.checkBoundsInclusiveLength(toIndex, getLocationRange)
 
+	if fromIndex > toIndex {
+		panic(InvalidSliceIndexError{
+			FromIndex:     fromIndex,
+			UpToIndex:     toIndex,
+			LocationRange: getLocationRange(),
+		})
+	}
+
 	if fromIndex == toIndex {
 		return NewStringValue("")
 	}
@@ -740,6 +880,7 @@ func (v *StringValue) GetMember(interpreter *Interpreter, _ func() LocationRange
 		return NewHostFunctionValue(
 			func(invocation Invocation) Value {
 				otherArray := invocation.Arguments[0].(*StringValue)
+				invocation.Interpreter.checkStringLength(len(v.Str) + len(otherArray.Str))
 				return v.Concat(otherArray)
 			},
 			sema.StringTypeConcatFunctionType,
@@ -750,7 +891,12 @@ func (v *StringValue) GetMember(interpreter *Interpreter, _ func() LocationRange
 			func(invocation Invocation) Value {
 				from := invocation.Arguments[0].(IntValue)
 				to := invocation.Arguments[1].(IntValue)
-				return v.Slice(from, to, invocation.GetLocationRange)
+				return v.Slice(
+					invocation.Interpreter,
+					invocation.GetLocationRange,
+					from.ToInt(),
+					to.ToInt(),
+				)
 			},
 			sema.StringTypeSliceFunctionType,
 		)
@@ -766,7 +912,9 @@ func (v *StringValue) GetMember(interpreter *Interpreter, _ func() LocationRange
 	case "toLower":
 		return NewHostFunctionValue(
 			func(invocation Invocation) Value {
-				return v.ToLower()
+				result := v.ToLower()
+				invocation.Interpreter.checkStringLength(len(result.Str))
+				return result
 			},
 			sema.StringTypeToLowerFunctionType,
 		)
@@ -896,6 +1044,16 @@ type ArrayValue struct {
 	array            *atree.Array
 	isDestroyed      bool
 	isResourceKinded *bool
+	readOnly         bool
+	// shared is the shared copy the value belongs to,
+	// if it still shares the slabs of the value it was transferred from (see TransferShared)
+	shared *sharedCopy
+	// lazyRoot is set instead of array if the array is empty
+	// and its root slab was not allocated yet (see atreeArray)
+	lazyRoot *lazyContainerRoot
+	// sentinel is true if the array is referred to by the empty container sentinel
+	// (see EmptyContainerStorable), so its root slab may be released when it becomes empty
+	sentinel bool
 }
 
 func NewArrayValue(
@@ -942,11 +1100,27 @@ func NewArrayValueWithIterator(
 	values func() Value,
 ) *ArrayValue {
 
+	// The root slab of an empty array is only allocated when it is needed
+
+	first := values()
+	if first == nil {
+		return &ArrayValue{
+			Type:     arrayType,
+			lazyRoot: newLazyContainerRoot(interpreter.Storage, atree.Address(address)),
+			sentinel: true,
+		}
+	}
+
 	array, err := atree.NewArrayFromBatchData(
 		interpreter.Storage,
 		atree.Address(address),
 		arrayType,
 		func() (atree.Value, error) {
+			if first != nil {
+				value := first
+				first = nil
+				return value, nil
+			}
 			return values(), nil
 		},
 	)
@@ -980,11 +1154,23 @@ func (v *ArrayValue) Accept(interpreter *Interpreter, visitor Visitor) {
 }
 
 func (v *ArrayValue) Iterate(f func(element Value) (resume bool)) {
+	if !v.hasRootSlab() {
+		return
+	}
+
 	err := v.array.Iterate(func(element atree.Value) (resume bool, err error) {
 		// atree.Array iteration provides low-level atree.Value,
 		// convert to high-level interpreter.Value
 
-		resume = f(MustConvertStoredValue(element))
+		value := MustConvertStoredValue(element)
+		if v.readOnly {
+			value = ReadOnlyValue(value)
+		}
+		if v.shared != nil {
+			value = v.shared.share(value)
+		}
+
+		resume = f(value)
 
 		return resume, nil
 	})
@@ -1021,6 +1207,9 @@ func (v *ArrayValue) StaticType() StaticType {
 }
 
 func (v *ArrayValue) Destroy(interpreter *Interpreter, getLocationRange func() LocationRange) {
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
 	v.Walk(func(element Value) {
 		maybeDestroy(interpreter, getLocationRange, element)
 	})
@@ -1032,16 +1221,28 @@ func (v *ArrayValue) IsDestroyed() bool {
 	return v.isDestroyed
 }
 
+func (v *ArrayValue) IsReadOnly() bool {
+	return v.readOnly
+}
+
+func (v *ArrayValue) checkMutable(getLocationRange func() LocationRange) {
+	if v.readOnly {
+		panic(ReadOnlyValueMutationError{
+			LocationRange: getLocationRange(),
+		})
+	}
+}
+
 func (v *ArrayValue) Concat(interpreter *Interpreter, getLocationRange func() LocationRange, other *ArrayValue) Value {
 
 	first := true
 
-	firstIterator, err := v.array.Iterator()
+	firstIterator, err := v.atreeArray().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
 
-	secondIterator, err := other.array.Iterator()
+	secondIterator, err := other.atreeArray().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
@@ -1097,6 +1298,141 @@ func (v *ArrayValue) Concat(interpreter *Interpreter, getLocationRange func() Lo
 	)
 }
 
+// Chunk returns a new array of arrays, which contain copies of the elements of this array,
+// split into chunks of the given size, in order. The last chunk may be shorter.
+//
+// For an array of type `[T]` or `[T; N]`, the result has type `[[T]]`.
+// Like the result of Concat, the result is not owned by any account.
+// If the size is not positive, an InvalidChunkSizeError is panicked.
+//
+func (v *ArrayValue) Chunk(interpreter *Interpreter, getLocationRange func() LocationRange, size int) *ArrayValue {
+	if size <= 0 {
+		panic(InvalidChunkSizeError{
+			Size:          size,
+			LocationRange: getLocationRange(),
+		})
+	}
+
+	chunkType := VariableSizedStaticType{
+		Type: v.Type.ElementType(),
+	}
+
+	count := v.Count()
+	index := 0
+
+	return NewArrayValueWithIterator(
+		interpreter,
+		VariableSizedStaticType{
+			Type: chunkType,
+		},
+		common.Address{},
+		func() Value {
+			if index >= count {
+				return nil
+			}
+
+			end := index + size
+			if end > count {
+				end = count
+			}
+
+			return NewArrayValueWithIterator(
+				interpreter,
+				chunkType,
+				common.Address{},
+				func() Value {
+					if index >= end {
+						return nil
+					}
+
+					element := v.Get(interpreter, getLocationRange, index)
+					index++
+
+					return element.Transfer(
+						interpreter,
+						getLocationRange,
+						atree.Address{},
+						false,
+						nil,
+					)
+				},
+			)
+		},
+	)
+}
+
+// Reduce folds the elements of the array from left to right:
+// combine is called with the current accumulator, initially the given initial value,
+// and each element, and returns the next accumulator. The final accumulator is returned.
+//
+// The elements passed to combine are owned by the array,
+// so combine must transfer an element to keep it, e.g. to store it in the accumulator.
+//
+// Reduce takes ownership of the initial value and of all intermediate accumulators:
+// when combine returns a different value than the accumulator it was passed,
+// the previous accumulator is removed, if it is an array, dictionary, or composite
+// which is not owned by any account. To keep a container accumulator,
+// combine should mutate and return it, instead of nesting it in a new accumulator.
+//
+func (v *ArrayValue) Reduce(
+	interpreter *Interpreter,
+	getLocationRange func() LocationRange,
+	initial Value,
+	combine func(acc, element Value) Value,
+) Value {
+	acc := initial
+
+	count := v.Count()
+	for index := 0; index < count; index++ {
+		element := v.Get(interpreter, getLocationRange, index)
+
+		next := combine(acc, element)
+		if next != acc {
+			interpreter.removeUnownedContainer(acc)
+		}
+
+		acc = next
+	}
+
+	return acc
+}
+
+// removeUnownedContainer removes the given value and all its slabs,
+// if it is an array, dictionary, or composite which is not owned by any account.
+//
+func (interpreter *Interpreter) removeUnownedContainer(value Value) {
+	var storageID atree.StorageID
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		if !value.hasRootSlab() {
+			// The root slab was not allocated yet, so there is nothing to remove
+			return
+		}
+		storageID = value.StorageID()
+
+	case *DictionaryValue:
+		if !value.hasRootSlab() {
+			// The root slab was not allocated yet, so there is nothing to remove
+			return
+		}
+		storageID = value.StorageID()
+
+	case *CompositeValue:
+		storageID = value.StorageID()
+
+	default:
+		return
+	}
+
+	if storageID.Address != (atree.Address{}) {
+		return
+	}
+
+	value.DeepRemove(interpreter)
+	interpreter.RemoveReferencedSlab(atree.StorageIDStorable(storageID))
+}
+
 func (v *ArrayValue) GetKey(interpreter *Interpreter, getLocationRange func() LocationRange, key Value) Value {
 	index := key.(NumberValue).ToInt()
 	return v.Get(interpreter, getLocationRange, index)
@@ -1113,14 +1449,25 @@ func (v *ArrayValue) handleIndexOutOfBoundsError(err error, index int, getLocati
 }
 
 func (v *ArrayValue) Get(interpreter *Interpreter, getLocationRange func() LocationRange, index int) Value {
-	storable, err := v.array.Get(uint64(index))
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationGet, tracingKindArray, time.Now())
+	}
+
+	storable, err := v.atreeArray().Get(uint64(index))
 	if err != nil {
 		v.handleIndexOutOfBoundsError(err, index, getLocationRange)
 
 		panic(ExternalError{err})
 	}
 
-	return StoredValue(storable, interpreter.Storage)
+	value := StoredValue(storable, interpreter.Storage)
+	if v.readOnly {
+		value = ReadOnlyValue(value)
+	}
+	if v.shared != nil {
+		value = v.shared.share(value)
+	}
+	return value
 }
 
 func (v *ArrayValue) SetKey(interpreter *Interpreter, getLocationRange func() LocationRange, key Value, value Value) {
@@ -1129,13 +1476,15 @@ func (v *ArrayValue) SetKey(interpreter *Interpreter, getLocationRange func() Lo
 }
 
 func (v *ArrayValue) Set(interpreter *Interpreter, getLocationRange func() LocationRange, index int, element Value) {
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
 
 	interpreter.checkContainerMutation(v.Type.ElementType(), element, getLocationRange)
 
 	element = element.Transfer(
 		interpreter,
 		getLocationRange,
-		v.array.Address(),
+		v.atreeArray().Address(),
 		true,
 		nil,
 	)
@@ -1147,6 +1496,7 @@ func (v *ArrayValue) Set(interpreter *Interpreter, getLocationRange func() Locat
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.array)
+	interpreter.invalidateEqualityCache(v.StorageID())
 
 	existingValue := StoredValue(existingStorable, interpreter.Storage)
 
@@ -1172,13 +1522,15 @@ func (v *ArrayValue) RecursiveString(seenReferences SeenReferences) string {
 }
 
 func (v *ArrayValue) Append(interpreter *Interpreter, getLocationRange func() LocationRange, element Value) {
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
 
 	interpreter.checkContainerMutation(v.Type.ElementType(), element, getLocationRange)
 
 	element = element.Transfer(
 		interpreter,
 		getLocationRange,
-		v.array.Address(),
+		v.atreeArray().Address(),
 		true,
 		nil,
 	)
@@ -1188,6 +1540,7 @@ func (v *ArrayValue) Append(interpreter *Interpreter, getLocationRange func() Lo
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.array)
+	interpreter.invalidateEqualityCache(v.StorageID())
 }
 
 func (v *ArrayValue) AppendAll(interpreter *Interpreter, getLocationRange func() LocationRange, other *ArrayValue) {
@@ -1202,13 +1555,19 @@ func (v *ArrayValue) InsertKey(interpreter *Interpreter, getLocationRange func()
 }
 
 func (v *ArrayValue) Insert(interpreter *Interpreter, getLocationRange func() LocationRange, index int, element Value) {
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationInsert, tracingKindArray, time.Now())
+	}
+
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
 
 	interpreter.checkContainerMutation(v.Type.ElementType(), element, getLocationRange)
 
 	element = element.Transfer(
 		interpreter,
 		getLocationRange,
-		v.array.Address(),
+		v.atreeArray().Address(),
 		true,
 		nil,
 	)
@@ -1220,6 +1579,7 @@ func (v *ArrayValue) Insert(interpreter *Interpreter, getLocationRange func() Lo
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.array)
+	interpreter.invalidateEqualityCache(v.StorageID())
 }
 
 func (v *ArrayValue) RemoveKey(interpreter *Interpreter, getLocationRange func() LocationRange, key Value) Value {
@@ -1228,13 +1588,23 @@ func (v *ArrayValue) RemoveKey(interpreter *Interpreter, getLocationRange func()
 }
 
 func (v *ArrayValue) Remove(interpreter *Interpreter, getLocationRange func() LocationRange, index int) Value {
-	storable, err := v.array.Remove(uint64(index))
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationRemove, tracingKindArray, time.Now())
+	}
+
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
+	storable, err := v.atreeArray().Remove(uint64(index))
 	if err != nil {
 		v.handleIndexOutOfBoundsError(err, index, getLocationRange)
 
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.array)
+	interpreter.invalidateEqualityCache(v.StorageID())
+
+	v.releaseRootSlab()
 
 	value := StoredValue(storable, interpreter.Storage)
 
@@ -1247,6 +1617,16 @@ func (v *ArrayValue) Remove(interpreter *Interpreter, getLocationRange func() Lo
 	)
 }
 
+// RemoveAt removes the element at the given index, shifting all following elements,
+// and returns the removed element, transferred out of the array's account.
+//
+// The caller owns the returned value, and must deep-remove it if it is no longer needed.
+// Out-of-range indices panic with an ArrayIndexOutOfBoundsError, like Get.
+//
+func (v *ArrayValue) RemoveAt(interpreter *Interpreter, getLocationRange func() LocationRange, index int) Value {
+	return v.Remove(interpreter, getLocationRange, index)
+}
+
 func (v *ArrayValue) RemoveFirst(interpreter *Interpreter, getLocationRange func() LocationRange) Value {
 	return v.Remove(interpreter, getLocationRange, 0)
 }
@@ -1286,7 +1666,7 @@ func (v *ArrayValue) GetMember(inter *Interpreter, _ func() LocationRange, name
 					invocation.GetLocationRange,
 					invocation.Arguments[0],
 				)
-				return VoidValue{}
+				return Void()
 			},
 			sema.ArrayAppendFunctionType(
 				v.SemaType(inter).ElementType(false),
@@ -1302,7 +1682,7 @@ func (v *ArrayValue) GetMember(inter *Interpreter, _ func() LocationRange, name
 					invocation.GetLocationRange,
 					otherArray,
 				)
-				return VoidValue{}
+				return Void()
 			},
 			sema.ArrayAppendAllFunctionType(
 				v.SemaType(inter),
@@ -1335,7 +1715,7 @@ func (v *ArrayValue) GetMember(inter *Interpreter, _ func() LocationRange, name
 					index,
 					element,
 				)
-				return VoidValue{}
+				return Void()
 			},
 			sema.ArrayInsertFunctionType(
 				v.SemaType(inter).ElementType(false),
@@ -1412,6 +1792,10 @@ func (*ArrayValue) SetMember(_ *Interpreter, _ func() LocationRange, _ string, _
 }
 
 func (v *ArrayValue) Count() int {
+	if !v.hasRootSlab() {
+		return 0
+	}
+
 	return int(v.array.Count())
 }
 
@@ -1485,8 +1869,9 @@ func (v *ArrayValue) Equal(interpreter *Interpreter, getLocationRange func() Loc
 	return true
 }
 
-func (v *ArrayValue) Storable(_ atree.SlabStorage, _ atree.Address, _ uint64) (atree.Storable, error) {
-	return atree.StorageIDStorable(v.StorageID()), nil
+func (v *ArrayValue) Storable(storage atree.SlabStorage, _ atree.Address, maxInlineSize uint64) (atree.Storable, error) {
+	unshareForStorage(v.shared)
+	return v.storable(storage, maxInlineSize), nil
 }
 
 func (v *ArrayValue) Transfer(
@@ -1497,6 +1882,22 @@ func (v *ArrayValue) Transfer(
 	storable atree.Storable,
 ) Value {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		if result, ok := hooks.transfer(interpreter, v, getLocationRange, address, remove, storable); ok {
+			return result
+		}
+
+		defer hooks.reportOperationTrace(tracingOperationTransfer, tracingKindArray, time.Now())
+	}
+
+	if remove {
+		v.checkMutable(getLocationRange)
+		v.unshare(interpreter)
+	} else if v.shared != nil && !v.NeedsStoreTo(address) {
+		// The shared copy itself is the result of the transfer, and is about to be stored
+		unshareForStorage(v.shared)
+	}
+
 	if interpreter.tracingEnabled {
 		startTime := time.Now()
 		defer func() {
@@ -1504,6 +1905,33 @@ func (v *ArrayValue) Transfer(
 		}()
 	}
 
+	// An empty array without a root slab has no slabs to transfer,
+	// only a storage ID for the root slab is reserved in the target account
+
+	if !v.hasRootSlab() {
+		isResourceKinded := v.IsResourceKinded(interpreter)
+
+		if !v.NeedsStoreTo(address) && isResourceKinded {
+			return v
+		}
+
+		lazyRoot := newLazyContainerRoot(interpreter.Storage, address)
+
+		if isResourceKinded {
+			v.lazyRoot = lazyRoot
+			return v
+		} else {
+			return &ArrayValue{
+				Type:             v.Type,
+				semaType:         v.semaType,
+				isResourceKinded: v.isResourceKinded,
+				lazyRoot:         lazyRoot,
+				sentinel:         true,
+				isDestroyed:      v.isDestroyed,
+			}
+		}
+	}
+
 	array := v.array
 
 	needsStoreTo := v.NeedsStoreTo(address)
@@ -1529,8 +1957,9 @@ func (v *ArrayValue) Transfer(
 					return nil, nil
 				}
 
-				element := MustConvertStoredValue(value).
-					Transfer(interpreter, getLocationRange, address, remove, nil)
+				element := interpreter.transferStoredValue(value, getLocationRange, address, remove)
+
+				interpreter.reportTransferProgress()
 
 				return element, nil
 			},
@@ -1547,6 +1976,7 @@ func (v *ArrayValue) Transfer(
 				panic(ExternalError{err})
 			}
 			interpreter.maybeValidateAtreeValue(v.array)
+			interpreter.invalidateEqualityCache(v.StorageID())
 
 			interpreter.RemoveReferencedSlab(storable)
 		}
@@ -1567,14 +1997,14 @@ func (v *ArrayValue) Transfer(
 }
 
 func (v *ArrayValue) Clone(interpreter *Interpreter) Value {
-	iterator, err := v.array.Iterator()
+	iterator, err := v.atreeArray().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
 
 	array, err := atree.NewArrayFromBatchData(
 		interpreter.Storage,
-		v.StorageID().Address,
+		atree.Address(v.GetOwner()),
 		v.array.Type(),
 		func() (atree.Value, error) {
 			value, err := iterator.Next()
@@ -1585,8 +2015,7 @@ func (v *ArrayValue) Clone(interpreter *Interpreter) Value {
 				return nil, nil
 			}
 
-			element := MustConvertStoredValue(value).
-				Clone(interpreter)
+			element := interpreter.cloneStoredValue(value)
 
 			return element, nil
 		},
@@ -1605,26 +2034,53 @@ func (v *ArrayValue) Clone(interpreter *Interpreter) Value {
 
 func (v *ArrayValue) DeepRemove(interpreter *Interpreter) {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationDeepRemove, tracingKindArray, time.Now())
+	}
+
+	v.checkMutable(ReturnEmptyLocationRange)
+
+	// A shared copy does not own any slabs
+
+	if v.shared != nil && v.shared.root == sharedValue(v) {
+		v.shared.release(interpreter)
+		return
+	}
+
+	v.unshare(interpreter)
+
+	// An empty array without a root slab has nothing to remove
+
+	if !v.hasRootSlab() {
+		return
+	}
+
 	// Remove nested values and storables
 
 	storage := v.array.Storage
 
 	err := v.array.PopIterate(func(storable atree.Storable) {
-		value := StoredValue(storable, storage)
-		value.DeepRemove(interpreter)
+		interpreter.deepRemoveStoredValue(storable, storage)
 		interpreter.RemoveReferencedSlab(storable)
 	})
 	if err != nil {
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.array)
+	interpreter.invalidateEqualityCache(v.StorageID())
 }
 
 func (v *ArrayValue) StorageID() atree.StorageID {
+	if v.lazyRoot != nil {
+		return v.lazyRoot.storageID
+	}
 	return v.array.StorageID()
 }
 
 func (v *ArrayValue) GetOwner() common.Address {
+	if v.shared != nil {
+		return common.Address(v.shared.address)
+	}
 	return common.Address(v.StorageID().Address)
 }
 
@@ -1639,6 +2095,45 @@ func (v *ArrayValue) NeedsStoreTo(address atree.Address) bool {
 	return address != v.StorageID().Address
 }
 
+// InferElementType returns the most specific common static type
+// of the actual elements of the array,
+// i.e. the least common super type of the elements' static types.
+//
+// If the elements have no common super type, or the array is empty,
+// AnyStruct is returned.
+//
+func (v *ArrayValue) InferElementType(interpreter *Interpreter) StaticType {
+
+	elementTypes := make([]sema.Type, 0, v.Count())
+
+	v.Iterate(func(element Value) (resume bool) {
+		elementType := element.StaticType()
+		if elementType == nil {
+			elementTypes = nil
+			return false
+		}
+
+		elementTypes = append(
+			elementTypes,
+			interpreter.MustConvertStaticToSemaType(elementType),
+		)
+		return true
+	})
+
+	if len(elementTypes) == 0 {
+		return PrimitiveStaticTypeAnyStruct
+	}
+
+	superType := sema.LeastCommonSuperType(elementTypes...)
+
+	switch superType {
+	case sema.InvalidType, sema.NeverType:
+		return PrimitiveStaticTypeAnyStruct
+	}
+
+	return ConvertSemaToStaticType(superType)
+}
+
 func (v *ArrayValue) IsResourceKinded(interpreter *Interpreter) bool {
 	if v.isResourceKinded == nil {
 		isResourceKinded := v.SemaType(interpreter).IsResourceType()
@@ -8096,9 +8591,13 @@ type CompositeValue struct {
 	Destructor          FunctionValue
 	Stringer            func(value *CompositeValue, seenReferences SeenReferences) string
 	isDestroyed         bool
+	readOnly            bool
 	typeID              common.TypeID
 	staticType          StaticType
 	dynamicType         DynamicType
+	// shared is the shared copy the value belongs to,
+	// if it still shares the slabs of the value it was transferred from (see TransferShared)
+	shared *sharedCopy
 }
 
 type ComputedField func(*Interpreter, func() LocationRange) Value
@@ -8151,6 +8650,80 @@ func NewCompositeValue(
 	return v
 }
 
+// NewCompositeValueFromType returns a new composite value of the given type,
+// with the given field values set in the order the fields are declared in the type's members.
+//
+// A value must be given for each declared field, except for predeclared fields
+// and fields which are ignored in serialization, and no other values may be given.
+//
+func NewCompositeValueFromType(
+	interpreter *Interpreter,
+	compositeType *sema.CompositeType,
+	fieldValues map[string]Value,
+	address common.Address,
+) (*CompositeValue, error) {
+
+	fields := make([]CompositeField, 0, len(fieldValues))
+	declared := make(map[string]struct{}, len(fieldValues))
+
+	var err error
+
+	compositeType.Members.Foreach(func(name string, member *sema.Member) {
+		if err != nil ||
+			member.DeclarationKind != common.DeclarationKindField ||
+			member.IgnoreInSerialization {
+
+			return
+		}
+
+		declared[name] = struct{}{}
+
+		value, ok := fieldValues[name]
+		if !ok {
+			if !member.Predeclared {
+				err = MissingCompositeFieldError{
+					TypeID:    compositeType.ID(),
+					FieldName: name,
+				}
+			}
+			return
+		}
+
+		fields = append(fields, CompositeField{
+			Name:  name,
+			Value: value,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(fields) != len(fieldValues) {
+		// NOTE: report the first unknown field in lexicographic order,
+		// so the error is deterministic
+		var unknown string
+		for name := range fieldValues { //nolint:maprangecheck
+			if _, ok := declared[name]; !ok && (unknown == "" || name < unknown) {
+				unknown = name
+			}
+		}
+
+		return nil, UnknownCompositeFieldError{
+			TypeID:    compositeType.ID(),
+			FieldName: unknown,
+		}
+	}
+
+	return NewCompositeValue(
+		interpreter,
+		compositeType.Location,
+		compositeType.QualifiedIdentifier(),
+		compositeType.Kind,
+		fields,
+		address,
+	), nil
+}
+
 var _ Value = &CompositeValue{}
 var _ EquatableValue = &CompositeValue{}
 var _ HashableValue = &CompositeValue{}
@@ -8185,7 +8758,7 @@ func (v *CompositeValue) DynamicType(interpreter *Interpreter, _ SeenReferences)
 		if v.Location == nil {
 			staticType, err = interpreter.getNativeCompositeType(v.QualifiedIdentifier)
 		} else {
-			staticType, err = interpreter.getUserCompositeType(v.Location, v.TypeID())
+			staticType, err = interpreter.getUserCompositeType(v.Location, v.QualifiedIdentifier, v.TypeID())
 		}
 		if err != nil {
 			panic(err)
@@ -8214,10 +8787,25 @@ func (v *CompositeValue) IsDestroyed() bool {
 	return v.isDestroyed
 }
 
-func (v *CompositeValue) Destroy(interpreter *Interpreter, getLocationRange func() LocationRange) {
-	interpreter = v.getInterpreter(interpreter)
+func (v *CompositeValue) IsReadOnly() bool {
+	return v.readOnly
+}
 
-	// if composite was deserialized, dynamically link in the destructor
+func (v *CompositeValue) checkMutable(getLocationRange func() LocationRange) {
+	if v.readOnly {
+		panic(ReadOnlyValueMutationError{
+			LocationRange: getLocationRange(),
+		})
+	}
+}
+
+func (v *CompositeValue) Destroy(interpreter *Interpreter, getLocationRange func() LocationRange) {
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
+	interpreter = v.getInterpreter(interpreter)
+
+	// if composite was deserialized, dynamically link in the destructor
 	if v.Destructor == nil {
 		v.Destructor = interpreter.typeCodes.CompositeCodes[v.TypeID()].DestructorFunction
 	}
@@ -8334,7 +8922,7 @@ func (v *CompositeValue) OwnerValue(interpreter *Interpreter, getLocationRange f
 	address := v.StorageID().Address
 
 	if address == (atree.Address{}) {
-		return NilValue{}
+		return Nil()
 	}
 
 	ownerAccount := interpreter.publicAccountHandler(interpreter, AddressValue(address))
@@ -8351,6 +8939,9 @@ func (v *CompositeValue) RemoveMember(
 	name string,
 ) Value {
 
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
 	// No need to clean up storable for passed-in key value,
 	// as atree never calls Storable()
 	existingKeyStorable, existingValueStorable, err := v.dictionary.Remove(
@@ -8365,6 +8956,7 @@ func (v *CompositeValue) RemoveMember(
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
 
 	storage := interpreter.Storage
 
@@ -8390,6 +8982,10 @@ func (v *CompositeValue) SetMember(
 	name string,
 	value Value,
 ) {
+
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
 	address := v.StorageID().Address
 
 	value = value.Transfer(
@@ -8410,6 +9006,7 @@ func (v *CompositeValue) SetMember(
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
 
 	if existingStorable != nil {
 		existingValue := StoredValue(existingStorable, interpreter.Storage)
@@ -8484,7 +9081,14 @@ func (v *CompositeValue) GetField(_ *Interpreter, _ func() LocationRange, name s
 		panic(ExternalError{err})
 	}
 
-	return StoredValue(storable, v.dictionary.Storage)
+	value := StoredValue(storable, v.dictionary.Storage)
+	if v.readOnly {
+		value = ReadOnlyValue(value)
+	}
+	if v.shared != nil {
+		value = v.shared.share(value)
+	}
+	return value
 }
 
 func (v *CompositeValue) Equal(interpreter *Interpreter, getLocationRange func() LocationRange, other Value) bool {
@@ -8520,8 +9124,7 @@ func (v *CompositeValue) Equal(interpreter *Interpreter, getLocationRange func()
 		// (if stored in different account, as storage ID is used as hash seed)
 		otherValue := otherComposite.GetField(interpreter, getLocationRange, fieldName)
 
-		equatableValue, ok := MustConvertStoredValue(value).(EquatableValue)
-		if !ok || !equatableValue.Equal(interpreter, getLocationRange, otherValue) {
+		if !interpreter.storedValueEqual(value, getLocationRange, otherValue) {
 			return false
 		}
 	}
@@ -8670,6 +9273,19 @@ func (v *CompositeValue) Transfer(
 	storable atree.Storable,
 ) Value {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		if result, ok := hooks.transfer(interpreter, v, getLocationRange, address, remove, storable); ok {
+			return result
+		}
+
+		defer hooks.reportOperationTrace(tracingOperationTransfer, tracingKindComposite, time.Now())
+	}
+
+	if remove {
+		v.checkMutable(getLocationRange)
+		v.unshare(interpreter)
+	}
+
 	dictionary := v.dictionary
 
 	needsStoreTo := v.NeedsStoreTo(address)
@@ -8702,8 +9318,9 @@ func (v *CompositeValue) Transfer(
 				// NOTE: key is stringAtreeValue
 				// and does not need to be converted or copied
 
-				value := MustConvertStoredValue(atreeValue).
-					Transfer(interpreter, getLocationRange, address, remove, nil)
+				value := interpreter.transferStoredValue(atreeValue, getLocationRange, address, remove)
+
+				interpreter.reportTransferProgress()
 
 				return atreeKey, value, nil
 			},
@@ -8721,6 +9338,7 @@ func (v *CompositeValue) Transfer(
 				panic(ExternalError{err})
 			}
 			interpreter.maybeValidateAtreeValue(v.dictionary)
+			interpreter.invalidateEqualityCache(v.StorageID())
 
 			interpreter.RemoveReferencedSlab(storable)
 		}
@@ -8774,8 +9392,8 @@ func (v *CompositeValue) Clone(interpreter *Interpreter) Value {
 				return nil, nil, nil
 			}
 
-			key := MustConvertStoredValue(atreeKey).Clone(interpreter)
-			value := MustConvertStoredValue(atreeValue).Clone(interpreter)
+			key := interpreter.cloneStoredValue(atreeKey)
+			value := interpreter.cloneStoredValue(atreeValue)
 
 			return key, value, nil
 		},
@@ -8804,6 +9422,14 @@ func (v *CompositeValue) Clone(interpreter *Interpreter) Value {
 
 func (v *CompositeValue) DeepRemove(interpreter *Interpreter) {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationDeepRemove, tracingKindComposite, time.Now())
+	}
+
+	v.checkMutable(ReturnEmptyLocationRange)
+
+	v.unshare(interpreter)
+
 	// Remove nested values and storables
 
 	storage := v.dictionary.Storage
@@ -8813,17 +9439,20 @@ func (v *CompositeValue) DeepRemove(interpreter *Interpreter) {
 		// and not a Value, so no need to deep remove
 		interpreter.RemoveReferencedSlab(nameStorable)
 
-		value := StoredValue(valueStorable, storage)
-		value.DeepRemove(interpreter)
+		interpreter.deepRemoveStoredValue(valueStorable, storage)
 		interpreter.RemoveReferencedSlab(valueStorable)
 	})
 	if err != nil {
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
 }
 
 func (v *CompositeValue) GetOwner() common.Address {
+	if v.shared != nil {
+		return common.Address(v.shared.address)
+	}
 	return common.Address(v.StorageID().Address)
 }
 
@@ -8832,9 +9461,17 @@ func (v *CompositeValue) GetOwner() common.Address {
 //
 func (v *CompositeValue) ForEachField(f func(fieldName string, fieldValue Value)) {
 	err := v.dictionary.Iterate(func(key atree.Value, value atree.Value) (resume bool, err error) {
+		fieldValue := MustConvertStoredValue(value)
+		if v.readOnly {
+			fieldValue = ReadOnlyValue(fieldValue)
+		}
+		if v.shared != nil {
+			fieldValue = v.shared.share(fieldValue)
+		}
+
 		f(
 			string(key.(stringAtreeValue)),
-			MustConvertStoredValue(value),
+			fieldValue,
 		)
 		return true, nil
 	})
@@ -8843,16 +9480,52 @@ func (v *CompositeValue) ForEachField(f func(fieldName string, fieldValue Value)
 	}
 }
 
+// ForEachFieldWithType iterates over all field-name field-value pairs of the composite value,
+// like ForEachField, and also provides the declared type of each field,
+// as declared in the composite type of the value.
+//
+// Fields which are not declared in the composite type, e.g. injected fields, have a nil type.
+// It panics if the composite type cannot be loaded.
+//
+func (v *CompositeValue) ForEachFieldWithType(
+	interpreter *Interpreter,
+	f func(name string, value Value, fieldType sema.Type),
+) {
+	compositeType, err := interpreter.GetCompositeType(v.Location, v.QualifiedIdentifier, v.TypeID())
+	if err != nil {
+		panic(err)
+	}
+
+	members := compositeType.Members
+
+	v.ForEachField(func(name string, value Value) {
+		var fieldType sema.Type
+
+		if members != nil {
+			member, ok := members.Get(name)
+			if ok && member.DeclarationKind == common.DeclarationKindField {
+				fieldType = member.TypeAnnotation.Type
+			}
+		}
+
+		f(name, value, fieldType)
+	})
+}
+
 func (v *CompositeValue) StorageID() atree.StorageID {
 	return v.dictionary.StorageID()
 }
 
 func (v *CompositeValue) RemoveField(
 	interpreter *Interpreter,
-	_ func() LocationRange,
+	getLocationRange func() LocationRange,
 	name string,
 ) {
 
+	v.checkMutable(getLocationRange)
+
+	v.unshare(interpreter)
+
 	existingKeyStorable, existingValueStorable, err := v.dictionary.Remove(
 		stringAtreeComparator,
 		stringAtreeHashInput,
@@ -8865,6 +9538,7 @@ func (v *CompositeValue) RemoveField(
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
 
 	storage := interpreter.Storage
 
@@ -8917,6 +9591,18 @@ type DictionaryValue struct {
 	isResourceKinded *bool
 	dictionary       *atree.OrderedMap
 	isDestroyed      bool
+	readOnly         bool
+	// shared is the shared copy the value belongs to,
+	// if it still shares the slabs of the value it was transferred from (see TransferShared)
+	shared *sharedCopy
+	// lazyRoot is set instead of dictionary if the dictionary is empty
+	// and its root slab was not allocated yet (see atreeMap)
+	lazyRoot *lazyContainerRoot
+	// sentinel is true if the dictionary is referred to by the empty container sentinel
+	// (see EmptyContainerStorable), so its root slab may be released when it becomes empty
+	sentinel bool
+	// contentHash is nil if the content hash is not enabled (see EnableContentHash)
+	contentHash *[32]byte
 }
 
 func NewDictionaryValue(
@@ -8944,6 +9630,22 @@ func NewDictionaryValueWithAddress(
 		panic("uneven number of keys and values")
 	}
 
+	if interpreter.duplicateKeyCheckEnabled {
+		// NOTE: check before the dictionary is created,
+		// so no slabs are allocated if there are duplicate keys
+		checkDuplicateDictionaryKeys(interpreter, keysAndValues)
+	}
+
+	// The root slab of an empty dictionary is only allocated when it is needed
+
+	if keysAndValuesCount == 0 {
+		return &DictionaryValue{
+			Type:     dictionaryType,
+			lazyRoot: newLazyContainerRoot(interpreter.Storage, atree.Address(address)),
+			sentinel: true,
+		}
+	}
+
 	dictionary, err := atree.NewMap(
 		interpreter.Storage,
 		atree.Address(address),
@@ -8970,6 +9672,49 @@ func NewDictionaryValueWithAddress(
 	return v
 }
 
+// checkDuplicateDictionaryKeys panics with a DuplicateDictionaryKeyError
+// if the given keys and values contain the same key more than once.
+//
+func checkDuplicateDictionaryKeys(interpreter *Interpreter, keysAndValues []Value) {
+	key, ok := findDuplicateDictionaryKey(interpreter, keysAndValues)
+	if ok {
+		panic(DuplicateDictionaryKeyError{
+			Key: key,
+		})
+	}
+}
+
+// findDuplicateDictionaryKey returns the first key in the given keys and values
+// which is equal to a preceding key, if any.
+//
+func findDuplicateDictionaryKey(interpreter *Interpreter, keysAndValues []Value) (Value, bool) {
+	keysByHashInput := make(map[string][]Value, len(keysAndValues)/2)
+
+	var scratch [32]byte
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := keysAndValues[i]
+
+		hashableKey, ok := key.(HashableValue)
+		if !ok {
+			continue
+		}
+
+		hashInput := string(hashableKey.HashInput(interpreter, ReturnEmptyLocationRange, scratch[:]))
+
+		for _, existingKey := range keysByHashInput[hashInput] {
+			equatableKey, ok := key.(EquatableValue)
+			if ok && equatableKey.Equal(interpreter, ReturnEmptyLocationRange, existingKey) {
+				return key, true
+			}
+		}
+
+		keysByHashInput[hashInput] = append(keysByHashInput[hashInput], key)
+	}
+
+	return nil, false
+}
+
 var _ Value = &DictionaryValue{}
 var _ atree.Value = &DictionaryValue{}
 var _ EquatableValue = &DictionaryValue{}
@@ -8990,14 +9735,25 @@ func (v *DictionaryValue) Accept(interpreter *Interpreter, visitor Visitor) {
 }
 
 func (v *DictionaryValue) Iterate(f func(key, value Value) (resume bool)) {
+	if !v.hasRootSlab() {
+		return
+	}
+
 	err := v.dictionary.Iterate(func(key, value atree.Value) (resume bool, err error) {
 		// atree.OrderedMap iteration provides low-level atree.Value,
 		// convert to high-level interpreter.Value
 
-		resume = f(
-			MustConvertStoredValue(key),
-			MustConvertStoredValue(value),
-		)
+		keyValue := MustConvertStoredValue(key)
+		valueValue := MustConvertStoredValue(value)
+		if v.readOnly {
+			keyValue = ReadOnlyValue(keyValue)
+			valueValue = ReadOnlyValue(valueValue)
+		}
+		if v.shared != nil {
+			valueValue = v.shared.share(valueValue)
+		}
+
+		resume = f(keyValue, valueValue)
 
 		return resume, nil
 	})
@@ -9042,7 +9798,22 @@ func (v *DictionaryValue) IsDestroyed() bool {
 	return v.isDestroyed
 }
 
+func (v *DictionaryValue) IsReadOnly() bool {
+	return v.readOnly
+}
+
+func (v *DictionaryValue) checkMutable(getLocationRange func() LocationRange) {
+	if v.readOnly {
+		panic(ReadOnlyValueMutationError{
+			LocationRange: getLocationRange(),
+		})
+	}
+}
+
 func (v *DictionaryValue) Destroy(interpreter *Interpreter, getLocationRange func() LocationRange) {
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
 	v.Iterate(func(key, value Value) (resume bool) {
 		// Resources cannot be keys at the moment, so should theoretically not be needed
 		maybeDestroy(interpreter, getLocationRange, key)
@@ -9059,6 +9830,10 @@ func (v *DictionaryValue) ContainsKey(
 	keyValue Value,
 ) BoolValue {
 
+	if !v.hasRootSlab() {
+		return false
+	}
+
 	valueComparator := newValueComparator(interpreter, getLocationRange)
 	hashInputProvider := newHashInputProvider(interpreter, getLocationRange)
 
@@ -9082,6 +9857,14 @@ func (v *DictionaryValue) Get(
 	keyValue Value,
 ) (Value, bool) {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationGet, tracingKindDictionary, time.Now())
+	}
+
+	if !v.hasRootSlab() {
+		return nil, false
+	}
+
 	valueComparator := newValueComparator(interpreter, getLocationRange)
 	hashInputProvider := newHashInputProvider(interpreter, getLocationRange)
 
@@ -9099,6 +9882,12 @@ func (v *DictionaryValue) Get(
 
 	storage := v.dictionary.Storage
 	value := StoredValue(storable, storage)
+	if v.readOnly {
+		value = ReadOnlyValue(value)
+	}
+	if v.shared != nil {
+		value = v.shared.share(value)
+	}
 	return value, true
 }
 
@@ -9108,7 +9897,7 @@ func (v *DictionaryValue) GetKey(interpreter *Interpreter, getLocationRange func
 		return NewSomeValueNonCopying(value)
 	}
 
-	return NilValue{}
+	return Nil()
 }
 
 func (v *DictionaryValue) SetKey(
@@ -9118,6 +9907,11 @@ func (v *DictionaryValue) SetKey(
 	value Value,
 ) {
 	interpreter.checkContainerMutation(v.Type.KeyType, keyValue, getLocationRange)
+
+	if someValue, ok := value.(*SomeValue); ok && interpreter.valueTypeCheckEnabled {
+		interpreter.checkDictionaryValueType(v.Type.ValueType, keyValue, someValue.Value, getLocationRange)
+	}
+
 	interpreter.checkContainerMutation(
 		OptionalStaticType{
 			Type: v.Type.ValueType,
@@ -9179,7 +9973,7 @@ func (v *DictionaryValue) GetMember(
 
 	case "keys":
 
-		iterator, err := v.dictionary.Iterator()
+		iterator, err := v.atreeMap().Iterator()
 		if err != nil {
 			panic(ExternalError{err})
 		}
@@ -9207,7 +10001,7 @@ func (v *DictionaryValue) GetMember(
 
 	case "values":
 
-		iterator, err := v.dictionary.Iterator()
+		iterator, err := v.atreeMap().Iterator()
 		if err != nil {
 			panic(ExternalError{err})
 		}
@@ -9296,6 +10090,10 @@ func (*DictionaryValue) SetMember(_ *Interpreter, _ func() LocationRange, _ stri
 }
 
 func (v *DictionaryValue) Count() int {
+	if !v.hasRootSlab() {
+		return 0
+	}
+
 	return int(v.dictionary.Count())
 }
 
@@ -9313,42 +10111,68 @@ func (v *DictionaryValue) Remove(
 	keyValue Value,
 ) OptionalValue {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationRemove, tracingKindDictionary, time.Now())
+	}
+
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
+	if !v.hasRootSlab() {
+		return Nil()
+	}
+
+	updateContentHash := v.updatesContentHash()
+
+	var contentHashChange [32]byte
+	if updateContentHash {
+		contentHashChange = v.contentHashChange(interpreter, getLocationRange, keyValue, nil)
+	}
+
 	valueComparator := newValueComparator(interpreter, getLocationRange)
 	hashInputProvider := newHashInputProvider(interpreter, getLocationRange)
 
 	// No need to clean up storable for passed-in key value,
 	// as atree never calls Storable()
-	existingKeyStorable, existingValueStorable, err := v.dictionary.Remove(
+	existingKeyStorable, existingValueStorable, err := v.atreeMap().Remove(
 		valueComparator,
 		hashInputProvider,
 		keyValue,
 	)
 	if err != nil {
 		if _, ok := err.(*atree.KeyNotFoundError); ok {
-			return NilValue{}
+			return Nil()
 		}
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
+
+	v.releaseRootSlab()
 
 	storage := interpreter.Storage
 
+	existingKeyValue := StoredValue(existingKeyStorable, storage)
+	existingValue := StoredValue(existingValueStorable, storage)
+
+	if updateContentHash {
+		xorContentHash(v.contentHash, contentHashChange)
+	}
+
 	// Key
 
-	existingKeyValue := StoredValue(existingKeyStorable, storage)
 	existingKeyValue.DeepRemove(interpreter)
 	interpreter.RemoveReferencedSlab(existingKeyStorable)
 
 	// Value
 
-	existingValue := StoredValue(existingValueStorable, storage).
-		Transfer(
-			interpreter,
-			getLocationRange,
-			atree.Address{},
-			true,
-			existingValueStorable,
-		)
+	existingValue = existingValue.Transfer(
+		interpreter,
+		getLocationRange,
+		atree.Address{},
+		true,
+		existingValueStorable,
+	)
 
 	return NewSomeValueNonCopying(existingValue)
 }
@@ -9367,10 +10191,36 @@ func (v *DictionaryValue) Insert(
 	keyValue, value Value,
 ) OptionalValue {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationInsert, tracingKindDictionary, time.Now())
+	}
+
+	v.checkMutable(getLocationRange)
+	v.unshare(interpreter)
+
+	if !IsHashableValue(keyValue) {
+		panic(NonHashableKeyError{
+			Value:         keyValue,
+			LocationRange: getLocationRange(),
+		})
+	}
+
 	interpreter.checkContainerMutation(v.Type.KeyType, keyValue, getLocationRange)
+
+	if interpreter.valueTypeCheckEnabled {
+		interpreter.checkDictionaryValueType(v.Type.ValueType, keyValue, value, getLocationRange)
+	}
+
 	interpreter.checkContainerMutation(v.Type.ValueType, value, getLocationRange)
 
-	address := v.dictionary.Address()
+	updateContentHash := v.updatesContentHash()
+
+	var contentHashChange [32]byte
+	if updateContentHash {
+		contentHashChange = v.contentHashChange(interpreter, getLocationRange, keyValue, value)
+	}
+
+	address := v.atreeMap().Address()
 
 	keyValue = keyValue.Transfer(
 		interpreter,
@@ -9403,23 +10253,188 @@ func (v *DictionaryValue) Insert(
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
+
+	if updateContentHash {
+		xorContentHash(v.contentHash, contentHashChange)
+	}
 
 	if existingValueStorable == nil {
-		return NilValue{}
+		return Nil()
 	}
 
-	existingValue := StoredValue(existingValueStorable, interpreter.Storage).
-		Transfer(
-			interpreter,
-			getLocationRange,
-			atree.Address{},
-			true,
-			existingValueStorable,
-		)
+	existingValue := StoredValue(existingValueStorable, interpreter.Storage)
+
+	existingValue = existingValue.Transfer(
+		interpreter,
+		getLocationRange,
+		atree.Address{},
+		true,
+		existingValueStorable,
+	)
 
 	return NewSomeValueNonCopying(existingValue)
 }
 
+// storableValue is an atree.Value which is stored as the given storable.
+// It allows re-inserting an existing storable, without re-creating it.
+//
+type storableValue struct {
+	storable atree.Storable
+}
+
+func (v storableValue) Storable(_ atree.SlabStorage, _ atree.Address, _ uint64) (atree.Storable, error) {
+	return v.storable, nil
+}
+
+// MigrateKeys returns a new dictionary with the given key type, owned by the same account,
+// which contains copies of the values of this dictionary,
+// with their keys replaced by the result of the given transform function.
+//
+// The dictionary itself is unchanged.
+// If a transformed key is not hashable, a NonHashableKeyError is returned,
+// if it is not a subtype of the new key type, a ContainerMutationError is returned,
+// and if two transformed keys are equal, a DuplicateDictionaryKeyError is returned.
+//
+func (v *DictionaryValue) MigrateKeys(
+	interpreter *Interpreter,
+	locationRange func() LocationRange,
+	newKeyType StaticType,
+	transform func(oldKey Value) Value,
+) (*DictionaryValue, error) {
+
+	expectedKeyType := interpreter.MustConvertStaticToSemaType(newKeyType)
+
+	keysAndValues := make([]Value, 0, v.Count()*2)
+
+	var err error
+	v.Iterate(func(key, value Value) (resume bool) {
+		newKey := transform(key)
+
+		if !IsHashableValue(newKey) {
+			err = NonHashableKeyError{
+				Value:         newKey,
+				LocationRange: locationRange(),
+			}
+			return false
+		}
+
+		actualKeyType := newKey.DynamicType(interpreter, SeenReferences{})
+		if !interpreter.IsSubType(actualKeyType, expectedKeyType) {
+			err = ContainerMutationError{
+				ExpectedType:  expectedKeyType,
+				ActualType:    interpreter.MustConvertStaticToSemaType(newKey.StaticType()),
+				LocationRange: locationRange(),
+			}
+			return false
+		}
+
+		keysAndValues = append(keysAndValues, newKey, value)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	duplicateKey, ok := findDuplicateDictionaryKey(interpreter, keysAndValues)
+	if ok {
+		return nil, DuplicateDictionaryKeyError{
+			Key: duplicateKey,
+		}
+	}
+
+	// The keys and values are cloned, so the new dictionary
+	// does not share any slabs with this dictionary
+
+	for i, keyOrValue := range keysAndValues {
+		keysAndValues[i] = keyOrValue.Clone(interpreter)
+	}
+
+	return NewDictionaryValueWithAddress(
+		interpreter,
+		DictionaryStaticType{
+			KeyType:   newKeyType,
+			ValueType: v.Type.ValueType,
+		},
+		v.GetOwner(),
+		keysAndValues...,
+	), nil
+}
+
+// Rebalance rewrites the underlying map into freshly packed slabs,
+// e.g. to improve read locality after many entries were removed.
+//
+// The entries, their iteration order, and the storage ID of the dictionary are unchanged.
+//
+func (v *DictionaryValue) Rebalance(interpreter *Interpreter) error {
+	if v.readOnly {
+		return ReadOnlyValueMutationError{}
+	}
+
+	return v.reinsertEntries(interpreter, ReturnEmptyLocationRange)
+}
+
+// reinsertEntries removes all entries of the dictionary, and inserts them again in iteration order.
+// The storables of the values are reused, so nested containers are not rewritten.
+//
+func (v *DictionaryValue) reinsertEntries(interpreter *Interpreter, getLocationRange func() LocationRange) error {
+
+	type entry struct {
+		keyStorable   atree.Storable
+		valueStorable atree.Storable
+	}
+
+	var entries []entry
+
+	// NOTE: PopIterate iterates in reverse order
+
+	err := v.atreeMap().PopIterate(func(keyStorable atree.Storable, valueStorable atree.Storable) {
+		entries = append(entries, entry{
+			keyStorable:   keyStorable,
+			valueStorable: valueStorable,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	valueComparator := newValueComparator(interpreter, getLocationRange)
+	hashInputProvider := newHashInputProvider(interpreter, getLocationRange)
+
+	storage := interpreter.Storage
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		keyValue := StoredValue(entry.keyStorable, storage)
+
+		_, err := v.dictionary.Set(
+			valueComparator,
+			hashInputProvider,
+			keyValue,
+			storableValue{storable: entry.valueStorable},
+		)
+		if err != nil {
+			return err
+		}
+
+		// The key's storable was re-created.
+		// If the key is a large immutable value, which was stored in a separate slab,
+		// the previous slab is no longer referenced
+
+		switch keyValue.(type) {
+		case *ArrayValue, *DictionaryValue, *CompositeValue:
+			break
+		default:
+			interpreter.RemoveReferencedSlab(entry.keyStorable)
+		}
+	}
+
+	interpreter.maybeValidateAtreeValue(v.dictionary)
+
+	return nil
+}
+
 type DictionaryEntryValues struct {
 	Key   Value
 	Value Value
@@ -9437,7 +10452,7 @@ func (v *DictionaryValue) ConformsToDynamicType(
 		return false
 	}
 
-	iterator, err := v.dictionary.Iterator()
+	iterator, err := v.atreeMap().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
@@ -9501,7 +10516,7 @@ func (v *DictionaryValue) Equal(interpreter *Interpreter, getLocationRange func(
 		return false
 	}
 
-	iterator, err := v.dictionary.Iterator()
+	iterator, err := v.atreeMap().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
@@ -9528,15 +10543,15 @@ func (v *DictionaryValue) Equal(interpreter *Interpreter, getLocationRange func(
 			return false
 		}
 
-		equatableValue, ok := MustConvertStoredValue(value).(EquatableValue)
-		if !ok || !equatableValue.Equal(interpreter, getLocationRange, otherValue) {
+		if !interpreter.storedValueEqual(value, getLocationRange, otherValue) {
 			return false
 		}
 	}
 }
 
-func (v *DictionaryValue) Storable(_ atree.SlabStorage, _ atree.Address, _ uint64) (atree.Storable, error) {
-	return atree.StorageIDStorable(v.StorageID()), nil
+func (v *DictionaryValue) Storable(storage atree.SlabStorage, _ atree.Address, maxInlineSize uint64) (atree.Storable, error) {
+	unshareForStorage(v.shared)
+	return v.storable(storage, maxInlineSize), nil
 }
 
 func (v *DictionaryValue) Transfer(
@@ -9547,6 +10562,22 @@ func (v *DictionaryValue) Transfer(
 	storable atree.Storable,
 ) Value {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		if result, ok := hooks.transfer(interpreter, v, getLocationRange, address, remove, storable); ok {
+			return result
+		}
+
+		defer hooks.reportOperationTrace(tracingOperationTransfer, tracingKindDictionary, time.Now())
+	}
+
+	if remove {
+		v.checkMutable(getLocationRange)
+		v.unshare(interpreter)
+	} else if v.shared != nil && !v.NeedsStoreTo(address) {
+		// The shared copy itself is the result of the transfer, and is about to be stored
+		unshareForStorage(v.shared)
+	}
+
 	if interpreter.tracingEnabled {
 		startTime := time.Now()
 		defer func() {
@@ -9554,6 +10585,33 @@ func (v *DictionaryValue) Transfer(
 		}()
 	}
 
+	// An empty dictionary without a root slab has no slabs to transfer,
+	// only a storage ID for the root slab is reserved in the target account
+
+	if !v.hasRootSlab() {
+		isResourceKinded := v.IsResourceKinded(interpreter)
+
+		if !v.NeedsStoreTo(address) && isResourceKinded {
+			return v
+		}
+
+		lazyRoot := newLazyContainerRoot(interpreter.Storage, address)
+
+		if isResourceKinded {
+			v.lazyRoot = lazyRoot
+			return v
+		} else {
+			return &DictionaryValue{
+				Type:             v.Type,
+				semaType:         v.semaType,
+				isResourceKinded: v.isResourceKinded,
+				lazyRoot:         lazyRoot,
+				sentinel:         true,
+				isDestroyed:      v.isDestroyed,
+			}
+		}
+	}
+
 	dictionary := v.dictionary
 
 	needsStoreTo := v.NeedsStoreTo(address)
@@ -9587,11 +10645,11 @@ func (v *DictionaryValue) Transfer(
 					return nil, nil, nil
 				}
 
-				key := MustConvertStoredValue(atreeKey).
-					Transfer(interpreter, getLocationRange, address, remove, nil)
+				key := interpreter.transferStoredValue(atreeKey, getLocationRange, address, remove)
+
+				value := interpreter.transferStoredValue(atreeValue, getLocationRange, address, remove)
 
-				value := MustConvertStoredValue(atreeValue).
-					Transfer(interpreter, getLocationRange, address, remove, nil)
+				interpreter.reportTransferProgress()
 
 				return key, value, nil
 			},
@@ -9609,6 +10667,7 @@ func (v *DictionaryValue) Transfer(
 				panic(ExternalError{err})
 			}
 			interpreter.maybeValidateAtreeValue(v.dictionary)
+			interpreter.invalidateEqualityCache(v.StorageID())
 
 			interpreter.RemoveReferencedSlab(storable)
 		}
@@ -9633,14 +10692,14 @@ func (v *DictionaryValue) Clone(interpreter *Interpreter) Value {
 	valueComparator := newValueComparator(interpreter, ReturnEmptyLocationRange)
 	hashInputProvider := newHashInputProvider(interpreter, ReturnEmptyLocationRange)
 
-	iterator, err := v.dictionary.Iterator()
+	iterator, err := v.atreeMap().Iterator()
 	if err != nil {
 		panic(ExternalError{err})
 	}
 
 	dictionary, err := atree.NewMapFromBatchData(
 		interpreter.Storage,
-		v.StorageID().Address,
+		atree.Address(v.GetOwner()),
 		atree.NewDefaultDigesterBuilder(),
 		v.dictionary.Type(),
 		valueComparator,
@@ -9656,11 +10715,9 @@ func (v *DictionaryValue) Clone(interpreter *Interpreter) Value {
 				return nil, nil, nil
 			}
 
-			key := MustConvertStoredValue(atreeKey).
-				Clone(interpreter)
+			key := interpreter.cloneStoredValue(atreeKey)
 
-			value := MustConvertStoredValue(atreeValue).
-				Clone(interpreter)
+			value := interpreter.cloneStoredValue(atreeValue)
 
 			return key, value, nil
 		},
@@ -9680,31 +10737,57 @@ func (v *DictionaryValue) Clone(interpreter *Interpreter) Value {
 
 func (v *DictionaryValue) DeepRemove(interpreter *Interpreter) {
 
+	if hooks := interpreter.containerHooks; hooks != nil {
+		defer hooks.reportOperationTrace(tracingOperationDeepRemove, tracingKindDictionary, time.Now())
+	}
+
+	v.checkMutable(ReturnEmptyLocationRange)
+
+	// A shared copy does not own any slabs
+
+	if v.shared != nil && v.shared.root == sharedValue(v) {
+		v.shared.release(interpreter)
+		return
+	}
+
+	v.unshare(interpreter)
+
+	// An empty dictionary without a root slab has nothing to remove
+
+	if !v.hasRootSlab() {
+		return
+	}
+
 	// Remove nested values and storables
 
 	storage := v.dictionary.Storage
 
 	err := v.dictionary.PopIterate(func(keyStorable atree.Storable, valueStorable atree.Storable) {
 
-		key := StoredValue(keyStorable, storage)
-		key.DeepRemove(interpreter)
+		interpreter.deepRemoveStoredValue(keyStorable, storage)
 		interpreter.RemoveReferencedSlab(keyStorable)
 
-		value := StoredValue(valueStorable, storage)
-		value.DeepRemove(interpreter)
+		interpreter.deepRemoveStoredValue(valueStorable, storage)
 		interpreter.RemoveReferencedSlab(valueStorable)
 	})
 	if err != nil {
 		panic(ExternalError{err})
 	}
 	interpreter.maybeValidateAtreeValue(v.dictionary)
+	interpreter.invalidateEqualityCache(v.StorageID())
 }
 
 func (v *DictionaryValue) GetOwner() common.Address {
+	if v.shared != nil {
+		return common.Address(v.shared.address)
+	}
 	return common.Address(v.StorageID().Address)
 }
 
 func (v *DictionaryValue) StorageID() atree.StorageID {
+	if v.lazyRoot != nil {
+		return v.lazyRoot.storageID
+	}
 	return v.dictionary.StorageID()
 }
 
@@ -9738,6 +10821,17 @@ type OptionalValue interface {
 
 type NilValue struct{}
 
+// NilValueSingleton is the nil value.
+// Nil values are immutable and carry no data, so this value can be shared.
+//
+var NilValueSingleton = NilValue{}
+
+// Nil returns the nil value.
+//
+func Nil() NilValue {
+	return NilValueSingleton
+}
+
 var _ Value = NilValue{}
 var _ atree.Storable = NilValue{}
 var _ EquatableValue = NilValue{}
@@ -9785,7 +10879,7 @@ func (v NilValue) RecursiveString(_ SeenReferences) string {
 
 var nilValueMapFunction = NewHostFunctionValue(
 	func(invocation Invocation) Value {
-		return NilValue{}
+		return Nil()
 	},
 	&sema.FunctionType{
 		ReturnTypeAnnotation: sema.NewTypeAnnotation(
@@ -10922,6 +12016,9 @@ func (v AddressValue) Transfer(
 	if remove {
 		interpreter.RemoveReferencedSlab(storable)
 	}
+	if address, ok := interpreter.transferAddressMap[common.Address(v)]; ok {
+		return AddressValue(address)
+	}
 	return v
 }
 
@@ -11104,6 +12201,34 @@ func (v PathValue) Equal(_ *Interpreter, _ func() LocationRange, other Value) bo
 		otherPath.Domain == v.Domain
 }
 
+// Compare orders paths first by domain, in the order of common.AllPathDomains,
+// and then by identifier.
+//
+// It returns -1 if v is less than other, 0 if they are equal, and 1 otherwise.
+//
+func (v PathValue) Compare(other PathValue) int {
+	domainIndex := pathDomainIndex(v.Domain)
+	otherDomainIndex := pathDomainIndex(other.Domain)
+
+	switch {
+	case domainIndex < otherDomainIndex:
+		return -1
+	case domainIndex > otherDomainIndex:
+		return 1
+	}
+
+	return strings.Compare(v.Identifier, other.Identifier)
+}
+
+func pathDomainIndex(domain common.PathDomain) int {
+	for i, pathDomain := range common.AllPathDomains {
+		if pathDomain == domain {
+			return i
+		}
+	}
+	return -1
+}
+
 func (v PathValue) HashInput(_ *Interpreter, _ func() LocationRange, scratch []byte) []byte {
 	scratch[0] = byte(HashInputTypePath)
 	scratch[1] = byte(v.Domain)
@@ -11117,7 +12242,7 @@ func (PathValue) IsStorable() bool {
 func convertPath(domain common.PathDomain, value Value) Value {
 	stringValue, ok := value.(*StringValue)
 	if !ok {
-		return NilValue{}
+		return Nil()
 	}
 
 	_, err := sema.CheckPathLiteral(
@@ -11127,7 +12252,7 @@ func convertPath(domain common.PathDomain, value Value) Value {
 		ReturnEmptyRange,
 	)
 	if err != nil {
-		return NilValue{}
+		return Nil()
 	}
 
 	return NewSomeValueNonCopying(PathValue{
@@ -11210,6 +12335,34 @@ type CapabilityValue struct {
 	BorrowType StaticType
 }
 
+// NewAuthorizedCapability returns a capability for the given path in the given account,
+// which borrows an authorized reference to the given type.
+//
+func NewAuthorizedCapability(address AddressValue, path PathValue, borrowedType StaticType) *CapabilityValue {
+	return &CapabilityValue{
+		Address: address,
+		Path:    path,
+		BorrowType: ReferenceStaticType{
+			Authorized: true,
+			Type:       borrowedType,
+		},
+	}
+}
+
+// NewUnauthorizedCapability returns a capability for the given path in the given account,
+// which borrows an unauthorized reference to the given type.
+//
+func NewUnauthorizedCapability(address AddressValue, path PathValue, borrowedType StaticType) *CapabilityValue {
+	return &CapabilityValue{
+		Address: address,
+		Path:    path,
+		BorrowType: ReferenceStaticType{
+			Authorized: false,
+			Type:       borrowedType,
+		},
+	}
+}
+
 var _ Value = &CapabilityValue{}
 var _ atree.Storable = &CapabilityValue{}
 var _ EquatableValue = &CapabilityValue{}
@@ -11359,6 +12512,13 @@ func (v *CapabilityValue) Transfer(
 		v.DeepRemove(interpreter)
 		interpreter.RemoveReferencedSlab(storable)
 	}
+	if address, ok := interpreter.transferAddressMap[common.Address(v.Address)]; ok {
+		return &CapabilityValue{
+			Address:    AddressValue(address),
+			Path:       v.Path,
+			BorrowType: v.BorrowType,
+		}
+	}
 	return v
 }
 
@@ -21,6 +21,9 @@ package interpreter_test
 import (
 	"fmt"
 	"go/types"
+	"math/rand"
+	"sort"
+	"strings"
 	"testing"
 
 	"golang.org/x/tools/go/packages"
@@ -1537,6 +1540,46 @@ func TestCapabilityValue_Equal(t *testing.T) {
 	})
 }
 
+func TestNewAuthorizedCapability(t *testing.T) {
+
+	t.Parallel()
+
+	address := AddressValue{0x1}
+	path := PathValue{
+		Domain:     common.PathDomainStorage,
+		Identifier: "test",
+	}
+
+	authorized := NewAuthorizedCapability(address, path, PrimitiveStaticTypeInt)
+	unauthorized := NewUnauthorizedCapability(address, path, PrimitiveStaticTypeInt)
+
+	require.Equal(t,
+		ReferenceStaticType{
+			Authorized: true,
+			Type:       PrimitiveStaticTypeInt,
+		},
+		authorized.BorrowType,
+	)
+	require.Equal(t,
+		ReferenceStaticType{
+			Authorized: false,
+			Type:       PrimitiveStaticTypeInt,
+		},
+		unauthorized.BorrowType,
+	)
+
+	// The capabilities only differ in the authorization of the borrow type
+
+	require.Equal(t, authorized.Address, unauthorized.Address)
+	require.Equal(t, authorized.Path, unauthorized.Path)
+
+	unauthorizedBorrowType := unauthorized.BorrowType.(ReferenceStaticType)
+	unauthorizedBorrowType.Authorized = true
+
+	require.Equal(t, authorized.BorrowType, unauthorizedBorrowType)
+	require.NotEqual(t, authorized.BorrowType, unauthorized.BorrowType)
+}
+
 func TestAddressValue_Equal(t *testing.T) {
 
 	t.Parallel()
@@ -1942,6 +1985,41 @@ func TestPathValue_Equal(t *testing.T) {
 	})
 }
 
+func TestPathValue_Compare(t *testing.T) {
+
+	t.Parallel()
+
+	identifiers := []string{"a", "b", "bar", "foo", "z"}
+
+	var expected []PathValue
+	for _, domain := range common.AllPathDomains {
+		for _, identifier := range identifiers {
+			expected = append(expected, PathValue{
+				Domain:     domain,
+				Identifier: identifier,
+			})
+		}
+	}
+
+	paths := make([]PathValue, len(expected))
+	copy(paths, expected)
+
+	random := rand.New(rand.NewSource(42))
+	random.Shuffle(len(paths), func(i, j int) {
+		paths[i], paths[j] = paths[j], paths[i]
+	})
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return paths[i].Compare(paths[j]) < 0
+	})
+
+	require.Equal(t, expected, paths)
+
+	for _, path := range paths {
+		require.Equal(t, 0, path.Compare(path))
+	}
+}
+
 func TestLinkValue_Equal(t *testing.T) {
 
 	t.Parallel()
@@ -2485,6 +2563,81 @@ func TestDictionaryValue_Equal(t *testing.T) {
 	})
 }
 
+func TestDictionaryValue_NonHashableKey(t *testing.T) {
+
+	t.Parallel()
+
+	dictionaryStaticType := DictionaryStaticType{
+		KeyType:   PrimitiveStaticTypeAnyStruct,
+		ValueType: PrimitiveStaticTypeAnyStruct,
+	}
+
+	test := func(name string, newKey func(inter *Interpreter) Value) {
+
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			inter := newTestInterpreter(t)
+
+			t.Run("insert", func(t *testing.T) {
+
+				dictionary := NewDictionaryValue(inter, dictionaryStaticType)
+
+				func() {
+					defer func() {
+						require.IsType(t, NonHashableKeyError{}, recover())
+					}()
+
+					dictionary.Insert(
+						inter,
+						ReturnEmptyLocationRange,
+						newKey(inter),
+						BoolValue(true),
+					)
+				}()
+
+				require.Equal(t, 0, dictionary.Count())
+			})
+
+			t.Run("construction", func(t *testing.T) {
+
+				defer func() {
+					require.IsType(t, NonHashableKeyError{}, recover())
+				}()
+
+				NewDictionaryValue(
+					inter,
+					dictionaryStaticType,
+					newKey(inter), BoolValue(true),
+				)
+			})
+		})
+	}
+
+	test("capability", func(_ *Interpreter) Value {
+		return &CapabilityValue{
+			Address: AddressValue{0x1},
+			Path: PathValue{
+				Domain:     common.PathDomainStorage,
+				Identifier: "test",
+			},
+		}
+	})
+
+	test("optional", func(_ *Interpreter) Value {
+		return NewSomeValueNonCopying(BoolValue(true))
+	})
+
+	test("void", func(_ *Interpreter) Value {
+		return VoidValue{}
+	})
+
+	test("struct", func(inter *Interpreter) Value {
+		return newTestCompositeValue(inter, common.Address{})
+	})
+}
+
 func TestCompositeValue_Equal(t *testing.T) {
 
 	t.Parallel()
@@ -3137,3 +3290,1364 @@ func TestNonStorable(t *testing.T) {
 	require.NoError(t, err)
 
 }
+
+func TestArrayValue_InferElementType(t *testing.T) {
+
+	t.Parallel()
+
+	anyStructArrayStaticType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeAnyStruct,
+	}
+
+	test := func(name string, expected StaticType, elements ...Value) {
+
+		t.Run(name, func(t *testing.T) {
+
+			t.Parallel()
+
+			inter := newTestInterpreter(t)
+
+			array := NewArrayValue(
+				inter,
+				anyStructArrayStaticType,
+				common.Address{},
+				elements...,
+			)
+
+			require.Equal(t,
+				expected,
+				array.InferElementType(inter),
+			)
+		})
+	}
+
+	test("empty", PrimitiveStaticTypeAnyStruct)
+
+	test("homogeneous",
+		PrimitiveStaticTypeInt,
+		NewIntValueFromInt64(1),
+		NewIntValueFromInt64(2),
+	)
+
+	test("mixed integers",
+		PrimitiveStaticTypeInteger,
+		NewIntValueFromInt64(1),
+		UInt8Value(2),
+	)
+
+	test("mixed optionals",
+		OptionalStaticType{
+			Type: PrimitiveStaticTypeString,
+		},
+		NewStringValue("a"),
+		NilValue{},
+	)
+
+	test("heterogeneous",
+		PrimitiveStaticTypeAnyStruct,
+		NewIntValueFromInt64(1),
+		NewStringValue("a"),
+		BoolValue(true),
+	)
+}
+
+func TestDeepEqual(t *testing.T) {
+
+	t.Parallel()
+
+	newEnum := func(
+		inter *Interpreter,
+		location common.Location,
+		qualifiedIdentifier string,
+		rawValue Value,
+	) *CompositeValue {
+		return NewCompositeValue(
+			inter,
+			location,
+			qualifiedIdentifier,
+			common.CompositeKindEnum,
+			[]CompositeField{
+				{
+					Name:  sema.EnumRawValueFieldName,
+					Value: rawValue,
+				},
+			},
+			common.Address{},
+		)
+	}
+
+	t.Run("enum, equal", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.True(t,
+			DeepEqual(
+				inter,
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+			),
+		)
+	})
+
+	t.Run("enum, different raw value", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			DeepEqual(
+				inter,
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(2)),
+			),
+		)
+	})
+
+	t.Run("enum, different raw value type", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			DeepEqual(
+				inter,
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+				newEnum(inter, utils.TestLocation, "E", UInt16Value(1)),
+			),
+		)
+	})
+
+	t.Run("enum, different identifier", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			DeepEqual(
+				inter,
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+				newEnum(inter, utils.TestLocation, "F", UInt8Value(1)),
+			),
+		)
+	})
+
+	t.Run("enum, different location", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			DeepEqual(
+				inter,
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+				newEnum(inter, common.IdentifierLocation("A"), "E", UInt8Value(1)),
+			),
+		)
+	})
+
+	t.Run("enum, struct", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			DeepEqual(
+				inter,
+				newEnum(inter, utils.TestLocation, "E", UInt8Value(1)),
+				NewCompositeValue(
+					inter,
+					utils.TestLocation,
+					"E",
+					common.CompositeKindStructure,
+					[]CompositeField{
+						{
+							Name:  sema.EnumRawValueFieldName,
+							Value: UInt8Value(1),
+						},
+					},
+					common.Address{},
+				),
+			),
+		)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.True(t, DeepEqual(inter, nil, nil))
+		require.False(t, DeepEqual(inter, nil, BoolValue(true)))
+		require.False(t, DeepEqual(inter, BoolValue(true), nil))
+	})
+
+	t.Run("equatable", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.True(t, DeepEqual(inter, NewStringValue("a"), NewStringValue("a")))
+		require.False(t, DeepEqual(inter, NewStringValue("a"), NewStringValue("b")))
+	})
+}
+
+func TestEqualUnwrappingOptional(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("some and value", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.True(t,
+			EqualUnwrappingOptional(
+				inter,
+				NewSomeValueNonCopying(UInt8Value(1)),
+				UInt8Value(1),
+			),
+		)
+		require.True(t,
+			EqualUnwrappingOptional(
+				inter,
+				UInt8Value(1),
+				NewSomeValueNonCopying(UInt8Value(1)),
+			),
+		)
+	})
+
+	t.Run("some and different value", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			EqualUnwrappingOptional(
+				inter,
+				NewSomeValueNonCopying(UInt8Value(1)),
+				UInt8Value(2),
+			),
+		)
+	})
+
+	t.Run("nil and value", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			EqualUnwrappingOptional(inter, NilValue{}, UInt8Value(1)),
+		)
+		require.False(t,
+			EqualUnwrappingOptional(inter, UInt8Value(1), NilValue{}),
+		)
+	})
+
+	t.Run("nil and nil", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.True(t,
+			EqualUnwrappingOptional(inter, NilValue{}, NilValue{}),
+		)
+	})
+
+	t.Run("nested optional", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		require.False(t,
+			EqualUnwrappingOptional(
+				inter,
+				NewSomeValueNonCopying(NewSomeValueNonCopying(UInt8Value(1))),
+				UInt8Value(1),
+			),
+		)
+	})
+}
+
+func TestStringLengthLimit(t *testing.T) {
+
+	t.Parallel()
+
+	const limit = 3
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithMaxStringLength(limit),
+	)
+	require.NoError(t, err)
+
+	construct := func(str string) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = r.(error)
+			}
+		}()
+
+		concat := NewStringValue(str[:1]).
+			GetMember(inter, ReturnEmptyLocationRange, "concat").(*HostFunctionValue)
+
+		concat.Function(Invocation{
+			Interpreter: inter,
+			Arguments: []Value{
+				NewStringValue(str[1:]),
+			},
+		})
+		return nil
+	}
+
+	decodeStorable := NewStorableDecoder(WithMaxDecodedStringLength(limit))
+
+	decode := func(str string) error {
+		encoded := append(
+			[]byte{
+				// tag
+				0xd8, CBORTagStringValue,
+				// UTF-8 string, n bytes follow
+				0x60 + byte(len(str)),
+			},
+			str...,
+		)
+
+		decoder := CBORDecMode.NewByteStreamDecoder(encoded)
+		_, err := decodeStorable(decoder, atree.StorageIDUndefined)
+		return err
+	}
+
+	for name, f := range map[string]func(string) error{
+		"construction": construct,
+		"decode":       decode,
+	} {
+		t.Run(name, func(t *testing.T) {
+
+			t.Run("under", func(t *testing.T) {
+				require.NoError(t, f("ab"))
+			})
+
+			t.Run("at", func(t *testing.T) {
+				require.NoError(t, f("abc"))
+			})
+
+			t.Run("over", func(t *testing.T) {
+				err := f("abcd")
+				require.Equal(t,
+					StringLengthLimitExceededError{
+						Length: 4,
+						Limit:  limit,
+					},
+					err,
+				)
+			})
+		})
+	}
+
+	t.Run("other interpreters and decoders", func(t *testing.T) {
+
+		// The limit is configured per interpreter and per decoder
+
+		otherInter := newTestInterpreter(t)
+
+		concat := NewStringValue("a").
+			GetMember(otherInter, ReturnEmptyLocationRange, "concat").(*HostFunctionValue)
+
+		require.NotPanics(t, func() {
+			concat.Function(Invocation{
+				Interpreter: otherInter,
+				Arguments: []Value{
+					NewStringValue("bcd"),
+				},
+			})
+		})
+
+		encoded, err := EncodeStorable(NewStringValue("abcd"))
+		require.NoError(t, err)
+
+		decoder := CBORDecMode.NewByteStreamDecoder(encoded)
+		_, err = DecodeStorable(decoder, atree.StorageIDUndefined)
+		require.NoError(t, err)
+	})
+}
+
+func TestArrayValue_RemoveStorageAccounting(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	arrayType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeAnyStruct,
+	}
+
+	r := rand.New(rand.NewSource(42))
+
+	randomElement := func(inter *Interpreter) Value {
+		switch r.Intn(3) {
+		case 0:
+			return NewIntValueFromInt64(r.Int63())
+		case 1:
+			// large enough to be stored in a separate slab
+			return NewStringValue(strings.Repeat("x", r.Intn(1000)))
+		default:
+			return NewArrayValue(
+				inter,
+				arrayType,
+				common.Address{},
+				NewIntValueFromInt64(r.Int63()),
+				NewStringValue("nested"),
+			)
+		}
+	}
+
+	usage := func(t *testing.T, storage InMemoryStorage) uint64 {
+		usage, err := storage.UsageByAccount()
+		require.NoError(t, err)
+		return usage[address]
+	}
+
+	for i := 0; i < 10; i++ {
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+			WithAtreeValueValidationEnabled(true),
+			WithAtreeStorageValidationEnabled(true),
+		)
+		require.NoError(t, err)
+
+		array := NewArrayValue(inter, arrayType, address)
+
+		baseline := usage(t, storage)
+
+		count := r.Intn(200)
+		for j := 0; j < count; j++ {
+			array.Append(inter, ReturnEmptyLocationRange, randomElement(inter))
+		}
+
+		if count > 0 {
+			require.Greater(t, usage(t, storage), baseline)
+		}
+
+		for array.Count() > 0 {
+			var removed Value
+			switch r.Intn(3) {
+			case 0:
+				removed = array.RemoveFirst(inter, ReturnEmptyLocationRange)
+			case 1:
+				removed = array.RemoveLast(inter, ReturnEmptyLocationRange)
+			default:
+				removed = array.RemoveAt(inter, ReturnEmptyLocationRange, r.Intn(array.Count()))
+			}
+
+			removed.DeepRemove(inter)
+		}
+
+		require.Equal(t, baseline, usage(t, storage))
+	}
+
+	t.Run("out of range", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			arrayType,
+			address,
+			NewIntValueFromInt64(1),
+		)
+
+		for _, index := range []int{-1, 1} {
+
+			var getErr, removeErr interface{}
+
+			func() {
+				defer func() {
+					getErr = recover()
+				}()
+				array.Get(inter, ReturnEmptyLocationRange, index)
+			}()
+
+			func() {
+				defer func() {
+					removeErr = recover()
+				}()
+				array.RemoveAt(inter, ReturnEmptyLocationRange, index)
+			}()
+
+			require.IsType(t, ArrayIndexOutOfBoundsError{}, removeErr)
+			require.Equal(t, getErr, removeErr)
+		}
+
+		require.Equal(t, 1, array.Count())
+	})
+}
+
+func TestVoidAndNilSingletons(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	require.Equal(t, VoidValue{}, Void())
+	require.Equal(t, VoidValueSingleton, Void())
+	require.True(t, Void().Equal(inter, ReturnEmptyLocationRange, VoidValue{}))
+	require.True(t, DeepEqual(inter, VoidValueSingleton, VoidValue{}))
+
+	require.Equal(t, NilValue{}, Nil())
+	require.Equal(t, NilValueSingleton, Nil())
+	require.True(t, Nil().Equal(inter, ReturnEmptyLocationRange, NilValue{}))
+	require.True(t, DeepEqual(inter, NilValueSingleton, NilValue{}))
+
+	require.False(t, DeepEqual(inter, Nil(), Void()))
+}
+
+func TestNewCompositeValueFromType(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.Address{0x1}
+
+	compositeType := &sema.CompositeType{
+		Location:   utils.TestLocation,
+		Identifier: "Test",
+		Kind:       common.CompositeKindStructure,
+		Members:    sema.NewStringMemberOrderedMap(),
+	}
+
+	for _, name := range []string{"z", "a", "m"} {
+		compositeType.Members.Set(
+			name,
+			sema.NewPublicConstantFieldMember(compositeType, name, sema.IntType, ""),
+		)
+	}
+
+	compositeType.Members.Set(
+		"f",
+		sema.NewPublicFunctionMember(compositeType, "f", &sema.FunctionType{}, ""),
+	)
+
+	fieldValues := func() map[string]Value {
+		return map[string]Value{
+			"a": NewIntValueFromInt64(1),
+			"m": NewIntValueFromInt64(2),
+			"z": NewIntValueFromInt64(3),
+		}
+	}
+
+	encode := func(t *testing.T, construct func(inter *Interpreter) *CompositeValue) ([]EncodedSlab, []string) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		value := construct(inter)
+
+		var names []string
+		value.ForEachField(func(name string, _ Value) {
+			names = append(names, name)
+		})
+
+		encoded, err := storage.EncodeOrdered()
+		require.NoError(t, err)
+
+		return encoded, names
+	}
+
+	t.Run("declared order", func(t *testing.T) {
+
+		t.Parallel()
+
+		expectedEncoding, expectedNames := encode(t, func(inter *Interpreter) *CompositeValue {
+			return NewCompositeValue(
+				inter,
+				utils.TestLocation,
+				"Test",
+				common.CompositeKindStructure,
+				[]CompositeField{
+					{Name: "z", Value: NewIntValueFromInt64(3)},
+					{Name: "a", Value: NewIntValueFromInt64(1)},
+					{Name: "m", Value: NewIntValueFromInt64(2)},
+				},
+				address,
+			)
+		})
+
+		// Go map iteration order is randomized, construct repeatedly
+
+		for i := 0; i < 20; i++ {
+			encoding, names := encode(t, func(inter *Interpreter) *CompositeValue {
+				value, err := NewCompositeValueFromType(inter, compositeType, fieldValues(), address)
+				require.NoError(t, err)
+				return value
+			})
+
+			require.Equal(t, expectedEncoding, encoding)
+			require.Equal(t, expectedNames, names)
+		}
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		values := fieldValues()
+		delete(values, "m")
+
+		_, err := NewCompositeValueFromType(inter, compositeType, values, address)
+		require.Equal(t,
+			MissingCompositeFieldError{
+				TypeID:    compositeType.ID(),
+				FieldName: "m",
+			},
+			err,
+		)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		values := fieldValues()
+		values["y"] = NewIntValueFromInt64(4)
+		values["x"] = NewIntValueFromInt64(5)
+
+		_, err := NewCompositeValueFromType(inter, compositeType, values, address)
+		require.Equal(t,
+			UnknownCompositeFieldError{
+				TypeID:    compositeType.ID(),
+				FieldName: "x",
+			},
+			err,
+		)
+	})
+}
+
+func TestStringValue_Slice(t *testing.T) {
+
+	t.Parallel()
+
+	inter := newTestInterpreter(t)
+
+	// "é" and "ä" are single characters consisting of multiple code points,
+	// "日本" and "\U0001F600" are characters consisting of multiple bytes
+
+	value := NewStringValue("café 日本 ä\U0001F600")
+
+	characters := []string{
+		"c", "a", "f", "é", " ", "日", "本", " ", "ä", "\U0001F600",
+	}
+
+	require.Equal(t, len(characters), value.Length())
+
+	for from := 0; from <= len(characters); from++ {
+		for to := from; to <= len(characters); to++ {
+			expected := strings.Join(characters[from:to], "")
+
+			slice := value.Slice(inter, ReturnEmptyLocationRange, from, to)
+			require.Equal(t, expected, slice.Str)
+			require.Equal(t, to-from, slice.Length())
+		}
+	}
+
+	t.Run("out of bounds", func(t *testing.T) {
+
+		t.Parallel()
+
+		require.PanicsWithValue(t,
+			StringIndexOutOfBoundsError{
+				Index:  11,
+				Length: 10,
+			},
+			func() {
+				value.Slice(inter, ReturnEmptyLocationRange, 0, 11)
+			},
+		)
+
+		require.PanicsWithValue(t,
+			StringIndexOutOfBoundsError{
+				Index:  -1,
+				Length: 10,
+			},
+			func() {
+				value.Slice(inter, ReturnEmptyLocationRange, -1, 2)
+			},
+		)
+	})
+
+	t.Run("reversed", func(t *testing.T) {
+
+		t.Parallel()
+
+		require.PanicsWithValue(t,
+			InvalidSliceIndexError{
+				FromIndex: 4,
+				UpToIndex: 3,
+			},
+			func() {
+				value.Slice(inter, ReturnEmptyLocationRange, 4, 3)
+			},
+		)
+	})
+}
+
+func TestDictionaryDuplicateKeyCheck(t *testing.T) {
+
+	t.Parallel()
+
+	dictionaryType := DictionaryStaticType{
+		KeyType:   PrimitiveStaticTypeString,
+		ValueType: PrimitiveStaticTypeString,
+	}
+
+	address := common.Address{0x1}
+
+	newInterpreter := func(t *testing.T, enabled bool) (*Interpreter, InMemoryStorage) {
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+			WithDuplicateKeyCheck(enabled),
+		)
+		require.NoError(t, err)
+
+		return inter, storage
+	}
+
+	// Large values are stored in separate slabs,
+	// which are leaked when they are overwritten
+
+	largeValue := func(c string) Value {
+		return NewStringValue(strings.Repeat(c, 2048))
+	}
+
+	t.Run("duplicate keys", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, storage := newInterpreter(t, true)
+
+		var duplicateKeyErr DuplicateDictionaryKeyError
+
+		func() {
+			defer func() {
+				var ok bool
+				duplicateKeyErr, ok = recover().(DuplicateDictionaryKeyError)
+				require.True(t, ok)
+			}()
+
+			_ = NewDictionaryValueWithAddress(
+				inter,
+				dictionaryType,
+				address,
+				NewStringValue("a"), largeValue("x"),
+				NewStringValue("b"), largeValue("y"),
+				NewStringValue("b"), largeValue("z"),
+			)
+		}()
+
+		require.Equal(t, "b", duplicateKeyErr.Key.(*StringValue).Str)
+
+		require.Empty(t, storage.Slabs)
+	})
+
+	t.Run("unique keys", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newInterpreter(t, true)
+
+		dictionary := NewDictionaryValueWithAddress(
+			inter,
+			dictionaryType,
+			address,
+			NewStringValue("a"), largeValue("x"),
+			NewStringValue("b"), largeValue("y"),
+		)
+
+		require.Equal(t, 2, dictionary.Count())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter, _ := newInterpreter(t, false)
+
+		dictionary := NewDictionaryValueWithAddress(
+			inter,
+			dictionaryType,
+			address,
+			NewStringValue("b"), largeValue("y"),
+			NewStringValue("b"), largeValue("z"),
+		)
+
+		require.Equal(t, 1, dictionary.Count())
+	})
+}
+
+func TestDictionaryValueTypeCheck(t *testing.T) {
+
+	t.Parallel()
+
+	newInterpreter := func(t *testing.T) *Interpreter {
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(NewInMemoryStorage()),
+			WithValueTypeCheck(true),
+		)
+		require.NoError(t, err)
+
+		return inter
+	}
+
+	t.Run("mismatch", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newInterpreter(t)
+
+		dictionary := NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeInt,
+			},
+		)
+
+		var valueTypeMismatchErr ValueTypeMismatchError
+
+		func() {
+			defer func() {
+				var ok bool
+				valueTypeMismatchErr, ok = recover().(ValueTypeMismatchError)
+				require.True(t, ok)
+			}()
+
+			_ = dictionary.Insert(
+				inter,
+				ReturnEmptyLocationRange,
+				NewStringValue("a"),
+				NewStringValue("not an integer"),
+			)
+		}()
+
+		require.Equal(t, "a", valueTypeMismatchErr.Key.(*StringValue).Str)
+		require.Equal(t, sema.IntType, valueTypeMismatchErr.ExpectedType)
+		require.Equal(t, sema.StringType, valueTypeMismatchErr.ActualType)
+
+		require.Equal(t, 0, dictionary.Count())
+	})
+
+	t.Run("AnyStruct", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newInterpreter(t)
+
+		dictionary := NewDictionaryValue(
+			inter,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: PrimitiveStaticTypeAnyStruct,
+			},
+		)
+
+		_ = dictionary.Insert(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue("a"),
+			NewStringValue("any struct"),
+		)
+
+		require.Equal(t, 1, dictionary.Count())
+	})
+}
+
+func TestDictionaryRebalance(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	const entryCount = 1000
+
+	keysAndValues := make([]Value, 0, entryCount*2)
+	for i := 0; i < entryCount; i++ {
+		keysAndValues = append(
+			keysAndValues,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+			NewStringValue(fmt.Sprintf("value%d", i)),
+		)
+	}
+
+	dictionary := NewDictionaryValueWithAddress(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeString,
+			ValueType: PrimitiveStaticTypeString,
+		},
+		common.Address{0x1},
+		keysAndValues...,
+	)
+
+	for i := 0; i < entryCount; i += 2 {
+		dictionary.Remove(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(fmt.Sprintf("key%d", i)),
+		)
+	}
+
+	entries := func() (entries []string) {
+		dictionary.Iterate(func(key, value Value) (resume bool) {
+			entries = append(entries, fmt.Sprintf("%s: %s", key, value))
+			return true
+		})
+		return
+	}
+
+	expectedEntries := entries()
+	require.Len(t, expectedEntries, entryCount/2)
+
+	storageID := dictionary.StorageID()
+	slabCount := storage.Count()
+
+	err = dictionary.Rebalance(inter)
+	require.NoError(t, err)
+
+	// The entries and their order are unchanged,
+	// and the entries are stored in fewer slabs
+
+	require.Equal(t, expectedEntries, entries())
+	require.Equal(t, storageID, dictionary.StorageID())
+	require.Less(t, storage.Count(), slabCount)
+
+	require.NoError(t, storage.CheckHealth())
+}
+
+func TestDictionaryMigrateKeys(t *testing.T) {
+
+	t.Parallel()
+
+	storage := NewInMemoryStorage()
+
+	inter, err := NewInterpreter(
+		nil,
+		utils.TestLocation,
+		WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	address := common.Address{0x1}
+
+	valueType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeString,
+	}
+
+	newValue := func(i uint64) Value {
+		return NewArrayValue(
+			inter,
+			valueType,
+			common.Address{},
+			NewStringValue(fmt.Sprintf("value%d", i)),
+		)
+	}
+
+	const entryCount = 100
+
+	keysAndValues := make([]Value, 0, entryCount*2)
+	for i := uint64(0); i < entryCount; i++ {
+		keysAndValues = append(
+			keysAndValues,
+			UInt64Value(i),
+			newValue(i),
+		)
+	}
+
+	dictionary := NewDictionaryValueWithAddress(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeUInt64,
+			ValueType: valueType,
+		},
+		address,
+		keysAndValues...,
+	)
+
+	t.Run("valid", func(t *testing.T) {
+
+		migrated, err := dictionary.MigrateKeys(
+			inter,
+			ReturnEmptyLocationRange,
+			PrimitiveStaticTypeString,
+			func(oldKey Value) Value {
+				return NewStringValue(oldKey.String())
+			},
+		)
+		require.NoError(t, err)
+
+		require.Equal(t,
+			DictionaryStaticType{
+				KeyType:   PrimitiveStaticTypeString,
+				ValueType: valueType,
+			},
+			migrated.Type,
+		)
+		require.Equal(t, address, migrated.GetOwner())
+		require.Equal(t, entryCount, migrated.Count())
+
+		for i := uint64(0); i < entryCount; i++ {
+			value, ok := migrated.Get(
+				inter,
+				ReturnEmptyLocationRange,
+				NewStringValue(fmt.Sprint(i)),
+			)
+			require.True(t, ok)
+			utils.RequireValuesEqual(t, inter, newValue(i), value)
+		}
+
+		// The original dictionary is unchanged
+
+		require.Equal(t, entryCount, dictionary.Count())
+		value, ok := dictionary.Get(inter, ReturnEmptyLocationRange, UInt64Value(1))
+		require.True(t, ok)
+		utils.RequireValuesEqual(t, inter, newValue(1), value)
+	})
+
+	t.Run("duplicate keys", func(t *testing.T) {
+
+		_, err := dictionary.MigrateKeys(
+			inter,
+			ReturnEmptyLocationRange,
+			PrimitiveStaticTypeString,
+			func(oldKey Value) Value {
+				return NewStringValue("key")
+			},
+		)
+		var duplicateKeyErr DuplicateDictionaryKeyError
+		require.ErrorAs(t, err, &duplicateKeyErr)
+	})
+
+	t.Run("non-hashable keys", func(t *testing.T) {
+
+		_, err := dictionary.MigrateKeys(
+			inter,
+			ReturnEmptyLocationRange,
+			PrimitiveStaticTypeAnyStruct,
+			func(oldKey Value) Value {
+				return newValue(0)
+			},
+		)
+		var nonHashableKeyErr NonHashableKeyError
+		require.ErrorAs(t, err, &nonHashableKeyErr)
+	})
+
+	t.Run("invalid key type", func(t *testing.T) {
+
+		_, err := dictionary.MigrateKeys(
+			inter,
+			ReturnEmptyLocationRange,
+			PrimitiveStaticTypeString,
+			func(oldKey Value) Value {
+				return oldKey
+			},
+		)
+		var containerMutationErr ContainerMutationError
+		require.ErrorAs(t, err, &containerMutationErr)
+	})
+}
+
+func TestArrayValue_Chunk(t *testing.T) {
+
+	t.Parallel()
+
+	elementType := PrimitiveStaticTypeInt
+
+	newArray := func(inter *Interpreter, values ...int64) *ArrayValue {
+		elements := make([]Value, len(values))
+		for i, value := range values {
+			elements[i] = NewIntValueFromInt64(value)
+		}
+
+		return NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: elementType,
+			},
+			common.Address{},
+			elements...,
+		)
+	}
+
+	t.Run("chunks", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := newArray(inter, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+
+		chunks := array.Chunk(inter, ReturnEmptyLocationRange, 3)
+
+		chunkType := VariableSizedStaticType{
+			Type: elementType,
+		}
+
+		require.Equal(t,
+			VariableSizedStaticType{
+				Type: chunkType,
+			},
+			chunks.Type,
+		)
+		require.Equal(t, common.Address{}, chunks.GetOwner())
+
+		expected := [][]int64{
+			{0, 1, 2},
+			{3, 4, 5},
+			{6, 7, 8},
+			{9},
+		}
+
+		require.Equal(t, len(expected), chunks.Count())
+
+		for i, expectedChunk := range expected {
+			chunk := chunks.Get(inter, ReturnEmptyLocationRange, i).(*ArrayValue)
+
+			require.Equal(t, chunkType, chunk.Type)
+			require.Equal(t, common.Address{}, chunk.GetOwner())
+
+			utils.RequireValuesEqual(t, inter, newArray(inter, expectedChunk...), chunk)
+		}
+
+		// The array is unchanged
+
+		utils.RequireValuesEqual(t,
+			inter,
+			newArray(inter, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9),
+			array,
+		)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		chunks := newArray(inter).Chunk(inter, ReturnEmptyLocationRange, 3)
+
+		require.Equal(t, 0, chunks.Count())
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := newArray(inter, 1, 2)
+
+		for _, size := range []int{0, -1} {
+			func() {
+				defer func() {
+					require.Equal(t,
+						InvalidChunkSizeError{
+							Size: size,
+						},
+						recover(),
+					)
+				}()
+
+				array.Chunk(inter, ReturnEmptyLocationRange, size)
+			}()
+		}
+	})
+}
+
+func TestArrayValue_Reduce(t *testing.T) {
+
+	t.Parallel()
+
+	t.Run("sum", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+			NewIntValueFromInt64(1),
+			NewIntValueFromInt64(2),
+			NewIntValueFromInt64(3),
+		)
+
+		sum := array.Reduce(
+			inter,
+			ReturnEmptyLocationRange,
+			NewIntValueFromInt64(0),
+			func(acc, element Value) Value {
+				return acc.(NumberValue).Plus(element.(NumberValue))
+			},
+		)
+
+		utils.RequireValuesEqual(t, inter, NewIntValueFromInt64(6), sum)
+	})
+
+	t.Run("concatenate strings", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeString,
+			},
+			common.Address{},
+			NewStringValue("a"),
+			NewStringValue("b"),
+			NewStringValue("c"),
+		)
+
+		result := array.Reduce(
+			inter,
+			ReturnEmptyLocationRange,
+			NewStringValue(""),
+			func(acc, element Value) Value {
+				return acc.(*StringValue).Concat(element.(*StringValue))
+			},
+		)
+
+		utils.RequireValuesEqual(t, inter, NewStringValue("abc"), result)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+
+		t.Parallel()
+
+		inter := newTestInterpreter(t)
+
+		array := NewArrayValue(
+			inter,
+			VariableSizedStaticType{
+				Type: PrimitiveStaticTypeInt,
+			},
+			common.Address{},
+		)
+
+		initial := NewIntValueFromInt64(42)
+
+		result := array.Reduce(
+			inter,
+			ReturnEmptyLocationRange,
+			initial,
+			func(_, _ Value) Value {
+				panic("unexpected call")
+			},
+		)
+
+		require.Equal(t, initial, result)
+	})
+
+	t.Run("container accumulators", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		arrayType := VariableSizedStaticType{
+			Type: PrimitiveStaticTypeInt,
+		}
+
+		array := NewArrayValue(
+			inter,
+			arrayType,
+			common.Address{},
+			NewIntValueFromInt64(1),
+			NewIntValueFromInt64(2),
+			NewIntValueFromInt64(3),
+		)
+
+		expected := NewArrayValue(
+			inter,
+			arrayType,
+			common.Address{},
+			NewIntValueFromInt64(1),
+			NewIntValueFromInt64(2),
+			NewIntValueFromInt64(3),
+		)
+
+		slabCount := storage.Count()
+
+		// Each call returns a new array, the intermediate arrays are removed
+
+		result := array.Reduce(
+			inter,
+			ReturnEmptyLocationRange,
+			NewArrayValue(inter, arrayType, common.Address{}),
+			func(acc, element Value) Value {
+				next := acc.Clone(inter).(*ArrayValue)
+				next.Append(inter, ReturnEmptyLocationRange, element)
+				return next
+			},
+		)
+
+		utils.RequireValuesEqual(t, inter, expected, result)
+
+		// Only the slab of the result was added
+
+		require.Equal(t, slabCount+1, storage.Count())
+	})
+}
@@ -0,0 +1,192 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"github.com/onflow/cadence/runtime/common"
+)
+
+// ValueKind is the kind of a value, see KindOf.
+//
+// In contrast to the Go type of a value, the kind is stable,
+// and can be used to dispatch on values, e.g. when serializing them.
+//
+//go:generate go run golang.org/x/tools/cmd/stringer -type=ValueKind -trimprefix=ValueKind
+//
+type ValueKind uint
+
+// !!! *WARNING* !!!
+//
+// Only add new kinds by appending them.
+//
+// DO *NOT* REPLACE OR REMOVE EXISTING KINDS!
+// DO *NOT* ADD NEW KINDS IN BETWEEN!
+
+const (
+	ValueKindUnknown ValueKind = iota
+	ValueKindVoid
+	ValueKindNil
+	ValueKindSome
+	ValueKindBool
+	ValueKindString
+	ValueKindAddress
+	ValueKindPath
+	ValueKindCapability
+	ValueKindLink
+	ValueKindType
+
+	// Integers
+
+	ValueKindInt
+	ValueKindInt8
+	ValueKindInt16
+	ValueKindInt32
+	ValueKindInt64
+	ValueKindInt128
+	ValueKindInt256
+	ValueKindUInt
+	ValueKindUInt8
+	ValueKindUInt16
+	ValueKindUInt32
+	ValueKindUInt64
+	ValueKindUInt128
+	ValueKindUInt256
+	ValueKindWord8
+	ValueKindWord16
+	ValueKindWord32
+	ValueKindWord64
+
+	// Fixed-point numbers
+
+	ValueKindFix64
+	ValueKindUFix64
+
+	// Containers
+
+	ValueKindArray
+	ValueKindDictionary
+	ValueKindComposite
+	ValueKindEnum
+	ValueKindSimpleComposite
+
+	// References
+
+	ValueKindStorageReference
+	ValueKindEphemeralReference
+
+	// Functions
+
+	ValueKindFunction
+)
+
+// KindOf returns the kind of the given value.
+//
+// Composites are of kind ValueKindEnum if they are enums, and ValueKindComposite otherwise.
+// All function values are of kind ValueKindFunction.
+// Values of unknown Go types are of kind ValueKindUnknown.
+//
+func KindOf(value Value) ValueKind {
+	switch value := value.(type) {
+	case VoidValue:
+		return ValueKindVoid
+	case NilValue:
+		return ValueKindNil
+	case *SomeValue:
+		return ValueKindSome
+	case BoolValue:
+		return ValueKindBool
+	case *StringValue:
+		return ValueKindString
+	case AddressValue:
+		return ValueKindAddress
+	case PathValue:
+		return ValueKindPath
+	case *CapabilityValue:
+		return ValueKindCapability
+	case LinkValue:
+		return ValueKindLink
+	case TypeValue:
+		return ValueKindType
+
+	case IntValue:
+		return ValueKindInt
+	case Int8Value:
+		return ValueKindInt8
+	case Int16Value:
+		return ValueKindInt16
+	case Int32Value:
+		return ValueKindInt32
+	case Int64Value:
+		return ValueKindInt64
+	case Int128Value:
+		return ValueKindInt128
+	case Int256Value:
+		return ValueKindInt256
+	case UIntValue:
+		return ValueKindUInt
+	case UInt8Value:
+		return ValueKindUInt8
+	case UInt16Value:
+		return ValueKindUInt16
+	case UInt32Value:
+		return ValueKindUInt32
+	case UInt64Value:
+		return ValueKindUInt64
+	case UInt128Value:
+		return ValueKindUInt128
+	case UInt256Value:
+		return ValueKindUInt256
+	case Word8Value:
+		return ValueKindWord8
+	case Word16Value:
+		return ValueKindWord16
+	case Word32Value:
+		return ValueKindWord32
+	case Word64Value:
+		return ValueKindWord64
+
+	case Fix64Value:
+		return ValueKindFix64
+	case UFix64Value:
+		return ValueKindUFix64
+
+	case *ArrayValue:
+		return ValueKindArray
+	case *DictionaryValue:
+		return ValueKindDictionary
+	case *CompositeValue:
+		if value.Kind == common.CompositeKindEnum {
+			return ValueKindEnum
+		}
+		return ValueKindComposite
+	case *SimpleCompositeValue:
+		return ValueKindSimpleComposite
+
+	case *StorageReferenceValue:
+		return ValueKindStorageReference
+	case *EphemeralReferenceValue:
+		return ValueKindEphemeralReference
+
+	case FunctionValue:
+		return ValueKindFunction
+
+	default:
+		return ValueKindUnknown
+	}
+}
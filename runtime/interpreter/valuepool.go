@@ -0,0 +1,156 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"sync"
+
+	"github.com/onflow/atree"
+)
+
+// Array and dictionary values which are only used temporarily,
+// e.g. the elements of a container while it is transferred, cloned, compared, or deep removed,
+// are recycled if value pooling is enabled, see WithValuePool.
+//
+// Only the Go-level wrappers are recycled, not the atree containers they wrap.
+//
+var arrayValuePool = sync.Pool{
+	New: func() interface{} {
+		return &ArrayValue{}
+	},
+}
+
+var dictionaryValuePool = sync.Pool{
+	New: func() interface{} {
+		return &DictionaryValue{}
+	},
+}
+
+// convertTransientStoredValue converts the given stored value like MustConvertStoredValue.
+//
+// If value pooling is enabled, array and dictionary values are taken from a pool.
+// The returned value must not be retained, and must be released using releaseTransientValue.
+//
+func (interpreter *Interpreter) convertTransientStoredValue(value atree.Value) Value {
+	if !interpreter.valuePoolEnabled {
+		return MustConvertStoredValue(value)
+	}
+
+	switch value := value.(type) {
+	case *atree.Array:
+		arrayValue := arrayValuePool.Get().(*ArrayValue)
+		arrayValue.Type = value.Type().(ArrayStaticType)
+		arrayValue.array = value
+		return arrayValue
+
+	case *atree.OrderedMap:
+		if dictionaryType, ok := value.Type().(DictionaryStaticType); ok {
+			dictionaryValue := dictionaryValuePool.Get().(*DictionaryValue)
+			dictionaryValue.Type = dictionaryType
+			dictionaryValue.dictionary = value
+			return dictionaryValue
+		}
+	}
+
+	return MustConvertStoredValue(value)
+}
+
+// releaseTransientValue returns the given value, which was returned by convertTransientStoredValue,
+// to its pool, if value pooling is enabled.
+//
+// The value is cleared, so the pool does not retain any references.
+//
+func (interpreter *Interpreter) releaseTransientValue(value Value) {
+	if !interpreter.valuePoolEnabled {
+		return
+	}
+
+	switch value := value.(type) {
+	case *ArrayValue:
+		*value = ArrayValue{}
+		arrayValuePool.Put(value)
+
+	case *DictionaryValue:
+		*value = DictionaryValue{}
+		dictionaryValuePool.Put(value)
+	}
+}
+
+// deepRemoveStoredValue deep removes the value of the given storable.
+//
+func (interpreter *Interpreter) deepRemoveStoredValue(storable atree.Storable, storage atree.SlabStorage) {
+	storedValue, err := storable.StoredValue(storage)
+	if err != nil {
+		panic(ExternalError{err})
+	}
+
+	value := interpreter.convertTransientStoredValue(storedValue)
+	value.DeepRemove(interpreter)
+	interpreter.releaseTransientValue(value)
+}
+
+// cloneStoredValue returns a clone of the given stored value.
+//
+func (interpreter *Interpreter) cloneStoredValue(storedValue atree.Value) Value {
+	value := interpreter.convertTransientStoredValue(storedValue)
+	clone := value.Clone(interpreter)
+
+	if clone != value {
+		interpreter.releaseTransientValue(value)
+	}
+
+	return clone
+}
+
+// transferStoredValue transfers the value of the given stored value, see Value.Transfer,
+// e.g. when constructing the transferred copy of a container.
+//
+// The converted stored value is only used temporarily,
+// unless the transfer returns it, e.g. when moving a resource.
+//
+func (interpreter *Interpreter) transferStoredValue(
+	storedValue atree.Value,
+	getLocationRange func() LocationRange,
+	address atree.Address,
+	remove bool,
+) Value {
+	value := interpreter.convertTransientStoredValue(storedValue)
+	transferred := value.Transfer(interpreter, getLocationRange, address, remove, nil)
+
+	if transferred != value {
+		interpreter.releaseTransientValue(value)
+	}
+
+	return transferred
+}
+
+// storedValueEqual returns true if the value of the given stored value is equal to the other value,
+// e.g. when iterating over the elements of a container to compare it.
+//
+func (interpreter *Interpreter) storedValueEqual(
+	storedValue atree.Value,
+	getLocationRange func() LocationRange,
+	other Value,
+) bool {
+	value := interpreter.convertTransientStoredValue(storedValue)
+	defer interpreter.releaseTransientValue(value)
+
+	equatableValue, ok := value.(EquatableValue)
+	return ok && equatableValue.Equal(interpreter, getLocationRange, other)
+}
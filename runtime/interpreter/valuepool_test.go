@@ -0,0 +1,222 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	. "github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func newNestedArrayValue(inter *Interpreter, count int) *ArrayValue {
+	elementType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeInt,
+	}
+
+	elements := make([]Value, count)
+	for i := range elements {
+		elements[i] = NewArrayValue(
+			inter,
+			elementType,
+			common.Address{},
+			NewIntValueFromInt64(int64(i)),
+		)
+	}
+
+	return NewArrayValue(
+		inter,
+		VariableSizedStaticType{
+			Type: elementType,
+		},
+		common.Address{},
+		elements...,
+	)
+}
+
+func TestValuePool(t *testing.T) {
+
+	t.Parallel()
+
+	for _, enabled := range []bool{false, true} {
+
+		storage := NewInMemoryStorage()
+
+		inter, err := NewInterpreter(
+			nil,
+			utils.TestLocation,
+			WithStorage(storage),
+			WithValuePool(enabled),
+		)
+		require.NoError(t, err)
+
+		value := newNestedArrayValue(inter, 100)
+
+		slabCount := storage.Count()
+
+		clone := value.Clone(inter)
+		utils.RequireValuesEqual(t, inter, value, clone)
+
+		// Removing the clone removes all its slabs
+
+		clone.DeepRemove(inter)
+		inter.RemoveReferencedSlab(atree.StorageIDStorable(clone.(*ArrayValue).StorageID()))
+
+		require.Equal(t, slabCount, storage.Count())
+
+		// Transferring constructs an equal copy
+
+		transferred := value.Transfer(
+			inter,
+			ReturnEmptyLocationRange,
+			atree.Address{0x1},
+			false,
+			nil,
+		).(*ArrayValue)
+
+		require.Equal(t, common.Address{0x1}, transferred.GetOwner())
+		require.True(t, value.Equal(inter, ReturnEmptyLocationRange, transferred))
+
+		transferred.Iterate(func(element Value) (resume bool) {
+			require.Equal(t, common.Address{0x1}, element.(*ArrayValue).GetOwner())
+			return true
+		})
+
+		// Comparing does not mutate the compared values
+
+		dictionary := newNestedDictionaryValue(inter, 100)
+		otherDictionary := newNestedDictionaryValue(inter, 100)
+
+		require.True(t, dictionary.Equal(inter, ReturnEmptyLocationRange, otherDictionary))
+		require.Equal(t, 100, dictionary.Count())
+		require.Equal(t, 100, otherDictionary.Count())
+	}
+}
+
+func newNestedDictionaryValue(inter *Interpreter, count int) *DictionaryValue {
+	valueType := VariableSizedStaticType{
+		Type: PrimitiveStaticTypeInt,
+	}
+
+	keysAndValues := make([]Value, 0, count*2)
+	for i := 0; i < count; i++ {
+		keysAndValues = append(
+			keysAndValues,
+			NewIntValueFromInt64(int64(i)),
+			NewArrayValue(
+				inter,
+				valueType,
+				common.Address{},
+				NewIntValueFromInt64(int64(i)),
+			),
+		)
+	}
+
+	return NewDictionaryValue(
+		inter,
+		DictionaryStaticType{
+			KeyType:   PrimitiveStaticTypeInt,
+			ValueType: valueType,
+		},
+		keysAndValues...,
+	)
+}
+
+// BenchmarkValuePool constructs, compares, and removes many copies of containers
+// which contain nested containers, with and without value pooling.
+//
+func BenchmarkValuePool(b *testing.B) {
+
+	benchmark := func(b *testing.B, f func(b *testing.B, inter *Interpreter)) {
+		for _, enabled := range []bool{false, true} {
+
+			name := "disabled"
+			if enabled {
+				name = "enabled"
+			}
+
+			b.Run(name, func(b *testing.B) {
+
+				inter, err := NewInterpreter(
+					nil,
+					utils.TestLocation,
+					WithStorage(NewInMemoryStorage()),
+					WithValuePool(enabled),
+				)
+				require.NoError(b, err)
+
+				f(b, inter)
+			})
+		}
+	}
+
+	b.Run("transfer", func(b *testing.B) {
+		benchmark(b, func(b *testing.B, inter *Interpreter) {
+			value := newNestedArrayValue(inter, 1000)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				transferred := value.Transfer(
+					inter,
+					ReturnEmptyLocationRange,
+					atree.Address{0x1},
+					false,
+					nil,
+				)
+				transferred.DeepRemove(inter)
+			}
+		})
+	})
+
+	b.Run("clone", func(b *testing.B) {
+		benchmark(b, func(b *testing.B, inter *Interpreter) {
+			value := newNestedArrayValue(inter, 1000)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				clone := value.Clone(inter)
+				clone.DeepRemove(inter)
+			}
+		})
+	})
+
+	b.Run("equal", func(b *testing.B) {
+		benchmark(b, func(b *testing.B, inter *Interpreter) {
+			dictionary := newNestedDictionaryValue(inter, 1000)
+			otherDictionary := newNestedDictionaryValue(inter, 1000)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if !dictionary.Equal(inter, ReturnEmptyLocationRange, otherDictionary) {
+					b.Fatal("dictionaries are not equal")
+				}
+			}
+		})
+	})
+}
@@ -41,31 +41,89 @@ type Storage struct {
 	contractUpdates map[interpreter.StorageKey]atree.Storable
 	Ledger          atree.Ledger
 	reportMetric    func(f func(), report func(metrics Metrics, duration time.Duration))
+	readRepair      bool
+	// repairedValues are the keys of the values which were repaired when read,
+	// and which are written in the next commit
+	repairedValues map[interpreter.StorageKey]struct{}
+	// repairedSlabs are the IDs of the slabs which were repaired when read,
+	// and which are written in the next commit
+	repairedSlabs  map[atree.StorageID]struct{}
+	decodeStorable atree.StorableDecoder
+	// pinnedSlabs are the slabs kept resident by Pin, see storage_pin.go
+	pinnedSlabs          map[atree.StorageID]pinnedSlab
+	pinnedSlabsSize      uint64
+	pinnedSlabsSizeLimit uint64
 }
 
 var _ atree.SlabStorage = &Storage{}
 var _ interpreter.Storage = &Storage{}
 
+type StorageOption func(*Storage)
+
+// WithReadRepair returns a storage option which configures if read repair is enabled.
+//
+// When enabled, stored values and slabs which are read from the ledger,
+// and which are not encoded canonically, are re-encoded,
+// and written back to the ledger in the next commit, like other writes.
+// This progressively canonicalizes the storage during normal access,
+// at the cost of re-encoding all read data.
+//
+func WithReadRepair(enabled bool) StorageOption {
+	return func(storage *Storage) {
+		storage.readRepair = enabled
+	}
+}
+
+// WithDecoderOptions returns a storage option which configures
+// the decoding of stored values and slabs, e.g. the maximum length of decoded strings.
+//
+func WithDecoderOptions(options ...interpreter.DecoderOption) StorageOption {
+	return func(storage *Storage) {
+		storage.decodeStorable = interpreter.NewStorableDecoder(options...)
+	}
+}
+
 func NewStorage(
 	ledger atree.Ledger,
 	reportMetric func(f func(), report func(metrics Metrics, duration time.Duration)),
+	options ...StorageOption,
 ) *Storage {
-	ledgerStorage := atree.NewLedgerBaseStorage(ledger)
-	persistentSlabStorage := atree.NewPersistentSlabStorage(
+	storage := &Storage{
+		Ledger:               ledger,
+		writes:               map[interpreter.StorageKey]atree.Storable{},
+		readCache:            map[interpreter.StorageKey]atree.Storable{},
+		contractUpdates:      map[interpreter.StorageKey]atree.Storable{},
+		reportMetric:         reportMetric,
+		pinnedSlabs:          map[atree.StorageID]pinnedSlab{},
+		pinnedSlabsSizeLimit: DefaultPinnedSlabsSizeLimit,
+		repairedValues:       map[interpreter.StorageKey]struct{}{},
+		repairedSlabs:        map[atree.StorageID]struct{}{},
+		decodeStorable:       interpreter.DecodeStorable,
+	}
+
+	for _, option := range options {
+		option(storage)
+	}
+
+	slabLedger := ledger
+	if storage.readRepair {
+		slabLedger = readRepairLedger{
+			Ledger:         ledger,
+			decodeStorable: storage.decodeStorable,
+			repairedSlabs:  storage.repairedSlabs,
+		}
+	}
+
+	ledgerStorage := atree.NewLedgerBaseStorage(slabLedger)
+	storage.PersistentSlabStorage = atree.NewPersistentSlabStorage(
 		ledgerStorage,
 		interpreter.CBOREncMode,
 		interpreter.CBORDecMode,
-		interpreter.DecodeStorable,
+		storage.decodeStorable,
 		interpreter.DecodeTypeInfo,
 	)
-	return &Storage{
-		Ledger:                ledger,
-		PersistentSlabStorage: persistentSlabStorage,
-		writes:                map[interpreter.StorageKey]atree.Storable{},
-		readCache:             map[interpreter.StorageKey]atree.Storable{},
-		contractUpdates:       map[interpreter.StorageKey]atree.Storable{},
-		reportMetric:          reportMetric,
-	}
+
+	return storage
 }
 
 // ValueExists returns true if a value exists in account storage.
@@ -125,7 +183,7 @@ func (s *Storage) ReadValue(
 
 	storable := s.readStorable(storageKey)
 	if storable == nil {
-		return interpreter.NilValue{}
+		return interpreter.Nil()
 	} else {
 		storedValue := interpreter.StoredValue(storable, s)
 		return interpreter.NewSomeValueNonCopying(storedValue)
@@ -171,7 +229,7 @@ func (s *Storage) readStorable(storageKey interpreter.StorageKey) atree.Storable
 
 	s.reportMetric(
 		func() {
-			readStorable, err = interpreter.DecodeStorable(decoder, atree.StorageIDUndefined)
+			readStorable, err = s.decodeStorable(decoder, atree.StorageIDUndefined)
 		},
 		func(metrics Metrics, duration time.Duration) {
 			metrics.ValueDecoded(duration)
@@ -181,6 +239,10 @@ func (s *Storage) readStorable(storageKey interpreter.StorageKey) atree.Storable
 		panic(err)
 	}
 
+	if s.readRepair {
+		s.repairStorable(storageKey, storedData, readStorable)
+	}
+
 	s.readCache[storageKey] = readStorable
 
 	return readStorable
@@ -331,6 +393,27 @@ func (s *Storage) Commit(inter *interpreter.Interpreter, commitContractUpdates b
 		}
 	}
 
+	// Third, write all values which were repaired when read,
+	// and which are not overwritten by the writes above
+
+	for storageKey := range s.repairedValues { //nolint:maprangecheck
+		if _, ok := s.writes[storageKey]; ok {
+			continue
+		}
+
+		if _, ok := s.contractUpdates[storageKey]; ok && commitContractUpdates {
+			continue
+		}
+
+		accountStorageEntries = append(
+			accountStorageEntries,
+			AccountStorageEntry{
+				StorageKey: storageKey,
+				Storable:   s.readCache[storageKey],
+			},
+		)
+	}
+
 	// Sort the account storage entries by storage key in lexicographic order
 
 	SortAccountStorageEntries(accountStorageEntries)
@@ -401,6 +484,31 @@ func (s *Storage) Commit(inter *interpreter.Interpreter, commitContractUpdates b
 		}
 	}
 
+	s.repairedValues = map[interpreter.StorageKey]struct{}{}
+
+	// Store the slabs which were repaired when read, so they are written by the commit below.
+	// Slabs which were removed since they were read are not written
+
+	// NOTE: ranging over maps is safe (deterministic), as the slabs are only marked as modified
+	for storageID := range s.repairedSlabs { //nolint:maprangecheck
+		slab, ok, err := s.PersistentSlabStorage.Retrieve(storageID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		err = s.PersistentSlabStorage.Store(storageID, slab)
+		if err != nil {
+			return err
+		}
+	}
+
+	for storageID := range s.repairedSlabs { //nolint:maprangecheck
+		delete(s.repairedSlabs, storageID)
+	}
+
 	// Commit the underlying slab storage's writes
 
 	// TODO: report encoding metric for all encoded slabs
@@ -571,3 +679,71 @@ func (s *Storage) CheckHealth() error {
 
 	return nil
 }
+
+// repairStorable records that the given storable, read from the given storage key,
+// must be written in the next commit, if its canonical encoding differs from the stored data.
+//
+func (s *Storage) repairStorable(storageKey interpreter.StorageKey, storedData []byte, storable atree.Storable) {
+	canonicalData, err := atree.Encode(storable, interpreter.CBOREncMode)
+	if err != nil {
+		panic(err)
+	}
+
+	if bytes.Equal(canonicalData, storedData) {
+		return
+	}
+
+	s.repairedValues[storageKey] = struct{}{}
+}
+
+// readRepairLedger is a ledger which returns the canonical encoding of the slabs it reads,
+// and records the slabs which are not encoded canonically,
+// so the storage writes them in the next commit.
+//
+// The ledger itself never writes.
+//
+type readRepairLedger struct {
+	atree.Ledger
+	decodeStorable atree.StorableDecoder
+	repairedSlabs  map[atree.StorageID]struct{}
+}
+
+func (l readRepairLedger) GetValue(owner, key []byte) ([]byte, error) {
+	data, err := l.Ledger.GetValue(owner, key)
+	if err != nil || len(data) == 0 || !atree.LedgerKeyIsSlabKey(string(key)) {
+		return data, err
+	}
+
+	var index atree.StorageIndex
+	copy(index[:], key[len(atree.LedgerBaseStorageSlabPrefix):])
+
+	id := atree.NewStorageID(
+		atree.Address(common.BytesToAddress(owner)),
+		index,
+	)
+
+	slab, err := atree.DecodeSlab(
+		id,
+		data,
+		interpreter.CBORDecMode,
+		l.decodeStorable,
+		interpreter.DecodeTypeInfo,
+	)
+	if err != nil {
+		// Leave reporting the invalid data to the slab storage
+		return data, nil
+	}
+
+	canonicalData, err := atree.Encode(slab, interpreter.CBOREncMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(canonicalData, data) {
+		return data, nil
+	}
+
+	l.repairedSlabs[id] = struct{}{}
+
+	return canonicalData, nil
+}
@@ -0,0 +1,84 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+type storageJSONLRecord struct {
+	Address string          `json:"address"`
+	Key     string          `json:"key"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// ExportStorageJSONL writes all values of the given storage to the given writer
+// as newline-delimited JSON records, one record per account storage value.
+//
+// Each record has the address, the key, and the JSON-CDC encoding of the value.
+// Records are written in the order of the storage keys.
+//
+func ExportStorageJSONL(
+	inter *interpreter.Interpreter,
+	storage interpreter.InMemoryStorage,
+	w io.Writer,
+) error {
+
+	storageKeys := make([]interpreter.StorageKey, 0, len(storage.AccountStorage))
+	for storageKey := range storage.AccountStorage {
+		storageKeys = append(storageKeys, storageKey)
+	}
+
+	sort.Slice(storageKeys, func(i, j int) bool {
+		return storageKeys[i].IsLess(storageKeys[j])
+	})
+
+	encoder := json.NewEncoder(w)
+
+	for _, storageKey := range storageKeys {
+		storable := storage.AccountStorage[storageKey]
+		value := interpreter.StoredValue(storable, storage)
+
+		exportedValue, err := ExportValue(value, inter)
+		if err != nil {
+			return err
+		}
+
+		encodedValue, err := jsoncdc.Encode(exportedValue)
+		if err != nil {
+			return err
+		}
+
+		err = encoder.Encode(storageJSONLRecord{
+			Address: storageKey.Address.HexWithPrefix(),
+			Key:     storageKey.Key,
+			Value:   encodedValue,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
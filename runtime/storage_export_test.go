@@ -0,0 +1,135 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence"
+	jsoncdc "github.com/onflow/cadence/encoding/json"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestExportStorageJSONL(t *testing.T) {
+
+	t.Parallel()
+
+	storage := interpreter.NewInMemoryStorage()
+
+	inter, err := interpreter.NewInterpreter(
+		nil,
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+	)
+	require.NoError(t, err)
+
+	largeBigInt, ok := new(big.Int).SetString("1000000000000000000000000000000000000000000000", 10)
+	require.True(t, ok)
+
+	address1 := common.Address{0x1}
+	address2 := common.Address{0x2}
+
+	write := func(address common.Address, key string, value interpreter.Value) {
+		storage.WriteValue(
+			inter,
+			address,
+			key,
+			interpreter.NewSomeValueNonCopying(value),
+		)
+	}
+
+	write(address2, "b", interpreter.NewStringValue("test"))
+	write(address1, "b", interpreter.NewIntValueFromBigInt(largeBigInt))
+	write(
+		address1,
+		"a",
+		interpreter.NewArrayValue(
+			inter,
+			interpreter.ByteArrayStaticType,
+			common.Address{},
+			interpreter.UInt8Value(1),
+			interpreter.UInt8Value(2),
+		),
+	)
+
+	var buffer bytes.Buffer
+	err = ExportStorageJSONL(inter, storage, &buffer)
+	require.NoError(t, err)
+
+	// Big integers are encoded as strings
+
+	require.Contains(t,
+		buffer.String(),
+		`"value":"1000000000000000000000000000000000000000000000"`,
+	)
+
+	type record struct {
+		Address string          `json:"address"`
+		Key     string          `json:"key"`
+		Value   json.RawMessage `json:"value"`
+	}
+
+	var records []record
+	var values []cadence.Value
+
+	scanner := bufio.NewScanner(&buffer)
+	for scanner.Scan() {
+		var r record
+		err := json.Unmarshal(scanner.Bytes(), &r)
+		require.NoError(t, err)
+
+		value, err := jsoncdc.Decode(r.Value)
+		require.NoError(t, err)
+
+		r.Value = nil
+		records = append(records, r)
+		values = append(values, value)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Equal(t,
+		[]record{
+			{Address: "0x0100000000000000", Key: "a"},
+			{Address: "0x0100000000000000", Key: "b"},
+			{Address: "0x0200000000000000", Key: "b"},
+		},
+		records,
+	)
+
+	require.Equal(t,
+		[]cadence.Value{
+			cadence.NewArray([]cadence.Value{
+				cadence.NewUInt8(1),
+				cadence.NewUInt8(2),
+			}),
+			cadence.NewIntFromBig(largeBigInt),
+			cadence.String("test"),
+		},
+		values,
+	)
+
+}
@@ -0,0 +1,143 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"github.com/onflow/atree"
+)
+
+// DefaultPinnedSlabsSizeLimit is the default limit of the total size of pinned slabs,
+// see WithPinnedSlabsSizeLimit.
+//
+const DefaultPinnedSlabsSizeLimit = 16 * 1024 * 1024
+
+type pinnedSlab struct {
+	slab atree.Slab
+	size uint64
+}
+
+// WithPinnedSlabsSizeLimit returns a storage option which limits
+// the total size of the slabs pinned by Pin.
+//
+func WithPinnedSlabsSizeLimit(limit uint64) StorageOption {
+	return func(storage *Storage) {
+		storage.pinnedSlabsSizeLimit = limit
+	}
+}
+
+// Pin keeps the slabs with the given storage IDs resident in memory, until they are unpinned.
+// Retrieving a pinned slab never reads the ledger, even after the cache is dropped.
+//
+// Slabs which do not exist, or which would exceed the size limit
+// of pinned slabs (see WithPinnedSlabsSizeLimit), are not pinned.
+// The size of a slab is determined when it is pinned.
+//
+func (s *Storage) Pin(ids []atree.StorageID) {
+	for _, id := range ids {
+		if _, ok := s.pinnedSlabs[id]; ok {
+			continue
+		}
+
+		slab, ok, err := s.PersistentSlabStorage.Retrieve(id)
+		if err != nil {
+			panic(err)
+		}
+		if !ok {
+			continue
+		}
+
+		size := uint64(slab.ByteSize())
+		if s.pinnedSlabsSize+size > s.pinnedSlabsSizeLimit {
+			continue
+		}
+
+		s.pinnedSlabs[id] = pinnedSlab{
+			slab: slab,
+			size: size,
+		}
+		s.pinnedSlabsSize += size
+	}
+}
+
+// Unpin releases the slabs with the given storage IDs, which were kept resident by Pin.
+//
+func (s *Storage) Unpin(ids []atree.StorageID) {
+	for _, id := range ids {
+		s.unpin(id)
+	}
+}
+
+func (s *Storage) unpin(id atree.StorageID) {
+	pinned, ok := s.pinnedSlabs[id]
+	if !ok {
+		return
+	}
+
+	delete(s.pinnedSlabs, id)
+	s.pinnedSlabsSize -= pinned.size
+}
+
+// IsPinned returns true if the slab with the given storage ID is pinned.
+//
+func (s *Storage) IsPinned(id atree.StorageID) bool {
+	_, ok := s.pinnedSlabs[id]
+	return ok
+}
+
+// Retrieve returns the slab with the given storage ID.
+// Pinned slabs are returned without accessing the ledger.
+//
+func (s *Storage) Retrieve(id atree.StorageID) (atree.Slab, bool, error) {
+	if pinned, ok := s.pinnedSlabs[id]; ok {
+		return pinned.slab, true, nil
+	}
+
+	return s.PersistentSlabStorage.Retrieve(id)
+}
+
+// Store stores the given slab.
+// If the slab is pinned, the pinned slab is replaced.
+//
+func (s *Storage) Store(id atree.StorageID, slab atree.Slab) error {
+	err := s.PersistentSlabStorage.Store(id, slab)
+	if err != nil {
+		return err
+	}
+
+	if pinned, ok := s.pinnedSlabs[id]; ok {
+		pinned.slab = slab
+		s.pinnedSlabs[id] = pinned
+	}
+
+	return nil
+}
+
+// Remove removes the slab with the given storage ID.
+// If the slab is pinned, it is unpinned.
+//
+func (s *Storage) Remove(id atree.StorageID) error {
+	err := s.PersistentSlabStorage.Remove(id)
+	if err != nil {
+		return err
+	}
+
+	s.unpin(id)
+
+	return nil
+}
@@ -0,0 +1,178 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onflow/atree"
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestStoragePin(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.BytesToAddress([]byte{0x1})
+
+	newLedger := func() (testLedger, *int, atree.StorageID) {
+		reads := 0
+		ledger := newTestLedger(
+			func(_, _, _ []byte) {
+				reads++
+			},
+			nil,
+		)
+
+		storage := NewStorage(
+			ledger,
+			func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+				f()
+			},
+		)
+
+		inter, err := interpreter.NewInterpreter(
+			nil,
+			utils.TestLocation,
+			interpreter.WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		array := interpreter.NewArrayValue(
+			inter,
+			interpreter.VariableSizedStaticType{
+				Type: interpreter.PrimitiveStaticTypeString,
+			},
+			address,
+			interpreter.NewStringValue("a"),
+			interpreter.NewStringValue("b"),
+		)
+
+		storage.WriteValue(inter, address, "test", interpreter.NewSomeValueNonCopying(array))
+
+		err = storage.Commit(inter, false)
+		require.NoError(t, err)
+
+		return ledger, &reads, array.StorageID()
+	}
+
+	t.Run("pinned slabs are served from memory", func(t *testing.T) {
+
+		t.Parallel()
+
+		ledger, reads, storageID := newLedger()
+
+		storage := NewStorage(
+			ledger,
+			func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+				f()
+			},
+		)
+
+		*reads = 0
+
+		storage.Pin([]atree.StorageID{storageID})
+		require.True(t, storage.IsPinned(storageID))
+		require.Equal(t, 1, *reads)
+
+		// Retrieving the pinned slab does not read the ledger,
+		// even after the cache is dropped
+
+		storage.DropCache()
+
+		for i := 0; i < 3; i++ {
+			_, ok, err := storage.Retrieve(storageID)
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+
+		require.Equal(t, 1, *reads)
+
+		// Retrieving the unpinned slab reads the ledger again
+
+		storage.Unpin([]atree.StorageID{storageID})
+		require.False(t, storage.IsPinned(storageID))
+
+		storage.DropCache()
+
+		_, ok, err := storage.Retrieve(storageID)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.Equal(t, 2, *reads)
+	})
+
+	t.Run("size limit", func(t *testing.T) {
+
+		t.Parallel()
+
+		ledger, reads, storageID := newLedger()
+
+		storage := NewStorage(
+			ledger,
+			func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+				f()
+			},
+			WithPinnedSlabsSizeLimit(1),
+		)
+
+		*reads = 0
+
+		storage.Pin([]atree.StorageID{storageID})
+		require.False(t, storage.IsPinned(storageID))
+
+		storage.DropCache()
+
+		_, ok, err := storage.Retrieve(storageID)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.Equal(t, 2, *reads)
+	})
+
+	t.Run("removed slabs are unpinned", func(t *testing.T) {
+
+		t.Parallel()
+
+		ledger, _, storageID := newLedger()
+
+		storage := NewStorage(
+			ledger,
+			func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+				f()
+			},
+		)
+
+		storage.Pin([]atree.StorageID{storageID})
+		require.True(t, storage.IsPinned(storageID))
+
+		err := storage.Remove(storageID)
+		require.NoError(t, err)
+		require.False(t, storage.IsPinned(storageID))
+
+		_, ok, err := storage.Retrieve(storageID)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
@@ -2129,3 +2129,325 @@ transaction {
 	)
 	require.NoError(t, err)
 }
+
+func TestStorageReadRepair(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.BytesToAddress([]byte{0x1})
+
+	canonicalValueData, err := atree.Encode(interpreter.UInt8Value(5), interpreter.CBOREncMode)
+	require.NoError(t, err)
+
+	// Encode the integer with a non-minimal argument length
+
+	nonCanonical := func(canonical []byte) []byte {
+		require.Equal(t, byte(0x05), canonical[len(canonical)-1])
+		data := make([]byte, len(canonical)-1, len(canonical)+1)
+		copy(data, canonical)
+		return append(data, 0x18, 0x05)
+	}
+
+	newStorage := func(enabled bool) (*Storage, testLedger, *int) {
+		writes := 0
+		ledger := newTestLedger(nil, func(_, _, _ []byte) {
+			writes++
+		})
+
+		storage := NewStorage(
+			ledger,
+			func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+				f()
+			},
+			WithReadRepair(enabled),
+		)
+
+		return storage, ledger, &writes
+	}
+
+	t.Run("value", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, ledger, writes := newStorage(true)
+
+		err := ledger.SetValue(address[:], []byte("test"), nonCanonical(canonicalValueData))
+		require.NoError(t, err)
+		*writes = 0
+
+		value := storage.ReadValue(nil, address, "test")
+		require.Equal(t,
+			interpreter.NewSomeValueNonCopying(interpreter.UInt8Value(5)),
+			value,
+		)
+
+		// The repaired value is only written when the storage is committed
+
+		require.Equal(t, 0, *writes)
+
+		err = storage.Commit(nil, false)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, *writes)
+
+		data, err := ledger.GetValue(address[:], []byte("test"))
+		require.NoError(t, err)
+		require.Equal(t, canonicalValueData, data)
+
+		// The repair is only written once
+
+		err = storage.Commit(nil, false)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, *writes)
+	})
+
+	t.Run("slab", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, ledger, writes := newStorage(true)
+
+		index := atree.StorageIndex{0, 0, 0, 0, 0, 0, 0, 1}
+		id := atree.NewStorageID(atree.Address(address), index)
+
+		canonicalSlabData, err := atree.Encode(
+			atree.StorableSlab{
+				StorageID: id,
+				Storable:  interpreter.UInt8Value(5),
+			},
+			interpreter.CBOREncMode,
+		)
+		require.NoError(t, err)
+
+		err = ledger.SetValue(address[:], atree.SlabIndexToLedgerKey(index), nonCanonical(canonicalSlabData))
+		require.NoError(t, err)
+		*writes = 0
+
+		slab, found, err := storage.Retrieve(id)
+		require.NoError(t, err)
+		require.True(t, found)
+		require.Equal(t,
+			atree.StorableSlab{
+				StorageID: id,
+				Storable:  interpreter.UInt8Value(5),
+			},
+			slab,
+		)
+
+		// The repaired slab is only written when the storage is committed
+
+		require.Equal(t, 0, *writes)
+
+		err = storage.Commit(nil, false)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, *writes)
+
+		data, err := ledger.GetValue(address[:], atree.SlabIndexToLedgerKey(index))
+		require.NoError(t, err)
+		require.Equal(t, canonicalSlabData, data)
+	})
+
+	t.Run("canonical", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, ledger, writes := newStorage(true)
+
+		err := ledger.SetValue(address[:], []byte("test"), canonicalValueData)
+		require.NoError(t, err)
+		*writes = 0
+
+		_ = storage.ReadValue(nil, address, "test")
+
+		err = storage.Commit(nil, false)
+		require.NoError(t, err)
+
+		require.Equal(t, 0, *writes)
+	})
+
+	t.Run("overwritten", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, ledger, writes := newStorage(true)
+
+		err := ledger.SetValue(address[:], []byte("test"), nonCanonical(canonicalValueData))
+		require.NoError(t, err)
+		*writes = 0
+
+		_ = storage.ReadValue(nil, address, "test")
+
+		// A value which is written after it was repaired is only written once
+
+		storage.WriteValue(nil, address, "test", interpreter.NilValue{})
+
+		err = storage.Commit(nil, false)
+		require.NoError(t, err)
+
+		require.Equal(t, 1, *writes)
+
+		data, err := ledger.GetValue(address[:], []byte("test"))
+		require.NoError(t, err)
+		require.Empty(t, data)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+
+		t.Parallel()
+
+		storage, ledger, writes := newStorage(false)
+
+		err := ledger.SetValue(address[:], []byte("test"), nonCanonical(canonicalValueData))
+		require.NoError(t, err)
+		*writes = 0
+
+		value := storage.ReadValue(nil, address, "test")
+		require.Equal(t,
+			interpreter.NewSomeValueNonCopying(interpreter.UInt8Value(5)),
+			value,
+		)
+
+		err = storage.Commit(nil, false)
+		require.NoError(t, err)
+
+		require.Equal(t, 0, *writes)
+	})
+}
+
+func TestStorageDecoderOptions(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.BytesToAddress([]byte{0x1})
+
+	data, err := atree.Encode(interpreter.NewStringValue("abcd"), interpreter.CBOREncMode)
+	require.NoError(t, err)
+
+	ledger := newTestLedger(nil, nil)
+
+	err = ledger.SetValue(address[:], []byte("test"), data)
+	require.NoError(t, err)
+
+	storage := NewStorage(
+		ledger,
+		func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+			f()
+		},
+		WithDecoderOptions(interpreter.WithMaxDecodedStringLength(3)),
+	)
+
+	require.PanicsWithValue(t,
+		interpreter.StringLengthLimitExceededError{
+			Length: 4,
+			Limit:  3,
+		},
+		func() {
+			storage.ReadValue(nil, address, "test")
+		},
+	)
+}
+
+func TestRuntimeStorageMaterialize(t *testing.T) {
+
+	t.Parallel()
+
+	address := common.BytesToAddress([]byte{0x1})
+
+	reads := 0
+	ledger := newTestLedger(
+		func(_, _, _ []byte) {
+			reads++
+		},
+		nil,
+	)
+
+	newStorageAndInterpreter := func() (*Storage, *interpreter.Interpreter) {
+		storage := NewStorage(
+			ledger,
+			func(f func(), _ func(metrics Metrics, duration time.Duration)) {
+				f()
+			},
+		)
+
+		inter, err := interpreter.NewInterpreter(
+			nil,
+			utils.TestLocation,
+			interpreter.WithStorage(storage),
+		)
+		require.NoError(t, err)
+
+		return storage, inter
+	}
+
+	// Store a dictionary which is large enough to be split into multiple slabs
+
+	storage, inter := newStorageAndInterpreter()
+
+	const count = 1000
+
+	keysAndValues := make([]interpreter.Value, 0, count*2)
+	for i := 0; i < count; i++ {
+		keysAndValues = append(
+			keysAndValues,
+			interpreter.NewStringValue(fmt.Sprintf("key%d", i)),
+			interpreter.NewIntValueFromInt64(int64(i)),
+		)
+	}
+
+	dictionary := interpreter.NewDictionaryValue(
+		inter,
+		interpreter.DictionaryStaticType{
+			KeyType:   interpreter.PrimitiveStaticTypeString,
+			ValueType: interpreter.PrimitiveStaticTypeInt,
+		},
+		keysAndValues...,
+	).Transfer(
+		inter,
+		interpreter.ReturnEmptyLocationRange,
+		atree.Address(address),
+		true,
+		nil,
+	)
+
+	storage.WriteValue(inter, address, "test", interpreter.NewSomeValueNonCopying(dictionary))
+
+	err := storage.Commit(inter, false)
+	require.NoError(t, err)
+
+	// Read the dictionary back using a new storage
+
+	storage, inter = newStorageAndInterpreter()
+
+	value := storage.ReadValue(inter, address, "test")
+
+	reads = 0
+
+	err = interpreter.Materialize(inter, value)
+	require.NoError(t, err)
+
+	require.Greater(t, reads, 0)
+
+	// Iterating the materialized dictionary must not read from the ledger
+
+	reads = 0
+
+	readDictionary := value.(*interpreter.SomeValue).Value.(*interpreter.DictionaryValue)
+
+	iterated := 0
+	readDictionary.Iterate(func(_, _ interpreter.Value) (resume bool) {
+		iterated++
+		return true
+	})
+
+	require.Equal(t, count, iterated)
+	require.Equal(t, 0, reads)
+
+	// Materializing again must not read from the ledger
+
+	err = interpreter.Materialize(inter, value)
+	require.NoError(t, err)
+
+	require.Equal(t, 0, reads)
+}
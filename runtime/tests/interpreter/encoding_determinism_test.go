@@ -0,0 +1,71 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestRandomEncodingDeterminism(t *testing.T) {
+
+	setupRandom(t, "encoding determinism")
+
+	// The interpreters share the program,
+	// so the types of random composites are available to both
+
+	program := &interpreter.Program{
+		Program:     ast.NewProgram([]ast.Declaration{}),
+		Elaboration: sema.NewElaboration(),
+	}
+
+	newInterpreter := func() *interpreter.Interpreter {
+		inter, err := interpreter.NewInterpreter(
+			program,
+			utils.TestLocation,
+			interpreter.WithStorage(interpreter.NewInMemoryStorage()),
+			interpreter.WithImportLocationHandler(
+				func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+					return interpreter.VirtualImport{
+						Elaboration: inter.Program.Elaboration,
+					}
+				},
+			),
+		)
+		require.NoError(t, err)
+
+		return inter
+	}
+
+	for i := 0; i < 3; i++ {
+		inter1 := newInterpreter()
+		inter2 := newInterpreter()
+
+		value := randomArrayValue(inter1, 0)
+
+		require.True(t, utils.AssertEncodingDeterministic(t, inter1, inter2, value))
+	}
+}
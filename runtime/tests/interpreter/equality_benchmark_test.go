@@ -0,0 +1,101 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+var benchmarkValueSize = flag.Int(
+	"benchmarkValueSize",
+	0,
+	"The maximum container size of the values generated for benchmarks. By default, several sizes are benchmarked",
+)
+
+// readCountingStorage is an in-memory storage which counts the retrieved slabs
+//
+type readCountingStorage struct {
+	interpreter.InMemoryStorage
+	reads int
+}
+
+func (s *readCountingStorage) Retrieve(id atree.StorageID) (atree.Slab, bool, error) {
+	s.reads++
+	return s.InMemoryStorage.Retrieve(id)
+}
+
+// BenchmarkValueEquality compares a randomly generated array with a deep copy of it.
+// The reported reads are the number of slabs retrieved per comparison.
+//
+// NOTE: not parallel, the generators use the package-level random source
+func BenchmarkValueEquality(b *testing.B) {
+
+	sizes := []int{10, 50, 100}
+	if *benchmarkValueSize > 0 {
+		sizes = []int{*benchmarkValueSize}
+	}
+
+	defer func() {
+		random = rand.New(rand.NewSource(0))
+	}()
+
+	for _, size := range sizes {
+		size := size
+
+		b.Run(fmt.Sprintf("size %d", size), func(b *testing.B) {
+			random = rand.New(rand.NewSource(0))
+
+			storage := &readCountingStorage{
+				InMemoryStorage: interpreter.NewInMemoryStorage(),
+			}
+
+			inter := newGeneratorTestInterpreter(b, storage)
+
+			config := defaultGeneratorConfig
+			config.MaxContainerSize = size
+
+			value := newValueGenerator(inter, common.Address{}, config).arrayValue(0)
+			valueCopy := deepCopyValue(inter, value)
+
+			storage.reads = 0
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if !utils.ValuesAreEqual(inter, value, valueCopy) {
+					b.Fatal("values are not equal")
+				}
+			}
+
+			b.StopTimer()
+
+			b.ReportMetric(float64(storage.reads)/float64(b.N), "reads/op")
+		})
+	}
+}
@@ -0,0 +1,394 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+// GeneratorConfig configures the random value generators
+//
+type GeneratorConfig struct {
+	// MaxDepth is the maximum depth of nested containers
+	MaxDepth int
+	// MaxContainerSize is the maximum number of elements of arrays and entries of dictionaries
+	MaxContainerSize int
+	// MaxCompositeFields is the maximum number of fields of composites
+	MaxCompositeFields int
+}
+
+var defaultGeneratorConfig = GeneratorConfig{
+	MaxDepth:           containerMaxDepth,
+	MaxContainerSize:   innerContainerMaxSize,
+	MaxCompositeFields: compositeMaxFields,
+}
+
+// valueGenerator generates random values.
+//
+// Values are generated in two steps: First, all random choices are made,
+// and the value is planned (see generatedValue). Then the types of the planned composites
+// are registered in the elaboration in bulk, and the value is constructed (see generate).
+// Constructing a container already requires the types of the composites nested in it.
+//
+// The buffers for the elements and fields of containers are reused, one per depth.
+//
+type valueGenerator struct {
+	inter        *interpreter.Interpreter
+	owner        common.Address
+	config       GeneratorConfig
+	valueBuffers [][]interpreter.Value
+	fieldBuffers [][]interpreter.CompositeField
+	// compositeTypes are the types of the composites planned since the last registration
+	compositeTypes []*sema.CompositeType
+}
+
+type generatedValueKind uint8
+
+const (
+	generatedValueKindLeaf generatedValueKind = iota
+	generatedValueKindSome
+	generatedValueKindArray
+	generatedValueKindDictionary
+	generatedValueKindComposite
+)
+
+// generatedValue is a planned random value
+//
+type generatedValue struct {
+	kind generatedValueKind
+	// value is the value of a leaf
+	value interpreter.Value
+	// nested are the nested values: the inner value of an optional,
+	// the elements of an array, the keys and values of a dictionary, interleaved,
+	// or the values of the fields of a composite
+	nested []*generatedValue
+	// fieldNames are the names of the fields of a composite
+	fieldNames []string
+	// compositeType is the type of a composite
+	compositeType *sema.CompositeType
+}
+
+func leafValue(value interpreter.Value) *generatedValue {
+	return &generatedValue{
+		kind:  generatedValueKindLeaf,
+		value: value,
+	}
+}
+
+func newValueGenerator(
+	inter *interpreter.Interpreter,
+	owner common.Address,
+	config GeneratorConfig,
+) *valueGenerator {
+	return &valueGenerator{
+		inter:  inter,
+		owner:  owner,
+		config: config,
+	}
+}
+
+// valueBuffer returns a buffer of the given size for the values of a container at the given depth.
+// The buffer is only valid until the next container at the same depth is generated.
+//
+func (g *valueGenerator) valueBuffer(depth int, size int) []interpreter.Value {
+	for len(g.valueBuffers) <= depth {
+		g.valueBuffers = append(g.valueBuffers, nil)
+	}
+
+	buffer := g.valueBuffers[depth]
+	if cap(buffer) < size {
+		buffer = make([]interpreter.Value, size)
+		g.valueBuffers[depth] = buffer
+	}
+
+	return buffer[:size]
+}
+
+// fieldBuffer returns a buffer of the given size for the fields of a composite at the given depth.
+// The buffer is only valid until the next composite at the same depth is generated.
+//
+func (g *valueGenerator) fieldBuffer(depth int, size int) []interpreter.CompositeField {
+	for len(g.fieldBuffers) <= depth {
+		g.fieldBuffers = append(g.fieldBuffers, nil)
+	}
+
+	buffer := g.fieldBuffers[depth]
+	if cap(buffer) < size {
+		buffer = make([]interpreter.CompositeField, size)
+		g.fieldBuffers[depth] = buffer
+	}
+
+	return buffer[:size]
+}
+
+// registerCompositeTypes adds the types of the composites planned since the last registration
+// to the elaboration, in one batch
+//
+func (g *valueGenerator) registerCompositeTypes() {
+	elaborationCompositeTypes := g.inter.Program.Elaboration.CompositeTypes
+
+	for i, compositeType := range g.compositeTypes {
+		elaborationCompositeTypes[compositeType.ID()] = compositeType
+		g.compositeTypes[i] = nil
+	}
+
+	g.compositeTypes = g.compositeTypes[:0]
+}
+
+// generate registers the types of the planned composites,
+// and constructs the given planned value at the given depth
+//
+func (g *valueGenerator) generate(value *generatedValue, currentDepth int) interpreter.Value {
+	g.registerCompositeTypes()
+	return g.construct(value, currentDepth)
+}
+
+// construct constructs the given planned value at the given depth.
+// The types of the composites of the value must already be registered.
+//
+func (g *valueGenerator) construct(value *generatedValue, currentDepth int) interpreter.Value {
+	switch value.kind {
+	case generatedValueKindLeaf:
+		return value.value
+
+	case generatedValueKindSome:
+		return &interpreter.SomeValue{
+			Value: g.construct(value.nested[0], currentDepth+1),
+		}
+
+	case generatedValueKindArray:
+		elements := g.valueBuffer(currentDepth, len(value.nested))
+
+		for i, element := range value.nested {
+			elements[i] = deepCopyValue(g.inter, g.construct(element, currentDepth+1))
+		}
+
+		return interpreter.NewArrayValue(
+			g.inter,
+			interpreter.VariableSizedStaticType{
+				Type: interpreter.PrimitiveStaticTypeAnyStruct,
+			},
+			g.owner,
+			elements...,
+		)
+
+	case generatedValueKindDictionary:
+		keyValues := g.valueBuffer(currentDepth, len(value.nested))
+
+		for i, keyOrValue := range value.nested {
+			keyValues[i] = g.construct(keyOrValue, currentDepth+1)
+		}
+
+		return interpreter.NewDictionaryValueWithAddress(
+			g.inter,
+			interpreter.DictionaryStaticType{
+				KeyType:   interpreter.PrimitiveStaticTypeAnyStruct,
+				ValueType: interpreter.PrimitiveStaticTypeAnyStruct,
+			},
+			g.owner,
+			keyValues...,
+		)
+
+	case generatedValueKindComposite:
+		return g.constructComposite(value, currentDepth)
+
+	default:
+		panic(fmt.Sprintf("unsupported generated value kind: %d", value.kind))
+	}
+}
+
+func (g *valueGenerator) constructComposite(value *generatedValue, currentDepth int) interpreter.Value {
+	compositeType := value.compositeType
+
+	if compositeType.Kind == common.CompositeKindEnum {
+		enum := interpreter.NewCompositeValue(
+			g.inter,
+			compositeType.Location,
+			compositeType.QualifiedIdentifier(),
+			compositeType.Kind,
+			[]interpreter.CompositeField{
+				{
+					Name:  sema.EnumRawValueFieldName,
+					Value: g.construct(value.nested[0], currentDepth+1),
+				},
+			},
+			g.owner,
+		)
+
+		if enum.GetField(nil, interpreter.ReturnEmptyLocationRange, sema.EnumRawValueFieldName) == nil {
+			panic("enum without raw value")
+		}
+
+		return enum
+	}
+
+	fields := g.fieldBuffer(currentDepth, len(value.nested))
+
+	for i, fieldValue := range value.nested {
+		fields[i] = interpreter.CompositeField{
+			Name:  value.fieldNames[i],
+			Value: g.construct(fieldValue, currentDepth+1),
+		}
+	}
+
+	return interpreter.NewCompositeValue(
+		g.inter,
+		compositeType.Location,
+		compositeType.Identifier,
+		compositeType.Kind,
+		fields,
+		g.owner,
+	)
+}
+
+// GenerateValues generates n random storable values owned by the given account.
+//
+// The values have the same distribution as the values generated by randomStorableValue:
+// All values are planned first, making the same random choices in the same order,
+// then the types of all their composites are registered in the elaboration in one batch,
+// and finally the values are constructed, reusing the buffers of the generator.
+//
+func GenerateValues(
+	inter *interpreter.Interpreter,
+	owner common.Address,
+	n int,
+	cfg GeneratorConfig,
+) []interpreter.Value {
+
+	generator := newValueGenerator(inter, owner, cfg)
+
+	plannedValues := make([]*generatedValue, n)
+	for i := 0; i < n; i++ {
+		plannedValues[i] = generator.planStorableValue(0)
+	}
+
+	generator.registerCompositeTypes()
+
+	values := make([]interpreter.Value, n)
+	for i, plannedValue := range plannedValues {
+		values[i] = generator.construct(plannedValue, 0)
+	}
+
+	return values
+}
+
+// newGeneratorTestInterpreter returns an interpreter with the given storage,
+// which can be used to generate random values
+//
+func newGeneratorTestInterpreter(tb testing.TB, storage interpreter.Storage) *interpreter.Interpreter {
+	inter, err := interpreter.NewInterpreter(
+		&interpreter.Program{
+			Program:     ast.NewProgram([]ast.Declaration{}),
+			Elaboration: sema.NewElaboration(),
+		},
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+		interpreter.WithImportLocationHandler(
+			func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+				return interpreter.VirtualImport{
+					Elaboration: inter.Program.Elaboration,
+				}
+			},
+		),
+	)
+	require.NoError(tb, err)
+	return inter
+}
+
+// NOTE: not parallel, the generators use the package-level random source
+func TestGenerateValues(t *testing.T) {
+
+	const valueCount = 5
+
+	defer func() {
+		random = rand.New(rand.NewSource(0))
+	}()
+
+	// With the same seed, bulk generation generates the same values
+	// as generating the values one at a time
+
+	random = rand.New(rand.NewSource(42))
+
+	bulkInter := newGeneratorTestInterpreter(t, interpreter.NewInMemoryStorage())
+	bulkValues := GenerateValues(bulkInter, common.Address{}, valueCount, defaultGeneratorConfig)
+
+	random = rand.New(rand.NewSource(42))
+
+	loopInter := newGeneratorTestInterpreter(t, interpreter.NewInMemoryStorage())
+
+	require.Len(t, bulkValues, valueCount)
+
+	for _, bulkValue := range bulkValues {
+		loopValue := randomStorableValue(loopInter, 0)
+
+		utils.RequireValuesEqual(t, loopInter, loopValue, bulkValue)
+		require.Equal(t, loopValue.String(), bulkValue.String())
+	}
+}
+
+// NOTE: not parallel, the generators use the package-level random source
+func BenchmarkGenerateValues(b *testing.B) {
+
+	const valueCount = 10
+
+	defer func() {
+		random = rand.New(rand.NewSource(0))
+	}()
+
+	b.Run("bulk", func(b *testing.B) {
+		// Use the same seed for both variants, so they generate the same values
+		random = rand.New(rand.NewSource(0))
+
+		inter := newGeneratorTestInterpreter(b, interpreter.NewInMemoryStorage())
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			_ = GenerateValues(inter, common.Address{}, valueCount, defaultGeneratorConfig)
+		}
+	})
+
+	b.Run("loop", func(b *testing.B) {
+		// Use the same seed for both variants, so they generate the same values
+		random = rand.New(rand.NewSource(0))
+
+		inter := newGeneratorTestInterpreter(b, interpreter.NewInMemoryStorage())
+
+		b.ReportAllocs()
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			values := make([]interpreter.Value, valueCount)
+			for j := 0; j < valueCount; j++ {
+				values[j] = randomStorableValue(inter, 0)
+			}
+		}
+	})
+}
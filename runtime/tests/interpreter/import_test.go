@@ -19,6 +19,7 @@
 package interpreter_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -144,6 +145,70 @@ func TestInterpretVirtualImport(t *testing.T) {
 	)
 }
 
+func TestInterpretImportResolutionError(t *testing.T) {
+
+	t.Parallel()
+
+	const code = `
+       import Foo
+
+       fun test() {}
+    `
+
+	valueElements := sema.NewStringImportElementOrderedMap()
+
+	valueElements.Set("Foo", sema.ImportElement{
+		DeclarationKind: common.DeclarationKindStructure,
+		Access:          ast.AccessPublic,
+		Type:            sema.AnyStructType,
+	})
+
+	resolutionErr := errors.New("unknown location")
+
+	var reportedLocation common.Location
+	var reportedErr error
+
+	_, err := parseCheckAndInterpretWithOptions(t,
+		code,
+		ParseCheckAndInterpretOptions{
+			Options: []interpreter.Option{
+				interpreter.WithImportLocationHandler(
+					func(_ *interpreter.Interpreter, _ common.Location) interpreter.Import {
+						return interpreter.FailedImport{
+							Err: resolutionErr,
+						}
+					},
+				),
+				interpreter.WithImportErrorHandler(
+					func(location common.Location, err error) {
+						reportedLocation = location
+						reportedErr = err
+					},
+				),
+			},
+			CheckerOptions: []sema.Option{
+				sema.WithImportHandler(
+					func(_ *sema.Checker, _ common.Location, _ ast.Range) (sema.Import, error) {
+						return sema.VirtualImport{
+							ValueElements: valueElements,
+						}, nil
+					},
+				),
+			},
+		},
+	)
+	require.Error(t, err)
+
+	var importErr interpreter.ImportResolutionError
+	require.ErrorAs(t, err, &importErr)
+
+	assert.Equal(t, common.IdentifierLocation("Foo"), importErr.Location)
+	assert.ErrorIs(t, err, resolutionErr)
+
+	assert.Equal(t, common.IdentifierLocation("Foo"), reportedLocation)
+	assert.Equal(t, resolutionErr, reportedErr)
+}
+
 // TestInterpretImportMultipleProgramsFromLocation demonstrates how two declarations (`a` and `b`)
 // can be imported from the same location (address location `0x1`).
 // The single location (address location `0x1`) is resolved to two locations (address locations `0x1.a` and `0x1.b`).
@@ -845,6 +845,17 @@ func TestInterpretStringSlicing(t *testing.T) {
 			Length:        6,
 			LocationRange: locationRange,
 		}},
+		{"abcdef", 4, 3, "", interpreter.InvalidSliceIndexError{
+			FromIndex: 4,
+			UpToIndex: 3,
+			LocationRange: interpreter.LocationRange{
+				Location: TestLocation,
+				Range: ast.Range{
+					StartPos: ast.Position{Offset: 116, Line: 4, Column: 31},
+					EndPos:   ast.Position{Offset: 140, Line: 4, Column: 55},
+				},
+			},
+		}},
 		// Unicode: indices are based on characters = grapheme clusters
 		{"cafe\\u{301}b", 0, 5, "cafe\u0301b", nil},
 		{"cafe\\u{301}ba\\u{308}", 0, 6, "cafe\u0301ba\u0308", nil},
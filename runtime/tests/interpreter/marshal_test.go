@@ -0,0 +1,145 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2019-2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/atree"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+func TestRandomMarshalValue(t *testing.T) {
+
+	setupRandom(t, "marshal value")
+
+	storage := interpreter.NewInMemoryStorage()
+	inter, err := interpreter.NewInterpreter(
+		&interpreter.Program{
+			Program:     ast.NewProgram([]ast.Declaration{}),
+			Elaboration: sema.NewElaboration(),
+		},
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+		interpreter.WithImportLocationHandler(
+			func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+				return interpreter.VirtualImport{
+					Elaboration: inter.Program.Elaboration,
+				}
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	t.Run("round trip", func(t *testing.T) {
+
+		for i := 0; i < 10; i++ {
+			value := randomStorableValue(inter, 0)
+
+			data, err := interpreter.MarshalValue(inter, value)
+			require.NoError(t, err)
+
+			decoded, err := interpreter.UnmarshalValueWithInterpreter(inter, data)
+			require.NoError(t, err)
+
+			require.True(t,
+				interpreter.DeepEqual(inter, value, decoded),
+				"value: %s, decoded: %s", value, decoded,
+			)
+
+			// The encoding is deterministic
+
+			otherData, err := interpreter.MarshalValue(inter, decoded)
+			require.NoError(t, err)
+			require.Equal(t, data, otherData)
+		}
+	})
+
+	t.Run("new storage", func(t *testing.T) {
+
+		value := interpreter.NewArrayValue(
+			inter,
+			interpreter.VariableSizedStaticType{
+				Type: interpreter.PrimitiveStaticTypeAnyStruct,
+			},
+			common.Address{},
+			interpreter.NewStringValue("a"),
+			interpreter.NewSomeValueNonCopying(interpreter.UInt8Value(1)),
+			interpreter.NilValue{},
+		)
+
+		data, err := interpreter.MarshalValue(inter, value)
+		require.NoError(t, err)
+
+		decoded, err := interpreter.UnmarshalValue(data)
+		require.NoError(t, err)
+
+		require.True(t, interpreter.DeepEqual(inter, value, decoded))
+	})
+
+	t.Run("dump", func(t *testing.T) {
+
+		value := interpreter.NewDictionaryValue(
+			inter,
+			interpreter.DictionaryStaticType{
+				KeyType:   interpreter.PrimitiveStaticTypeString,
+				ValueType: interpreter.PrimitiveStaticTypeAnyStruct,
+			},
+			interpreter.NewStringValue("a"), interpreter.UInt8Value(1),
+			interpreter.NewStringValue("b"), interpreter.NewSomeValueNonCopying(interpreter.BoolValue(true)),
+		)
+
+		var buffer bytes.Buffer
+		err := interpreter.DumpValue(inter, value, &buffer)
+		require.NoError(t, err)
+
+		loaded, err := interpreter.LoadDumpedValue(&buffer)
+		require.NoError(t, err)
+
+		require.True(t, interpreter.DeepEqual(inter, value, loaded))
+	})
+
+	t.Run("size", func(t *testing.T) {
+
+		owner := common.Address{'A'}
+
+		value := randomArrayValue(inter, 0).Transfer(
+			inter,
+			interpreter.ReturnEmptyLocationRange,
+			atree.Address(owner),
+			false,
+			nil,
+		)
+
+		data, err := interpreter.MarshalValue(inter, value)
+		require.NoError(t, err)
+
+		slabStorageSize, _ := getSlabStorageSize(t, storage)
+
+		require.LessOrEqual(t, len(data), slabStorageSize)
+	})
+}
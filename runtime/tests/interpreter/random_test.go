@@ -0,0 +1,225 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/ast"
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+	"github.com/onflow/cadence/runtime/sema"
+	"github.com/onflow/cadence/runtime/tests/utils"
+)
+
+var recordRandomDraws = flag.String("recordRandomDraws", "", "Record the random draws of the smoke tests to the given file")
+var replayRandomDraws = flag.String("replayRandomDraws", "", "Replay the random draws of the smoke tests from the given file")
+
+// random is the source of all random decisions of the value generators
+//
+var random = rand.New(rand.NewSource(0))
+
+// randomDraws is a sequence of draws from a random source
+//
+type randomDraws []uint64
+
+// recordingSource is a random source which records all draws
+//
+type recordingSource struct {
+	source rand.Source64
+	draws  randomDraws
+}
+
+var _ rand.Source64 = &recordingSource{}
+
+func newRecordingSource(seed int64) *recordingSource {
+	return &recordingSource{
+		source: rand.NewSource(seed).(rand.Source64),
+	}
+}
+
+func (s *recordingSource) Seed(seed int64) {
+	s.source.Seed(seed)
+	s.draws = nil
+}
+
+func (s *recordingSource) Uint64() uint64 {
+	draw := s.source.Uint64()
+	s.draws = append(s.draws, draw)
+	return draw
+}
+
+func (s *recordingSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+// replayingSource is a random source which replays previously recorded draws.
+//
+// Once all draws are replayed, zero is drawn.
+// Test cases can be minimized by pruning or zeroing draws before replaying them.
+//
+type replayingSource struct {
+	draws randomDraws
+}
+
+var _ rand.Source64 = &replayingSource{}
+
+func newReplayingSource(draws randomDraws) *replayingSource {
+	return &replayingSource{
+		draws: draws,
+	}
+}
+
+func (*replayingSource) Seed(_ int64) {
+	panic("cannot seed replaying source")
+}
+
+func (s *replayingSource) Uint64() uint64 {
+	if len(s.draws) == 0 {
+		return 0
+	}
+	draw := s.draws[0]
+	s.draws = s.draws[1:]
+	return draw
+}
+
+func (s *replayingSource) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+func (d randomDraws) String() string {
+	var builder strings.Builder
+	for _, draw := range d {
+		builder.WriteString(strconv.FormatUint(draw, 10))
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+func parseRandomDraws(data string) (randomDraws, error) {
+	var draws randomDraws
+	for _, line := range strings.Fields(data) {
+		draw, err := strconv.ParseUint(line, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		draws = append(draws, draw)
+	}
+	return draws, nil
+}
+
+// setupRandom sets up the random source of the value generators for the given smoke test.
+//
+// By default, the source is seeded with the current time, and the seed is printed.
+// If the `replayRandomDraws` flag is set, the draws in the given file are replayed.
+// If the `recordRandomDraws` flag is set, the draws are written to the given file
+// when the test finishes.
+//
+func setupRandom(t *testing.T, name string) {
+	if *replayRandomDraws != "" {
+		data, err := ioutil.ReadFile(*replayRandomDraws)
+		require.NoError(t, err)
+
+		draws, err := parseRandomDraws(string(data))
+		require.NoError(t, err)
+
+		fmt.Printf("Replaying %d random draws for %s test\n", len(draws), name)
+		random = rand.New(newReplayingSource(draws))
+		return
+	}
+
+	seed := time.Now().UnixNano()
+	fmt.Printf("Seed used for %s test: %d \n", name, seed)
+
+	if *recordRandomDraws == "" {
+		random = rand.New(rand.NewSource(seed))
+		return
+	}
+
+	source := newRecordingSource(seed)
+	random = rand.New(source)
+
+	t.Cleanup(func() {
+		err := ioutil.WriteFile(*recordRandomDraws, []byte(source.draws.String()), 0644)
+		require.NoError(t, err)
+	})
+}
+
+// NOTE: not parallel, the generators use the package-level random source
+func TestRandomDrawsRecordAndReplay(t *testing.T) {
+
+	newInterpreter := func() *interpreter.Interpreter {
+		inter, err := interpreter.NewInterpreter(
+			&interpreter.Program{
+				Program:     ast.NewProgram([]ast.Declaration{}),
+				Elaboration: sema.NewElaboration(),
+			},
+			utils.TestLocation,
+			interpreter.WithStorage(interpreter.NewInMemoryStorage()),
+			interpreter.WithImportLocationHandler(
+				func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+					return interpreter.VirtualImport{
+						Elaboration: inter.Program.Elaboration,
+					}
+				},
+			),
+		)
+		require.NoError(t, err)
+		return inter
+	}
+
+	defer func() {
+		random = rand.New(rand.NewSource(0))
+	}()
+
+	recorder := newRecordingSource(42)
+	random = rand.New(recorder)
+
+	recordingInter := newInterpreter()
+	recorded := randomStorableValue(recordingInter, containerMaxDepth-1)
+
+	require.NotEmpty(t, recorder.draws)
+
+	draws, err := parseRandomDraws(recorder.draws.String())
+	require.NoError(t, err)
+	require.Equal(t, recorder.draws, draws)
+
+	random = rand.New(newReplayingSource(draws))
+
+	replayingInter := newInterpreter()
+	replayed := randomStorableValue(replayingInter, containerMaxDepth-1)
+
+	utils.RequireValuesEqual(t, replayingInter, recorded, replayed)
+	require.Equal(t, recorded.String(), replayed.String())
+
+	// Replaying pruned draws still generates a value
+
+	random = rand.New(newReplayingSource(draws[:len(draws)/2]))
+
+	_ = randomStorableValue(newInterpreter(), containerMaxDepth-1)
+}
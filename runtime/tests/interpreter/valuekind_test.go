@@ -0,0 +1,97 @@
+/*
+ * Cadence - The resource-oriented smart contract programming language
+ *
+ * Copyright 2021 Dapper Labs, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *   http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package interpreter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/onflow/cadence/runtime/common"
+	"github.com/onflow/cadence/runtime/interpreter"
+)
+
+// NOTE: not parallel, the generators use the package-level random source
+func TestRandomValueKinds(t *testing.T) {
+
+	expectedKinds := map[int]interpreter.ValueKind{
+		Int:    interpreter.ValueKindInt,
+		Int8:   interpreter.ValueKindInt8,
+		Int16:  interpreter.ValueKindInt16,
+		Int32:  interpreter.ValueKindInt32,
+		Int64:  interpreter.ValueKindInt64,
+		Int128: interpreter.ValueKindInt128,
+		Int256: interpreter.ValueKindInt256,
+
+		UInt:     interpreter.ValueKindUInt,
+		UInt8:    interpreter.ValueKindUInt8,
+		UInt16:   interpreter.ValueKindUInt16,
+		UInt32:   interpreter.ValueKindUInt32,
+		UInt64_1: interpreter.ValueKindUInt64,
+		UInt64_2: interpreter.ValueKindUInt64,
+		UInt64_3: interpreter.ValueKindUInt64,
+		UInt64_4: interpreter.ValueKindUInt64,
+		UInt128:  interpreter.ValueKindUInt128,
+		UInt256:  interpreter.ValueKindUInt256,
+
+		Word8:  interpreter.ValueKindWord8,
+		Word16: interpreter.ValueKindWord16,
+		Word32: interpreter.ValueKindWord32,
+		Word64: interpreter.ValueKindWord64,
+
+		Fix64:  interpreter.ValueKindFix64,
+		UFix64: interpreter.ValueKindUFix64,
+
+		String_1: interpreter.ValueKindString,
+		String_2: interpreter.ValueKindString,
+		String_3: interpreter.ValueKindString,
+		String_4: interpreter.ValueKindString,
+		String_5: interpreter.ValueKindString,
+
+		Bool_True:  interpreter.ValueKindBool,
+		Bool_False: interpreter.ValueKindBool,
+		Path:       interpreter.ValueKindPath,
+		Address:    interpreter.ValueKindAddress,
+		Enum:       interpreter.ValueKindEnum,
+
+		Void:       interpreter.ValueKindVoid,
+		Nil:        interpreter.ValueKindNil,
+		Capability: interpreter.ValueKindCapability,
+
+		Some:         interpreter.ValueKindSome,
+		Array_1:      interpreter.ValueKindArray,
+		Array_2:      interpreter.ValueKindArray,
+		Dictionary_1: interpreter.ValueKindDictionary,
+		Dictionary_2: interpreter.ValueKindDictionary,
+		Composite:    interpreter.ValueKindComposite,
+	}
+
+	require.Len(t, expectedKinds, Composite+1)
+
+	inter := newGeneratorTestInterpreter(t, interpreter.NewInMemoryStorage())
+
+	// Generate values at the maximum depth, so nested values are small
+
+	generator := newValueGenerator(inter, common.Address{}, defaultGeneratorConfig)
+
+	for n := Int; n <= Composite; n++ {
+		value := generator.storableValueOfKind(n, containerMaxDepth)
+		require.Equal(t, expectedKinds[n], interpreter.KindOf(value), "%d: %s", n, value)
+	}
+}
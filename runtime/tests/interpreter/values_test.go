@@ -19,14 +19,15 @@
 package interpreter
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"math"
 	"math/big"
-	"math/rand"
+	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -48,15 +49,14 @@ const compositeMaxFields = 10
 
 var runSmokeTests = flag.Bool("runSmokeTests", false, "Run smoke tests on values")
 var validateAtree = flag.Bool("validateAtree", true, "Enable atree validation")
+var dumpFailedValues = flag.String("dumpFailedValues", "", "Dump the values of failed smoke tests to files in the given directory")
 
 func TestRandomMapOperations(t *testing.T) {
 	if !*runSmokeTests {
 		t.SkipNow()
 	}
 
-	seed := time.Now().UnixNano()
-	fmt.Printf("Seed used for map opearations test: %d \n", seed)
-	rand.Seed(seed)
+	setupRandom(t, "map operations")
 
 	storage := interpreter.NewInMemoryStorage()
 	inter, err := interpreter.NewInterpreter(
@@ -127,6 +127,8 @@ func TestRandomMapOperations(t *testing.T) {
 		assert.Equal(t, orgOwner, owner)
 	})
 
+	dumpValueOnFailure(t, inter, testMap)
+
 	t.Run("iterate", func(t *testing.T) {
 		require.Equal(t, testMap.Count(), entries.size())
 
@@ -256,37 +258,31 @@ func TestRandomMapOperations(t *testing.T) {
 
 		require.Equal(t, 0, dictionary.Count())
 
-		// Get the initial storage size before inserting values
-		startingStorageSize, startingSlabCounts := getSlabStorageSize(t, storage)
+		// Storage size after removals should be same as the size before insertion.
+		utils.AssertNoStorageLeak(t, storage, func() {
+			// Insert
+			for _, keyValue := range keyValues {
+				dictionary.Insert(inter, interpreter.ReturnEmptyLocationRange, keyValue[0], keyValue[1])
+			}
 
-		// Insert
-		for _, keyValue := range keyValues {
-			dictionary.Insert(inter, interpreter.ReturnEmptyLocationRange, keyValue[0], keyValue[1])
-		}
+			require.Equal(t, newEntries.size(), dictionary.Count())
 
-		require.Equal(t, newEntries.size(), dictionary.Count())
+			// Remove
+			newEntries.foreach(func(orgKey, orgValue interpreter.Value) (exit bool) {
+				removedValue := dictionary.Remove(inter, interpreter.ReturnEmptyLocationRange, orgKey)
 
-		// Remove
-		newEntries.foreach(func(orgKey, orgValue interpreter.Value) (exit bool) {
-			removedValue := dictionary.Remove(inter, interpreter.ReturnEmptyLocationRange, orgKey)
+				assert.IsType(t, &interpreter.SomeValue{}, removedValue)
+				someValue := removedValue.(*interpreter.SomeValue)
 
-			assert.IsType(t, &interpreter.SomeValue{}, removedValue)
-			someValue := removedValue.(*interpreter.SomeValue)
+				// Removed value must be same as the original value
+				utils.AssertValuesEqual(t, inter, orgValue, someValue.Value)
 
-			// Removed value must be same as the original value
-			utils.AssertValuesEqual(t, inter, orgValue, someValue.Value)
+				return false
+			})
 
-			return false
+			// Dictionary must be empty
+			require.Equal(t, 0, dictionary.Count())
 		})
-
-		// Dictionary must be empty
-		require.Equal(t, 0, dictionary.Count())
-
-		storageSize, slabCounts := getSlabStorageSize(t, storage)
-
-		// Storage size after removals should be same as the size before insertion.
-		assert.Equal(t, startingStorageSize, storageSize)
-		assert.Equal(t, startingSlabCounts, slabCounts)
 	})
 
 	t.Run("remove enum key", func(t *testing.T) {
@@ -302,49 +298,43 @@ func TestRandomMapOperations(t *testing.T) {
 
 		require.Equal(t, 0, dictionary.Count())
 
-		// Get the initial storage size after creating empty dictionary
-		startingStorageSize, startingSlabCounts := getSlabStorageSize(t, storage)
+		// Storage size after removals should be same as the size before insertion.
+		utils.AssertNoStorageLeak(t, storage, func() {
+			newEntries := newValueMap(numberOfValues)
 
-		newEntries := newValueMap(numberOfValues)
+			keyValues := make([][2]interpreter.Value, numberOfValues)
+			for i := 0; i < numberOfValues; i++ {
+				// Create a random enum as key
+				key := generateRandomHashableValue(inter, Enum)
+				value := interpreter.Void()
 
-		keyValues := make([][2]interpreter.Value, numberOfValues)
-		for i := 0; i < numberOfValues; i++ {
-			// Create a random enum as key
-			key := generateRandomHashableValue(inter, Enum)
-			value := interpreter.VoidValue{}
+				newEntries.put(inter, key, value)
 
-			newEntries.put(inter, key, value)
+				keyValues[i][0] = key
+				keyValues[i][1] = value
+			}
 
-			keyValues[i][0] = key
-			keyValues[i][1] = value
-		}
+			// Insert
+			for _, keyValue := range keyValues {
+				dictionary.Insert(inter, interpreter.ReturnEmptyLocationRange, keyValue[0], keyValue[1])
+			}
 
-		// Insert
-		for _, keyValue := range keyValues {
-			dictionary.Insert(inter, interpreter.ReturnEmptyLocationRange, keyValue[0], keyValue[1])
-		}
+			// Remove
+			newEntries.foreach(func(orgKey, orgValue interpreter.Value) (exit bool) {
+				removedValue := dictionary.Remove(inter, interpreter.ReturnEmptyLocationRange, orgKey)
 
-		// Remove
-		newEntries.foreach(func(orgKey, orgValue interpreter.Value) (exit bool) {
-			removedValue := dictionary.Remove(inter, interpreter.ReturnEmptyLocationRange, orgKey)
+				assert.IsType(t, &interpreter.SomeValue{}, removedValue)
+				someValue := removedValue.(*interpreter.SomeValue)
 
-			assert.IsType(t, &interpreter.SomeValue{}, removedValue)
-			someValue := removedValue.(*interpreter.SomeValue)
+				// Removed value must be same as the original value
+				utils.AssertValuesEqual(t, inter, orgValue, someValue.Value)
 
-			// Removed value must be same as the original value
-			utils.AssertValuesEqual(t, inter, orgValue, someValue.Value)
+				return false
+			})
 
-			return false
+			// Dictionary must be empty
+			require.Equal(t, 0, dictionary.Count())
 		})
-
-		// Dictionary must be empty
-		require.Equal(t, 0, dictionary.Count())
-
-		storageSize, slabCounts = getSlabStorageSize(t, storage)
-
-		// Storage size after removals should be same as the size before insertion.
-		assert.Equal(t, startingStorageSize, storageSize)
-		assert.Equal(t, startingSlabCounts, slabCounts)
 	})
 
 	t.Run("random insert & remove", func(t *testing.T) {
@@ -365,65 +355,59 @@ func TestRandomMapOperations(t *testing.T) {
 
 		require.Equal(t, 0, dictionary.Count())
 
-		// Get the initial storage size before inserting values
-		startingStorageSize, startingSlabCounts := getSlabStorageSize(t, storage)
+		// Storage size after removals should be same as the size before insertion.
+		utils.AssertNoStorageLeak(t, storage, func() {
+			insertCount := 0
+			deleteCount := 0
 
-		insertCount := 0
-		deleteCount := 0
+			isInsert := func() bool {
+				if dictionary.Count() == 0 {
+					return true
+				}
 
-		isInsert := func() bool {
-			if dictionary.Count() == 0 {
-				return true
-			}
+				if insertCount >= numberOfValues {
+					return false
+				}
 
-			if insertCount >= numberOfValues {
-				return false
+				return randomInt(1) == 1
 			}
 
-			return randomInt(1) == 1
-		}
-
-		for insertCount < numberOfValues || dictionary.Count() > 0 {
-			// Perform a random operation out of insert/remove
-			if isInsert() {
-				key := keyValues[insertCount][0]
-				if _, ok := key.(*interpreter.CompositeValue); ok {
-					key = deepCopyValue(inter, key)
-				}
+			for insertCount < numberOfValues || dictionary.Count() > 0 {
+				// Perform a random operation out of insert/remove
+				if isInsert() {
+					key := keyValues[insertCount][0]
+					if _, ok := key.(*interpreter.CompositeValue); ok {
+						key = deepCopyValue(inter, key)
+					}
 
-				value := deepCopyValue(inter, keyValues[insertCount][1])
+					value := deepCopyValue(inter, keyValues[insertCount][1])
 
-				dictionary.Insert(
-					inter,
-					interpreter.ReturnEmptyLocationRange,
-					key,
-					value,
-				)
-				insertCount++
-			} else {
-				key := keyValues[deleteCount][0]
-				orgValue := keyValues[deleteCount][1]
+					dictionary.Insert(
+						inter,
+						interpreter.ReturnEmptyLocationRange,
+						key,
+						value,
+					)
+					insertCount++
+				} else {
+					key := keyValues[deleteCount][0]
+					orgValue := keyValues[deleteCount][1]
 
-				removedValue := dictionary.Remove(inter, interpreter.ReturnEmptyLocationRange, key)
+					removedValue := dictionary.Remove(inter, interpreter.ReturnEmptyLocationRange, key)
 
-				assert.IsType(t, &interpreter.SomeValue{}, removedValue)
-				someValue := removedValue.(*interpreter.SomeValue)
+					assert.IsType(t, &interpreter.SomeValue{}, removedValue)
+					someValue := removedValue.(*interpreter.SomeValue)
 
-				// Removed value must be same as the original value
-				utils.AssertValuesEqual(t, inter, orgValue, someValue.Value)
+					// Removed value must be same as the original value
+					utils.AssertValuesEqual(t, inter, orgValue, someValue.Value)
 
-				deleteCount++
+					deleteCount++
+				}
 			}
-		}
-
-		// Dictionary must be empty
-		require.Equal(t, 0, dictionary.Count())
-
-		storageSize, slabCounts := getSlabStorageSize(t, storage)
 
-		// Storage size after removals should be same as the size before insertion.
-		assert.Equal(t, startingStorageSize, storageSize)
-		assert.Equal(t, startingSlabCounts, slabCounts)
+			// Dictionary must be empty
+			require.Equal(t, 0, dictionary.Count())
+		})
 	})
 
 	t.Run("move", func(t *testing.T) {
@@ -490,9 +474,7 @@ func TestRandomArrayOperations(t *testing.T) {
 		t.SkipNow()
 	}
 
-	seed := time.Now().UnixNano()
-	fmt.Printf("Seed used for array opearations test: %d \n", seed)
-	rand.Seed(seed)
+	setupRandom(t, "array operations")
 
 	storage := interpreter.NewInMemoryStorage()
 	inter, err := interpreter.NewInterpreter(
@@ -550,6 +532,8 @@ func TestRandomArrayOperations(t *testing.T) {
 		assert.Equal(t, orgOwner, owner)
 	})
 
+	dumpValueOnFailure(t, inter, testArray)
+
 	t.Run("iterate", func(t *testing.T) {
 		require.Equal(t, testArray.Count(), len(elements))
 
@@ -693,37 +677,31 @@ func TestRandomArrayOperations(t *testing.T) {
 
 		require.Equal(t, 0, testArray.Count())
 
-		// Get the initial storage size before inserting values
-		startingStorageSize, startingSlabCounts := getSlabStorageSize(t, storage)
-
-		// Insert
-		for index, element := range newElements {
-			testArray.Insert(
-				inter,
-				interpreter.ReturnEmptyLocationRange,
-				index,
-				deepCopyValue(inter, element),
-			)
-		}
-
-		require.Equal(t, len(newElements), testArray.Count())
-
-		// Remove
-		for _, element := range newElements {
-			removedValue := testArray.Remove(inter, interpreter.ReturnEmptyLocationRange, 0)
+		// Storage size after removals should be same as the size before insertion.
+		utils.AssertNoStorageLeak(t, storage, func() {
+			// Insert
+			for index, element := range newElements {
+				testArray.Insert(
+					inter,
+					interpreter.ReturnEmptyLocationRange,
+					index,
+					deepCopyValue(inter, element),
+				)
+			}
 
-			// Removed value must be same as the original value
-			utils.AssertValuesEqual(t, inter, element, removedValue)
-		}
+			require.Equal(t, len(newElements), testArray.Count())
 
-		// Array must be empty
-		require.Equal(t, 0, testArray.Count())
+			// Remove
+			for _, element := range newElements {
+				removedValue := testArray.Remove(inter, interpreter.ReturnEmptyLocationRange, 0)
 
-		storageSize, slabCounts := getSlabStorageSize(t, storage)
+				// Removed value must be same as the original value
+				utils.AssertValuesEqual(t, inter, element, removedValue)
+			}
 
-		// Storage size after removals should be same as the size before insertion.
-		assert.Equal(t, startingStorageSize, storageSize)
-		assert.Equal(t, startingSlabCounts, slabCounts)
+			// Array must be empty
+			require.Equal(t, 0, testArray.Count())
+		})
 	})
 
 	t.Run("random insert & remove", func(t *testing.T) {
@@ -743,54 +721,48 @@ func TestRandomArrayOperations(t *testing.T) {
 
 		require.Equal(t, 0, testArray.Count())
 
-		// Get the initial storage size before inserting values
-		startingStorageSize, startingSlabCounts := getSlabStorageSize(t, storage)
+		// Storage size after removals should be same as the size before insertion.
+		utils.AssertNoStorageLeak(t, storage, func() {
+			insertCount := 0
+			deleteCount := 0
 
-		insertCount := 0
-		deleteCount := 0
+			isInsert := func() bool {
+				if testArray.Count() == 0 {
+					return true
+				}
 
-		isInsert := func() bool {
-			if testArray.Count() == 0 {
-				return true
-			}
+				if insertCount >= numberOfValues {
+					return false
+				}
 
-			if insertCount >= numberOfValues {
-				return false
+				return randomInt(1) == 1
 			}
 
-			return randomInt(1) == 1
-		}
-
-		for insertCount < numberOfValues || testArray.Count() > 0 {
-			// Perform a random operation out of insert/remove
-			if isInsert() {
-				value := deepCopyValue(inter, elements[insertCount])
-
-				testArray.Append(
-					inter,
-					interpreter.ReturnEmptyLocationRange,
-					value,
-				)
-				insertCount++
-			} else {
-				orgValue := elements[deleteCount]
-				removedValue := testArray.RemoveFirst(inter, interpreter.ReturnEmptyLocationRange)
-
-				// Removed value must be same as the original value
-				utils.AssertValuesEqual(t, inter, orgValue, removedValue)
-
-				deleteCount++
+			for insertCount < numberOfValues || testArray.Count() > 0 {
+				// Perform a random operation out of insert/remove
+				if isInsert() {
+					value := deepCopyValue(inter, elements[insertCount])
+
+					testArray.Append(
+						inter,
+						interpreter.ReturnEmptyLocationRange,
+						value,
+					)
+					insertCount++
+				} else {
+					orgValue := elements[deleteCount]
+					removedValue := testArray.RemoveFirst(inter, interpreter.ReturnEmptyLocationRange)
+
+					// Removed value must be same as the original value
+					utils.AssertValuesEqual(t, inter, orgValue, removedValue)
+
+					deleteCount++
+				}
 			}
-		}
-
-		// Dictionary must be empty
-		require.Equal(t, 0, testArray.Count())
-
-		storageSize, slabCounts := getSlabStorageSize(t, storage)
 
-		// Storage size after removals should be same as the size before insertion.
-		assert.Equal(t, startingStorageSize, storageSize)
-		assert.Equal(t, startingSlabCounts, slabCounts)
+			// Dictionary must be empty
+			require.Equal(t, 0, testArray.Count())
+		})
 	})
 
 	t.Run("move", func(t *testing.T) {
@@ -848,9 +820,7 @@ func TestRandomCompositeValueOperations(t *testing.T) {
 		t.SkipNow()
 	}
 
-	seed := time.Now().UnixNano()
-	fmt.Printf("Seed used for compsoite opearations test: %d \n", seed)
-	rand.Seed(seed)
+	setupRandom(t, "composite operations")
 
 	storage := interpreter.NewInMemoryStorage()
 	inter, err := interpreter.NewInterpreter(
@@ -891,6 +861,8 @@ func TestRandomCompositeValueOperations(t *testing.T) {
 		assert.Equal(t, orgOwner, owner)
 	})
 
+	dumpValueOnFailure(t, inter, testComposite)
+
 	t.Run("iterate", func(t *testing.T) {
 		fieldCount := 0
 		testComposite.ForEachField(func(name string, value interpreter.Value) {
@@ -993,6 +965,253 @@ func TestRandomCompositeValueOperations(t *testing.T) {
 	})
 }
 
+func TestRandomTransferStability(t *testing.T) {
+	if !*runSmokeTests {
+		t.SkipNow()
+	}
+
+	setupRandom(t, "transfer stability")
+
+	storage := interpreter.NewInMemoryStorage()
+	inter, err := interpreter.NewInterpreter(
+		&interpreter.Program{
+			Program:     ast.NewProgram([]ast.Declaration{}),
+			Elaboration: sema.NewElaboration(),
+		},
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+		interpreter.WithImportLocationHandler(
+			func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+				return interpreter.VirtualImport{
+					Elaboration: inter.Program.Elaboration,
+				}
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	const iterations = 10
+
+	t.Run("array", func(t *testing.T) {
+		value := randomArrayValue(inter, 0)
+		utils.AssertTransferStable(t, inter, storage, value, iterations)
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+		value := randomDictionaryValue(inter, 0)
+		utils.AssertTransferStable(t, inter, storage, value, iterations)
+	})
+
+	t.Run("composite", func(t *testing.T) {
+		value := randomCompositeValue(inter, common.CompositeKindStructure, 0)
+		utils.AssertTransferStable(t, inter, storage, value, iterations)
+	})
+}
+
+func TestRandomEnumKeyBytes(t *testing.T) {
+	if !*runSmokeTests {
+		t.SkipNow()
+	}
+
+	setupRandom(t, "enum key bytes")
+
+	storage := interpreter.NewInMemoryStorage()
+	inter, err := interpreter.NewInterpreter(
+		&interpreter.Program{
+			Program:     ast.NewProgram([]ast.Declaration{}),
+			Elaboration: sema.NewElaboration(),
+		},
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+		interpreter.WithImportLocationHandler(
+			func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+				return interpreter.VirtualImport{
+					Elaboration: inter.Program.Elaboration,
+				}
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	const numberOfValues = 100
+
+	keys := make([]*interpreter.CompositeValue, numberOfValues)
+	for i := range keys {
+		keys[i] = generateRandomHashableValue(inter, Enum).(*interpreter.CompositeValue)
+	}
+
+	keyBytes := func(key *interpreter.CompositeValue) string {
+		encoded, err := key.EnumKeyBytes(inter)
+		require.NoError(t, err)
+		return string(encoded)
+	}
+
+	entries := newValueMap(numberOfValues)
+
+	// Enums which are equal must have the same key bytes
+
+	encodedKeys := make(map[interface{}]string, numberOfValues)
+	distinctEncodedKeys := make(map[string]struct{}, numberOfValues)
+
+	for _, key := range keys {
+		encoded := keyBytes(key)
+
+		internalKey := entries.internalKey(key)
+		if existing, ok := encodedKeys[internalKey]; ok {
+			require.Equal(t, existing, encoded)
+		}
+
+		encodedKeys[internalKey] = encoded
+		distinctEncodedKeys[encoded] = struct{}{}
+	}
+
+	require.Len(t, distinctEncodedKeys, len(encodedKeys))
+
+	// The key bytes must be independent of the order in which they are produced,
+	// and of the owner of the enum
+
+	owner := common.Address{'A'}
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		key := keys[i]
+
+		expected := encodedKeys[entries.internalKey(key)]
+		require.Equal(t, expected, keyBytes(key))
+
+		transferred := key.Transfer(
+			inter,
+			interpreter.ReturnEmptyLocationRange,
+			atree.Address(owner),
+			false,
+			nil,
+		).(*interpreter.CompositeValue)
+
+		require.Equal(t, expected, keyBytes(transferred))
+	}
+}
+
+func TestRandomCompositeFieldTypes(t *testing.T) {
+	if !*runSmokeTests {
+		t.SkipNow()
+	}
+
+	setupRandom(t, "composite field types")
+
+	storage := interpreter.NewInMemoryStorage()
+	inter, err := interpreter.NewInterpreter(
+		&interpreter.Program{
+			Program:     ast.NewProgram([]ast.Declaration{}),
+			Elaboration: sema.NewElaboration(),
+		},
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+		interpreter.WithImportLocationHandler(
+			func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+				return interpreter.VirtualImport{
+					Elaboration: inter.Program.Elaboration,
+				}
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	composite := randomCompositeValue(inter, common.CompositeKindStructure, 0).(*interpreter.CompositeValue)
+
+	compositeType := inter.Program.Elaboration.CompositeTypes[composite.TypeID()]
+	require.NotNil(t, compositeType)
+
+	// Add a field which is not declared in the composite type
+
+	const undeclaredFieldName = "undeclared field"
+
+	composite.SetMember(
+		inter,
+		interpreter.ReturnEmptyLocationRange,
+		undeclaredFieldName,
+		interpreter.NewStringValue("test"),
+	)
+
+	fieldCount := 0
+
+	composite.ForEachFieldWithType(inter, func(name string, value interpreter.Value, fieldType sema.Type) {
+		fieldCount++
+
+		if name == undeclaredFieldName {
+			require.Nil(t, fieldType)
+			return
+		}
+
+		member, ok := compositeType.Members.Get(name)
+		require.True(t, ok)
+		require.Equal(t, member.TypeAnnotation.Type, fieldType)
+
+		utils.AssertValuesEqual(
+			t,
+			inter,
+			composite.GetField(inter, interpreter.ReturnEmptyLocationRange, name),
+			value,
+		)
+	})
+
+	require.Equal(t, compositeType.Members.Len()+1, fieldCount)
+}
+
+func TestRandomDeepRemoveAll(t *testing.T) {
+	if !*runSmokeTests {
+		t.SkipNow()
+	}
+
+	setupRandom(t, "deep remove all")
+
+	storage := interpreter.NewInMemoryStorage()
+	inter, err := interpreter.NewInterpreter(
+		&interpreter.Program{
+			Program:     ast.NewProgram([]ast.Declaration{}),
+			Elaboration: sema.NewElaboration(),
+		},
+		utils.TestLocation,
+		interpreter.WithStorage(storage),
+		interpreter.WithImportLocationHandler(
+			func(inter *interpreter.Interpreter, location common.Location) interpreter.Import {
+				return interpreter.VirtualImport{
+					Elaboration: inter.Program.Elaboration,
+				}
+			},
+		),
+	)
+	require.NoError(t, err)
+
+	const valueCount = 100
+
+	values := make([]interpreter.Value, valueCount)
+	for i := range values {
+		values[i] = randomStorableValue(inter, 0)
+	}
+
+	// Generating the random values leaves behind temporary slabs,
+	// so remove copies of the values, which only allocate the slabs they need
+
+	baseline := make(map[atree.StorageID]struct{}, len(storage.Slabs))
+	for storageID := range storage.Slabs {
+		baseline[storageID] = struct{}{}
+	}
+
+	for i, value := range values {
+		values[i] = interpreter.CopyValue(inter, value)
+	}
+
+	require.Greater(t, storage.Count(), len(baseline))
+
+	err = interpreter.DeepRemoveAll(inter, storage, values)
+	require.NoError(t, err)
+
+	require.Equal(t, len(baseline), storage.Count())
+	for storageID := range storage.Slabs {
+		_, ok := baseline[storageID]
+		require.True(t, ok)
+	}
+}
+
 func newCompositeValue(
 	orgOwner common.Address,
 	fieldsCount int,
@@ -1067,20 +1286,36 @@ func newCompositeValue(
 	return testComposite, orgFields
 }
 
-func getSlabStorageSize(t *testing.T, storage interpreter.InMemoryStorage) (totalSize int, slabCounts int) {
-	slabs, err := storage.Encode()
+// dumpValueOnFailure dumps the given value to a file in the directory given by the `dumpFailedValues` flag,
+// if the test fails. The value is captured immediately, before the test mutates it.
+//
+// The dumped value can be loaded using interpreter.LoadDumpedValue.
+//
+func dumpValueOnFailure(t *testing.T, inter *interpreter.Interpreter, value interpreter.Value) {
+	if *dumpFailedValues == "" || value == nil {
+		return
+	}
+
+	var buffer bytes.Buffer
+	err := interpreter.DumpValue(inter, value, &buffer)
 	require.NoError(t, err)
 
-	for id, slab := range slabs {
-		if id.Address == atree.AddressUndefined {
-			continue
+	t.Cleanup(func() {
+		if !t.Failed() {
+			return
 		}
 
-		totalSize += len(slab)
-		slabCounts++
-	}
+		path := filepath.Join(*dumpFailedValues, t.Name()+".bin")
 
-	return
+		err := ioutil.WriteFile(path, buffer.Bytes(), 0644)
+		require.NoError(t, err)
+
+		fmt.Printf("Dumped value of failed test %s to %s\n", t.Name(), path)
+	})
+}
+
+func getSlabStorageSize(t *testing.T, storage interpreter.InMemoryStorage) (totalSize int, slabCounts int) {
+	return utils.SlabStorageSize(t, storage)
 }
 
 // deepCopyValue deep copies values at a higher level
@@ -1157,7 +1392,7 @@ func deepCopyValue(inter *interpreter.Interpreter, value interpreter.Value) inte
 		return v
 
 	case interpreter.VoidValue:
-		return interpreter.VoidValue{}
+		return interpreter.Void()
 
 	case *interpreter.DictionaryValue:
 		keyValues := make([]interpreter.Value, 0, v.Count()*2)
@@ -1216,91 +1451,158 @@ func deepCopyValue(inter *interpreter.Interpreter, value interpreter.Value) inte
 	case *interpreter.SomeValue:
 		return interpreter.NewSomeValueNonCopying(deepCopyValue(inter, v.Value))
 	case interpreter.NilValue:
-		return interpreter.NilValue{}
+		return interpreter.Nil()
 	default:
 		panic("unreachable")
 	}
 }
 
 func randomStorableValue(inter *interpreter.Interpreter, currentDepth int) interpreter.Value {
+	return newValueGenerator(inter, common.Address{}, defaultGeneratorConfig).storableValue(currentDepth)
+}
+
+func (g *valueGenerator) storableValue(currentDepth int) interpreter.Value {
+	return g.generate(g.planStorableValue(currentDepth), currentDepth)
+}
+
+func (g *valueGenerator) planStorableValue(currentDepth int) *generatedValue {
 	n := 0
-	if currentDepth < containerMaxDepth {
+	if currentDepth < g.config.MaxDepth {
 		n = randomInt(Composite)
 	} else {
 		n = randomInt(Capability)
 	}
 
+	return g.planStorableValueOfKind(n, currentDepth)
+}
+
+// storableValueOfKind generates a random storable value of the given kind,
+// one of the constants of the kinds of generated values, e.g. Array_1
+//
+func (g *valueGenerator) storableValueOfKind(n int, currentDepth int) interpreter.Value {
+	return g.generate(g.planStorableValueOfKind(n, currentDepth), currentDepth)
+}
+
+func (g *valueGenerator) planStorableValueOfKind(n int, currentDepth int) *generatedValue {
 	switch n {
 
 	// Non-hashable
 	case Void:
-		return interpreter.VoidValue{}
+		return leafValue(interpreter.Void())
 	case Nil:
-		return interpreter.NilValue{}
+		return leafValue(interpreter.Nil())
 	case Dictionary_1, Dictionary_2:
-		return randomDictionaryValue(inter, currentDepth)
+		return g.planDictionaryValue(currentDepth)
 	case Array_1, Array_2:
-		return randomArrayValue(inter, currentDepth)
+		return g.planArrayValue(currentDepth)
 	case Composite:
-		return randomCompositeValue(inter, common.CompositeKindStructure, currentDepth)
+		return g.planCompositeValue(common.CompositeKindStructure, currentDepth)
 	case Capability:
-		return &interpreter.CapabilityValue{
-			Address: randomAddressValue(),
-			Path:    randomPathValue(),
-			BorrowType: interpreter.ReferenceStaticType{
-				Authorized: false,
-				Type:       interpreter.PrimitiveStaticTypeAnyStruct,
-			},
-		}
+		return leafValue(
+			interpreter.NewUnauthorizedCapability(
+				randomAddressValue(),
+				randomPathValue(),
+				interpreter.PrimitiveStaticTypeAnyStruct,
+			),
+		)
 	case Some:
-		return &interpreter.SomeValue{
-			Value: randomStorableValue(inter, currentDepth+1),
+		return &generatedValue{
+			kind:   generatedValueKindSome,
+			nested: []*generatedValue{g.planStorableValue(currentDepth + 1)},
 		}
 
 	// Hashable
 	default:
-		return generateRandomHashableValue(inter, n)
+		return g.planHashableValue(n)
 	}
 }
 
-func randomHashableValue(interpreter *interpreter.Interpreter) interpreter.Value {
-	return generateRandomHashableValue(interpreter, randomInt(Enum))
+func randomHashableValue(inter *interpreter.Interpreter) interpreter.Value {
+	return generateRandomHashableValue(inter, randomInt(Enum))
 }
 
 func generateRandomHashableValue(inter *interpreter.Interpreter, n int) interpreter.Value {
+	return newValueGenerator(inter, common.Address{}, defaultGeneratorConfig).hashableValue(n)
+}
+
+func (g *valueGenerator) hashableValue(n int) interpreter.Value {
+	return g.generate(g.planHashableValue(n), 0)
+}
+
+func (g *valueGenerator) planHashableValue(n int) *generatedValue {
+	if n != Enum {
+		return leafValue(primitiveHashableValue(n))
+	}
+
+	// Get a random integer subtype to be used as the raw-type of enum
+	typ := randomInt(Word64)
+
+	rawValue := primitiveHashableValue(typ).(interpreter.NumberValue)
+
+	identifier := randomUTF8String()
+
+	address := make([]byte, 8)
+	random.Read(address)
+
+	location := common.AddressLocation{
+		Address: common.BytesToAddress(address),
+		Name:    identifier,
+	}
+
+	enumType := &sema.CompositeType{
+		Identifier:  identifier,
+		EnumRawType: intSubtype(typ),
+		Kind:        common.CompositeKindEnum,
+		Location:    location,
+	}
+
+	g.compositeTypes = append(g.compositeTypes, enumType)
+
+	return &generatedValue{
+		kind:          generatedValueKindComposite,
+		compositeType: enumType,
+		fieldNames:    []string{sema.EnumRawValueFieldName},
+		nested:        []*generatedValue{leafValue(rawValue)},
+	}
+}
+
+// primitiveHashableValue generates a random hashable value of the given kind,
+// which is not an enum
+//
+func primitiveHashableValue(n int) interpreter.Value {
 	switch n {
 
 	// Int
 	case Int:
-		return interpreter.NewIntValueFromInt64(int64(sign()) * rand.Int63())
+		return interpreter.NewIntValueFromInt64(int64(sign()) * random.Int63())
 	case Int8:
 		return interpreter.Int8Value(randomInt(math.MaxUint8))
 	case Int16:
 		return interpreter.Int16Value(randomInt(math.MaxUint16))
 	case Int32:
-		return interpreter.Int32Value(int32(sign()) * rand.Int31())
+		return interpreter.Int32Value(int32(sign()) * random.Int31())
 	case Int64:
-		return interpreter.Int64Value(int64(sign()) * rand.Int63())
+		return interpreter.Int64Value(int64(sign()) * random.Int63())
 	case Int128:
-		return interpreter.NewInt128ValueFromInt64(int64(sign()) * rand.Int63())
+		return interpreter.NewInt128ValueFromInt64(int64(sign()) * random.Int63())
 	case Int256:
-		return interpreter.NewInt256ValueFromInt64(int64(sign()) * rand.Int63())
+		return interpreter.NewInt256ValueFromInt64(int64(sign()) * random.Int63())
 
 	// UInt
 	case UInt:
-		return interpreter.NewUIntValueFromUint64(rand.Uint64())
+		return interpreter.NewUIntValueFromUint64(random.Uint64())
 	case UInt8:
 		return interpreter.UInt8Value(randomInt(math.MaxUint8))
 	case UInt16:
 		return interpreter.UInt16Value(randomInt(math.MaxUint16))
 	case UInt32:
-		return interpreter.UInt32Value(rand.Uint32())
+		return interpreter.UInt32Value(random.Uint32())
 	case UInt64_1, UInt64_2, UInt64_3, UInt64_4: // should be more common
-		return interpreter.UInt64Value(rand.Uint64())
+		return interpreter.UInt64Value(random.Uint64())
 	case UInt128:
-		return interpreter.NewUInt128ValueFromUint64(rand.Uint64())
+		return interpreter.NewUInt128ValueFromUint64(random.Uint64())
 	case UInt256:
-		return interpreter.NewUInt256ValueFromUint64(rand.Uint64())
+		return interpreter.NewUInt256ValueFromUint64(random.Uint64())
 
 	// Word
 	case Word8:
@@ -1308,16 +1610,16 @@ func generateRandomHashableValue(inter *interpreter.Interpreter, n int) interpre
 	case Word16:
 		return interpreter.Word16Value(randomInt(math.MaxUint16))
 	case Word32:
-		return interpreter.Word32Value(rand.Uint32())
+		return interpreter.Word32Value(random.Uint32())
 	case Word64:
-		return interpreter.Word64Value(rand.Uint64())
+		return interpreter.Word64Value(random.Uint64())
 
 	// Fixed point
 	case Fix64:
-		return interpreter.NewFix64ValueWithInteger(int64(sign()) * rand.Int63n(sema.Fix64TypeMaxInt))
+		return interpreter.NewFix64ValueWithInteger(int64(sign()) * random.Int63n(sema.Fix64TypeMaxInt))
 	case UFix64:
 		return interpreter.NewUFix64ValueWithInteger(
-			uint64(rand.Int63n(
+			uint64(random.Int63n(
 				int64(sema.UFix64TypeMaxInt),
 			)),
 		)
@@ -1341,51 +1643,6 @@ func generateRandomHashableValue(inter *interpreter.Interpreter, n int) interpre
 	case Path:
 		return randomPathValue()
 
-	case Enum:
-		// Get a random integer subtype to be used as the raw-type of enum
-		typ := randomInt(Word64)
-
-		rawValue := generateRandomHashableValue(inter, typ).(interpreter.NumberValue)
-
-		identifier := randomUTF8String()
-
-		address := make([]byte, 8)
-		rand.Read(address)
-
-		location := common.AddressLocation{
-			Address: common.BytesToAddress(address),
-			Name:    identifier,
-		}
-
-		enumType := &sema.CompositeType{
-			Identifier:  identifier,
-			EnumRawType: intSubtype(typ),
-			Kind:        common.CompositeKindEnum,
-			Location:    location,
-		}
-
-		inter.Program.Elaboration.CompositeTypes[enumType.ID()] = enumType
-
-		enum := interpreter.NewCompositeValue(
-			inter,
-			location,
-			enumType.QualifiedIdentifier(),
-			enumType.Kind,
-			[]interpreter.CompositeField{
-				{
-					Name:  sema.EnumRawValueFieldName,
-					Value: rawValue,
-				},
-			},
-			common.Address{},
-		)
-
-		if enum.GetField(nil, interpreter.ReturnEmptyLocationRange, sema.EnumRawValueFieldName) == nil {
-			panic("enum without raw value")
-		}
-
-		return enum
-
 	default:
 		panic(fmt.Sprintf("unsupported:  %d", n))
 	}
@@ -1401,12 +1658,12 @@ func sign() int {
 
 func randomAddressValue() interpreter.AddressValue {
 	data := make([]byte, 8)
-	rand.Read(data)
+	random.Read(data)
 	return interpreter.NewAddressValueFromBytes(data)
 }
 
 func randomPathValue() interpreter.PathValue {
-	randomDomain := rand.Intn(len(common.AllPathDomains))
+	randomDomain := random.Intn(len(common.AllPathDomains))
 	identifier := randomUTF8String()
 
 	return interpreter.PathValue{
@@ -1419,49 +1676,55 @@ func randomDictionaryValue(
 	inter *interpreter.Interpreter,
 	currentDepth int,
 ) interpreter.Value {
+	return newValueGenerator(inter, common.Address{}, defaultGeneratorConfig).dictionaryValue(currentDepth)
+}
+
+func (g *valueGenerator) dictionaryValue(currentDepth int) interpreter.Value {
+	return g.generate(g.planDictionaryValue(currentDepth), currentDepth)
+}
+
+func (g *valueGenerator) planDictionaryValue(currentDepth int) *generatedValue {
 
-	entryCount := randomInt(innerContainerMaxSize)
-	keyValues := make([]interpreter.Value, entryCount*2)
+	entryCount := randomInt(g.config.MaxContainerSize)
+	keyValues := make([]*generatedValue, entryCount*2)
 
 	for i := 0; i < entryCount; i++ {
-		key := randomHashableValue(inter)
-		value := randomStorableValue(inter, currentDepth+1)
+		key := g.planHashableValue(randomInt(Enum))
+		value := g.planStorableValue(currentDepth + 1)
 		keyValues[i*2] = key
 		keyValues[i*2+1] = value
 	}
 
-	return interpreter.NewDictionaryValueWithAddress(
-		inter,
-		interpreter.DictionaryStaticType{
-			KeyType:   interpreter.PrimitiveStaticTypeAnyStruct,
-			ValueType: interpreter.PrimitiveStaticTypeAnyStruct,
-		},
-		common.Address{},
-		keyValues...,
-	)
+	return &generatedValue{
+		kind:   generatedValueKindDictionary,
+		nested: keyValues,
+	}
 }
 
 func randomInt(upperBound int) int {
-	return rand.Intn(upperBound + 1)
+	return random.Intn(upperBound + 1)
 }
 
 func randomArrayValue(inter *interpreter.Interpreter, currentDepth int) interpreter.Value {
-	elementsCount := randomInt(innerContainerMaxSize)
-	elements := make([]interpreter.Value, elementsCount)
+	return newValueGenerator(inter, common.Address{}, defaultGeneratorConfig).arrayValue(currentDepth)
+}
+
+func (g *valueGenerator) arrayValue(currentDepth int) interpreter.Value {
+	return g.generate(g.planArrayValue(currentDepth), currentDepth)
+}
+
+func (g *valueGenerator) planArrayValue(currentDepth int) *generatedValue {
+	elementsCount := randomInt(g.config.MaxContainerSize)
+	elements := make([]*generatedValue, elementsCount)
 
 	for i := 0; i < elementsCount; i++ {
-		value := randomStorableValue(inter, currentDepth+1)
-		elements[i] = deepCopyValue(inter, value)
+		elements[i] = g.planStorableValue(currentDepth + 1)
 	}
 
-	return interpreter.NewArrayValue(
-		inter,
-		interpreter.VariableSizedStaticType{
-			Type: interpreter.PrimitiveStaticTypeAnyStruct,
-		},
-		common.Address{},
-		elements...,
-	)
+	return &generatedValue{
+		kind:   generatedValueKindArray,
+		nested: elements,
+	}
 }
 
 func randomCompositeValue(
@@ -1469,27 +1732,38 @@ func randomCompositeValue(
 	kind common.CompositeKind,
 	currentDepth int,
 ) interpreter.Value {
+	return newValueGenerator(inter, common.Address{}, defaultGeneratorConfig).compositeValue(kind, currentDepth)
+}
+
+func (g *valueGenerator) compositeValue(
+	kind common.CompositeKind,
+	currentDepth int,
+) interpreter.Value {
+	return g.generate(g.planCompositeValue(kind, currentDepth), currentDepth)
+}
+
+func (g *valueGenerator) planCompositeValue(
+	kind common.CompositeKind,
+	currentDepth int,
+) *generatedValue {
 
 	identifier := randomUTF8String()
 
 	address := make([]byte, 8)
-	rand.Read(address)
+	random.Read(address)
 
 	location := common.AddressLocation{
 		Address: common.BytesToAddress(address),
 		Name:    identifier,
 	}
 
-	fieldsCount := randomInt(compositeMaxFields)
-	fields := make([]interpreter.CompositeField, fieldsCount)
+	fieldsCount := randomInt(g.config.MaxCompositeFields)
+	fieldNames := make([]string, fieldsCount)
+	fieldValues := make([]*generatedValue, fieldsCount)
 
 	for i := 0; i < fieldsCount; i++ {
-		fieldName := randomUTF8String()
-
-		fields[i] = interpreter.CompositeField{
-			Name:  fieldName,
-			Value: randomStorableValue(inter, currentDepth+1),
-		}
+		fieldNames[i] = randomUTF8String()
+		fieldValues[i] = g.planStorableValue(currentDepth + 1)
 	}
 
 	compositeType := &sema.CompositeType{
@@ -1499,29 +1773,28 @@ func randomCompositeValue(
 	}
 
 	compositeType.Members = sema.NewStringMemberOrderedMap()
-	for _, field := range fields {
+	for _, fieldName := range fieldNames {
 		compositeType.Members.Set(
-			field.Name,
+			fieldName,
 			sema.NewPublicConstantFieldMember(
 				compositeType,
-				field.Name,
+				fieldName,
 				sema.AnyStructType, // TODO: handle resources
 				"",
 			),
 		)
 	}
 
-	// Add the type to the elaboration, to short-circuit the type-lookup
-	inter.Program.Elaboration.CompositeTypes[compositeType.ID()] = compositeType
+	// The type is added to the elaboration before the composite is constructed,
+	// to short-circuit the type-lookup, see valueGenerator.generate
+	g.compositeTypes = append(g.compositeTypes, compositeType)
 
-	return interpreter.NewCompositeValue(
-		inter,
-		location,
-		identifier,
-		kind,
-		fields,
-		common.Address{},
-	)
+	return &generatedValue{
+		kind:          generatedValueKindComposite,
+		compositeType: compositeType,
+		fieldNames:    fieldNames,
+		nested:        fieldValues,
+	}
 }
 
 func intSubtype(n int) sema.Type {
@@ -1710,6 +1983,6 @@ func randomUTF8String() string {
 
 func randomUTF8StringOfSize(size int) string {
 	identifier := make([]byte, size)
-	rand.Read(identifier)
+	random.Read(identifier)
 	return strings.ToValidUTF8(string(identifier), "$")
 }
@@ -21,10 +21,12 @@ package utils
 import (
 	"encoding/hex"
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 
 	"github.com/go-test/deep"
+	"github.com/onflow/atree"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/onflow/cadence/runtime/interpreter"
@@ -126,15 +128,7 @@ func UpdateTransaction(name string, contract []byte) []byte {
 }
 
 func ValuesAreEqual(inter *interpreter.Interpreter, expected, actual interpreter.Value) bool {
-	if expected == nil {
-		return actual == nil
-	}
-
-	if expected, ok := expected.(interpreter.EquatableValue); ok {
-		return expected.Equal(inter, interpreter.ReturnEmptyLocationRange, actual)
-	}
-
-	return assert.ObjectsAreEqual(expected, actual)
+	return interpreter.DeepEqual(inter, expected, actual)
 }
 
 func AssertValuesEqual(t testing.TB, interpreter *interpreter.Interpreter, expected, actual interpreter.Value) bool {
@@ -192,3 +186,195 @@ func AssertValueSlicesEqual(t testing.TB, inter *interpreter.Interpreter, expect
 
 	return true
 }
+
+// EncodingDeterminismTestAddress is the account address
+// to which AssertEncodingDeterministic writes values.
+//
+var EncodingDeterminismTestAddress = common.Address{0xd, 0xe, 0x7}
+
+// AssertEncodingDeterministic asserts that the given value is encoded to the same slabs
+// when it is built on each of the two given interpreters.
+//
+// The value is marshaled using the first interpreter, unmarshaled into the storage of each interpreter,
+// and written to EncodingDeterminismTestAddress, which must be unused in both storages.
+// Both interpreters must use an in-memory storage.
+//
+func AssertEncodingDeterministic(
+	t testing.TB,
+	inter1, inter2 *interpreter.Interpreter,
+	value interpreter.Value,
+) bool {
+
+	data, err := interpreter.MarshalValue(inter1, value)
+	if !assert.NoError(t, err) {
+		return false
+	}
+
+	encode := func(inter *interpreter.Interpreter) ([]interpreter.EncodedSlab, bool) {
+		storage, ok := inter.Storage.(interpreter.InMemoryStorage)
+		if !assert.True(t, ok, "storage must be an in-memory storage") {
+			return nil, false
+		}
+
+		value, err := interpreter.UnmarshalValueWithInterpreter(inter, data)
+		if !assert.NoError(t, err) {
+			return nil, false
+		}
+
+		storage.WriteValue(
+			inter,
+			EncodingDeterminismTestAddress,
+			"value",
+			interpreter.NewSomeValueNonCopying(value),
+		)
+
+		slabs, err := storage.EncodeOrdered()
+		if !assert.NoError(t, err) {
+			return nil, false
+		}
+
+		// Only compare the slabs of the account the value was written to,
+		// the storages may contain other values
+
+		var result []interpreter.EncodedSlab
+		for _, slab := range slabs {
+			if common.Address(slab.ID.Address) != EncodingDeterminismTestAddress {
+				continue
+			}
+			result = append(result, slab)
+		}
+
+		return result, true
+	}
+
+	slabs1, ok := encode(inter1)
+	if !ok {
+		return false
+	}
+
+	slabs2, ok := encode(inter2)
+	if !ok {
+		return false
+	}
+
+	return assert.Equal(t, slabs1, slabs2)
+}
+
+// SlabStorageSize returns the total size and the number of the slabs
+// in the given storage, excluding temporary slabs (slabs without an address).
+//
+func SlabStorageSize(t testing.TB, storage interpreter.InMemoryStorage) (totalSize int, slabCount int) {
+	slabs, err := storage.Encode()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for id, slab := range slabs {
+		if id.Address == atree.AddressUndefined {
+			continue
+		}
+
+		totalSize += len(slab)
+		slabCount++
+	}
+
+	return
+}
+
+// AssertNoStorageLeak asserts that the size and the number of the slabs
+// in the given storage are the same before and after calling fn.
+//
+// For example, inserting elements into a container and removing them again
+// should leave the storage as it was before.
+//
+func AssertNoStorageLeak(t testing.TB, storage interpreter.InMemoryStorage, fn func()) bool {
+	startingSize, startingSlabCount := SlabStorageSize(t, storage)
+
+	fn()
+
+	size, slabCount := SlabStorageSize(t, storage)
+
+	return assert.Equal(t, startingSize, size, "storage size") &&
+		assert.Equal(t, startingSlabCount, slabCount, "slab count")
+}
+
+// TransferStabilityTestAddresses are the account addresses
+// between which AssertTransferStable transfers values.
+//
+var TransferStabilityTestAddresses = [2]common.Address{
+	{0x7, 0x5, 0x1},
+	{0x7, 0x5, 0x2},
+}
+
+// AssertTransferStable asserts that transferring the given value back and forth
+// between the two TransferStabilityTestAddresses does not change the value,
+// and does not leak or lose storage.
+//
+// A copy of the value is transferred to the first address, and then moved
+// to the second address and back the given number of times.
+// After each round, the moved value must be equal to the given value,
+// and the size and the number of the slabs in the storage must be the same as before the first round.
+// Finally, the copy is removed again. The given value is unchanged.
+//
+func AssertTransferStable(
+	t testing.TB,
+	inter *interpreter.Interpreter,
+	storage interpreter.InMemoryStorage,
+	value interpreter.Value,
+	iterations int,
+) bool {
+
+	move := func(value interpreter.Value, from, to common.Address) interpreter.Value {
+		storable, err := value.Storable(storage, atree.Address(from), math.MaxUint64)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		return value.Transfer(
+			inter,
+			interpreter.ReturnEmptyLocationRange,
+			atree.Address(to),
+			true,
+			storable,
+		)
+	}
+
+	first := TransferStabilityTestAddresses[0]
+	second := TransferStabilityTestAddresses[1]
+
+	current := value.Transfer(
+		inter,
+		interpreter.ReturnEmptyLocationRange,
+		atree.Address(first),
+		false,
+		nil,
+	)
+
+	startingSize, startingSlabCount := SlabStorageSize(t, storage)
+
+	for i := 0; i < iterations; i++ {
+		current = move(current, first, second)
+		current = move(current, second, first)
+
+		if !AssertValuesEqual(t, inter, value, current) {
+			return false
+		}
+
+		size, slabCount := SlabStorageSize(t, storage)
+		if !assert.Equal(t, startingSize, size, "storage size after round %d", i) ||
+			!assert.Equal(t, startingSlabCount, slabCount, "slab count after round %d", i) {
+
+			return false
+		}
+	}
+
+	storable, err := current.Storable(storage, atree.Address(first), math.MaxUint64)
+	if !assert.NoError(t, err) {
+		return false
+	}
+
+	current.DeepRemove(inter)
+	inter.RemoveReferencedSlab(storable)
+
+	return true
+}